@@ -0,0 +1,178 @@
+#ifndef CPU_TIME_H
+#define CPU_TIME_H
+
+#include "utils.h"
+#include "bpf_dbg.h"
+
+#define MAX_CPU_TIME_ENTRIES 10000
+
+// Enables the accounting of the on-CPU and off-CPU time of the requests.
+// To be injected from the user space during the eBPF program load & initialization
+volatile const u8 cpu_time_enabled = 0;
+
+// Format of the sched/sched_switch tracepoint, as described in
+// /sys/kernel/tracing/events/sched/sched_switch/format. It is defined here because
+// the Go programs don't include vmlinux.h
+struct sched_switch_args {
+    u64 common; // common fields of all the tracepoints
+    char prev_comm[16];
+    s32 prev_pid;
+    s32 prev_prio;
+    s64 prev_state;
+    char next_comm[16];
+    s32 next_pid;
+    s32 next_prio;
+};
+
+// CPU time of the thread, or goroutine, that serves an ongoing request
+typedef struct cpu_time {
+    u64 start_ns;       // start of the request, to tell it apart from previous requests
+    u64 last_switch_ns; // last time that the thread or goroutine was scheduled in or out
+    u64 on_cpu_ns;
+    u64 off_cpu_ns;
+    u8  on_cpu;
+} cpu_time_t;
+
+struct {
+    __uint(type, BPF_MAP_TYPE_LRU_HASH);
+    __type(key, u64); // key: thread ID, or pointer to the goroutine
+    __type(value, cpu_time_t);
+    __uint(max_entries, MAX_CPU_TIME_ENTRIES);
+    __uint(pinning, LIBBPF_PIN_BY_NAME);
+} request_cpu_time SEC(".maps");
+
+// Goroutine that runs on each thread (M) of a Go program
+struct {
+    __uint(type, BPF_MAP_TYPE_LRU_HASH);
+    __type(key, u32);   // key: thread ID
+    __type(value, u64); // value: pointer to the goroutine
+    __uint(max_entries, MAX_CPU_TIME_ENTRIES);
+    __uint(pinning, LIBBPF_PIN_BY_NAME);
+} running_goroutines SEC(".maps");
+
+// Thread (M) that runs each goroutine of a Go program. The running_goroutines entry of a thread
+// becomes stale when its goroutine is moved to another thread, so it is only valid if both
+// maps agree.
+struct {
+    __uint(type, BPF_MAP_TYPE_LRU_HASH);
+    __type(key, u64);   // key: pointer to the goroutine
+    __type(value, u32); // value: thread ID
+    __uint(max_entries, MAX_CPU_TIME_ENTRIES);
+    __uint(pinning, LIBBPF_PIN_BY_NAME);
+} goroutine_threads SEC(".maps");
+
+// Starts accounting the CPU time of a request that is served by the current thread, or
+// goroutine. If the request is already accounted (e.g. it is read in several chunks), the
+// accounting is not restarted.
+static __always_inline void cpu_time_start(u64 key, u64 start_ns) {
+    if (!cpu_time_enabled) {
+        return;
+    }
+    cpu_time_t *t = bpf_map_lookup_elem(&request_cpu_time, &key);
+    if (t && t->start_ns == start_ns) {
+        return;
+    }
+    cpu_time_t ct = {
+        .start_ns = start_ns,
+        .last_switch_ns = bpf_ktime_get_ns(),
+        .on_cpu = 1,
+    };
+    bpf_map_update_elem(&request_cpu_time, &key, &ct, BPF_ANY);
+}
+
+// Accounts the time since the last switch of the thread, or goroutine, as on-CPU or off-CPU
+static __always_inline void cpu_time_switch(u64 key, u8 on_cpu, u64 now) {
+    cpu_time_t *t = bpf_map_lookup_elem(&request_cpu_time, &key);
+    if (!t || t->on_cpu == on_cpu) {
+        return;
+    }
+    u64 elapsed = now - t->last_switch_ns;
+    if (t->on_cpu) {
+        t->on_cpu_ns += elapsed;
+    } else {
+        t->off_cpu_ns += elapsed;
+    }
+    t->on_cpu = on_cpu;
+    t->last_switch_ns = now;
+}
+
+// Stops accounting the CPU time of the request, and returns it. If the thread, or goroutine,
+// has started serving another request in the meantime (e.g. event loops that serve several
+// requests concurrently), the CPU time of the request is unknown and it is left as zero.
+static __always_inline void cpu_time_end(u64 key, u64 start_ns, u64 *on_cpu_ns, u64 *off_cpu_ns) {
+    if (!cpu_time_enabled) {
+        return;
+    }
+    cpu_time_t *t = bpf_map_lookup_elem(&request_cpu_time, &key);
+    if (!t || t->start_ns != start_ns) {
+        return;
+    }
+    cpu_time_switch(key, !t->on_cpu, bpf_ktime_get_ns());
+    *on_cpu_ns = t->on_cpu_ns;
+    *off_cpu_ns = t->off_cpu_ns;
+    bpf_dbg_printk("request cpu time on=%lld, off=%lld", t->on_cpu_ns, t->off_cpu_ns);
+    bpf_map_delete_elem(&request_cpu_time, &key);
+}
+
+// The CPU time of a thread is accounted to the goroutine that runs on it, in Go programs,
+// or to the thread itself otherwise
+static __always_inline u64 cpu_time_key(u32 tid) {
+    u64 *goroutine = bpf_map_lookup_elem(&running_goroutines, &tid);
+    if (goroutine) {
+        u64 g = *goroutine;
+        u32 *g_tid = bpf_map_lookup_elem(&goroutine_threads, &g);
+        if (g_tid && *g_tid == tid) {
+            return g;
+        }
+    }
+    return tid;
+}
+
+static __always_inline void cpu_time_sched_switch(struct sched_switch_args *ctx) {
+    u64 now = bpf_ktime_get_ns();
+    // PID 0 is the idle task of each CPU
+    if (ctx->prev_pid) {
+        cpu_time_switch(cpu_time_key(ctx->prev_pid), 0, now);
+    }
+    if (ctx->next_pid) {
+        cpu_time_switch(cpu_time_key(ctx->next_pid), 1, now);
+    }
+}
+
+// The Go scheduler runs the goroutine on the current thread, so the goroutine that was
+// running on it before is switched out
+static __always_inline void cpu_time_run_goroutine(u32 tid, u64 goroutine) {
+    if (!cpu_time_enabled) {
+        return;
+    }
+    u64 now = bpf_ktime_get_ns();
+    u64 prev = cpu_time_key(tid);
+    if (prev == goroutine) {
+        return;
+    }
+    // a goroutine that has been moved to another thread isn't switched out, as it still runs there
+    if (prev != tid) {
+        cpu_time_switch(prev, 0, now);
+    }
+    cpu_time_switch(goroutine, 1, now);
+    bpf_map_update_elem(&running_goroutines, &tid, &goroutine, BPF_ANY);
+    bpf_map_update_elem(&goroutine_threads, &goroutine, &tid, BPF_ANY);
+}
+
+// The goroutine that runs on the current thread stops running (e.g. it's parked waiting for
+// I/O, a lock or a channel, or it exits), so it doesn't run on the thread anymore. Otherwise, its
+// time would be accounted from the scheduling of the thread, even if it runs other goroutines,
+// or none, until the Go scheduler runs another goroutine on it.
+static __always_inline void cpu_time_leave_goroutine(u32 tid, u64 goroutine) {
+    if (!cpu_time_enabled) {
+        return;
+    }
+    if (cpu_time_key(tid) != goroutine) {
+        return;
+    }
+    cpu_time_switch(goroutine, 0, bpf_ktime_get_ns());
+    bpf_map_delete_elem(&running_goroutines, &tid);
+    bpf_map_delete_elem(&goroutine_threads, &goroutine);
+}
+
+#endif
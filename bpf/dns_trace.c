@@ -0,0 +1,201 @@
+#include "vmlinux.h"
+#include "common.h"
+#include "bpf_helpers.h"
+#include "bpf_tracing.h"
+#include "bpf_core_read.h"
+#include "bpf_endian.h"
+#include "bpf_dbg.h"
+#include "pid.h"
+#include "sockaddr.h"
+#include "msghdr.h"
+#include "ringbuf.h"
+
+#define DNS_PORT 53
+#define DNS_HEADER_LEN 12
+#define DNS_MAX_MESSAGE_LEN 512 // max size of a DNS message over UDP, without EDNS
+#define DNS_QR_RESPONSE 0x80    // QR bit of the first flags byte
+#define MAX_ONGOING_DNS_QUERIES 10000
+
+char __license[] SEC("license") = "Dual MIT/GPL";
+
+typedef struct dns_header {
+    u16 id;
+    u8  flags1; // QR, opcode, AA, TC, RD
+    u8  flags2; // RA, Z, RCODE
+    u16 qdcount;
+    u16 ancount;
+    u16 nscount;
+    u16 arcount;
+} dns_header_t;
+
+// The queries are matched with their responses by the socket they are sent from and
+// the ID of the DNS message
+typedef struct dns_query_key {
+    u64 sk;
+    u16 id;
+} dns_query_key_t;
+
+typedef struct dns_query {
+    connection_info_t conn_info;
+    u64 start_monotime_ns;
+} dns_query_t;
+
+// Here we keep the information that is sent on the ring buffer. The response
+// contains both the question and the answers, so userspace parses them from buf.
+typedef struct dns_event {
+    connection_info_t conn_info;
+    u64 start_monotime_ns;
+    u64 end_monotime_ns;
+    u32 pid;
+    u32 len; // number of valid bytes in buf
+    u8  buf[DNS_MAX_MESSAGE_LEN];
+} dns_event_t;
+
+// Force emitting struct dns_event into the ELF for automatic creation of Golang struct
+const dns_event_t *unused_dns_event __attribute__((unused));
+
+// Temporary tracking of the udp_recvmsg arguments, by PID/TID
+typedef struct dns_recv_args {
+    u64 sk;
+    u64 buf;
+} dns_recv_args_t;
+
+struct {
+    __uint(type, BPF_MAP_TYPE_HASH);
+    __type(key, u64);
+    __type(value, dns_recv_args_t);
+    __uint(max_entries, MAX_ONGOING_DNS_QUERIES);
+} active_dns_recv_args SEC(".maps");
+
+struct {
+    __uint(type, BPF_MAP_TYPE_LRU_HASH);
+    __type(key, dns_query_key_t);
+    __type(value, dns_query_t);
+    __uint(max_entries, MAX_ONGOING_DNS_QUERIES);
+} ongoing_dns_queries SEC(".maps");
+
+// dns_dest_port returns the destination port of the UDP message, either from the socket, if
+// it is connected, or from the destination address of the message
+static __always_inline u16 dns_dest_port(connection_info_t *info, struct msghdr *msg) {
+    if (info->d_port) {
+        return info->d_port;
+    }
+    void *name = BPF_CORE_READ(msg, msg_name);
+    if (!name) {
+        return 0;
+    }
+    // the port is at the same offset in sockaddr_in and sockaddr_in6
+    u16 port = 0;
+    bpf_probe_read_kernel(&port, sizeof(port), name + offsetof(struct sockaddr_in, sin_port));
+    return bpf_ntohs(port);
+}
+
+SEC("kprobe/udp_sendmsg")
+int BPF_KPROBE(kprobe_udp_sendmsg, struct sock *sk, struct msghdr *msg, size_t len) {
+    u64 id = bpf_get_current_pid_tgid();
+    if (!valid_pid(id) || len < DNS_HEADER_LEN) {
+        return 0;
+    }
+
+    dns_query_t query = {};
+    if (!parse_sock_info(sk, &query.conn_info)) {
+        return 0;
+    }
+    if (dns_dest_port(&query.conn_info, msg) != DNS_PORT) {
+        return 0;
+    }
+
+    void *buf = read_msghdr_buf(msg);
+    dns_header_t hdr = {};
+    if (!buf || bpf_probe_read_user(&hdr, sizeof(hdr), buf)) {
+        return 0;
+    }
+    if (hdr.flags1 & DNS_QR_RESPONSE) {
+        return 0;
+    }
+
+    bpf_dbg_printk("=== udp_sendmsg DNS query id=%d, sk=%llx, dns_id=%d ===", id, sk, hdr.id);
+
+    // the padding of the key must be zeroed
+    dns_query_key_t key;
+    __builtin_memset(&key, 0, sizeof(key));
+    key.sk = (u64)sk;
+    key.id = hdr.id;
+    query.conn_info.d_port = DNS_PORT;
+    query.start_monotime_ns = bpf_ktime_get_ns();
+    bpf_map_update_elem(&ongoing_dns_queries, &key, &query, BPF_ANY);
+    return 0;
+}
+
+// The received message is copied into the user buffer when udp_recvmsg returns, so the
+// buffer is remembered here and read from the kretprobe
+SEC("kprobe/udp_recvmsg")
+int BPF_KPROBE(kprobe_udp_recvmsg, struct sock *sk, struct msghdr *msg) {
+    u64 id = bpf_get_current_pid_tgid();
+    if (!valid_pid(id)) {
+        return 0;
+    }
+
+    void *buf = read_msghdr_buf(msg);
+    if (!buf) {
+        return 0;
+    }
+    dns_recv_args_t args = {
+        .sk = (u64)sk,
+        .buf = (u64)buf,
+    };
+    bpf_map_update_elem(&active_dns_recv_args, &id, &args, BPF_ANY);
+    return 0;
+}
+
+SEC("kretprobe/udp_recvmsg")
+int BPF_KRETPROBE(kretprobe_udp_recvmsg, int copied) {
+    u64 id = bpf_get_current_pid_tgid();
+    dns_recv_args_t *args = bpf_map_lookup_elem(&active_dns_recv_args, &id);
+    if (!args) {
+        return 0;
+    }
+    u64 sk = args->sk;
+    void *buf = (void *)args->buf;
+    bpf_map_delete_elem(&active_dns_recv_args, &id);
+
+    if (copied < DNS_HEADER_LEN) {
+        return 0;
+    }
+    dns_header_t hdr = {};
+    if (bpf_probe_read_user(&hdr, sizeof(hdr), buf) || !(hdr.flags1 & DNS_QR_RESPONSE)) {
+        return 0;
+    }
+
+    dns_query_key_t key;
+    __builtin_memset(&key, 0, sizeof(key));
+    key.sk = sk;
+    key.id = hdr.id;
+    // only the responses to the DNS queries that were seen before are submitted
+    dns_query_t *query = bpf_map_lookup_elem(&ongoing_dns_queries, &key);
+    if (!query) {
+        return 0;
+    }
+
+    bpf_dbg_printk("=== udp_recvmsg DNS response id=%d, sk=%llx, dns_id=%d ===", id, sk, hdr.id);
+
+    dns_event_t *event = ringbuf_reserve(sizeof(dns_event_t));
+    if (event) {
+        __builtin_memcpy(&event->conn_info, &query->conn_info, sizeof(connection_info_t));
+        event->start_monotime_ns = query->start_monotime_ns;
+        event->end_monotime_ns = bpf_ktime_get_ns();
+        event->pid = pid_from_pid_tgid(id);
+        u64 len = copied;
+        if (len > DNS_MAX_MESSAGE_LEN) {
+            len = DNS_MAX_MESSAGE_LEN;
+        }
+        // otherwise the compiler passes the register of copied to the helper, whose bounds
+        // aren't known by the verifier
+        asm volatile("" : "+r"(len));
+        event->len = len;
+        bpf_probe_read_user(event->buf, len, buf);
+        bpf_ringbuf_submit(event, get_flags());
+    }
+    bpf_map_delete_elem(&ongoing_dns_queries, &key);
+    return 0;
+}
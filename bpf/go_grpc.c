@@ -89,7 +89,7 @@ int uprobe_server_handleStream_return(struct pt_regs *ctx) {
     void *stream_ptr = GO_PARAM4(&(invocation->regs));
     bpf_dbg_printk("stream_ptr %lx, method pos %lx", stream_ptr, grpc_stream_method_ptr_pos);
 
-    http_request_trace *trace = bpf_ringbuf_reserve(&events, sizeof(http_request_trace), 0);
+    http_request_trace *trace = ringbuf_reserve(sizeof(http_request_trace));
     if (!trace) {
         bpf_dbg_printk("can't reserve space in the ringbuffer");
         return 0;
@@ -233,7 +233,7 @@ int uprobe_ClientConn_Invoke_return(struct pt_regs *ctx) {
         return 0;
     }
 
-    http_request_trace *trace = bpf_ringbuf_reserve(&events, sizeof(http_request_trace), 0);
+    http_request_trace *trace = ringbuf_reserve(sizeof(http_request_trace));
     if (!trace) {
         bpf_dbg_printk("can't reserve space in the ringbuffer");
         return 0;
@@ -0,0 +1,160 @@
+#ifndef GO_HEADERS_H
+#define GO_HEADERS_H
+
+#include "utils.h"
+#include "bpf_helpers.h"
+#include "bpf_dbg.h"
+#include "ringbuf.h"
+
+// It requires go_traceparent.h to be included before, for the Go map definitions
+
+// These need to line up with the definitions in pkg/internal/ebpf/common/headers.go
+#define MAX_CAPTURED_HEADERS 8 // must be power of 2
+#define HEADER_NAME_LEN 32
+#define HEADER_VALUE_LEN 64
+
+// These need to line up with the headerKind* Go identifiers
+#define HEADER_KIND_REQUEST  1
+#define HEADER_KIND_RESPONSE 2
+
+// It's not a span: the record carries the captured headers of the span with the same id and
+// start time, which is sent right after it. It needs to line up with the eventTypeHTTPHeaders Go identifier.
+#define EVENT_HTTP_HEADERS 16
+
+// If set, the headers in the captured_headers map are sent to user space
+volatile const u8 capture_headers = 0;
+
+typedef struct header_key {
+    u8 kind;
+    u8 name[HEADER_NAME_LEN]; // lowercase
+} header_key_t;
+
+typedef struct http_headers {
+    u8  type; // Must be first, with the same position as the type of the http_request_trace
+    // the goroutine id is shared by the sibling requests of the same parent goroutine, and by the
+    // successive requests of a connection, so the start time is required to identify the span
+    u64 id;
+    u64 start_monotime_ns;
+    u8  values[MAX_CAPTURED_HEADERS][HEADER_VALUE_LEN];
+} __attribute__((packed)) http_headers_t;
+
+// Force emitting struct http_headers into the ELF for automatic creation of Golang struct
+const http_headers_t *unused_http_headers __attribute__((unused));
+
+// Position of each of the captured headers in the values of the http_headers_t record,
+// as populated from user space
+struct {
+    __uint(type, BPF_MAP_TYPE_HASH);
+    __type(key, header_key_t);
+    __type(value, u8);
+    __uint(max_entries, MAX_CAPTURED_HEADERS);
+} captured_headers SEC(".maps");
+
+// Temporary storage for the header keys
+struct {
+    __uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+    __type(key, int);
+    __type(value, header_key_t);
+    __uint(max_entries, 1);
+} header_key_mem SEC(".maps");
+
+// read_captured_headers copies into the record the values of the captured headers of
+// the http.Header map whose address is stored in headers_ptr_ptr
+static __always_inline void read_captured_headers(void *headers_ptr_ptr, u8 kind, http_headers_t *out) {
+    void *headers_ptr = 0;
+    if (bpf_probe_read(&headers_ptr, sizeof(headers_ptr), headers_ptr_ptr) || !headers_ptr) {
+        return;
+    }
+    u64 headers_count = 0;
+    if (bpf_probe_read(&headers_count, sizeof(headers_count), headers_ptr) || !headers_count) {
+        return;
+    }
+    unsigned char log_2_bucket_count = 0;
+    bpf_probe_read(&log_2_bucket_count, sizeof(log_2_bucket_count), headers_ptr + OFFSET_OF_GO_RUNTIME_HMAP_FIELD_B);
+    u64 bucket_count = 1 << log_2_bucket_count;
+    void *header_buckets = 0;
+    if (bpf_probe_read(&header_buckets, sizeof(header_buckets), headers_ptr + OFFSET_OF_GO_RUNTIME_HMAP_FIELD_BUCKETS)) {
+        return;
+    }
+
+    int zero = 0;
+    struct map_bucket *map_value = bpf_map_lookup_elem(&golang_mapbucket_storage_map, &zero);
+    header_key_t *key = bpf_map_lookup_elem(&header_key_mem, &zero);
+    if (!map_value || !key) {
+        return;
+    }
+
+    for (u64 j = 0; j < MAX_BUCKETS; j++) {
+        if (j >= bucket_count) {
+            break;
+        }
+        if (bpf_probe_read(map_value, sizeof(struct map_bucket), header_buckets + (j * sizeof(struct map_bucket)))) {
+            continue;
+        }
+        for (u64 i = 0; i < 8; i++) {
+            if (map_value->tophash[i] == 0) {
+                continue;
+            }
+            s64 name_len = map_value->keys[i].len;
+            if (name_len <= 0 || name_len > HEADER_NAME_LEN) {
+                continue;
+            }
+            __builtin_memset(key, 0, sizeof(header_key_t));
+            key->kind = kind;
+            bpf_probe_read(key->name, name_len, map_value->keys[i].str);
+            // Go canonicalizes the header names, but not the gRPC metadata
+            for (int c = 0; c < HEADER_NAME_LEN; c++) {
+                if (key->name[c] >= 'A' && key->name[c] <= 'Z') {
+                    key->name[c] += 'a' - 'A';
+                }
+            }
+            u8 *slot = bpf_map_lookup_elem(&captured_headers, key);
+            if (!slot) {
+                continue;
+            }
+            // only the first value of each header is captured
+            struct go_string value = {0};
+            if (bpf_probe_read(&value, sizeof(value), map_value->values[i].array)) {
+                continue;
+            }
+            s64 value_len = value.len;
+            if (value_len <= 0) {
+                continue;
+            }
+            if (value_len > HEADER_VALUE_LEN - 1) {
+                value_len = HEADER_VALUE_LEN - 1;
+            }
+            bpf_probe_read(out->values[*slot & (MAX_CAPTURED_HEADERS - 1)], value_len, value.str);
+        }
+    }
+}
+
+// send_captured_headers sends the captured request and response headers of the span with the given
+// id and start time. It must be invoked once the span has been sampled with ringbuf_sample, and before
+// reserving the space for the span in the ring buffer, so the headers are read first by user space.
+static __always_inline void send_captured_headers(u64 id, u64 start_monotime_ns,
+                                                  void *req_headers_ptr_ptr, void *resp_headers_ptr_ptr) {
+    if (!capture_headers) {
+        return;
+    }
+    http_headers_t *headers = ringbuf_reserve_sampled(sizeof(http_headers_t));
+    if (!headers) {
+        return;
+    }
+    __builtin_memset(headers->values, 0, sizeof(headers->values));
+    headers->type = EVENT_HTTP_HEADERS;
+    headers->id = id;
+    headers->start_monotime_ns = start_monotime_ns;
+
+    if (req_headers_ptr_ptr) {
+        read_captured_headers(req_headers_ptr_ptr, HEADER_KIND_REQUEST, headers);
+    }
+    if (resp_headers_ptr_ptr) {
+        read_captured_headers(resp_headers_ptr_ptr, HEADER_KIND_RESPONSE, headers);
+    }
+
+    bpf_dbg_printk("sending captured headers for id %llx, start %lld", id, start_monotime_ns);
+    bpf_ringbuf_submit(headers, get_flags());
+}
+
+#endif
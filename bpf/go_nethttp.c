@@ -17,6 +17,8 @@
 #include "go_common.h"
 #include "go_nethttp.h"
 #include "go_traceparent.h"
+#include "go_headers.h"
+#include "cpu_time.h"
 
 struct {
     __uint(type, BPF_MAP_TYPE_HASH);
@@ -25,6 +27,28 @@ struct {
     __uint(max_entries, MAX_CONCURRENT_REQUESTS);
 } ongoing_http_client_requests SEC(".maps");
 
+// Server trace, waiting for the handler to return to read the size of the response
+typedef struct server_http_trace {
+    u64 resp_ptr; // pointer to the http.response
+    u64 req_ptr;  // pointer to the http.Request
+    http_request_trace trace;
+} server_http_trace_t;
+
+struct {
+    __uint(type, BPF_MAP_TYPE_LRU_HASH);
+    __type(key, void *); // key: pointer to the goroutine that invoked ServeHTTP
+    __type(value, server_http_trace_t);
+    __uint(max_entries, MAX_CONCURRENT_REQUESTS);
+} ongoing_server_traces SEC(".maps");
+
+// Temporary storage for the server traces, which are too big for the stack
+struct {
+    __uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+    __type(key, int);
+    __type(value, server_http_trace_t);
+    __uint(max_entries, 1);
+} server_trace_mem SEC(".maps");
+
 /* HTTP Server */
 
 // This instrumentation attaches uprobe to the following function:
@@ -46,6 +70,10 @@ int uprobe_ServeHTTP(struct pt_regs *ctx) {
         bpf_dbg_printk("can't update map element");
     }
 
+    // The CPU time of the goroutine is accounted to the request until the handler returns
+    cpu_time_run_goroutine((u32)bpf_get_current_pid_tgid(), (u64)goroutine_addr);
+    cpu_time_start((u64)goroutine_addr, invocation.start_monotime_ns);
+
     return 0;
 }
 
@@ -73,12 +101,70 @@ int uprobe_startBackgroundRead(struct pt_regs *ctx) {
     return 0;
 }
 
+// Reads the attributes of a server trace from the http.Request. Returns false if they can't be read.
+static __always_inline bool read_server_request(void *req_ptr, http_request_trace *trace) {
+    // Get method from Request.Method
+    if (!read_go_str("method", req_ptr, method_ptr_pos, &trace->method, sizeof(trace->method))) {
+        bpf_printk("can't read http Request.Method");
+        return false;
+    }
+
+    // Get the remote peer information from Request.RemoteAddr
+    if (!read_go_str("remote_addr", req_ptr, remoteaddr_ptr_pos, &trace->remote_addr, sizeof(trace->remote_addr))) {
+        bpf_printk("can't read http Request.RemoteAddr");
+        return false;
+    }
+
+    // Get the host information the remote supplied
+    if (!read_go_str("host", req_ptr, host_ptr_pos, &trace->host, sizeof(trace->host))) {
+        bpf_printk("can't read http Request.Host");
+        return false;
+    }
+
+    // Get path from Request.URL
+    void *url_ptr = 0;
+    bpf_probe_read(&url_ptr, sizeof(url_ptr), (void *)(req_ptr + url_ptr_pos));
+
+    if (!url_ptr || !read_go_str("path", url_ptr, path_ptr_pos, &trace->path, sizeof(trace->path))) {
+        bpf_printk("can't read http Request.URL.Path");
+        return false;
+    }
+
+    // Request.ContentLength is -1 if the size of the request body is unknown
+    bpf_probe_read(&trace->content_length, sizeof(trace->content_length), (void *)(req_ptr + content_length_ptr_pos));
+
+    // Get traceparent from the Request.Header
+    void *traceparent_ptr = extract_traceparent_from_req_headers((void*)(req_ptr + req_header_ptr_pos));
+    if (traceparent_ptr != NULL) {
+        long res = bpf_probe_read(trace->traceparent, sizeof(trace->traceparent), traceparent_ptr);
+        if (res < 0) {
+            bpf_printk("can't copy traceparent header");
+            return false;
+        }
+    }
+    return true;
+}
+
+// Sets the start times of the server trace of the passed goroutine
+static __always_inline void set_server_start(void *goroutine_addr, func_invocation *invocation, http_request_trace *trace) {
+    trace->start_monotime_ns = invocation->start_monotime_ns;
+    goroutine_metadata *g_metadata = bpf_map_lookup_elem(&ongoing_goroutines, &goroutine_addr);
+    if (g_metadata) {
+        trace->go_start_monotime_ns = g_metadata->timestamp;
+        bpf_map_delete_elem(&ongoing_goroutines, &goroutine_addr);
+    } else {
+        trace->go_start_monotime_ns = invocation->start_monotime_ns;
+    }
+}
+
 SEC("uprobe/WriteHeader")
 int uprobe_WriteHeader(struct pt_regs *ctx) {
     bpf_dbg_printk("=== uprobe/WriteHeader === ");
     void *goroutine_addr = GOROUTINE_PTR(ctx);
     bpf_dbg_printk("goroutine_addr %lx", goroutine_addr);
 
+    // the goroutine that invoked ServeHTTP, which sends the trace when it returns
+    void *server_go = goroutine_addr;
     func_invocation *invocation =
         bpf_map_lookup_elem(&ongoing_server_requests, &goroutine_addr);
     bpf_map_delete_elem(&ongoing_server_requests, &goroutine_addr);
@@ -88,6 +174,7 @@ int uprobe_WriteHeader(struct pt_regs *ctx) {
             bpf_dbg_printk("found parent goroutine for header [%llx]", parent_go);
             invocation = bpf_map_lookup_elem(&ongoing_server_requests, &parent_go);
             bpf_map_delete_elem(&ongoing_server_requests, &parent_go);
+            server_go = parent_go;
         }
         if (!invocation) {
             bpf_dbg_printk("can't read http invocation metadata");
@@ -95,26 +182,22 @@ int uprobe_WriteHeader(struct pt_regs *ctx) {
         }
     }
 
-    http_request_trace *trace = bpf_ringbuf_reserve(&events, sizeof(http_request_trace), 0);
-    if (!trace) {
-        bpf_dbg_printk("can't reserve space in the ringbuffer");
+    int zero = 0;
+    server_http_trace_t *pending = bpf_map_lookup_elem(&server_trace_mem, &zero);
+    if (!pending) {
         return 0;
     }
+    __builtin_memset(pending, 0, sizeof(server_http_trace_t));
+    http_request_trace *trace = &pending->trace;
+
     trace->type = EVENT_HTTP_REQUEST;
     trace->id = (u64)goroutine_addr;
-    trace->start_monotime_ns = invocation->start_monotime_ns;
     trace->end_monotime_ns = bpf_ktime_get_ns();
-
-    goroutine_metadata *g_metadata = bpf_map_lookup_elem(&ongoing_goroutines, &goroutine_addr);
-    if (g_metadata) {
-        trace->go_start_monotime_ns = g_metadata->timestamp;
-        bpf_map_delete_elem(&ongoing_goroutines, &goroutine_addr);
-    } else {
-        trace->go_start_monotime_ns = invocation->start_monotime_ns;
-    }
+    set_server_start(goroutine_addr, invocation, trace);
 
     // Read the response argument
     void *resp_ptr = GO_PARAM1(ctx);
+    pending->resp_ptr = (u64)resp_ptr;
 
     // Get request struct
     void *req_ptr = 0;
@@ -122,58 +205,88 @@ int uprobe_WriteHeader(struct pt_regs *ctx) {
 
     if (!req_ptr) {
         bpf_printk("can't find req inside the response value");
-        bpf_ringbuf_discard(trace, 0);
         return 0;
     }
+    pending->req_ptr = (u64)req_ptr;
 
-    // Get method from Request.Method
-    if (!read_go_str("method", req_ptr, method_ptr_pos, &trace->method, sizeof(trace->method))) {
-        bpf_printk("can't read http Request.Method");
-        bpf_ringbuf_discard(trace, 0);
+    if (!read_server_request(req_ptr, trace)) {
         return 0;
     }
 
-    // Get the remote peer information from Request.RemoteAddr
-    if (!read_go_str("remote_addr", req_ptr, remoteaddr_ptr_pos, &trace->remote_addr, sizeof(trace->remote_addr))) {
-        bpf_printk("can't read http Request.RemoteAddr");
-        bpf_ringbuf_discard(trace, 0);
-        return 0;
-    }
+    trace->status = (u16)(((u64)GO_PARAM2(ctx)) & 0x0ffff);
 
-    // Get the host information the remote supplied
-    if (!read_go_str("host", req_ptr, host_ptr_pos, &trace->host, sizeof(trace->host))) {
-        bpf_printk("can't read http Request.Host");
-        bpf_ringbuf_discard(trace, 0);
-        return 0;
+    // the trace is sent when the handler returns, once the response body has been written
+    if (bpf_map_update_elem(&ongoing_server_traces, &server_go, pending, BPF_ANY)) {
+        bpf_dbg_printk("can't update server trace map element");
     }
 
-    // Get path from Request.URL
-    void *url_ptr = 0;
-    bpf_probe_read(&url_ptr, sizeof(url_ptr), (void *)(req_ptr + url_ptr_pos));
-
-    if (!url_ptr || !read_go_str("path", url_ptr, path_ptr_pos, &trace->path, sizeof(trace->path))) {
-        bpf_printk("can't read http Request.URL.Path");
-        bpf_ringbuf_discard(trace, 0);
-        return 0;
-    }
+    return 0;
+}
 
-    bpf_probe_read(&trace->content_length, sizeof(trace->content_length), (void *)(req_ptr + content_length_ptr_pos));
+// This instrumentation attaches to the return of the same functions as uprobe_ServeHTTP
+SEC("uprobe/ServeHTTP_return")
+int uprobe_ServeHTTPReturns(struct pt_regs *ctx) {
+    bpf_dbg_printk("=== uprobe/ServeHTTP_return === ");
+    void *goroutine_addr = GOROUTINE_PTR(ctx);
+    bpf_dbg_printk("goroutine_addr %lx", goroutine_addr);
 
-    // Get traceparent from the Request.Header
-    void *traceparent_ptr = extract_traceparent_from_req_headers((void*)(req_ptr + req_header_ptr_pos));
-    if (traceparent_ptr != NULL) {
-        long res = bpf_probe_read(trace->traceparent, sizeof(trace->traceparent), traceparent_ptr);
-        if (res < 0) {
-            bpf_printk("can't copy traceparent header");
-            bpf_ringbuf_discard(trace, 0);
+    server_http_trace_t *pending = bpf_map_lookup_elem(&ongoing_server_traces, &goroutine_addr);
+    if (pending) {
+        void *resp_ptr = (void *)pending->resp_ptr;
+        // Get the number of body bytes written by the handler from response.written
+        bpf_probe_read(&pending->trace.response_length, sizeof(pending->trace.response_length),
+                       (void *)(resp_ptr + resp_written_pos));
+    } else {
+        // WriteHeader wasn't invoked, so the handler didn't write any response and net/http
+        // replies with the 200 status after the handler returns. If the invocation is missing,
+        // this is a nested handler of a trace that has been already sent.
+        func_invocation *invocation = bpf_map_lookup_elem(&ongoing_server_requests, &goroutine_addr);
+        if (!invocation) {
+            return 0;
+        }
+        int zero = 0;
+        pending = bpf_map_lookup_elem(&server_trace_mem, &zero);
+        if (!pending) {
+            bpf_map_delete_elem(&ongoing_server_requests, &goroutine_addr);
             return 0;
         }
+        __builtin_memset(pending, 0, sizeof(server_http_trace_t));
+        http_request_trace *trace = &pending->trace;
+        trace->type = EVENT_HTTP_REQUEST;
+        trace->id = (u64)goroutine_addr;
+        trace->end_monotime_ns = bpf_ktime_get_ns();
+        set_server_start(goroutine_addr, invocation, trace);
+        trace->status = 200;
+        // the ResponseWriter might be wrapped by the handler, but the Request is the ServeHTTP argument
+        void *req_ptr = GO_PARAM4(&(invocation->regs));
+        bpf_map_delete_elem(&ongoing_server_requests, &goroutine_addr);
+        if (!req_ptr || !read_server_request(req_ptr, trace)) {
+            return 0;
+        }
+        pending->req_ptr = (u64)req_ptr;
     }
 
-    trace->status = (u16)(((u64)GO_PARAM2(ctx)) & 0x0ffff);
+    // the trace is packed, so its fields can't be passed by reference
+    u64 on_cpu_ns = 0;
+    u64 off_cpu_ns = 0;
+    cpu_time_end((u64)goroutine_addr, pending->trace.start_monotime_ns, &on_cpu_ns, &off_cpu_ns);
+    pending->trace.on_cpu_ns = on_cpu_ns;
+    pending->trace.off_cpu_ns = off_cpu_ns;
 
     // submit the completed trace via ringbuffer
-    bpf_ringbuf_submit(trace, get_flags());
+    if (ringbuf_sample()) {
+        // Headers of the Request and the headers the handler has set in the response, if any
+        void *resp_ptr = (void *)pending->resp_ptr;
+        send_captured_headers(pending->trace.id, pending->trace.start_monotime_ns,
+                              (void *)(pending->req_ptr + req_header_ptr_pos),
+                              resp_ptr ? (void *)(resp_ptr + handler_header_ptr_pos) : NULL);
+        if (bpf_ringbuf_output(&events, &pending->trace, sizeof(http_request_trace), get_flags())) {
+            bpf_dbg_printk("can't send the trace to the ringbuffer");
+            ringbuf_stat_add(RINGBUF_STAT_DROPPED, 1);
+        }
+    }
+
+    bpf_map_delete_elem(&ongoing_server_traces, &goroutine_addr);
 
     return 0;
 }
@@ -215,25 +328,34 @@ int uprobe_roundTripReturn(struct pt_regs *ctx) {
         return 0;
     }
 
-    http_request_trace *trace = bpf_ringbuf_reserve(&events, sizeof(http_request_trace), 0);
+    // Read arguments from the original set of registers
+
+    // Get request/response struct
+    void *req_ptr = GO_PARAM2(&(invocation->regs));
+    void *resp_ptr = (void *)GO_PARAM1(ctx);
+    u64 id = find_parent_goroutine(goroutine_addr);
+
+    if (!ringbuf_sample()) {
+        return 0;
+    }
+
+    // the response is nil if the round trip failed
+    send_captured_headers(id, invocation->start_monotime_ns, (void *)(req_ptr + req_header_ptr_pos),
+                          resp_ptr ? (void *)(resp_ptr + resp_header_ptr_pos) : NULL);
+
+    http_request_trace *trace = ringbuf_reserve_sampled(sizeof(http_request_trace));
     if (!trace) {
         bpf_dbg_printk("can't reserve space in the ringbuffer");
         return 0;
     }
 
-    trace->id = find_parent_goroutine(goroutine_addr);
+    trace->id = id;
 
     trace->type = EVENT_HTTP_CLIENT;
     trace->start_monotime_ns = invocation->start_monotime_ns;
     trace->go_start_monotime_ns = invocation->start_monotime_ns;
     trace->end_monotime_ns = bpf_ktime_get_ns();
 
-    // Read arguments from the original set of registers
-
-    // Get request/response struct
-    void *req_ptr = GO_PARAM2(&(invocation->regs));
-    void *resp_ptr = (void *)GO_PARAM1(ctx);
-
     // Get method from Request.Method
     if (!read_go_str("method", req_ptr, method_ptr_pos, &trace->method, sizeof(trace->method))) {
         bpf_printk("can't read http Request.Method");
@@ -273,6 +395,9 @@ int uprobe_roundTripReturn(struct pt_regs *ctx) {
 
     bpf_probe_read(&trace->status, sizeof(trace->status), (void *)(resp_ptr + status_code_ptr_pos));
 
+    // Response.ContentLength is -1 if the size of the response body is unknown
+    bpf_probe_read(&trace->response_length, sizeof(trace->response_length), (void *)(resp_ptr + resp_content_length_ptr_pos));
+
     bpf_dbg_printk("status %d, offset %d, resp_ptr %lx", trace->status, status_code_ptr_pos, (u64)resp_ptr);
 
     // submit the completed trace via ringbuffer
@@ -27,5 +27,9 @@ volatile const u64 host_ptr_pos;
 volatile const u64 content_length_ptr_pos;
 volatile const u64 resp_req_pos;
 volatile const u64 req_header_ptr_pos;
+volatile const u64 handler_header_ptr_pos;
+volatile const u64 resp_header_ptr_pos;
+volatile const u64 resp_written_pos;
+volatile const u64 resp_content_length_ptr_pos;
 
 #endif
@@ -13,6 +13,7 @@
 #include "utils.h"
 #include "bpf_dbg.h"
 #include "go_common.h"
+#include "cpu_time.h"
 
 SEC("uprobe/runtime_newproc1")
 int uprobe_proc_newproc1(struct pt_regs *ctx) {
@@ -78,6 +79,42 @@ int uprobe_proc_goexit1(struct pt_regs *ctx) {
     // We also clean-up ongoing_server_requests so that we can handle hijacked requests, where the ServeHTTP
     // finishes, but we never call WriteHeader to clean-up the ongoing requests
     bpf_map_delete_elem(&ongoing_server_requests, &goroutine_addr);
+    bpf_map_delete_elem(&request_cpu_time, &goroutine_addr);
+    cpu_time_leave_goroutine((u32)bpf_get_current_pid_tgid(), (u64)goroutine_addr);
 
     return 0;
 }
+
+// func execute(gp *g, inheritTime bool)
+// The scheduler runs the goroutine gp on the current thread (M). We track which goroutine
+// runs on each thread to account the CPU time of the threads to the goroutines that serve
+// the ongoing requests.
+SEC("uprobe/runtime_execute")
+int uprobe_runtime_execute(struct pt_regs *ctx) {
+    void *goroutine_addr = GO_PARAM1(ctx);
+    bpf_dbg_printk("=== uprobe/runtime execute goroutine_addr %lx === ", goroutine_addr);
+
+    cpu_time_run_goroutine((u32)bpf_get_current_pid_tgid(), (u64)goroutine_addr);
+
+    return 0;
+}
+
+// func gopark(unlockf func(*g, unsafe.Pointer) bool, lock unsafe.Pointer, reason waitReason, traceReason traceBlockReason, traceskip int)
+// The current goroutine is parked, so it leaves the thread (M) until it's made ready again and
+// the scheduler runs it, maybe on another thread.
+SEC("uprobe/runtime_gopark")
+int uprobe_runtime_gopark(struct pt_regs *ctx) {
+    void *goroutine_addr = GOROUTINE_PTR(ctx);
+    bpf_dbg_printk("=== uprobe/runtime gopark goroutine_addr %lx === ", goroutine_addr);
+
+    cpu_time_leave_goroutine((u32)bpf_get_current_pid_tgid(), (u64)goroutine_addr);
+
+    return 0;
+}
+
+// The threads running the goroutines are also scheduled in and out by the kernel
+SEC("tracepoint/sched/sched_switch")
+int tracepoint_sched_switch(struct sched_switch_args *ctx) {
+    cpu_time_sched_switch(ctx);
+    return 0;
+}
@@ -58,7 +58,7 @@ int uprobe_queryDCReturn(struct pt_regs *ctx) {
     }
     bpf_map_delete_elem(&ongoing_sql_queries, &goroutine_addr);
 
-    http_request_trace *trace = bpf_ringbuf_reserve(&events, sizeof(http_request_trace), 0);
+    http_request_trace *trace = ringbuf_reserve(sizeof(http_request_trace));
     if (trace) {
         trace->type = EVENT_SQL_CLIENT;
         trace->id = (u64)goroutine_addr;
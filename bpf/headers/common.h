@@ -18,6 +18,7 @@
 #define __VMLINUX_H__
 
 #define BPF_RB_AVAIL_DATA   0
+#define BPF_RB_RING_SIZE    1
 #define BPF_RB_NO_WAKEUP    1
 #define BPF_RB_FORCE_WAKEUP 2
 
@@ -0,0 +1,22 @@
+#ifndef HISTOGRAM_H
+#define HISTOGRAM_H
+
+#include "common.h"
+
+#define MAX_HISTOGRAM_BOUNDS 16
+
+// Increments the bucket of the histogram where the value falls. The values beyond the last
+// bound are accounted in the extra bucket at index MAX_HISTOGRAM_BOUNDS. It's done in the loop,
+// since the verifier would otherwise explore the rest of the program for each combination
+// of the buckets of the histograms.
+static __always_inline void histogram_observe(u64 *buckets, u64 value, volatile const u64 *bounds, u32 len) {
+    for (u32 i = 0; i < MAX_HISTOGRAM_BOUNDS; i++) {
+        if (i >= len || value <= bounds[i]) {
+            buckets[i]++;
+            return;
+        }
+    }
+    buckets[MAX_HISTOGRAM_BOUNDS]++;
+}
+
+#endif
@@ -0,0 +1,81 @@
+#ifndef HTTP_CAPTURE_H
+#define HTTP_CAPTURE_H
+
+#include "common.h"
+#include "bpf_helpers.h"
+#include "bpf_dbg.h"
+#include "http_types.h"
+#include "http_metrics.h"
+#include "http_sock.h"
+#include "ringbuf.h"
+
+// The maximum number of bytes that can be captured from a request is MAX_CAPTURE_CHUNKS * TRACE_BUF_SIZE
+#define MAX_CAPTURE_CHUNKS 16
+
+// To be Injected from the user space during the eBPF program load & initialization.
+// Number of bytes, from the beginning of the request, that are sent to user space
+// to read the full URL and the request headers.
+volatile const u32 max_capture_bytes = TRACE_BUF_SIZE;
+// If set, the responses of the server requests are also captured, to read their headers
+volatile const u8 capture_responses = 0;
+
+// Temporary storage for the chunks, which are too big for the stack
+struct {
+    __uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+    __type(key, int);
+    __type(value, http_buf_t);
+    __uint(max_entries, 1);
+} capture_chunk_mem SEC(".maps");
+
+// send_capture_chunks sends the first max_capture_bytes of the request or response in u_buf as a
+// sequence of variable-length chunks, so user space can reassemble them. Only the bytes that have
+// been actually read are sent, instead of a full TRACE_BUF_SIZE record. Nothing is sent when the
+// metrics are aggregated in the kernel, as user space doesn't decode the requests.
+// The chunks follow the sampling decision of the request in info, so they are sent only if its
+// event is also sent.
+static __always_inline void send_capture_chunks(void *u_buf, u32 size, http_info_t *info, u64 flags) {
+    if (aggregate_metrics) {
+        return;
+    }
+    int zero = 0;
+    http_buf_t *chunk = bpf_map_lookup_elem(&capture_chunk_mem, &zero);
+    if (!chunk) {
+        return;
+    }
+
+    if (size > max_capture_bytes) {
+        size = max_capture_bytes;
+    }
+
+    if (!http_sampled(info)) {
+        return;
+    }
+
+    chunk->flags = flags;
+    chunk->conn_info = info->conn_info;
+
+    for (u32 i = 0; i < MAX_CAPTURE_CHUNKS; i++) {
+        // 64-bit arithmetic, so the verifier keeps the bounds of len after the clamp
+        u64 offset = (u64)i * TRACE_BUF_SIZE;
+        if (offset >= size) {
+            break;
+        }
+        u64 len = size - offset;
+        if (len > TRACE_BUF_SIZE) {
+            len = TRACE_BUF_SIZE;
+        }
+        chunk->offset = offset;
+        chunk->len = len;
+        bpf_probe_read(chunk->buf, len, u_buf + offset);
+
+        bpf_dbg_printk("Sending capture chunk, offset %d, len %d", offset, len);
+
+        // the chunks have a variable size, so they can't be reserved with ringbuf_reserve_sampled
+        if (bpf_ringbuf_output(&events, chunk, offsetof(http_buf_t, buf) + len, get_flags())) {
+            ringbuf_stat_add(RINGBUF_STAT_DROPPED, 1);
+            break;
+        }
+    }
+}
+
+#endif
@@ -0,0 +1,148 @@
+#ifndef HTTP_IGNORE_H
+#define HTTP_IGNORE_H
+
+#include "common.h"
+#include "bpf_helpers.h"
+#include "bpf_builtins.h"
+#include "http_types.h"
+#include "ringbuf.h"
+
+#define IGNORE_PREFIX_LEN 64
+#define MAX_IGNORE_ENTRIES 256
+#define USER_AGENT_HEADER_LEN 11 // user-agent:
+#define USER_AGENT_HEADER_START 0x6567612d72657375 // "user-age", as a little-endian u64
+
+// These need to line up with the ignoreKind* Go identifiers
+#define IGNORE_KIND_METHOD     1
+#define IGNORE_KIND_PATH       2
+#define IGNORE_KIND_USER_AGENT 3
+
+// If set, the requests are matched against the ignore_prefixes and ignore_peers
+// maps before being sent to user space
+volatile const u8 ignore_requests = 0;
+
+typedef struct ignore_prefix_key {
+    u32 prefixlen; // in bits, including the kind
+    u8  kind;
+    u8  prefix[IGNORE_PREFIX_LEN];
+} ignore_prefix_key_t;
+
+typedef struct ignore_peer_key {
+    u32 prefixlen; // in bits
+    u8  addr[IP_V6_ADDR_LEN];
+} ignore_peer_key_t;
+
+// Prefixes of the methods, paths and user agents of the requests to ignore,
+// as populated from user space
+struct {
+    __uint(type, BPF_MAP_TYPE_LPM_TRIE);
+    __type(key, ignore_prefix_key_t);
+    __type(value, u8);
+    __uint(max_entries, MAX_IGNORE_ENTRIES);
+    __uint(map_flags, BPF_F_NO_PREALLOC);
+} ignore_prefixes SEC(".maps");
+
+// CIDRs of the peers whose requests are ignored. IPv4 addresses are mapped to IPv6
+struct {
+    __uint(type, BPF_MAP_TYPE_LPM_TRIE);
+    __type(key, ignore_peer_key_t);
+    __type(value, u8);
+    __uint(max_entries, MAX_IGNORE_ENTRIES);
+    __uint(map_flags, BPF_F_NO_PREALLOC);
+} ignore_peers SEC(".maps");
+
+typedef struct ignore_mem {
+    ignore_prefix_key_t key;
+    // copy of the request buffer, followed by zeros so a full prefix can be
+    // read from any position of the request
+    unsigned char buf[FULL_BUF_SIZE + IGNORE_PREFIX_LEN];
+} ignore_mem_t;
+
+// Temporary storage for the prefix keys, which are too big for the stack
+struct {
+    __uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+    __type(key, int);
+    __type(value, ignore_mem_t);
+    __uint(max_entries, 1);
+} ignore_mem SEC(".maps");
+
+// Returns the position of the User-Agent header value in the buffer, or 0 if it is not found
+static __always_inline int user_agent_pos(unsigned char *buf) {
+    for (int i = 0; i < FULL_BUF_SIZE - USER_AGENT_HEADER_LEN - 2; i++) {
+        if (buf[i] != '\n') {
+            continue;
+        }
+        // case-insensitive comparison: setting the lowercase bit doesn't
+        // modify the '-' and ':' characters of the header name. The first 8 bytes
+        // are compared at once to keep the loop cheap for the verifier.
+        if ((*(u64 *)&buf[i + 1] | 0x2020202020202020) != USER_AGENT_HEADER_START ||
+            (buf[i + 9] | 0x20) != 'n' || (buf[i + 10] | 0x20) != 't' || buf[i + 11] != ':') {
+            continue;
+        }
+        int pos = i + 1 + USER_AGENT_HEADER_LEN;
+        if (buf[pos] == ' ') {
+            pos++;
+        }
+        return pos;
+    }
+    return 0;
+}
+
+static __always_inline bool ignored_prefix(ignore_mem_t *mem, u8 kind, int start) {
+    if (start < 0 || start >= FULL_BUF_SIZE) {
+        return false;
+    }
+    ignore_prefix_key_t *key = &mem->key;
+    key->prefixlen = (sizeof(key->kind) + sizeof(key->prefix)) * 8;
+    key->kind = kind;
+    // the zero padding after the request lets us copy a fixed size without a loop,
+    // which the verifier would otherwise explore for each start position
+    bpf_memcpy(key->prefix, &mem->buf[start], sizeof(key->prefix));
+    return bpf_map_lookup_elem(&ignore_prefixes, key) != NULL;
+}
+
+// Returns true if the request matches any of the user-provided ignore criteria
+static __always_inline bool ignore_request(http_info_t *info) {
+    if (!ignore_requests) {
+        return false;
+    }
+
+    // the peer of the server requests is the client, at the source of the sorted connection info
+    ignore_peer_key_t peer = { .prefixlen = IP_V6_ADDR_LEN * 8 };
+    if (info->type == EVENT_HTTP_CLIENT) {
+        __bpf_memcpy_builtin(peer.addr, info->conn_info.d_addr, sizeof(peer.addr));
+    } else {
+        __bpf_memcpy_builtin(peer.addr, info->conn_info.s_addr, sizeof(peer.addr));
+    }
+    if (bpf_map_lookup_elem(&ignore_peers, &peer)) {
+        return true;
+    }
+
+    int zero = 0;
+    ignore_mem_t *mem = bpf_map_lookup_elem(&ignore_mem, &zero);
+    if (!mem) {
+        return false;
+    }
+    bpf_memcpy(mem->buf, info->buf, FULL_BUF_SIZE);
+
+    // the method is stored with its trailing space, so it's matched exactly
+    if (ignored_prefix(mem, IGNORE_KIND_METHOD, 0)) {
+        return true;
+    }
+
+    int path_start = 0;
+    for (int i = 0; i < METHOD_MAX_PATH_START; i++) {
+        if (info->buf[i] == ' ') {
+            path_start = i + 1;
+            break;
+        }
+    }
+    if (path_start && ignored_prefix(mem, IGNORE_KIND_PATH, path_start)) {
+        return true;
+    }
+
+    int ua_start = user_agent_pos(info->buf);
+    return ua_start && ignored_prefix(mem, IGNORE_KIND_USER_AGENT, ua_start);
+}
+
+#endif
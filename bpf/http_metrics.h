@@ -0,0 +1,249 @@
+#ifndef HTTP_METRICS_H
+#define HTTP_METRICS_H
+
+#include "common.h"
+#include "bpf_helpers.h"
+#include "bpf_builtins.h"
+#include "bpf_dbg.h"
+#include "http_types.h"
+#include "histogram.h"
+
+#define METRICS_PATH_LEN 96
+#define MAX_METRICS_ENTRIES 4096
+
+#ifndef EEXIST
+#define EEXIST 17
+#endif
+
+#define HTTP_METHOD_UNKNOWN 0
+#define HTTP_METHOD_GET     1
+#define HTTP_METHOD_POST    2
+#define HTTP_METHOD_PUT     3
+#define HTTP_METHOD_PATCH   4
+#define HTTP_METHOD_DELETE  5
+#define HTTP_METHOD_HEAD    6
+#define HTTP_METHOD_OPTIONS 7
+#define HTTP_METHOD_CONNECT 8
+#define HTTP_METHOD_TRACE   9
+
+// If set, the finished requests are aggregated into the http_metrics map instead
+// of being sent through the events ring buffer
+volatile const u8 aggregate_metrics = 0;
+
+// Upper bounds of the histograms buckets, in nanoseconds and bytes. The values
+// beyond the last bound are accounted in an extra bucket.
+volatile const u64 duration_bounds_ns[MAX_HISTOGRAM_BOUNDS] = {};
+volatile const u32 duration_bounds_len = 0;
+volatile const u64 size_bounds[MAX_HISTOGRAM_BOUNDS] = {};
+volatile const u32 size_bounds_len = 0;
+
+typedef struct http_metrics_key {
+    // identifier of the URL path, without query, as stored in the http_metrics_paths map
+    u64 path_id;
+    u32 pid;
+    u16 status;
+    u8  method;
+    u8  type;
+} http_metrics_key_t;
+
+typedef struct http_metrics_value {
+    u64 count;
+    u64 duration_sum_ns;
+    u64 size_sum;
+    u64 duration_buckets[MAX_HISTOGRAM_BOUNDS + 1];
+    u64 size_buckets[MAX_HISTOGRAM_BOUNDS + 1];
+} http_metrics_value_t;
+
+typedef struct http_metrics_path {
+    u8 path[METRICS_PATH_LEN];
+} http_metrics_path_t;
+
+// Aggregated metrics of the finished requests. Userspace periodically reads and deletes its entries.
+// The map doesn't evict entries, so the requests are not aggregated but accounted in the
+// http_metrics_dropped map while the map is full.
+struct {
+    __uint(type, BPF_MAP_TYPE_PERCPU_HASH);
+    __type(key, http_metrics_key_t);
+    __type(value, http_metrics_value_t);
+    __uint(max_entries, MAX_METRICS_ENTRIES);
+} http_metrics SEC(".maps");
+
+// Identifier of the URL paths in the http_metrics keys. Userspace periodically reads and deletes
+// its entries after reading the http_metrics map, so a new identifier is assigned to the paths of
+// the next requests. The identifiers are never reused. The requests with a path that can't be assigned
+// an identifier because the map is full are not aggregated but accounted in the http_metrics_dropped map,
+// so services with many distinct paths (e.g. containing identifiers) might need a shorter aggregation interval.
+struct {
+    __uint(type, BPF_MAP_TYPE_HASH);
+    __type(key, http_metrics_path_t);
+    __type(value, u64);
+    __uint(max_entries, MAX_METRICS_ENTRIES);
+} http_metrics_paths SEC(".maps");
+
+// Number of path identifiers that have been assigned from each CPU
+struct {
+    __uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+    __type(key, int);
+    __type(value, u32);
+    __uint(max_entries, 1);
+} http_metrics_path_seq SEC(".maps");
+
+// Number of requests that couldn't be aggregated because the http_metrics or the http_metrics_paths
+// maps were full
+struct {
+    __uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+    __type(key, int);
+    __type(value, u64);
+    __uint(max_entries, 1);
+} http_metrics_dropped SEC(".maps");
+
+// Temporary storage for the URL path of the requests. Keeping it out of the stack also lets
+// the verifier prune the paths of the copy loop, which would otherwise differ in the stack contents.
+struct {
+    __uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+    __type(key, int);
+    __type(value, http_metrics_path_t);
+    __uint(max_entries, 1);
+} http_metrics_path_mem SEC(".maps");
+
+// Temporary storage for the value of new http_metrics entries, which is too big for the stack
+struct {
+    __uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+    __type(key, int);
+    __type(value, http_metrics_value_t);
+    __uint(max_entries, 1);
+} http_metrics_value_mem SEC(".maps");
+
+static __always_inline u8 http_method(unsigned char *buf) {
+    switch (buf[0]) {
+    case 'G': return HTTP_METHOD_GET;
+    case 'D': return HTTP_METHOD_DELETE;
+    case 'H': return HTTP_METHOD_HEAD;
+    case 'O': return HTTP_METHOD_OPTIONS;
+    case 'C': return HTTP_METHOD_CONNECT;
+    case 'T': return HTTP_METHOD_TRACE;
+    case 'P':
+        switch (buf[1]) {
+        case 'O': return HTTP_METHOD_POST;
+        case 'U': return HTTP_METHOD_PUT;
+        case 'A': return HTTP_METHOD_PATCH;
+        }
+    }
+    return HTTP_METHOD_UNKNOWN;
+}
+
+// Stores the URL path of the request into the passed struct. Returns 0 if the path can't be found.
+static __always_inline int http_path(unsigned char *buf, http_metrics_path_t *path) {
+    int start = 0;
+    // the path starts after the first space
+    for (int i = 0; i < METHOD_MAX_PATH_START; i++) {
+        if (buf[i] == ' ') {
+            start = i + 1;
+            break;
+        }
+    }
+    if (!start) {
+        return 0;
+    }
+    for (int i = 0; i < METRICS_PATH_LEN - 1; i++) {
+        int pos = start + i;
+        if (pos >= FULL_BUF_SIZE) {
+            break;
+        }
+        unsigned char c = buf[pos];
+        if (c == ' ' || c == '?' || c == 0) {
+            break;
+        }
+        path->path[i] = c;
+    }
+    return 1;
+}
+
+// Returns the identifier of the passed path, assigning a new one if the path has no identifier.
+// The identifiers are made of the CPU number and a per-CPU sequence, so they are unique without
+// requiring atomic operations. Returns 0 if the identifier can't be assigned.
+static __always_inline u64 http_path_id(http_metrics_path_t *path) {
+    u64 *id = bpf_map_lookup_elem(&http_metrics_paths, path);
+    if (id) {
+        return *id;
+    }
+    int zero = 0;
+    u32 *seq = bpf_map_lookup_elem(&http_metrics_path_seq, &zero);
+    if (!seq) {
+        return 0;
+    }
+    (*seq)++;
+    u64 new_id = ((u64)bpf_get_smp_processor_id() << 32) | *seq;
+    long err = bpf_map_update_elem(&http_metrics_paths, path, &new_id, BPF_NOEXIST);
+    // another CPU might have assigned an identifier to the same path in the meantime
+    if (err && err != -EEXIST) {
+        bpf_dbg_printk("can't assign an identifier to the path, err %d", err);
+        return 0;
+    }
+    id = bpf_map_lookup_elem(&http_metrics_paths, path);
+    if (!id) {
+        return 0;
+    }
+    return *id;
+}
+
+static __always_inline void http_metrics_drop() {
+    int zero = 0;
+    u64 *dropped = bpf_map_lookup_elem(&http_metrics_dropped, &zero);
+    if (dropped) {
+        (*dropped)++;
+    }
+}
+
+static __always_inline void aggregate_http_metrics(http_info_t *info) {
+    int zero = 0;
+    http_metrics_path_t *path = bpf_map_lookup_elem(&http_metrics_path_mem, &zero);
+    if (!path) {
+        return;
+    }
+    bpf_memset(path, 0, sizeof(http_metrics_path_t));
+    http_metrics_key_t key = {
+        .pid = info->pid,
+        .status = info->status,
+        .method = http_method(info->buf),
+        .type = info->type,
+    };
+    if (http_path(info->buf, path)) {
+        key.path_id = http_path_id(path);
+        // the requests with unknown path are not merged with the ones without path (path_id 0)
+        if (!key.path_id) {
+            http_metrics_drop();
+            return;
+        }
+    }
+
+    http_metrics_value_t *value = bpf_map_lookup_elem(&http_metrics, &key);
+    if (!value) {
+        value = bpf_map_lookup_elem(&http_metrics_value_mem, &zero);
+        if (!value) {
+            return;
+        }
+        bpf_memset(value, 0, sizeof(http_metrics_value_t));
+        long err = bpf_map_update_elem(&http_metrics, &key, value, BPF_NOEXIST);
+        // another CPU might have added the same key in the meantime
+        if (err && err != -EEXIST) {
+            bpf_dbg_printk("can't aggregate the request metrics, err %d", err);
+            http_metrics_drop();
+            return;
+        }
+        value = bpf_map_lookup_elem(&http_metrics, &key);
+        if (!value) {
+            http_metrics_drop();
+            return;
+        }
+    }
+    u64 duration = info->end_monotime_ns - info->start_monotime_ns;
+    // the map is per-CPU, so no atomic operations are needed
+    value->count++;
+    value->duration_sum_ns += duration;
+    value->size_sum += info->len;
+    histogram_observe(value->duration_buckets, duration, duration_bounds_ns, duration_bounds_len);
+    histogram_observe(value->size_buckets, info->len, size_bounds, size_bounds_len);
+}
+
+#endif
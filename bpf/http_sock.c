@@ -9,6 +9,7 @@
 #include "ringbuf.h"
 #include "http_sock.h"
 #include "http_ssl.h"
+#include "http_capture.h"
 
 char __license[] SEC("license") = "Dual MIT/GPL";
 
@@ -51,8 +52,7 @@ struct {
 } dead_pids SEC(".maps");
 
 // Used by accept to grab the sock details
-SEC("kretprobe/sock_alloc")
-int BPF_KRETPROBE(kretprobe_sock_alloc, struct socket *sock) {
+static __always_inline int handle_sock_alloc(struct socket *sock) {
     u64 id = bpf_get_current_pid_tgid();
 
     if (!valid_pid(id)) {
@@ -76,14 +76,23 @@ int BPF_KRETPROBE(kretprobe_sock_alloc, struct socket *sock) {
     return 0;
 }
 
+SEC("kretprobe/sock_alloc")
+int BPF_KRETPROBE(kretprobe_sock_alloc, struct socket *sock) {
+    return handle_sock_alloc(sock);
+}
+
+SEC("fexit/sock_alloc")
+int BPF_PROG(fexit_sock_alloc, struct socket *sock) {
+    return handle_sock_alloc(sock);
+}
+
 // We tap into accept and connect to figure out if a request is inbound or
 // outbound. However, in some cases servers can optimise the accept path if
 // the same request is sent over and over. For that reason, in case we miss the
 // initial accept, we establish an active filtered connection here. By default
 // sets the type to be server HTTP, in client mode we'll overwrite the 
 // data in the map, since those cannot be optimised.
-SEC("kprobe/tcp_rcv_established")
-int BPF_KPROBE(kprobe_tcp_rcv_established, struct sock *sk, struct sk_buff *skb) {
+static __always_inline int handle_tcp_rcv_established(struct sock *sk) {
     u64 id = bpf_get_current_pid_tgid();
 
     if (!valid_pid(id)) {
@@ -106,15 +115,23 @@ int BPF_KPROBE(kprobe_tcp_rcv_established, struct sock *sk, struct sk_buff *skb)
     return 0;
 }
 
+SEC("kprobe/tcp_rcv_established")
+int BPF_KPROBE(kprobe_tcp_rcv_established, struct sock *sk, struct sk_buff *skb) {
+    return handle_tcp_rcv_established(sk);
+}
+
+SEC("fentry/tcp_rcv_established")
+int BPF_PROG(fentry_tcp_rcv_established, struct sock *sk, struct sk_buff *skb) {
+    return handle_tcp_rcv_established(sk);
+}
+
 // We tap into both sys_accept and sys_accept4.
 // We don't care about the accept entry arguments, since we get only peer information
 // we don't have the full picture for the socket.
 // 
 // Note: A current limitation is that likely we won't capture the first accept request. The
 // process may have already reached accept, before the instrumenter has launched.
-SEC("kretprobe/sys_accept4")
-int BPF_KRETPROBE(kretprobe_sys_accept4, uint fd)
-{
+static __always_inline int handle_accept_ret(long fd) {
     u64 id = bpf_get_current_pid_tgid();
 
     if (!valid_pid(id)) {
@@ -125,7 +142,7 @@ int BPF_KRETPROBE(kretprobe_sys_accept4, uint fd)
 
     // The file descriptor is the value returned from the accept4 syscall.
     // If we got a negative file descriptor we don't have a connection
-    if ((int)fd < 0) {
+    if (fd < 0) {
         goto cleanup;
     }
 
@@ -155,9 +172,21 @@ cleanup:
     return 0;
 }
 
+SEC("kretprobe/sys_accept4")
+int BPF_KRETPROBE(kretprobe_sys_accept4, uint fd)
+{
+    return handle_accept_ret((int)fd);
+}
+
+// Stable alternative to the sys_accept and sys_accept4 kretprobes
+SEC("tracepoint/syscalls/sys_exit_accept4")
+int tracepoint_sys_exit_accept(struct trace_event_raw_sys_exit *ctx)
+{
+    return handle_accept_ret(ctx->ret);
+}
+
 // Used by connect so that we can grab the sock details
-SEC("kprobe/tcp_connect")
-int BPF_KPROBE(kprobe_tcp_connect, struct sock *sk) {
+static __always_inline int handle_tcp_connect(void *sk) {
     u64 id = bpf_get_current_pid_tgid();
 
     if (!valid_pid(id)) {
@@ -178,11 +207,19 @@ int BPF_KPROBE(kprobe_tcp_connect, struct sock *sk) {
     return 0;
 }
 
+SEC("kprobe/tcp_connect")
+int BPF_KPROBE(kprobe_tcp_connect, struct sock *sk) {
+    return handle_tcp_connect(sk);
+}
+
+SEC("fentry/tcp_connect")
+int BPF_PROG(fentry_tcp_connect, struct sock *sk) {
+    return handle_tcp_connect(sk);
+}
+
 // We tap into sys_connect so we can track properly the processes doing
 // HTTP client calls
-SEC("kretprobe/sys_connect")
-int BPF_KRETPROBE(kretprobe_sys_connect, int fd)
-{
+static __always_inline int handle_connect_ret(long fd) {
     u64 id = bpf_get_current_pid_tgid();
 
     if (!valid_pid(id)) {
@@ -222,6 +259,19 @@ cleanup:
     return 0;
 }
 
+SEC("kretprobe/sys_connect")
+int BPF_KRETPROBE(kretprobe_sys_connect, int fd)
+{
+    return handle_connect_ret(fd);
+}
+
+// Stable alternative to the sys_connect kretprobe
+SEC("tracepoint/syscalls/sys_exit_connect")
+int tracepoint_sys_exit_connect(struct trace_event_raw_sys_exit *ctx)
+{
+    return handle_connect_ret(ctx->ret);
+}
+
 SEC("kprobe/sys_exit")
 int BPF_KPROBE(kprobe_sys_exit, int status) {
     u64 id = bpf_get_current_pid_tgid();
@@ -297,14 +347,15 @@ int socket__http_filter(struct __sk_buff *skb) {
                 if (!meta) {
                     return 0;
                 }
-            } else { // it must be a request, let's read more bytes
-                u32 full_len = skb->len - tcp.hdr_len;
-                if (full_len > FULL_BUF_SIZE) {
-                    full_len = FULL_BUF_SIZE;
-                }
-                read_skb_bytes(skb, tcp.hdr_len, info.buf, full_len);
-                processing_buf = info.buf;
             }
+            // let's read more bytes, to get the URL of the requests or the Content-Length of the responses.
+            // For the responses, info.buf is only used as a temporary buffer.
+            u32 full_len = skb->len - tcp.hdr_len;
+            if (full_len > FULL_BUF_SIZE) {
+                full_len = FULL_BUF_SIZE;
+            }
+            read_skb_bytes(skb, tcp.hdr_len, info.buf, full_len);
+            processing_buf = info.buf;
         }
         if (packet_type) {
             bpf_dbg_printk("=== http_filter len=%d pid=%d %s ===", (skb->len - tcp.hdr_len), (meta != NULL) ? pid_from_pid_tgid(meta->id) : -1, buf);
@@ -312,6 +363,9 @@ int socket__http_filter(struct __sk_buff *skb) {
         }
 
         process_http(&info, &tcp, packet_type, (skb->len - tcp.hdr_len), processing_buf, meta);
+    } else if (!client) {
+        // it might be the continuation of a response, whose body size is being counted
+        count_response_bytes(&conn, &tcp, skb->len - tcp.hdr_len);
     }
 
     return 0;
@@ -337,8 +391,7 @@ int BPF_UPROBE(uprobe_ssl_do_handshake, void *s) {
     return 0;
 }
 
-SEC("kprobe/tcp_sendmsg")
-int BPF_KPROBE(kprobe_tcp_sendmsg, struct sock *sk, struct msghdr *msg, size_t size) {
+static __always_inline int handle_tcp_sendmsg(struct sock *sk, struct msghdr *msg, size_t size) {
     u64 id = bpf_get_current_pid_tgid();
 
     if (!valid_pid(id)) {
@@ -364,27 +417,37 @@ int BPF_KPROBE(kprobe_tcp_sendmsg, struct sock *sk, struct msghdr *msg, size_t s
                 u8 packet_type = 0;
                 if (is_http(small_buf, 16, &packet_type)) {
                     if (packet_type == PACKET_TYPE_REQUEST) {
-                        http_buf_t *trace = bpf_ringbuf_reserve(&events, sizeof(http_buf_t), 0);
-                        if (trace) {
-                            trace->conn_info = info;
-                            trace->flags |= CONN_INFO_FLAG_TRACE;
-
-                            s64 buf_len = (s64)size & (TRACE_BUF_SIZE - 1);
-                            bpf_probe_read(trace->buf, buf_len, u_buf);
-
-                            if (buf_len < TRACE_BUF_SIZE) {
-                                trace->buf[buf_len] = '\0';
-                            }
-
-                            bpf_dbg_printk("Sending client buffer %s, copied_size %d", trace->buf, size);
-
-                            bpf_ringbuf_submit(trace, get_flags());
+                        // the buffer is captured before the socket filter sees the request, so the
+                        // request is set here to keep the sampling decision of its chunks
+                        http_info_t in = {0};
+                        in.conn_info = info;
+                        in.type = EVENT_HTTP_CLIENT;
+                        http_info_t *req = get_or_set_http_info(&in, packet_type);
+                        if (req) {
+                            bpf_dbg_printk("Sending client buffer, copied_size %d", size);
+                            send_capture_chunks(u_buf, size, req, CONN_INFO_FLAG_TRACE);
                         }
                     }
                 }
             } else {
                 bpf_dbg_printk("couldn't find msghdr u_buf");
             }
+        } else if (capture_responses) {
+            // the response headers of the server requests
+            void *u_buf = read_msghdr_buf(msg);
+
+            if (u_buf) {
+                unsigned char small_buf[16];
+                bpf_probe_read(small_buf, 16, u_buf);
+
+                u8 packet_type = 0;
+                // the response is only sent if its request is known
+                http_info_t *req = bpf_map_lookup_elem(&ongoing_http, &info);
+                if (req && is_http(small_buf, 16, &packet_type) && packet_type == PACKET_TYPE_RESPONSE) {
+                    bpf_dbg_printk("Sending server response buffer, copied_size %d", size);
+                    send_capture_chunks(u_buf, size, req, CONN_INFO_FLAG_TRACE | CONN_INFO_FLAG_RESPONSE);
+                }
+            }
         }
 
         // Checks if it's sandwitched between active SSL handshake uprobe/uretprobe
@@ -401,6 +464,16 @@ int BPF_KPROBE(kprobe_tcp_sendmsg, struct sock *sk, struct msghdr *msg, size_t s
     return 0;
 }
 
+SEC("kprobe/tcp_sendmsg")
+int BPF_KPROBE(kprobe_tcp_sendmsg, struct sock *sk, struct msghdr *msg, size_t size) {
+    return handle_tcp_sendmsg(sk, msg, size);
+}
+
+SEC("fentry/tcp_sendmsg")
+int BPF_PROG(fentry_tcp_sendmsg, struct sock *sk, struct msghdr *msg, size_t size) {
+    return handle_tcp_sendmsg(sk, msg, size);
+}
+
 SEC("uretprobe/libssl.so:SSL_do_handshake")
 int BPF_URETPROBE(uretprobe_ssl_do_handshake, int ret) {
     u64 id = bpf_get_current_pid_tgid();
@@ -648,32 +721,29 @@ int BPF_KRETPROBE(kretprobe_tcp_recvmsg, int copied_len) {
 
         // we only care about connections setup by the socket filter as HTTP
         http_info_t *http_info = bpf_map_lookup_elem(&ongoing_http, &info);
-        if (http_info && http_info->type != EVENT_HTTP_CLIENT && !http_info->ssl) {
-            struct msghdr *msg = (struct msghdr *)args->msghdr_ptr;
-            void *u_buf = read_msghdr_buf(msg);
-            
-            if (u_buf) {
-                http_buf_t *trace = bpf_ringbuf_reserve(&events, sizeof(http_buf_t), 0);
-                if (trace) {
-                    trace->conn_info = info;
-                    trace->flags |= CONN_INFO_FLAG_TRACE;
-
-                    int buf_len = copied_len & (TRACE_BUF_SIZE - 1);
-                    bpf_probe_read(trace->buf, buf_len, u_buf);
-
-                    if (buf_len < TRACE_BUF_SIZE) {
-                        trace->buf[buf_len] = '\0';
-                    }
-
-                    bpf_dbg_printk("Sending buffer %s, copied_size %d", trace->buf, copied_len);
-
-                    bpf_ringbuf_submit(trace, get_flags());
+        if (http_info && http_info->type != EVENT_HTTP_CLIENT) {
+            track_serving_thread(http_info, id);
+            if (!http_info->ssl) {
+                struct msghdr *msg = (struct msghdr *)args->msghdr_ptr;
+                void *u_buf = read_msghdr_buf(msg);
+
+                if (u_buf) {
+                    bpf_dbg_printk("Sending buffer, copied_size %d", copied_len);
+                    send_capture_chunks(u_buf, copied_len, http_info, CONN_INFO_FLAG_TRACE);
+                } else {
+                    bpf_dbg_printk("Couldn't read msghdr buffer");
                 }
-            } else {
-                bpf_dbg_printk("Couldn't read msghdr buffer");
             }
         }
     }
 
     return 0;
 }
+
+// Accounts the on-CPU and off-CPU time of the threads that serve the ongoing requests
+SEC("tracepoint/sched/sched_switch")
+int tracepoint_sched_switch(struct sched_switch_args *ctx)
+{
+    cpu_time_sched_switch(ctx);
+    return 0;
+}
@@ -6,7 +6,11 @@
 #include "bpf_builtins.h"
 #include "http_types.h"
 #include "ringbuf.h"
+#include "http_metrics.h"
+#include "http_ignore.h"
 #include "pid.h"
+#include "msghdr.h"
+#include "cpu_time.h"
 
 #define MIN_HTTP_SIZE 12 // HTTP/1.1 CCC is the smallest valid request we can have
 #define RESPONSE_STATUS_POS 9 // HTTP/1.1 <--
@@ -14,6 +18,9 @@
 #define PACKET_TYPE_REQUEST 1
 #define PACKET_TYPE_RESPONSE 2
 
+#define CONTENT_LENGTH_HEADER_LEN 15
+#define CONTENT_LENGTH_MAX_CHARS 14 // up to 10 digits, and the whitespace before them
+
 // Keeps track of the tcp sequences we've seen for a connection
 // With multiple network interfaces the same sequence can be seen again
 struct {
@@ -74,90 +81,6 @@ static __always_inline bool is_http(unsigned char *p, u32 len, u8 *packet_type)
     return true;
 }
 
-// Newer version of uio.h iov_iter than what we have in vmlinux.h.
-struct _iov_iter {
-	u8 iter_type;
-	bool copy_mc;
-	bool nofault;
-	bool data_source;
-	bool user_backed;
-	union {
-		size_t iov_offset;
-		int last_offset;
-	};
-	union {
-		struct iovec __ubuf_iovec;
-		struct {
-			union {
-				const struct iovec *__iov;
-				const struct kvec *kvec;
-				const struct bio_vec *bvec;
-				struct xarray *xarray;
-				void *ubuf;
-			};
-			size_t count;
-		};
-	};
-};
-
-static __always_inline void *read_msghdr_buf(struct msghdr *msg) {
-    unsigned int m_flags;
-    struct iov_iter msg_iter;
-
-    bpf_probe_read_kernel(&m_flags, sizeof(unsigned int), &(msg->msg_flags));
-    bpf_probe_read_kernel(&msg_iter, sizeof(struct iov_iter), &(msg->msg_iter));
-
-    u8 msg_iter_type = 0;
-
-    if (bpf_core_field_exists(msg_iter.iter_type)) {
-        bpf_probe_read(&msg_iter_type, sizeof(u8), &(msg_iter.iter_type));
-        bpf_dbg_printk("msg iter type exists, read value %d", msg_iter_type);
-    }
-
-    bpf_dbg_printk("msg type %x, iter type %d", m_flags, msg_iter_type);
-
-    struct iovec *iov = NULL;
-
-    if (bpf_core_field_exists(msg_iter.iov)) {
-        bpf_probe_read(&iov, sizeof(struct iovec *), &(msg_iter.iov));
-        bpf_dbg_printk("iov exists, read value %llx", iov);
-    } else {
-        // TODO: I wonder if there's a way to check for field existence without having to
-        // make fake structures that match the new version of the kernel code. This code
-        // here assumes the kernel iov_iter structure is the format with __iov and __ubuf_iovec.
-        struct _iov_iter _msg_iter;
-        bpf_probe_read_kernel(&_msg_iter, sizeof(struct _iov_iter), &(msg->msg_iter));
-        
-        bpf_dbg_printk("new kernel, iov doesn't exist");
-
-        if (msg_iter_type == 5) {
-            struct iovec vec;
-            bpf_probe_read(&vec, sizeof(struct iovec), &(_msg_iter.__ubuf_iovec));
-            bpf_dbg_printk("ubuf base %llx", vec.iov_base);
-
-            return vec.iov_base;
-        } else {
-            bpf_probe_read(&iov, sizeof(struct iovec *), &(_msg_iter.__iov));
-        }     
-    }
-    
-    if (!iov) {
-        return NULL;
-    }
-
-    if (msg_iter_type == 6) {// Direct char buffer
-        bpf_dbg_printk("direct char buffer type=6 iov %llx", iov);
-        return iov;
-    }
-
-    struct iovec vec;
-    bpf_probe_read(&vec, sizeof(struct iovec), iov);
-
-    bpf_dbg_printk("standard iov %llx base %llx", iov, vec.iov_base);
-
-    return vec.iov_base;    
-}
-
 // Copying 16 bytes at a time from the skb buffer is the only way to keep the verifier happy.
 static __always_inline void read_skb_bytes(const void *skb, u32 offset, unsigned char *buf, const u32 len) {
     u32 max = offset + len;
@@ -189,14 +112,29 @@ static __always_inline void read_skb_bytes(const void *skb, u32 offset, unsigned
     }
 }
 
+// Decides once per request whether it's sent to user space, according to the fill level of the
+// ring buffer, so its captured chunks and its event are either all sent or all discarded
+static __always_inline bool http_sampled(http_info_t *info) {
+    if (info->sampling == SAMPLING_UNDECIDED) {
+        info->sampling = ringbuf_sample() ? SAMPLING_IN : SAMPLING_OUT;
+    }
+    return info->sampling == SAMPLING_IN;
+}
+
 static __always_inline void finish_http(http_info_t *info) {
     if (info->start_monotime_ns != 0 && info->status != 0 && info->pid != 0) {
-        http_info_t *trace = bpf_ringbuf_reserve(&events, sizeof(http_info_t), 0);
-        if (trace) {
-            bpf_dbg_printk("Sending trace %lx", info);
-
-            bpf_memcpy(trace, info, sizeof(http_info_t));
-            bpf_ringbuf_submit(trace, get_flags());
+        if (ignore_request(info)) {
+            bpf_dbg_printk("Ignoring request %lx", info);
+        } else if (aggregate_metrics) {
+            aggregate_http_metrics(info);
+        } else if (http_sampled(info)) {
+            http_info_t *trace = ringbuf_reserve_sampled(sizeof(http_info_t));
+            if (trace) {
+                bpf_dbg_printk("Sending trace %lx", info);
+
+                bpf_memcpy(trace, info, sizeof(http_info_t));
+                bpf_ringbuf_submit(trace, get_flags());
+            }
         }
 
         bpf_map_delete_elem(&http_tcp_seq, &info->conn_info);
@@ -209,6 +147,11 @@ static __always_inline http_info_t *get_or_set_http_info(http_info_t *info, u8 p
     if (packet_type == PACKET_TYPE_REQUEST) {
         http_info_t *old_info = bpf_map_lookup_elem(&ongoing_http, &info->conn_info);
         if (old_info) {
+            if (!old_info->start_monotime_ns) {
+                // the client requests are set when their buffer is captured, before the socket
+                // filter sees them, so the sampling decision of the captured chunks is kept
+                info->sampling = old_info->sampling;
+            }
             finish_http(old_info); // this will delete ongoing_http for this connection info if there's full stale request
         }
 
@@ -226,19 +169,141 @@ static __always_inline bool still_reading(http_info_t *info) {
     return info->status == 0 && info->start_monotime_ns != 0;
 }
 
+// The thread that reads the request is assumed to be the one that serves it, so its CPU
+// time is accounted to the request until its response is sent
+static __always_inline void track_serving_thread(http_info_t *info, u64 id) {
+    if (!cpu_time_enabled || !still_reading(info)) {
+        return;
+    }
+    info->serving_tid = (u32)id;
+    cpu_time_start(info->serving_tid, info->start_monotime_ns);
+}
+
 static __always_inline void process_http_request(http_info_t *info) {
     info->start_monotime_ns = bpf_ktime_get_ns();
     info->status = 0;
     info->len = 0;
 }
 
-static __always_inline void process_http_response(http_info_t *info, unsigned char *buf, http_connection_metadata_t *meta) {
+// Case-insensitive comparison with the Content-Length header name. Setting the 0x20 bit
+// lowercases the letters, and it doesn't change the '-' and ':' characters. The differences are
+// accumulated without branching, as the verifier would otherwise fork at each byte of each
+// position of the buffer.
+static __always_inline bool is_content_length_header(unsigned char *buf, u32 pos) {
+    if (pos > FULL_BUF_SIZE - CONTENT_LENGTH_HEADER_LEN) {
+        return false;
+    }
+    u8 diff = ((buf[pos] | 0x20) ^ 'c') | ((buf[pos + 1] | 0x20) ^ 'o') | ((buf[pos + 2] | 0x20) ^ 'n') |
+              ((buf[pos + 3] | 0x20) ^ 't') | ((buf[pos + 4] | 0x20) ^ 'e') | ((buf[pos + 5] | 0x20) ^ 'n') |
+              ((buf[pos + 6] | 0x20) ^ 't') | (buf[pos + 7] ^ '-') | ((buf[pos + 8] | 0x20) ^ 'l') |
+              ((buf[pos + 9] | 0x20) ^ 'e') | ((buf[pos + 10] | 0x20) ^ 'n') | ((buf[pos + 11] | 0x20) ^ 'g') |
+              ((buf[pos + 12] | 0x20) ^ 't') | ((buf[pos + 13] | 0x20) ^ 'h') | (buf[pos + 14] ^ ':');
+    return diff == 0;
+}
+
+// The digits are read from a window of CONTENT_LENGTH_MAX_CHARS bytes that always fits
+// in the buffer, so the verifier doesn't need to check the bounds of each position.
+static __always_inline u32 parse_content_length(unsigned char *buf, u64 pos) {
+    u64 start = pos;
+    if (start > FULL_BUF_SIZE - CONTENT_LENGTH_MAX_CHARS) {
+        start = FULL_BUF_SIZE - CONTENT_LENGTH_MAX_CHARS;
+    }
+    u32 value = 0;
+    bool digits = false;
+    for (int j = 0; j < CONTENT_LENGTH_MAX_CHARS; j++) {
+        if (start + j < pos) {
+            continue;
+        }
+        unsigned char c = buf[start + j];
+        if (c >= '0' && c <= '9') {
+            value = value * 10 + (c - '0');
+            digits = true;
+        } else if (digits || (c != ' ' && c != '\t')) {
+            break;
+        }
+    }
+    return value;
+}
+
+// Looks for the Content-Length header or the end of the headers section in the first
+// FULL_BUF_SIZE bytes of a response. info->resp_len is set to the position of the header value
+// with resp_counting unset, or to the position of the body with resp_counting set, or to 0 if
+// none of them is found.
+// The positions aren't compared with the length of the response here, otherwise the verifier
+// would explore the rest of the program once for each position.
+static __always_inline void find_response_length(http_info_t *info, unsigned char *buf) {
+    info->resp_len = 0;
+    info->resp_counting = 1;
+    info->resp_unknown = 0;
+    for (int i = 0; i < FULL_BUF_SIZE - 1; i++) {
+        if (buf[i] != '\n') {
+            continue;
+        }
+        if (buf[i + 1] == '\r' || buf[i + 1] == '\n') {
+            info->resp_len = i + ((buf[i + 1] == '\r') ? 3 : 2);
+            return;
+        }
+        if (is_content_length_header(buf, i + 1)) {
+            info->resp_len = i + 1 + CONTENT_LENGTH_HEADER_LEN;
+            info->resp_counting = 0;
+            return;
+        }
+    }
+}
+
+// Reads the size of the response body from the Content-Length header, if it's in the first
+// FULL_BUF_SIZE bytes of the response. Otherwise, e.g. for chunked responses, the size is
+// calculated by counting the body bytes, starting from the ones after the headers section
+// in this buffer. The size of the chunked responses includes the chunk delimiters. If neither the
+// header nor the end of the headers section are found, the size is unknown and it's not counted.
+static __always_inline void process_response_length(http_info_t *info, unsigned char *buf, u32 len) {
+    find_response_length(info, buf);
+    // the position is read back from the map, so the verifier continues from a single state,
+    // regardless of where it has been found
+    asm volatile("" ::: "memory");
+    u64 pos = info->resp_len;
+    if (!info->resp_counting) {
+        info->resp_len = parse_content_length(buf, pos);
+    } else if (!pos) {
+        info->resp_len = 0;
+        info->resp_counting = 0;
+        info->resp_unknown = 1;
+    } else if (pos < len) {
+        info->resp_len = len - pos;
+    } else {
+        // the body starts after the end of the packet
+        info->resp_len = 0;
+    }
+}
+
+// buf contains the first FULL_BUF_SIZE bytes of the response, whose total length is len
+static __always_inline void process_http_response(http_info_t *info, unsigned char *buf, u32 len, http_connection_metadata_t *meta) {
     info->pid = pid_from_pid_tgid(meta->id);
     info->type = meta->type;
     info->status = 0;
     info->status += (buf[RESPONSE_STATUS_POS]     - '0') * 100;
     info->status += (buf[RESPONSE_STATUS_POS + 1] - '0') * 10;
     info->status += (buf[RESPONSE_STATUS_POS + 2] - '0');
+    process_response_length(info, buf, len);
+    // the CPU time is accounted until the response is sent. Otherwise, the time until the request
+    // finishes (e.g. waiting for the next request of a keep-alive connection) would be accounted
+    if (info->serving_tid) {
+        cpu_time_end(info->serving_tid, info->start_monotime_ns, &info->on_cpu_ns, &info->off_cpu_ns);
+    }
+}
+
+// Adds the bytes of a response packet that is not the beginning of the response
+// to the response body size
+static __always_inline void count_response_bytes(connection_info_t *conn, protocol_info_t *tcp, u32 len) {
+    http_info_t *info = bpf_map_lookup_elem(&ongoing_http, conn);
+    if (!info || info->ssl || !info->resp_counting || !still_responding(info)) {
+        return;
+    }
+    // the same packet can be seen from multiple network interfaces
+    if (tcp_dup(conn, tcp)) {
+        return;
+    }
+    info->resp_len += len;
 }
 
 static __always_inline void process_http(http_info_t *in, protocol_info_t *tcp, u8 packet_type, u32 packet_len, unsigned char *buf, http_connection_metadata_t *meta) {
@@ -250,7 +315,7 @@ static __always_inline void process_http(http_info_t *in, protocol_info_t *tcp,
     if (packet_type == PACKET_TYPE_REQUEST) {
         process_http_request(info);
     } else if (packet_type == PACKET_TYPE_RESPONSE) {
-        process_http_response(info, buf, meta);
+        process_http_response(info, buf, packet_len, meta);
     }
 
     if (still_reading(info)) {
@@ -6,6 +6,7 @@
 #include "bpf_builtins.h"
 #include "http_types.h"
 #include "http_sock.h"
+#include "http_capture.h"
 
 #define MAX_CONCURRENT_SSL_REQUESTS 10000
 
@@ -72,28 +73,20 @@ struct {
     __type(value, ssl_args_t);
 } active_ssl_write_args SEC(".maps");
 
-static __always_inline void send_trace_buff(void *orig_buf, int orig_len, connection_info_t *info) {
-    http_buf_t *trace = bpf_ringbuf_reserve(&events, sizeof(http_buf_t), 0);
-    if (trace) {
-        trace->conn_info = *info;
-        trace->flags |= CONN_INFO_FLAG_TRACE;
-
-        int buf_len = orig_len & (TRACE_BUF_SIZE - 1);
-        bpf_probe_read(&trace->buf, buf_len, orig_buf);
-        
-        if (buf_len < TRACE_BUF_SIZE) {
-            trace->buf[buf_len] = '\0';
-        }
-
-        /*bpf_dbg_printk("Sending buffer %c%c%c%c%c%c%c%c%c%c, copied_size %d", 
-            trace->buf[0], trace->buf[1], trace->buf[2], 
-            trace->buf[3], trace->buf[4], trace->buf[5],
-            trace->buf[6], trace->buf[7], trace->buf[8],
-            trace->buf[9], orig_len);
-        */
-
-        bpf_ringbuf_submit(trace, get_flags());
+// Temporary storage of the buffers that are read or written through SSL. It leaves room in the
+// stack of the SSL probes for the in-kernel aggregation of the metrics.
+struct {
+    __uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+    __type(key, int);
+    __type(value, unsigned char[FULL_BUF_SIZE]);
+    __uint(max_entries, 1);
+} ssl_buf_mem SEC(".maps");
+
+static __always_inline void send_trace_buff(void *orig_buf, int orig_len, http_info_t *info) {
+    if (orig_len <= 0) {
+        return;
     }
+    send_capture_chunks(orig_buf, orig_len, info, CONN_INFO_FLAG_TRACE);
 }
 
 static __always_inline void https_buffer_event(void *buf, int len, connection_info_t *conn, void *orig_buf, int orig_len) {
@@ -112,7 +105,7 @@ static __always_inline void https_buffer_event(void *buf, int len, connection_in
         //dbg_print_http_connection_info(conn); // commented out since GitHub CI doesn't like this call
 
         if (packet_type == PACKET_TYPE_REQUEST && (info->status == 0)) {
-            send_trace_buff(orig_buf, orig_len, conn);
+            send_trace_buff(orig_buf, orig_len, info);
             process_http_request(info);
             info->len = len;
             bpf_memcpy(info->buf, buf, FULL_BUF_SIZE);
@@ -127,7 +120,11 @@ static __always_inline void https_buffer_event(void *buf, int len, connection_in
                 meta = &dummy_meta;
             }
 
-            process_http_response(info, buf, meta);
+            process_http_response(info, buf, orig_len, meta);
+
+            if (capture_responses && info->type == EVENT_HTTP_REQUEST && orig_len > 0) {
+                send_capture_chunks(orig_buf, orig_len, info, CONN_INFO_FLAG_TRACE | CONN_INFO_FLAG_RESPONSE);
+            }
 
             // We sometimes don't see the TCP close in the filter, I wish we didn't have to 
             // do this here, but let the filter handle it.
@@ -195,17 +192,19 @@ static __always_inline void handle_ssl_buf(u64 id, ssl_args_t *args, int bytes_l
             conn = bpf_map_lookup_elem(&ssl_to_conn, &ssl);
         }
 
-        if (conn) {
+        int zero = 0;
+        unsigned char *buf = bpf_map_lookup_elem(&ssl_buf_mem, &zero);
+        if (conn && buf) {
             void *read_buf = (void *)args->buf;
-            char buf[FULL_BUF_SIZE] = {0};
-            
+            bpf_memset(buf, 0, FULL_BUF_SIZE);
+
             u32 len = bytes_len & 0x0fffffff; // keep the verifier happy
 
             if (len > FULL_BUF_SIZE) {
                 len = FULL_BUF_SIZE;
             }
 
-            bpf_probe_read(&buf, len * sizeof(char), read_buf);
+            bpf_probe_read(buf, len * sizeof(char), read_buf);
             bpf_dbg_printk("buffer from SSL %s", buf);
             https_buffer_event(buf, len, conn, read_buf, bytes_len);
         } else {
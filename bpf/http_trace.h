@@ -39,6 +39,9 @@ typedef struct http_request_trace_t {
     u32 host_port;
     s64 content_length;
     u8  traceparent[TRACEPARENT_LEN];
+    s64 response_length;
+    u64 on_cpu_ns;  // on-CPU time of the goroutine that served the request
+    u64 off_cpu_ns; // off-CPU time of the goroutine that served the request
 } __attribute__((packed)) http_request_trace;
 
 #endif
\ No newline at end of file
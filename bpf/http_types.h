@@ -7,9 +7,16 @@
 
 #define FULL_BUF_SIZE 160 // should be enough for most URLs, we may need to extend it if not. Must be multiple of 16 for the copy to work.
 #define BUF_COPY_BLOCK_SIZE 16
-#define TRACE_BUF_SIZE 1024 // must be power of 2, we do an & to limit the buffer size
+#define TRACE_BUF_SIZE 1024 // size of each of the chunks of a captured request
+#define METHOD_MAX_PATH_START 8 // the longest method (OPTIONS) plus the space
 
 #define CONN_INFO_FLAG_TRACE 0x1
+#define CONN_INFO_FLAG_RESPONSE 0x2 // the captured buffer is a response
+
+// Adaptive sampling decision of a request, which applies to its captured chunks and its event
+#define SAMPLING_UNDECIDED 0
+#define SAMPLING_IN        1 // the request is sent to user space
+#define SAMPLING_OUT       2 // the request is discarded
 
 // Struct to keep information on the connections in flight 
 // s = source, d = destination
@@ -34,6 +41,13 @@ typedef struct http_info {
     u16 status;    
     u8  type;
     u8  ssl;
+    u32 resp_len;      // size of the response body
+    u8  resp_counting; // the response has no Content-Length, so its body bytes are counted
+    u8  sampling;      // SAMPLING_* decision of the request
+    u8  resp_unknown;  // the size of the response body can't be known
+    u32 serving_tid;   // thread that reads the request, whose CPU time is accounted
+    u64 on_cpu_ns;
+    u64 off_cpu_ns;
 } http_info_t;
 
 // Here we keep information on the packets passing through the socket filter
@@ -52,6 +66,8 @@ typedef struct http_connection_metadata {
 typedef struct http_buf {
     u64 flags; // Must be fist we use it to tell what kind of packet we have on the ring buffer
     connection_info_t conn_info;
+    u32 offset; // position of the chunk in the captured request
+    u32 len;    // number of valid bytes in buf
     u8  buf[TRACE_BUF_SIZE];
 } http_buf_t;
 
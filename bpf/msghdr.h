@@ -0,0 +1,93 @@
+#ifndef MSGHDR_HELPERS_H
+#define MSGHDR_HELPERS_H
+
+#include "common.h"
+#include "bpf_helpers.h"
+#include "bpf_core_read.h"
+#include "bpf_dbg.h"
+
+// Newer version of uio.h iov_iter than what we have in vmlinux.h.
+struct _iov_iter {
+	u8 iter_type;
+	bool copy_mc;
+	bool nofault;
+	bool data_source;
+	bool user_backed;
+	union {
+		size_t iov_offset;
+		int last_offset;
+	};
+	union {
+		struct iovec __ubuf_iovec;
+		struct {
+			union {
+				const struct iovec *__iov;
+				const struct kvec *kvec;
+				const struct bio_vec *bvec;
+				struct xarray *xarray;
+				void *ubuf;
+			};
+			size_t count;
+		};
+	};
+};
+
+static __always_inline void *read_msghdr_buf(struct msghdr *msg) {
+    unsigned int m_flags;
+    struct iov_iter msg_iter;
+
+    bpf_probe_read_kernel(&m_flags, sizeof(unsigned int), &(msg->msg_flags));
+    bpf_probe_read_kernel(&msg_iter, sizeof(struct iov_iter), &(msg->msg_iter));
+
+    u8 msg_iter_type = 0;
+
+    if (bpf_core_field_exists(msg_iter.iter_type)) {
+        bpf_probe_read(&msg_iter_type, sizeof(u8), &(msg_iter.iter_type));
+        bpf_dbg_printk("msg iter type exists, read value %d", msg_iter_type);
+    }
+
+    bpf_dbg_printk("msg type %x, iter type %d", m_flags, msg_iter_type);
+
+    struct iovec *iov = NULL;
+
+    if (bpf_core_field_exists(msg_iter.iov)) {
+        bpf_probe_read(&iov, sizeof(struct iovec *), &(msg_iter.iov));
+        bpf_dbg_printk("iov exists, read value %llx", iov);
+    } else {
+        // TODO: I wonder if there's a way to check for field existence without having to
+        // make fake structures that match the new version of the kernel code. This code
+        // here assumes the kernel iov_iter structure is the format with __iov and __ubuf_iovec.
+        struct _iov_iter _msg_iter;
+        bpf_probe_read_kernel(&_msg_iter, sizeof(struct _iov_iter), &(msg->msg_iter));
+        
+        bpf_dbg_printk("new kernel, iov doesn't exist");
+
+        if (msg_iter_type == 5) {
+            struct iovec vec;
+            bpf_probe_read(&vec, sizeof(struct iovec), &(_msg_iter.__ubuf_iovec));
+            bpf_dbg_printk("ubuf base %llx", vec.iov_base);
+
+            return vec.iov_base;
+        } else {
+            bpf_probe_read(&iov, sizeof(struct iovec *), &(_msg_iter.__iov));
+        }     
+    }
+    
+    if (!iov) {
+        return NULL;
+    }
+
+    if (msg_iter_type == 6) {// Direct char buffer
+        bpf_dbg_printk("direct char buffer type=6 iov %llx", iov);
+        return iov;
+    }
+
+    struct iovec vec;
+    bpf_probe_read(&vec, sizeof(struct iovec), iov);
+
+    bpf_dbg_printk("standard iov %llx base %llx", iov, vec.iov_base);
+
+    return vec.iov_base;    
+}
+
+#endif
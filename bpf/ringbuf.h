@@ -24,12 +24,97 @@ struct {
     __uint(max_entries, 1 << 24);
 } events SEC(".maps");
 
+// These need to line up with the ringBufStat* Go identifiers
+#define RINGBUF_STAT_DROPPED 0       // events that couldn't be reserved because the ring buffer was full
+#define RINGBUF_STAT_SAMPLED_OUT 1   // events that were discarded by the adaptive sampling
+#define RINGBUF_STAT_SAMPLING_RATE 2 // 1 out of N events is currently sent
+#define RINGBUF_STAT_FILL_PCT 3      // fill level of the ring buffer, in percentage, at the last reservation
+#define RINGBUF_STAT_LAST_WAKEUP 4   // monotonic time, in nanoseconds, of the last forced wakeup
+#define RINGBUF_STATS_LEN 5
+
+// 1 out of 2^MAX_SAMPLING_SHIFT events are kept when the ring buffer is totally full
+#define MAX_SAMPLING_SHIFT 10
+
+struct {
+    __uint(type, BPF_MAP_TYPE_ARRAY);
+    __type(key, u32);
+    __type(value, u64);
+    __uint(max_entries, RINGBUF_STATS_LEN);
+} ringbuf_stats SEC(".maps");
+
 // To be Injected from the user space during the eBPF program load & initialization
 volatile const u32 wakeup_data_bytes;
+// If > 0, user space is woken up when no wakeup has been sent during this time, even if
+// less than wakeup_data_bytes are accumulated
+volatile const u64 wakeup_timeout_ns = 0;
+
+// If > 0, the events are sampled when the ring buffer fill level crosses the given percentage.
+// The sampling rate grows exponentially as the ring buffer gets fuller.
+volatile const u32 sampling_fill_threshold = 0;
+
+static __always_inline void ringbuf_stat_add(u32 stat, u64 value) {
+    u64 *v = bpf_map_lookup_elem(&ringbuf_stats, &stat);
+    if (v) {
+        __sync_fetch_and_add(v, value);
+    }
+}
+
+static __always_inline void ringbuf_stat_set(u32 stat, u64 value) {
+    u64 *v = bpf_map_lookup_elem(&ringbuf_stats, &stat);
+    if (v) {
+        *v = value;
+    }
+}
+
+// ringbuf_sample returns whether the next event should be sent, according to the
+// fill level of the ring buffer
+static __always_inline u8 ringbuf_sample() {
+    u64 size = bpf_ringbuf_query(&events, BPF_RB_RING_SIZE);
+    if (!size) {
+        return 1;
+    }
+    u64 fill_pct = bpf_ringbuf_query(&events, BPF_RB_AVAIL_DATA) * 100 / size;
+    ringbuf_stat_set(RINGBUF_STAT_FILL_PCT, fill_pct);
+    if (!sampling_fill_threshold || fill_pct < sampling_fill_threshold || sampling_fill_threshold >= 100) {
+        ringbuf_stat_set(RINGBUF_STAT_SAMPLING_RATE, 1);
+        return 1;
+    }
+    u64 shift = 1 + (fill_pct - sampling_fill_threshold) * (MAX_SAMPLING_SHIFT - 1) / (100 - sampling_fill_threshold);
+    if (shift > MAX_SAMPLING_SHIFT) {
+        shift = MAX_SAMPLING_SHIFT;
+    }
+    ringbuf_stat_set(RINGBUF_STAT_SAMPLING_RATE, 1 << shift);
+    if (bpf_get_prandom_u32() & ((1 << shift) - 1)) {
+        ringbuf_stat_add(RINGBUF_STAT_SAMPLED_OUT, 1);
+        return 0;
+    }
+    return 1;
+}
+
+// ringbuf_reserve_sampled reserves space for an event that has already been sampled with
+// ringbuf_sample, accounting the events that are dropped because the ring buffer is full
+static __always_inline void *ringbuf_reserve_sampled(u64 size) {
+    void *event = bpf_ringbuf_reserve(&events, size, 0);
+    if (!event) {
+        ringbuf_stat_add(RINGBUF_STAT_DROPPED, 1);
+    }
+    return event;
+}
+
+// ringbuf_reserve reserves space for an event in the ring buffer, accounting the events that
+// are dropped because the ring buffer is full, or discarded by the adaptive sampling
+static __always_inline void *ringbuf_reserve(u64 size) {
+    if (!ringbuf_sample()) {
+        return NULL;
+    }
+    return ringbuf_reserve_sampled(size);
+}
 
 // get_flags prevents waking the userspace process up on each ringbuf message.
 // If wakeup_data_bytes > 0, it will wait until wakeup_data_bytes are accumulated
-// into the buffer before waking the userspace.
+// into the buffer before waking the userspace, or until wakeup_timeout_ns have passed
+// since the last wakeup. In idle periods, userspace reads the messages that have been
+// submitted without wakeup after its own polling timeout.
 static __always_inline long get_flags()
 {
 	long sz;
@@ -37,8 +122,19 @@ static __always_inline long get_flags()
 	if (!wakeup_data_bytes)
 		return 0;
 
+	u32 last_wakeup_key = RINGBUF_STAT_LAST_WAKEUP;
+	u64 *last_wakeup = bpf_map_lookup_elem(&ringbuf_stats, &last_wakeup_key);
+	u64 now = bpf_ktime_get_ns();
+
 	sz = bpf_ringbuf_query(&events, BPF_RB_AVAIL_DATA);
-	return sz >= wakeup_data_bytes ? BPF_RB_FORCE_WAKEUP : BPF_RB_NO_WAKEUP;
+	if (sz >= wakeup_data_bytes
+	    || (wakeup_timeout_ns && last_wakeup && now - *last_wakeup >= wakeup_timeout_ns)) {
+		if (last_wakeup) {
+			*last_wakeup = now;
+		}
+		return BPF_RB_FORCE_WAKEUP;
+	}
+	return BPF_RB_NO_WAKEUP;
 }
 
 #endif
\ No newline at end of file
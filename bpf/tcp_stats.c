@@ -0,0 +1,239 @@
+#include "vmlinux.h"
+#include "common.h"
+#include "bpf_helpers.h"
+#include "bpf_tracing.h"
+#include "bpf_core_read.h"
+#include "bpf_dbg.h"
+#include "pid.h"
+#include "sockaddr.h"
+#include "histogram.h"
+
+#define MAX_TCP_CONNECTIONS 10000
+#define MAX_TCP_METRICS_ENTRIES 4096
+
+char __license[] SEC("license") = "Dual MIT/GPL";
+
+// Upper bounds of the histograms buckets of the connection durations and the smoothed
+// round trip times. The values beyond the last bound are accounted in an extra bucket.
+volatile const u64 conn_duration_bounds_ns[MAX_HISTOGRAM_BOUNDS] = {};
+volatile const u32 conn_duration_bounds_len = 0;
+volatile const u64 srtt_bounds_us[MAX_HISTOGRAM_BOUNDS] = {};
+volatile const u32 srtt_bounds_len = 0;
+
+// The metrics are aggregated by the source (client) and destination (server) of the connections.
+// The ephemeral port of the client is ignored to keep the number of entries bounded.
+typedef struct tcp_metrics_key {
+    u8 src_addr[IP_V6_ADDR_LEN];
+    u8 dst_addr[IP_V6_ADDR_LEN];
+    u32 pid;
+    u16 dst_port;
+    // 1 if the instrumented process is the client side of the connection
+    u8 client;
+} tcp_metrics_key_t;
+
+typedef struct tcp_metrics_value {
+    // bytes sent from the source to the destination
+    u64 bytes_sent;
+    // bytes received by the source from the destination
+    u64 bytes_received;
+    u64 opened;
+    u64 closed;
+    // connections whose establishment failed (e.g. refused or timed out)
+    u64 refused;
+    u64 retransmits;
+    u64 duration_sum_ns;
+    u64 srtt_sum_us;
+    u64 duration_buckets[MAX_HISTOGRAM_BOUNDS + 1];
+    u64 srtt_buckets[MAX_HISTOGRAM_BOUNDS + 1];
+} tcp_metrics_value_t;
+
+typedef struct tcp_conn {
+    tcp_metrics_key_t key;
+    u64 start_monotime_ns;
+} tcp_conn_t;
+
+// Connections of the instrumented processes, by their struct sock pointer. They are
+// registered when the processes accept or start them, so the events that happen outside
+// of the process context (e.g. state changes in a softirq) can be attributed to them.
+struct {
+    __uint(type, BPF_MAP_TYPE_LRU_HASH);
+    __type(key, u64);
+    __type(value, tcp_conn_t);
+    __uint(max_entries, MAX_TCP_CONNECTIONS);
+} tcp_conns SEC(".maps");
+
+// Aggregated metrics of the connections. Userspace periodically reads and deletes its entries.
+struct {
+    __uint(type, BPF_MAP_TYPE_LRU_PERCPU_HASH);
+    __type(key, tcp_metrics_key_t);
+    __type(value, tcp_metrics_value_t);
+    __uint(max_entries, MAX_TCP_METRICS_ENTRIES);
+} tcp_metrics SEC(".maps");
+
+// Temporary storage for the value of new tcp_metrics entries, which is too big for the stack
+struct {
+    __uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+    __type(key, int);
+    __type(value, tcp_metrics_value_t);
+    __uint(max_entries, 1);
+} tcp_metrics_value_mem SEC(".maps");
+
+static __always_inline tcp_metrics_value_t *tcp_metrics_for(tcp_metrics_key_t *key) {
+    tcp_metrics_value_t *value = bpf_map_lookup_elem(&tcp_metrics, key);
+    if (value) {
+        return value;
+    }
+    int zero = 0;
+    value = bpf_map_lookup_elem(&tcp_metrics_value_mem, &zero);
+    if (!value) {
+        return 0;
+    }
+    __builtin_memset(value, 0, sizeof(tcp_metrics_value_t));
+    bpf_map_update_elem(&tcp_metrics, key, value, BPF_NOEXIST);
+    return bpf_map_lookup_elem(&tcp_metrics, key);
+}
+
+static __always_inline void track_connection(struct sock *sk, u32 pid, u8 client) {
+    connection_info_t info = {};
+    if (!parse_sock_info(sk, &info)) {
+        return;
+    }
+
+    // the padding of the key must be zeroed
+    tcp_conn_t conn;
+    __builtin_memset(&conn, 0, sizeof(conn));
+    conn.start_monotime_ns = bpf_ktime_get_ns();
+    conn.key.pid = pid;
+    conn.key.client = client;
+    // the local address of the client connections is the source, and the remote
+    // address of the server connections is the source
+    if (client) {
+        __builtin_memcpy(conn.key.src_addr, info.s_addr, sizeof(conn.key.src_addr));
+        __builtin_memcpy(conn.key.dst_addr, info.d_addr, sizeof(conn.key.dst_addr));
+        conn.key.dst_port = info.d_port;
+    } else {
+        __builtin_memcpy(conn.key.src_addr, info.d_addr, sizeof(conn.key.src_addr));
+        __builtin_memcpy(conn.key.dst_addr, info.s_addr, sizeof(conn.key.dst_addr));
+        conn.key.dst_port = info.s_port;
+    }
+
+    u64 sk_ptr = (u64)sk;
+    bpf_map_update_elem(&tcp_conns, &sk_ptr, &conn, BPF_ANY);
+
+    // the client connections are accounted as opened once they are established
+    if (!client) {
+        tcp_metrics_value_t *value = tcp_metrics_for(&conn.key);
+        if (value) {
+            value->opened++;
+        }
+    }
+}
+
+static __always_inline void close_connection(struct sock *sk, tcp_conn_t *conn) {
+    tcp_metrics_value_t *value = tcp_metrics_for(&conn->key);
+    if (!value) {
+        return;
+    }
+    struct tcp_sock *tp = (struct tcp_sock *)sk;
+    // srtt_us is stored left-shifted by 3 bits
+    u64 srtt_us = BPF_CORE_READ(tp, srtt_us) >> 3;
+    u32 retransmits = BPF_CORE_READ(tp, total_retrans);
+    u64 duration = bpf_ktime_get_ns() - conn->start_monotime_ns;
+
+    // the map is per-CPU, so no atomic operations are needed
+    value->closed++;
+    value->retransmits += retransmits;
+    value->duration_sum_ns += duration;
+    value->srtt_sum_us += srtt_us;
+    histogram_observe(value->duration_buckets, duration, conn_duration_bounds_ns, conn_duration_bounds_len);
+    histogram_observe(value->srtt_buckets, srtt_us, srtt_bounds_us, srtt_bounds_len);
+}
+
+// The bytes are accounted from the point of view of the source (client) of the connection
+static __always_inline void count_bytes(struct sock *sk, u64 bytes, u8 sent) {
+    u64 sk_ptr = (u64)sk;
+    tcp_conn_t *conn = bpf_map_lookup_elem(&tcp_conns, &sk_ptr);
+    if (!conn) {
+        return;
+    }
+    tcp_metrics_value_t *value = tcp_metrics_for(&conn->key);
+    if (!value) {
+        return;
+    }
+    if (sent == conn->key.client) {
+        value->bytes_sent += bytes;
+    } else {
+        value->bytes_received += bytes;
+    }
+}
+
+SEC("kretprobe/inet_csk_accept")
+int BPF_KRETPROBE(kretprobe_inet_csk_accept, struct sock *sk) {
+    u64 id = bpf_get_current_pid_tgid();
+    u32 pid = valid_pid(id);
+    if (!pid || !sk) {
+        return 0;
+    }
+
+    bpf_dbg_printk("=== tcp accept id=%d, sk=%llx ===", id, sk);
+    track_connection(sk, pid, 0);
+    return 0;
+}
+
+SEC("kprobe/tcp_connect")
+int BPF_KPROBE(kprobe_tcp_connect, struct sock *sk) {
+    u64 id = bpf_get_current_pid_tgid();
+    u32 pid = valid_pid(id);
+    if (!pid) {
+        return 0;
+    }
+
+    bpf_dbg_printk("=== tcp connect id=%d, sk=%llx ===", id, sk);
+    track_connection(sk, pid, 1);
+    return 0;
+}
+
+SEC("tracepoint/sock/inet_sock_set_state")
+int tracepoint_tcp_set_state(struct trace_event_raw_inet_sock_set_state *ctx) {
+    if (ctx->protocol != IPPROTO_TCP) {
+        return 0;
+    }
+    u64 sk_ptr = (u64)ctx->skaddr;
+    tcp_conn_t *conn = bpf_map_lookup_elem(&tcp_conns, &sk_ptr);
+    if (!conn) {
+        return 0;
+    }
+
+    if (ctx->oldstate == TCP_SYN_SENT) {
+        tcp_metrics_value_t *value = tcp_metrics_for(&conn->key);
+        if (!value) {
+            return 0;
+        }
+        if (ctx->newstate == TCP_ESTABLISHED) {
+            value->opened++;
+        } else if (ctx->newstate == TCP_CLOSE) {
+            value->refused++;
+            bpf_map_delete_elem(&tcp_conns, &sk_ptr);
+        }
+    } else if (ctx->newstate == TCP_CLOSE) {
+        close_connection((struct sock *)ctx->skaddr, conn);
+        bpf_map_delete_elem(&tcp_conns, &sk_ptr);
+    }
+    return 0;
+}
+
+SEC("kprobe/tcp_sendmsg")
+int BPF_KPROBE(kprobe_tcp_sendmsg, struct sock *sk, struct msghdr *msg, size_t size) {
+    count_bytes(sk, size, 1);
+    return 0;
+}
+
+// tcp_cleanup_rbuf is invoked after the received data is copied to user space
+SEC("kprobe/tcp_cleanup_rbuf")
+int BPF_KPROBE(kprobe_tcp_cleanup_rbuf, struct sock *sk, int copied) {
+    if (copied <= 0) {
+        return 0;
+    }
+    count_bytes(sk, copied, 0);
+    return 0;
+}
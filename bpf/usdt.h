@@ -0,0 +1,107 @@
+#ifndef USDT_H
+#define USDT_H
+
+#include "utils.h"
+#include "bpf_dbg.h"
+
+// Helpers to read the arguments of the USDT probes from the programs returned by the
+// USDTProbes method of the tracers. The user space parses the argument locations of each
+// probe from the .note.stapsdt section, stores them in the usdt_specs map and attaches
+// the program with the spec id as the attach cookie, as libbpf does. Reading the arguments
+// requires the bpf_get_attach_cookie helper (kernel 5.15+).
+
+// These need to line up with the exec.USDTArg* Go identifiers
+#define USDT_ARG_CONST     0 // constant value
+#define USDT_ARG_REG       1 // value of a register
+#define USDT_ARG_REG_DEREF 2 // value in the memory address of a register, plus an offset
+
+// These need to line up with the usdtMaxArgs Go constant
+#define USDT_MAX_ARGS 12
+#define MAX_USDT_SPECS 1024
+
+typedef struct usdt_arg_spec {
+    u64 val_off; // constant value, or offset of the argument from the register address
+    u32 reg_off; // offset of the register in struct pt_regs
+    u8  arg_type;
+    u8  arg_signed;
+    u8  arg_bitshift; // 64 - the size of the argument in bits, to trim and sign-extend it
+    u8  _pad;
+} usdt_arg_spec_t;
+
+typedef struct usdt_spec {
+    usdt_arg_spec_t args[USDT_MAX_ARGS];
+    u16 arg_cnt;
+    u8  _pad[6];
+} usdt_spec_t;
+
+struct {
+    __uint(type, BPF_MAP_TYPE_HASH);
+    __type(key, u32); // key: spec id, which is the attach cookie of the program
+    __type(value, usdt_spec_t);
+    __uint(max_entries, MAX_USDT_SPECS);
+} usdt_specs SEC(".maps");
+
+static __always_inline usdt_spec_t *usdt_spec(struct pt_regs *ctx) {
+    u32 spec_id = (u32)bpf_get_attach_cookie(ctx);
+    return bpf_map_lookup_elem(&usdt_specs, &spec_id);
+}
+
+// Returns the number of arguments of the probe, or a negative number if they are unknown
+static __always_inline int usdt_arg_cnt(struct pt_regs *ctx) {
+    usdt_spec_t *spec = usdt_spec(ctx);
+    if (!spec) {
+        return -1;
+    }
+    return spec->arg_cnt;
+}
+
+// Reads the argument n of the probe, starting at 0, into res. Returns 0 on success.
+static __always_inline int usdt_arg(struct pt_regs *ctx, u32 n, long *res) {
+    usdt_spec_t *spec = usdt_spec(ctx);
+    if (!spec) {
+        bpf_dbg_printk("can't find the USDT spec");
+        return -1;
+    }
+    if (n >= spec->arg_cnt) {
+        return -1;
+    }
+    // keeps the compiler from dropping the check below when it already knows the bounds
+    // of n (e.g. in a loop), so the verifier knows them too
+    asm volatile("" : "+r"(n));
+    if (n >= USDT_MAX_ARGS) {
+        return -1;
+    }
+    usdt_arg_spec_t *arg = &spec->args[n];
+    u64 val = 0;
+    switch (arg->arg_type) {
+    case USDT_ARG_CONST:
+        val = arg->val_off;
+        break;
+    case USDT_ARG_REG:
+        if (bpf_probe_read_kernel(&val, sizeof(val), (void *)ctx + arg->reg_off)) {
+            return -1;
+        }
+        break;
+    case USDT_ARG_REG_DEREF:
+        if (bpf_probe_read_kernel(&val, sizeof(val), (void *)ctx + arg->reg_off)) {
+            return -1;
+        }
+        // reading the whole 64 bits, which are trimmed below (little endian only)
+        if (bpf_probe_read_user(&val, sizeof(val), (void *)(val + arg->val_off))) {
+            return -1;
+        }
+        break;
+    default:
+        return -1;
+    }
+    val <<= arg->arg_bitshift;
+    if (arg->arg_signed) {
+        val = ((s64)val) >> arg->arg_bitshift;
+    } else {
+        val = val >> arg->arg_bitshift;
+    }
+    *res = (long)val;
+    return 0;
+}
+
+#endif
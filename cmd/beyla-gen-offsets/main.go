@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/grafana/go-offsets-tracker/pkg/offsets"
+
+	"github.com/grafana/beyla/pkg/beyla"
+)
+
+const usage = `Usage: %s [-o <file>] [-source <dir>[@<version>]]... [executable]...
+
+Generates an offsets file for the struct fields that Beyla needs to read from Go
+executables. The offsets are taken from the DWARF info of the provided executables, or
+from the compilation of local Go distributions or library source trees (e.g. gRPC).
+The version of library source trees must be provided with the @<version> suffix.
+
+If the output file already exists, the new offsets are merged into it. The generated file
+can be provided to Beyla with the ebpf.go_offsets_file option.
+
+`
+
+type sources []string
+
+func (s *sources) String() string     { return strings.Join(*s, ",") }
+func (s *sources) Set(v string) error { *s = append(*s, v); return nil }
+
+func main() {
+	var srcs sources
+	outPath := flag.String("o", "offsets.json", "path of the output offsets file")
+	flag.Var(&srcs, "source", "Go distribution or library source tree, with an optional @<version> suffix. Can be repeated")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), usage, os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if len(srcs) == 0 && flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := run(*outPath, srcs, flag.Args()); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func run(outPath string, srcs sources, executables []string) error {
+	track := &offsets.Track{}
+	if _, err := os.Stat(outPath); err == nil {
+		if track, err = offsets.Open(outPath); err != nil {
+			return err
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	for _, src := range srcs {
+		dir, version, _ := strings.Cut(src, "@")
+		fmt.Fprintln(os.Stderr, "compiling against", dir)
+		found, err := beyla.OffsetsFromSource(dir, version)
+		if err != nil {
+			return fmt.Errorf("source %s: %w", src, err)
+		}
+		if err := beyla.MergeOffsets(track, found); err != nil {
+			return fmt.Errorf("source %s: %w", src, err)
+		}
+	}
+	for _, exe := range executables {
+		fmt.Fprintln(os.Stderr, "inspecting", exe)
+		found, err := beyla.OffsetsFromExecutable(exe)
+		if err != nil {
+			return fmt.Errorf("executable %s: %w", exe, err)
+		}
+		if err := beyla.MergeOffsets(track, found); err != nil {
+			return fmt.Errorf("executable %s: %w", exe, err)
+		}
+	}
+
+	out, err := os.Create(outPath)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	return beyla.WriteOffsets(out, track)
+}
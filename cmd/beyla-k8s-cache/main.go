@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/grafana/beyla/pkg/beyla"
+	"github.com/grafana/beyla/pkg/buildinfo"
+)
+
+func main() {
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelInfo,
+	})))
+
+	slog.Info("Grafana Beyla Kubernetes metadata cache", "Version", buildinfo.Version)
+
+	cfg := beyla.KubeCacheConfig{}
+	flag.IntVar(&cfg.Port, "port", 8999, "port of the HTTP metadata cache service")
+	flag.StringVar(&cfg.KubeconfigPath, "kubeconfig", "", "path to the kubeconfig file. If unset, it will look in the usual locations")
+	flag.DurationVar(&cfg.InformersSyncTimeout, "sync-timeout", 30*time.Second, "maximum time to wait for the synchronization of the informers")
+	flag.Parse()
+
+	ctx, _ := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+
+	if err := beyla.RunKubeCache(ctx, &cfg); err != nil {
+		slog.Error("Beyla Kubernetes metadata cache stopped", "error", err)
+		os.Exit(-1)
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/grafana/beyla/pkg/beyla"
+)
+
+const inspectUsage = `Usage: %s inspect [-config <file>] <PID or executable path>
+
+Reports how Beyla would instrument the given process or executable file, without
+instrumenting it. Inspecting executable files does not require root privileges.
+
+`
+
+// inspect runs the inspect subcommand and returns the exit code
+func inspect(args []string) int {
+	// only warnings and errors are logged, to not pollute the report
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn})))
+
+	flags := flag.NewFlagSet("inspect", flag.ExitOnError)
+	configPath := flags.String("config", "", "path to the configuration file")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), inspectUsage, os.Args[0])
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	insp, err := beyla.Inspect(loadConfig(configPath), flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+	printInspection(os.Stdout, insp)
+	return 0
+}
+
+func printInspection(out io.Writer, insp *beyla.Inspection) {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	defer w.Flush()
+
+	if insp.Pid != 0 {
+		fmt.Fprintf(w, "PID:\t%d\n", insp.Pid)
+	}
+	fmt.Fprintf(w, "Executable:\t%s\n", insp.ExePath)
+	fmt.Fprintf(w, "Instrumentation:\t%s\n", insp.Type)
+	fmt.Fprintf(w, "Language:\t%s\n", orUnknown(insp.Language))
+	fmt.Fprintf(w, "Runtime:\t%s\n", orUnknown(insp.RuntimeName))
+	if insp.RuntimeVersion != "" {
+		fmt.Fprintf(w, "Runtime version:\t%s\n", insp.RuntimeVersion)
+	}
+	if insp.GoError != "" {
+		fmt.Fprintf(w, "Go instrumentation:\tnot available: %s\n", insp.GoError)
+	}
+
+	if len(insp.Modules) > 0 {
+		fmt.Fprintf(w, "\nGo modules:\n")
+		for _, mod := range sortedKeys(insp.Modules) {
+			fmt.Fprintf(w, "  %s\t%s\n", mod, insp.Modules[mod])
+		}
+	}
+
+	if len(insp.Functions) > 0 {
+		fmt.Fprintf(w, "\nGo functions:\tstart\treturns\n")
+		for _, fn := range sortedKeys(insp.Functions) {
+			offs := insp.Functions[fn]
+			returns := make([]string, 0, len(offs.Returns))
+			for _, r := range offs.Returns {
+				returns = append(returns, fmt.Sprintf("0x%x", r))
+			}
+			fmt.Fprintf(w, "  %s\t0x%x\t%s\n", fn, offs.Start, strings.Join(returns, ","))
+		}
+	}
+
+	if len(insp.Fields) > 0 {
+		fmt.Fprintf(w, "\nStruct fields:\toffset\tsource\n")
+		for _, f := range insp.Fields {
+			offset := "-"
+			if f.Offset != nil {
+				offset = fmt.Sprint(f.Offset)
+			}
+			fmt.Fprintf(w, "  %s.%s (%s)\t%s\t%s\n", f.Struct, f.Field, f.Constant, offset, f.Source)
+		}
+	}
+
+	fmt.Fprintf(w, "\nShared libraries:\n")
+	if len(insp.SharedLibs) == 0 {
+		fmt.Fprintf(w, "  (none)\n")
+	}
+	for _, lib := range insp.SharedLibs {
+		fmt.Fprintf(w, "  %s\n", lib)
+	}
+
+	fmt.Fprintf(w, "\nTracers:\n")
+	for _, t := range insp.Tracers {
+		status := "kept"
+		if !t.Kept {
+			status = "discarded"
+		}
+		if len(t.MissingFunctions) > 0 {
+			status += " (missing: " + strings.Join(t.MissingFunctions, ", ") + ")"
+		}
+		fmt.Fprintf(w, "  %s\t%s\n", t.Name, status)
+	}
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
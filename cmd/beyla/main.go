@@ -16,18 +16,26 @@ import (
 	otelsdk "go.opentelemetry.io/otel/sdk"
 
 	"github.com/grafana/beyla/pkg/beyla"
+	"github.com/grafana/beyla/pkg/buildinfo"
 )
 
-var Version = "main"
-
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "inspect":
+			os.Exit(inspect(os.Args[2:]))
+		case "preflight":
+			os.Exit(preflight(os.Args[2:]))
+		}
+	}
+
 	lvl := slog.LevelVar{}
 	lvl.Set(slog.LevelInfo)
 	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
 		Level: &lvl,
 	})))
 
-	slog.Info("Grafana Beyla", "Version", Version, "OpenTelemetry SDK Version", otelsdk.Version())
+	slog.Info("Grafana Beyla", "Version", buildinfo.Version, "OpenTelemetry SDK Version", otelsdk.Version())
 
 	configPath := flag.String("config", "", "path to the configuration file")
 	flag.Parse()
@@ -52,18 +60,31 @@ func main() {
 	// child process isn't found.
 	ctx, _ := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 
-	// TODO: when we split Beyla in two executables, this code can be split:
-	// in two parts:
-	// 1st executable - Invoke FindTarget, which also mounts the BPF maps
-	// 2nd executable - Invoke ReadAndForward, receiving the BPF map mountpoint as argument
 	instr := beyla.New(config)
-	if err := instr.FindAndInstrument(ctx); err != nil {
-		slog.Error("Beyla couldn't find target process", "error", err)
-		os.Exit(-1)
-	}
-	if err := instr.ReadAndForward(ctx); err != nil {
-		slog.Error("Beyla couldn't start read and forwarding", "error", err)
-		os.Exit(-1)
+	switch config.Split.Mode {
+	case beyla.SplitModeLoader:
+		// privileged loader: only discovers and instruments the processes, pinning the BPF maps
+		if err := instr.FindAndPin(ctx); err != nil {
+			slog.Error("Beyla couldn't start the loader", "error", err)
+			os.Exit(-1)
+		}
+		<-ctx.Done()
+	case beyla.SplitModeProcessor:
+		// unprivileged processor: reads the maps pinned by the loader, then processes and exports the traces
+		instr.ReceivePinned(ctx)
+		if err := instr.ReadAndForward(ctx); err != nil {
+			slog.Error("Beyla couldn't start read and forwarding", "error", err)
+			os.Exit(-1)
+		}
+	default:
+		if err := instr.FindAndInstrument(ctx); err != nil {
+			slog.Error("Beyla couldn't find target process", "error", err)
+			os.Exit(-1)
+		}
+		if err := instr.ReadAndForward(ctx); err != nil {
+			slog.Error("Beyla couldn't start read and forwarding", "error", err)
+			os.Exit(-1)
+		}
 	}
 
 	if gc := os.Getenv("GOCOVERDIR"); gc != "" {
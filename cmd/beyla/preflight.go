@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+
+	"github.com/grafana/beyla/pkg/beyla"
+)
+
+const preflightUsage = `Usage: %s preflight [-config <file>]
+
+Checks that the host provides the kernel features and privileges that are required by
+Beyla, and suggests how to fix the failed checks. It returns a non-zero exit code if
+any check fails.
+
+`
+
+// preflight runs the preflight subcommand and returns the exit code
+func preflight(args []string) int {
+	// only warnings and errors are logged, to not pollute the report
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn})))
+
+	flags := flag.NewFlagSet("preflight", flag.ExitOnError)
+	configPath := flags.String("config", "", "path to the configuration file")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), preflightUsage, os.Args[0])
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return 2
+	}
+
+	report := beyla.Preflight(loadConfig(configPath))
+	printPreflight(os.Stdout, report)
+	if report.Failed() {
+		return 1
+	}
+	return 0
+}
+
+func printPreflight(out io.Writer, report *beyla.PreflightReport) {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "CHECK\tSTATUS\tDETAILS\n")
+	for _, c := range report.Checks {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", c.Name, c.Status, c.Details)
+	}
+	_ = w.Flush()
+
+	first := true
+	for _, c := range report.Checks {
+		if c.Status == beyla.PreflightPass || c.Hint == "" {
+			continue
+		}
+		if first {
+			fmt.Fprintf(out, "\nHints:\n")
+			first = false
+		}
+		fmt.Fprintf(out, "  %s (%s): %s\n", c.Name, c.Status, c.Hint)
+	}
+}
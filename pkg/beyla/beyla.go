@@ -10,15 +10,23 @@ import (
 
 	"github.com/grafana/beyla/pkg/internal/connector"
 	"github.com/grafana/beyla/pkg/internal/discover"
+	"github.com/grafana/beyla/pkg/internal/ebpf"
 	"github.com/grafana/beyla/pkg/internal/imetrics"
 	"github.com/grafana/beyla/pkg/internal/pipe"
 	"github.com/grafana/beyla/pkg/internal/pipe/global"
 	"github.com/grafana/beyla/pkg/internal/request"
+	"github.com/grafana/beyla/pkg/internal/split"
 )
 
 // Config as provided by the user to configure and run Beyla
 type Config pipe.Config
 
+// Modes of execution of Beyla, as specified in the Config.Split.Mode property
+const (
+	SplitModeLoader    = split.ModeLoader
+	SplitModeProcessor = split.ModeProcessor
+)
+
 func log() *slog.Logger {
 	return slog.With("component", "beyla.Instrumenter")
 }
@@ -30,9 +38,8 @@ type Instrumenter struct {
 	ctxInfo *global.ContextInfo
 
 	// tracesInput is used to communicate the found traces between the ProcessFinder and
-	// the ProcessTracer.
-	// TODO: When we split beyla into two executables, probably the BPF map
-	// should be the traces' communication mechanism instead of a native channel
+	// the ProcessTracer. When Beyla runs as a separate loader and processor, the
+	// pinned BPF maps are read by the processor and forwarded to this channel.
 	tracesInput chan []request.Span
 }
 
@@ -84,14 +91,84 @@ func (i *Instrumenter) FindAndInstrument(ctx context.Context) error {
 	return nil
 }
 
+// FindAndPin runs Beyla as a privileged loader: it searches in background for any new
+// executable matching the selection criteria, instruments it and pins the BPF maps of its
+// tracers. The pinned maps are notified to the processors that connect to the loader socket.
+func (i *Instrumenter) FindAndPin(ctx context.Context) error {
+	loader, err := split.Listen(&i.config.Split)
+	if err != nil {
+		return fmt.Errorf("couldn't start loader socket: %w", err)
+	}
+	go loader.Serve(ctx)
+	finder := discover.NewProcessFinder(ctx, i.config, i.ctxInfo.Metrics)
+	foundProcesses, err := finder.Start(i.config)
+	if err != nil {
+		return fmt.Errorf("couldn't start Process Finder: %w", err)
+	}
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				log().Debug("stopped searching for new processes to instrument")
+				return
+			case pt := <-foundProcesses:
+				go i.pin(ctx, loader, pt)
+			}
+		}
+	}()
+	return nil
+}
+
+func (i *Instrumenter) pin(ctx context.Context, loader *split.Loader, pt *ebpf.ProcessTracer) {
+	pinned, err := pt.Pin(ctx)
+	if err != nil {
+		log().Error("can't pin process tracer", "error", err)
+		return
+	}
+	if err := loader.Publish(pinned); err != nil {
+		log().Error("can't notify pinned process tracer", "error", err, "path", pinned.PinPath)
+	}
+}
+
+// ReceivePinned runs Beyla as an unprivileged processor: it receives from the loader socket
+// the processes that have been instrumented by a privileged loader, and reads in background
+// the traces from their pinned BPF maps. They are processed and forwarded by ReadAndForward.
+func (i *Instrumenter) ReceivePinned(ctx context.Context) {
+	pinnedEvents := split.Receive(ctx, &i.config.Split)
+	go func() {
+		// stops the tracers of each pinned process, by its pin path
+		stopTracers := map[string]context.CancelFunc{}
+		for ev := range pinnedEvents {
+			pp := ev.Process
+			if ev.Removed {
+				if stop, ok := stopTracers[pp.PinPath]; ok {
+					log().Info("stopped reading traces from pinned process tracer", "path", pp.PinPath)
+					stop()
+					delete(stopTracers, pp.PinPath)
+				}
+				continue
+			}
+			tracers, err := discover.PinnedTracers(i.config, i.ctxInfo.Metrics, pp)
+			if err != nil {
+				log().Error("can't read pinned process tracer", "error", err, "path", pp.PinPath)
+				continue
+			}
+			log().Info("reading traces from pinned process tracer", "path", pp.PinPath, "service", pp.Service.String())
+			tracersCtx, stop := context.WithCancel(ctx)
+			stopTracers[pp.PinPath] = stop
+			for _, t := range tracers {
+				go t.Run(tracersCtx, i.tracesInput, pp.Service)
+			}
+		}
+	}()
+}
+
 // ReadAndForward keeps listening for traces in the BPF map, then reads,
 // processes and forwards them
 func (i *Instrumenter) ReadAndForward(ctx context.Context) error {
 	log := log()
 	log.Debug("creating instrumentation pipeline")
 
-	// TODO: when we split the executable, tracer should be reconstructed somehow
-	// from this instance
 	bp, err := pipe.Build(ctx, i.config, i.ctxInfo, i.tracesInput)
 	if err != nil {
 		return fmt.Errorf("can't instantiate instrumentation pipeline: %w", err)
@@ -114,6 +191,13 @@ func buildContextInfo(config *pipe.Config) *global.ContextInfo {
 		ReportRoutes:  config.Routes != nil,
 		Prometheus:    promMgr,
 		K8sDecoration: config.Kubernetes.Enabled(),
+
+		MetricRequestHeaders:  config.EBPF.HTTPHeaders.MetricRequestHeaders(),
+		MetricResponseHeaders: config.EBPF.HTTPHeaders.MetricResponseHeaders(),
+	}
+	if ctxInfo.K8sDecoration {
+		ctxInfo.K8sPodLabels = config.Kubernetes.PodLabels
+		ctxInfo.K8sPodAnnotations = config.Kubernetes.PodAnnotations
 	}
 	if config.InternalMetrics.Prometheus.Port != 0 {
 		slog.Debug("reporting internal metrics as Prometheus")
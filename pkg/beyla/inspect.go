@@ -0,0 +1,16 @@
+package beyla
+
+import (
+	"github.com/grafana/beyla/pkg/internal/discover"
+	"github.com/grafana/beyla/pkg/internal/pipe"
+)
+
+// Inspection reports how Beyla would instrument a given process or executable
+type Inspection = discover.Inspection
+
+// Inspect analyzes a process, if the target is a PID, or an executable file otherwise, and
+// reports how Beyla would instrument it. It doesn't load any eBPF program so, for executable
+// files, it doesn't require root privileges.
+func Inspect(config *Config, target string) (*Inspection, error) {
+	return discover.Inspect((*pipe.Config)(config), target)
+}
@@ -0,0 +1,45 @@
+package beyla
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/grafana/beyla/pkg/internal/transform/kube"
+)
+
+// KubeCacheConfig configures the Kubernetes metadata cache service
+type KubeCacheConfig struct {
+	// Port where the cache service listens for HTTP requests
+	Port int
+	// KubeconfigPath is optional. If unset, it will look in the usual location.
+	KubeconfigPath string
+	// InformersSyncTimeout is the maximum time to wait for the informers' synchronization
+	InformersSyncTimeout time.Duration
+}
+
+// RunKubeCache starts cluster-wide Kubernetes informers and serves the metadata of the
+// Pods and Services to the Beyla instances that are configured with node-local informers.
+// It blocks until the passed context is cancelled.
+func RunKubeCache(ctx context.Context, cfg *KubeCacheConfig) error {
+	log := slog.With("component", "beyla.KubeCache")
+	meta := kube.Metadata{}
+	if err := meta.InitFromConfig(cfg.KubeconfigPath, cfg.InformersSyncTimeout); err != nil {
+		return fmt.Errorf("starting kubernetes informers: %w", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle(kube.CachePath, meta.CacheHandler())
+	server := &http.Server{Addr: fmt.Sprintf(":%d", cfg.Port), Handler: mux}
+	go func() {
+		<-ctx.Done()
+		_ = server.Close()
+	}()
+	log.Info("serving kubernetes metadata", "port", cfg.Port, "path", kube.CachePath)
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
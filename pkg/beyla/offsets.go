@@ -0,0 +1,40 @@
+package beyla
+
+import (
+	"debug/elf"
+	"fmt"
+	"io"
+
+	"github.com/grafana/go-offsets-tracker/pkg/offsets"
+
+	"github.com/grafana/beyla/pkg/internal/goexec"
+)
+
+// OffsetsFromExecutable returns the offsets database entries for the struct fields required
+// by Beyla, as read from the DWARF info of a Go executable.
+func OffsetsFromExecutable(path string) (*offsets.Track, error) {
+	elfFile, err := elf.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't open ELF file %s: %w", path, err)
+	}
+	defer elfFile.Close()
+	return goexec.OffsetsFromExecutable(elfFile, nil)
+}
+
+// OffsetsFromSource returns the offsets database entries for the struct fields required by
+// Beyla, by compiling a program against a local Go distribution or library source tree. The
+// version is required for library source trees, and optional for Go distributions.
+func OffsetsFromSource(sourceDir, version string) (*offsets.Track, error) {
+	return goexec.OffsetsFromSource(sourceDir, version)
+}
+
+// MergeOffsets merges the src offsets database into dst. The src entries override the dst
+// entries for the same struct fields and versions.
+func MergeOffsets(dst, src *offsets.Track) error {
+	return goexec.MergeOffsets(dst, src)
+}
+
+// WriteOffsets writes an offsets database in the format expected by the ebpf.go_offsets_file option
+func WriteOffsets(out io.Writer, track *offsets.Track) error {
+	return goexec.WriteOffsets(out, track)
+}
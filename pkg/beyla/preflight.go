@@ -0,0 +1,24 @@
+package beyla
+
+import (
+	"github.com/grafana/beyla/pkg/internal/discover"
+	"github.com/grafana/beyla/pkg/internal/ebpf/preflight"
+	"github.com/grafana/beyla/pkg/internal/pipe"
+)
+
+// PreflightReport lists the results of the checks of the kernel features and privileges
+// that are required by Beyla
+type PreflightReport = preflight.Report
+
+// Status of a preflight check
+const (
+	PreflightPass = preflight.StatusPass
+	PreflightWarn = preflight.StatusWarn
+	PreflightFail = preflight.StatusFail
+)
+
+// Preflight checks that the host provides the kernel features and privileges that are
+// required by the eBPF tracers of Beyla.
+func Preflight(config *Config) *PreflightReport {
+	return discover.Preflight((*pipe.Config)(config))
+}
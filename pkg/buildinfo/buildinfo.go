@@ -0,0 +1,5 @@
+// Package buildinfo provides information about the current Beyla build
+package buildinfo
+
+// Version of Beyla. It is overridden at build time from the Makefile, via the linker flags.
+var Version = "main"
@@ -11,6 +11,7 @@ import (
 	"github.com/mariomac/pipes/pkg/node"
 
 	"github.com/grafana/beyla/pkg/internal/ebpf"
+	"github.com/grafana/beyla/pkg/internal/ebpf/preflight"
 	"github.com/grafana/beyla/pkg/internal/goexec"
 	"github.com/grafana/beyla/pkg/internal/imetrics"
 	"github.com/grafana/beyla/pkg/internal/pipe"
@@ -24,6 +25,9 @@ type TraceAttacher struct {
 	Ctx               context.Context
 	DiscoveredTracers chan *ebpf.ProcessTracer
 	Metrics           imetrics.Reporter
+	// Preflight report, used to discard the tracers that are not supported by the host.
+	// If nil, all the tracers are considered as supported.
+	Preflight *preflight.Report
 
 	log *slog.Logger
 }
@@ -65,6 +69,7 @@ func (ta *TraceAttacher) getTracer(ie Instrumentable) (*ebpf.ProcessTracer, bool
 	default:
 		ta.log.Warn("unexpected instrumentable type. This is basically a bug", "type", ie.Type)
 	}
+	programs = ta.Preflight.Filter(programs)
 	if len(programs) == 0 {
 		ta.log.Warn("no instrumentable functions found. Ignoring", "pid", ie.FileInfo.Pid, "cmd", ie.FileInfo.CmdExePath)
 		return nil, false
@@ -86,6 +91,7 @@ func (ta *TraceAttacher) getTracer(ie Instrumentable) (*ebpf.ProcessTracer, bool
 		Exe:        exe,
 		PinPath:    path.Join(ta.Cfg.EBPF.BpfBaseDir, fmt.Sprintf("%d-%d", os.Getpid(), ie.FileInfo.Pid)),
 		SystemWide: ta.Cfg.Discovery.SystemWide,
+		EBPFConfig: &ta.Cfg.EBPF,
 	}, true
 }
 
@@ -8,11 +8,14 @@ import (
 	"github.com/mariomac/pipes/pkg/node"
 
 	"github.com/grafana/beyla/pkg/internal/ebpf"
+	"github.com/grafana/beyla/pkg/internal/ebpf/dns"
 	"github.com/grafana/beyla/pkg/internal/ebpf/goruntime"
 	"github.com/grafana/beyla/pkg/internal/ebpf/gosql"
 	"github.com/grafana/beyla/pkg/internal/ebpf/grpc"
 	"github.com/grafana/beyla/pkg/internal/ebpf/httpfltr"
 	"github.com/grafana/beyla/pkg/internal/ebpf/nethttp"
+	"github.com/grafana/beyla/pkg/internal/ebpf/preflight"
+	"github.com/grafana/beyla/pkg/internal/ebpf/tcpstats"
 	"github.com/grafana/beyla/pkg/internal/imetrics"
 	"github.com/grafana/beyla/pkg/internal/pipe"
 )
@@ -26,6 +29,8 @@ type ProcessFinder struct {
 }
 
 func NewProcessFinder(ctx context.Context, cfg *pipe.Config, metrics imetrics.Reporter) *ProcessFinder {
+	report := Preflight(cfg)
+	report.Log()
 	return &ProcessFinder{
 		Watcher:         Watcher{Ctx: ctx, PollInterval: cfg.Discovery.PollInterval},
 		CriteriaMatcher: CriteriaMatcher{Cfg: cfg},
@@ -35,6 +40,7 @@ func NewProcessFinder(ctx context.Context, cfg *pipe.Config, metrics imetrics.Re
 			Ctx:               ctx,
 			DiscoveredTracers: make(chan *ebpf.ProcessTracer),
 			Metrics:           metrics,
+			Preflight:         report,
 		},
 	}
 }
@@ -55,20 +61,40 @@ func (pf *ProcessFinder) Start(cfg *pipe.Config) (<-chan *ebpf.ProcessTracer, er
 	return pf.DiscoveredTracers, nil
 }
 
+// Preflight checks that the host supports all the tracers that Beyla might load
+func Preflight(cfg *pipe.Config) *preflight.Report {
+	return preflight.Run(cfg.EBPF.BpfBaseDir,
+		append(newGoTracersGroup(cfg, imetrics.NoopReporter{}), newNonGoTracersGroup(cfg, imetrics.NoopReporter{})...))
+}
+
 // auxiliary functions to instantiate the go and non-go tracers on diverse steps of the
 // discovery pipeline
 
 func newGoTracersGroup(cfg *pipe.Config, metrics imetrics.Reporter) []ebpf.Tracer {
 	// Each program is an eBPF source: net/http, grpc...
-	return []ebpf.Tracer{
+	return append([]ebpf.Tracer{
 		&nethttp.Tracer{Cfg: &cfg.EBPF, Metrics: metrics},
 		&nethttp.GinTracer{Tracer: nethttp.Tracer{Cfg: &cfg.EBPF, Metrics: metrics}},
 		&grpc.Tracer{Cfg: &cfg.EBPF, Metrics: metrics},
 		&goruntime.Tracer{Cfg: &cfg.EBPF, Metrics: metrics},
 		&gosql.Tracer{Cfg: &cfg.EBPF, Metrics: metrics},
-	}
+	}, newNetworkTracersGroup(cfg, metrics)...)
 }
 
 func newNonGoTracersGroup(cfg *pipe.Config, metrics imetrics.Reporter) []ebpf.Tracer {
-	return []ebpf.Tracer{&httpfltr.Tracer{Cfg: cfg, Metrics: metrics}}
+	return append([]ebpf.Tracer{&httpfltr.Tracer{Cfg: cfg, Metrics: metrics}},
+		newNetworkTracersGroup(cfg, metrics)...)
+}
+
+// newNetworkTracersGroup returns the optional tracers that are attached to any
+// instrumented process, independently of its language
+func newNetworkTracersGroup(cfg *pipe.Config, metrics imetrics.Reporter) []ebpf.Tracer {
+	var tracers []ebpf.Tracer
+	if cfg.EBPF.NetworkMetrics {
+		tracers = append(tracers, &tcpstats.Tracer{Cfg: cfg, Metrics: metrics})
+	}
+	if cfg.EBPF.DNSTracing {
+		tracers = append(tracers, &dns.Tracer{Cfg: cfg, Metrics: metrics})
+	}
+	return tracers
 }
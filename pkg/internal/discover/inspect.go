@@ -0,0 +1,179 @@
+package discover
+
+import (
+	"debug/elf"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/beyla/pkg/internal/discover/services"
+	"github.com/grafana/beyla/pkg/internal/ebpf"
+	"github.com/grafana/beyla/pkg/internal/exec"
+	"github.com/grafana/beyla/pkg/internal/goexec"
+	"github.com/grafana/beyla/pkg/internal/imetrics"
+	"github.com/grafana/beyla/pkg/internal/pipe"
+	"github.com/grafana/beyla/pkg/internal/transform/process"
+)
+
+// Inspection reports how Beyla would instrument a given process or executable
+type Inspection struct {
+	// Pid of the inspected process. Zero if an executable file has been inspected.
+	Pid     int32
+	ExePath string
+	Type    InstrumentableType
+	// Language, RuntimeName and RuntimeVersion are empty if they can't be detected
+	Language       string
+	RuntimeName    string
+	RuntimeVersion string
+	// Modules and their versions, for Go executables. The Go version is stored in the "go" key.
+	Modules map[string]string
+	// GoError explains why the Go-specific instrumentation can't be used, if that's the case
+	GoError string
+	// Functions whose Go probes have been found in the executable, with their offsets
+	Functions map[string]goexec.FuncOffsets
+	Fields    []goexec.FieldOffsetInfo
+	// SharedLibs that are dynamically linked by the executable (or mapped by the process)
+	SharedLibs []string
+	Tracers    []TracerInspection
+}
+
+// TracerInspection reports whether a tracer would instrument the inspected executable
+type TracerInspection struct {
+	Name string
+	Kept bool
+	// MissingFunctions lists the required Go functions that are not in the executable
+	MissingFunctions []string
+}
+
+// Inspect analyzes the provided process, if the target is a PID, or the executable file
+// otherwise. It does not load any eBPF program, so it doesn't require root privileges
+// unless the target process belongs to another user.
+func Inspect(cfg *pipe.Config, target string) (*Inspection, error) {
+	if cfg.EBPF.GoOffsetsFile != "" {
+		if err := goexec.LoadOffsetsFile(cfg.EBPF.GoOffsetsFile); err != nil {
+			return nil, err
+		}
+	}
+	fi, err := inspectedFile(target)
+	if err != nil {
+		return nil, err
+	}
+	defer fi.ELF.Close()
+
+	insp := &Inspection{Pid: fi.Pid, ExePath: fi.CmdExePath, Type: InstrumentableGeneric}
+	if rt, ok := process.DetectRuntime(fi); ok {
+		insp.Language, insp.RuntimeName, insp.RuntimeVersion = rt.Language, rt.Name, rt.Version
+	}
+	insp.SharedLibs = sharedLibs(fi)
+
+	goTracers := newGoTracersGroup(cfg, imetrics.NoopReporter{})
+	var offsets *goexec.Offsets
+	if cfg.Discovery.SkipGoSpecificTracers {
+		insp.GoError = "Go-specific tracers are disabled by configuration"
+	} else if offsets, err = goexec.InspectOffsets(fi, goFunctionNames(goTracers)); err != nil {
+		insp.GoError = err.Error()
+	} else if isGoProxy(offsets) {
+		insp.GoError = "only Go runtime functions found. Treating it as a proxy"
+	} else {
+		insp.Type = InstrumentableGolang
+	}
+	// non-Go executables don't have modules information
+	insp.Modules, _ = goexec.LibraryVersions(fi.ELF)
+	if offsets != nil {
+		insp.Functions = offsets.Funcs
+		if insp.Fields, err = goexec.InspectFieldOffsets(fi.ELF); err != nil {
+			return nil, fmt.Errorf("inspecting struct field offsets: %w", err)
+		}
+	}
+
+	if insp.Type == InstrumentableGolang {
+		kept := map[ebpf.Tracer]struct{}{}
+		for _, t := range filterNotFoundPrograms(goTracers, offsets) {
+			kept[t] = struct{}{}
+		}
+		for _, t := range goTracers {
+			_, ok := kept[t]
+			insp.Tracers = append(insp.Tracers, TracerInspection{
+				Name: ebpf.TracerName(t), Kept: ok, MissingFunctions: missingFunctions(t, offsets),
+			})
+		}
+	} else {
+		for _, t := range newNonGoTracersGroup(cfg, imetrics.NoopReporter{}) {
+			insp.Tracers = append(insp.Tracers, TracerInspection{Name: ebpf.TracerName(t), Kept: true})
+		}
+	}
+	return insp, nil
+}
+
+// inspectedFile returns the ELF info of the process, if the target is a PID, or of the
+// executable file otherwise
+func inspectedFile(target string) (*exec.FileInfo, error) {
+	pid, err := strconv.Atoi(target)
+	if err != nil {
+		fi := &exec.FileInfo{CmdExePath: target, ProExeLinkPath: target}
+		if fi.ELF, err = elf.Open(target); err != nil {
+			return nil, fmt.Errorf("can't open ELF file %s: %w", target, err)
+		}
+		return fi, nil
+	}
+	pp := processPorts{pid: PID(pid)}
+	proc, err := processInfo(pp)
+	if err != nil {
+		return nil, fmt.Errorf("inspecting process %d: %w", pid, err)
+	}
+	return exec.FindExecELF(proc, serviceID(&services.Attributes{}, proc))
+}
+
+// goFunctionNames returns the names of all the Go functions that are inspected by the
+// provided tracers, without duplicates
+func goFunctionNames(tracers []ebpf.Tracer) []string {
+	var names []string
+	unique := map[string]struct{}{}
+	for _, t := range tracers {
+		for fn := range t.GoProbes() {
+			if _, ok := unique[fn]; !ok {
+				unique[fn] = struct{}{}
+				names = append(names, fn)
+			}
+		}
+	}
+	return names
+}
+
+func missingFunctions(t ebpf.Tracer, offsets *goexec.Offsets) []string {
+	var missing []string
+	for fn, fp := range t.GoProbes() {
+		if _, ok := offsets.Funcs[fn]; fp.Required && !ok {
+			missing = append(missing, fn)
+		}
+	}
+	slices.Sort(missing)
+	return missing
+}
+
+// sharedLibs returns the libraries that are dynamically linked by the executable and, for
+// running processes, also the libraries that are mapped in memory (e.g. loaded with dlopen)
+func sharedLibs(fi *exec.FileInfo) []string {
+	unique := map[string]struct{}{}
+	if libs, err := fi.ELF.ImportedLibraries(); err == nil {
+		for _, lib := range libs {
+			unique[lib] = struct{}{}
+		}
+	}
+	if fi.Pid != 0 {
+		if maps, err := exec.FindLibMaps(fi.Pid); err == nil {
+			for _, m := range maps {
+				if strings.Contains(m.Pathname, ".so") {
+					unique[m.Pathname] = struct{}{}
+				}
+			}
+		}
+	}
+	libs := make([]string, 0, len(unique))
+	for lib := range unique {
+		libs = append(libs, lib)
+	}
+	slices.Sort(libs)
+	return libs
+}
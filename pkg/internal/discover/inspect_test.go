@@ -0,0 +1,33 @@
+package discover
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/beyla/pkg/internal/pipe"
+)
+
+func TestInspect_GoExecutable(t *testing.T) {
+	exe, err := os.Executable()
+	require.NoError(t, err)
+	// the test executable is a Go executable
+	insp, err := Inspect(&pipe.Config{}, exe)
+	require.NoError(t, err)
+	assert.Equal(t, exe, insp.ExePath)
+	assert.Zero(t, insp.Pid)
+	assert.Equal(t, "go", insp.Language)
+	assert.Equal(t, "go", insp.RuntimeName)
+	assert.NotEmpty(t, insp.Modules["go"])
+	assert.NotEmpty(t, insp.Tracers)
+	for _, tr := range insp.Tracers {
+		assert.Equal(t, tr.Kept, len(tr.MissingFunctions) == 0, tr.Name)
+	}
+}
+
+func TestInspect_NotFound(t *testing.T) {
+	_, err := Inspect(&pipe.Config{}, "/this/file/does/not/exist")
+	assert.Error(t, err)
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/shirou/gopsutil/process"
 
 	"github.com/grafana/beyla/pkg/internal/discover/services"
+	"github.com/grafana/beyla/pkg/internal/exec"
 	"github.com/grafana/beyla/pkg/internal/pipe"
 )
 
@@ -42,7 +43,9 @@ type matcher struct {
 // ProcessMatch matches a found process with the first selection criteria it fulfilled.
 type ProcessMatch struct {
 	Criteria *services.Attributes
-	Process  *services.ProcessInfo
+	// CriteriaEntry is the position, starting at 1, of the Criteria in the discovery criteria list
+	CriteriaEntry int
+	Process       *services.ProcessInfo
 }
 
 func (m *matcher) run(in <-chan []Event[processPorts], out chan<- []Event[ProcessMatch]) {
@@ -77,7 +80,7 @@ func (m *matcher) filter(events []Event[processPorts]) []Event[ProcessMatch] {
 				m.log.Debug("found process", "pid", proc.Pid, "comm", comm)
 				matches = append(matches, Event[ProcessMatch]{
 					Type: EventCreated,
-					Obj:  ProcessMatch{Criteria: &m.criteria[i], Process: proc},
+					Obj:  ProcessMatch{Criteria: &m.criteria[i], CriteriaEntry: i + 1, Process: proc},
 				})
 				m.processHistory[ev.Obj.pid] = struct{}{}
 				break
@@ -88,12 +91,19 @@ func (m *matcher) filter(events []Event[processPorts]) []Event[ProcessMatch] {
 }
 
 func (m *matcher) matchProcess(p *services.ProcessInfo, a *services.Attributes) bool {
-	if !a.Path.IsSet() && a.OpenPorts.Len() == 0 {
+	if !a.Path.IsSet() && a.OpenPorts.Len() == 0 && !a.SystemdUnit.IsSet() && !a.CgroupPath.IsSet() {
 		return false
 	}
 	if a.Path.IsSet() && !m.matchByExecutable(p, a) {
 		return false
 	}
+	// processes out of a systemd unit don't match any systemd unit criteria
+	if a.SystemdUnit.IsSet() && (p.SystemdUnit == "" || !a.SystemdUnit.MatchString(p.SystemdUnit)) {
+		return false
+	}
+	if a.CgroupPath.IsSet() && (p.CgroupPath == "" || !a.CgroupPath.MatchString(p.CgroupPath)) {
+		return false
+	}
 	if a.OpenPorts.Len() > 0 {
 		return m.matchByPort(p, a)
 	}
@@ -155,10 +165,17 @@ var processInfo = func(pp processPorts) (*services.ProcessInfo, error) {
 			return nil, fmt.Errorf("can't read /proc/<pid>/fd information: %w", err)
 		}
 	}
+	cgroupPath, err := exec.CgroupPath(proc.Pid)
+	if err != nil {
+		// not a blocker, as the control group is only required for some selection criteria
+		slog.Debug("can't read process control group", "pid", proc.Pid, "error", err)
+	}
 	return &services.ProcessInfo{
-		Pid:       proc.Pid,
-		PPid:      ppid,
-		ExePath:   exePath,
-		OpenPorts: pp.openPorts,
+		Pid:         proc.Pid,
+		PPid:        ppid,
+		ExePath:     exePath,
+		OpenPorts:   pp.openPorts,
+		CgroupPath:  cgroupPath,
+		SystemdUnit: exec.SystemdUnit(cgroupPath),
 	}, nil
 }
@@ -8,6 +8,7 @@ import (
 	"gopkg.in/yaml.v3"
 
 	"github.com/grafana/beyla/pkg/internal/discover/services"
+	"github.com/grafana/beyla/pkg/internal/exec"
 	"github.com/grafana/beyla/pkg/internal/pipe"
 	"github.com/grafana/beyla/pkg/internal/testutil"
 )
@@ -72,3 +73,42 @@ func TestCriteriaMatcher(t *testing.T) {
 	assert.Equal(t, "", m.Obj.Criteria.Namespace)
 	assert.Equal(t, services.ProcessInfo{Pid: 6, ExePath: "/bin/clientweird99"}, *m.Obj.Process)
 }
+
+func TestCriteriaMatcher_Systemd(t *testing.T) {
+	pipeConfig := pipe.Config{}
+	require.NoError(t, yaml.Unmarshal([]byte(`discovery:
+  services:
+  - systemd_unit_regexp: ^billing-.*\.service$
+  - name: payments
+    cgroup_path_regexp: ^/system\.slice/payments
+    open_ports: 8080
+`), &pipeConfig))
+
+	matcherFunc, err := CriteriaMatcherProvider(CriteriaMatcher{Cfg: &pipeConfig})
+	require.NoError(t, err)
+	discoveredProcesses := make(chan []Event[processPorts], 10)
+	filteredProcesses := make(chan []Event[ProcessMatch], 10)
+	go matcherFunc(discoveredProcesses, filteredProcesses)
+	defer close(discoveredProcesses)
+
+	processInfo = func(pp processPorts) (*services.ProcessInfo, error) {
+		cgroup := map[PID]string{
+			1: "/system.slice/billing-api.service", 2: "/user.slice/user-1000.slice/session-2.scope",
+			3: "/system.slice/payments.service", 4: "/system.slice/payments.service"}[pp.pid]
+		return &services.ProcessInfo{Pid: int32(pp.pid), OpenPorts: pp.openPorts,
+			CgroupPath: cgroup, SystemdUnit: exec.SystemdUnit(cgroup)}, nil
+	}
+	discoveredProcesses <- []Event[processPorts]{
+		{Type: EventCreated, Obj: processPorts{pid: 1}},                            // pass
+		{Type: EventCreated, Obj: processPorts{pid: 2}},                            // filter
+		{Type: EventCreated, Obj: processPorts{pid: 3, openPorts: []uint32{8081}}}, // filter
+		{Type: EventCreated, Obj: processPorts{pid: 4, openPorts: []uint32{8080}}}, // pass
+	}
+
+	matches := testutil.ReadChannel(t, filteredProcesses, testTimeout)
+	require.Len(t, matches, 2)
+	assert.Equal(t, int32(1), matches[0].Obj.Process.Pid)
+	assert.Equal(t, "billing-api.service", matches[0].Obj.Process.SystemdUnit)
+	assert.Equal(t, int32(4), matches[1].Obj.Process.Pid)
+	assert.Equal(t, "payments", matches[1].Obj.Criteria.Name)
+}
@@ -0,0 +1,34 @@
+package discover
+
+import (
+	"fmt"
+
+	"github.com/grafana/beyla/pkg/internal/ebpf"
+	"github.com/grafana/beyla/pkg/internal/imetrics"
+	"github.com/grafana/beyla/pkg/internal/pipe"
+)
+
+// PinnedTracers instantiates the tracers of a process that has been instrumented by a
+// privileged loader, and loads their maps from the pinned paths, so they can be run
+// without loading the eBPF programs again.
+func PinnedTracers(cfg *pipe.Config, metrics imetrics.Reporter, pp *ebpf.PinnedProcess) ([]ebpf.Tracer, error) {
+	available := map[string]ebpf.Tracer{}
+	for _, t := range newGoTracersGroup(cfg, metrics) {
+		available[ebpf.TracerName(t)] = t
+	}
+	for _, t := range newNonGoTracersGroup(cfg, metrics) {
+		available[ebpf.TracerName(t)] = t
+	}
+	tracers := make([]ebpf.Tracer, 0, len(pp.Tracers))
+	for name, dir := range pp.Tracers {
+		t, ok := available[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown tracer %s. Are the loader and processor versions the same?", name)
+		}
+		if err := t.LoadPinnedMaps(dir); err != nil {
+			return nil, fmt.Errorf("loading %s maps: %w", name, err)
+		}
+		tracers = append(tracers, t)
+	}
+	return tracers, nil
+}
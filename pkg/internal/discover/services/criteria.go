@@ -16,6 +16,11 @@ type ProcessInfo struct {
 	PPid      int32
 	ExePath   string
 	OpenPorts []uint32
+	// CgroupPath is the path of the control group of the process. It might be empty if unknown.
+	CgroupPath string
+	// SystemdUnit is the name of the systemd service unit that the process belongs to,
+	// if any (e.g. billing-api.service).
+	SystemdUnit string
 }
 
 // DiscoveryConfig for the discover.ProcessFinder pipeline
@@ -45,19 +50,22 @@ type DefinitionCriteria []Attributes
 func (dc DefinitionCriteria) Validate() error {
 	// an empty definition criteria is valid
 	for i := range dc {
-		if len(dc[i].OpenPorts.ranges) == 0 && dc[i].Path.re == nil {
-			return fmt.Errorf("attribute [%d] should define at least the open_ports or exe_path_regexp property", i)
+		if len(dc[i].OpenPorts.ranges) == 0 && dc[i].Path.re == nil &&
+			dc[i].SystemdUnit.re == nil && dc[i].CgroupPath.re == nil {
+			return fmt.Errorf("attribute [%d] should define at least the open_ports, exe_path_regexp, "+
+				"systemd_unit_regexp or cgroup_path_regexp property", i)
 		}
 	}
 	return nil
 }
 
 // Attributes that specify a given instrumented service.
-// Each instance has to define either the OpenPorts or Path property, or both. These are used to match
-// a given executable. If both OpenPorts and Path are defined, the inspected executable must fulfill both
-// properties.
+// Each instance has to define at least one of the OpenPorts, Path, SystemdUnit or CgroupPath properties.
+// These are used to match a given executable. If many of them are defined, the inspected executable must
+// fulfill all of them.
 type Attributes struct {
-	// Name will define a name for the matching service. If unset, it will take the name of the executable process
+	// Name will define a name for the matching service. If unset, it will take the name of the systemd
+	// unit of the process (if any) or the name of the executable process
 	Name string `yaml:"name"`
 	// Namespace will define a namespace for the matching service. If unset, it will be left empty.
 	Namespace string `yaml:"namespace"`
@@ -66,6 +74,12 @@ type Attributes struct {
 	OpenPorts PortEnum `yaml:"open_ports"`
 	// Path allows defining the regular expression matching the full executable path.
 	Path PathRegexp `yaml:"exe_path_regexp"`
+	// SystemdUnit allows defining the regular expression matching the name of the systemd
+	// service unit of the process (e.g. billing-api.service)
+	SystemdUnit PathRegexp `yaml:"systemd_unit_regexp"`
+	// CgroupPath allows defining the regular expression matching the path of the control
+	// group of the process (e.g. /system.slice/billing-api.service)
+	CgroupPath PathRegexp `yaml:"cgroup_path_regexp"`
 }
 
 // PortEnum defines an enumeration of ports. It allows defining a set of single ports as well a set of
@@ -6,11 +6,13 @@ import (
 
 	"github.com/mariomac/pipes/pkg/node"
 
+	"github.com/grafana/beyla/pkg/internal/discover/services"
 	"github.com/grafana/beyla/pkg/internal/exec"
 	"github.com/grafana/beyla/pkg/internal/goexec"
 	"github.com/grafana/beyla/pkg/internal/imetrics"
 	"github.com/grafana/beyla/pkg/internal/pipe"
 	"github.com/grafana/beyla/pkg/internal/svc"
+	"github.com/grafana/beyla/pkg/internal/transform/process"
 )
 
 // ExecTyper classifies the discovered executables according to the
@@ -21,6 +23,9 @@ type ExecTyper struct {
 	Metrics imetrics.Reporter
 }
 
+// SystemdUnitKey is the resource attribute that contains the systemd unit of the instrumented service
+const SystemdUnitKey = "systemd.unit"
+
 type InstrumentableType int
 
 const (
@@ -54,6 +59,11 @@ func ExecTyperProvider(ecfg ExecTyper) (node.MiddleFunc[[]Event[ProcessMatch], [
 		currentPids:      map[int32]*exec.FileInfo{},
 		instrumentedPids: map[int32]struct{}{},
 	}
+	if ecfg.Cfg.EBPF.GoOffsetsFile != "" {
+		if err := goexec.LoadOffsetsFile(ecfg.Cfg.EBPF.GoOffsetsFile); err != nil {
+			return nil, err
+		}
+	}
 	// TODO: do it per executable
 	if !ecfg.Cfg.Discovery.SkipGoSpecificTracers {
 		t.loadAllGoFunctionNames()
@@ -87,7 +97,8 @@ func (t *typer) FilterClassify(evs []Event[ProcessMatch]) []Event[Instrumentable
 		ev := &evs[i]
 		switch evs[i].Type {
 		case EventCreated:
-			svcID := svc.ID{Name: ev.Obj.Criteria.Name, Namespace: ev.Obj.Criteria.Namespace}
+			svcID := serviceID(ev.Obj.Criteria, ev.Obj.Process)
+			svcID.DiscoveryEntry = ev.Obj.CriteriaEntry
 			if elfFile, err := exec.FindExecELF(ev.Obj.Process, svcID); err != nil {
 				t.log.Warn("error finding process ELF. Ignoring", "error", err)
 			} else {
@@ -110,6 +121,11 @@ func (t *typer) FilterClassify(evs []Event[ProcessMatch]) []Event[Instrumentable
 		// instrumented. We skip it in that case
 		if _, ok := t.instrumentedPids[inst.FileInfo.Pid]; !ok {
 			t.log.Info("instrumenting process", "cmd", inst.FileInfo.CmdExePath, "pid", inst.FileInfo.Pid)
+			// in system-wide mode, the attributes of each process are not known until
+			// each request is traced, so they are added by the transform.ProcessDecorator
+			if !t.cfg.Discovery.SystemWide {
+				process.Decorate(inst.FileInfo)
+			}
 			out = append(out, Event[Instrumentable]{Type: EventCreated, Obj: inst})
 			t.instrumentedPids[inst.FileInfo.Pid] = struct{}{}
 		}
@@ -117,6 +133,20 @@ func (t *typer) FilterClassify(evs []Event[ProcessMatch]) []Event[Instrumentable
 	return out
 }
 
+// serviceID returns the service ID of a matched process. If the selection criteria does not
+// specify the service name and the process belongs to a systemd service unit, the service name
+// is the unit name. Otherwise, it is left empty so it is later set from the executable name.
+func serviceID(criteria *services.Attributes, proc *services.ProcessInfo) svc.ID {
+	id := svc.ID{Name: criteria.Name, Namespace: criteria.Namespace}
+	if proc.SystemdUnit != "" {
+		if id.Name == "" {
+			id.Name = exec.SystemdServiceName(proc.SystemdUnit)
+		}
+		id.Metadata = map[string]string{SystemdUnitKey: proc.SystemdUnit}
+	}
+	return id
+}
+
 // asInstrumentable classifies the type of executable (Go, generic...) and,
 // in case of belonging to a forked process, returns its parent.
 func (t *typer) asInstrumentable(execElf *exec.FileInfo) Instrumentable {
@@ -177,15 +207,5 @@ func isGoProxy(offsets *goexec.Offsets) bool {
 }
 
 func (t *typer) loadAllGoFunctionNames() {
-	uniqueFunctions := map[string]struct{}{}
-	t.allGoFunctions = nil
-	for _, p := range newGoTracersGroup(t.cfg, t.metrics) {
-		for funcName := range p.GoProbes() {
-			// avoid duplicating function names
-			if _, ok := uniqueFunctions[funcName]; !ok {
-				uniqueFunctions[funcName] = struct{}{}
-				t.allGoFunctions = append(t.allGoFunctions, funcName)
-			}
-		}
-	}
+	t.allGoFunctions = goFunctionNames(newGoTracersGroup(t.cfg, t.metrics))
 }
@@ -0,0 +1,26 @@
+package discover
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/grafana/beyla/pkg/internal/discover/services"
+	"github.com/grafana/beyla/pkg/internal/svc"
+)
+
+func TestServiceID(t *testing.T) {
+	// service name defaults to the systemd unit name
+	id := serviceID(&services.Attributes{}, &services.ProcessInfo{SystemdUnit: "billing-api.service"})
+	assert.Equal(t, svc.ID{Name: "billing-api", Metadata: map[string]string{"systemd.unit": "billing-api.service"}}, id)
+
+	// unless it is explicitly set by the user
+	id = serviceID(&services.Attributes{Name: "billing", Namespace: "shop"},
+		&services.ProcessInfo{SystemdUnit: "billing-api.service"})
+	assert.Equal(t, svc.ID{Name: "billing", Namespace: "shop",
+		Metadata: map[string]string{"systemd.unit": "billing-api.service"}}, id)
+
+	// processes out of systemd units will take later the executable name
+	id = serviceID(&services.Attributes{}, &services.ProcessInfo{})
+	assert.Equal(t, svc.ID{}, id)
+}
@@ -27,6 +27,9 @@ type bpfHttpRequestTrace struct {
 	HostPort          uint32
 	ContentLength     int64
 	Traceparent       [55]uint8
+	ResponseLength    int64
+	OnCpuNs           uint64
+	OffCpuNs          uint64
 }
 
 // loadBpf returns the embedded CollectionSpec for bpf.
@@ -0,0 +1,28 @@
+package ebpfcommon
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+)
+
+// process names of the PIDs, for the tracers that run in system-wide mode
+var processNames, _ = lru.New[uint32, string](64)
+
+// ProcessName returns the command name of the process with the given PID, or an
+// empty string if the process doesn't exist anymore
+func ProcessName(pid uint32) string {
+	if name, ok := processNames.Get(pid); ok {
+		return name
+	}
+	comm, err := os.ReadFile(filepath.Join("/proc", strconv.FormatUint(uint64(pid), 10), "comm"))
+	if err != nil {
+		return ""
+	}
+	name := strings.TrimSpace(string(comm))
+	processNames.Add(pid, name)
+	return name
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"io"
+	"strings"
 	"time"
 
 	"github.com/cilium/ebpf"
@@ -25,10 +26,13 @@ type TracerConfig struct {
 
 	// WakeupLen specifies how many messages need to be accumulated in the eBPF ringbuffer
 	// before sending a wakeup request.
-	// High values of WakeupLen could add a noticeable metric delay in services with low
-	// requests/second.
-	// TODO: see if there is a way to force eBPF to wakeup userspace on timeout
+	// In services with low requests/second, the accumulated messages are read after
+	// WakeupTimeout at most.
 	WakeupLen int `yaml:"wakeup_len" env:"BPF_WAKEUP_LEN"`
+	// WakeupTimeout is the maximum time that the messages can wait in the eBPF ringbuffer
+	// before being read. The eBPF programs force a wakeup request if no wakeup has been sent
+	// during this time, and user space checks for pending messages at least once per timeout.
+	WakeupTimeout time.Duration `yaml:"wakeup_timeout" env:"BPF_WAKEUP_TIMEOUT"`
 	// BatchLength allows specifying how many traces will be batched at the initial
 	// stage before being forwarded to the next stage
 	BatchLength int `yaml:"batch_length" env:"BPF_BATCH_LENGTH"`
@@ -39,8 +43,80 @@ type TracerConfig struct {
 	// BpfBaseDir specifies the base directory where the BPF pinned maps will be mounted.
 	// By default, it will be /var/run/beyla
 	BpfBaseDir string `yaml:"bpf_fs_base_dir" env:"BPF_FS_BASE_DIR"`
+
+	// GoOffsetsFile is the path of an optional offsets file, as generated by the beyla-gen-offsets
+	// tool, whose struct field offsets override the offsets database that is embedded in Beyla.
+	GoOffsetsFile string `yaml:"go_offsets_file" env:"BPF_GO_OFFSETS_FILE"`
+
+	// AggregateMetrics enables the in-kernel aggregation of the request metrics of the generic
+	// HTTP tracer. Instead of sending each request to user space, the aggregated histograms and
+	// counters are periodically read, which reduces the overhead in services with very high
+	// request rates. Traces can't be exported in this mode.
+	AggregateMetrics bool `yaml:"aggregate_metrics" env:"BPF_AGGREGATE_METRICS"`
+	// AggregationInterval specifies how often the in-kernel aggregated metrics (from both
+	// AggregateMetrics and NetworkMetrics) are read
+	AggregationInterval time.Duration `yaml:"aggregation_interval" env:"BPF_AGGREGATION_INTERVAL"`
+
+	// NetworkMetrics enables the network metrics tracer, which reports the metrics of the TCP
+	// connections of the instrumented services, by source and destination: bytes sent and
+	// received, opened, closed and refused connections, retransmits, round trip times and
+	// connection durations.
+	NetworkMetrics bool `yaml:"network_metrics" env:"BPF_NETWORK_METRICS"`
+
+	// DNSTracing enables the DNS tracer, which reports the DNS queries over UDP of the
+	// instrumented services as client spans, and decorates the client spans with the
+	// host names that the services resolved, instead of the IP addresses.
+	DNSTracing bool `yaml:"dns_tracing" env:"BPF_DNS_TRACING"`
+
+	// CPUTime enables the accounting of the time that the thread, or goroutine, serving each
+	// HTTP request spends on CPU and off CPU (e.g. waiting for I/O or locks) while the request
+	// is in flight. It requires tracking the context switches of the kernel scheduler and, for
+	// Go programs, of the Go scheduler, so it has some overhead.
+	CPUTime bool `yaml:"cpu_time" env:"BPF_CPU_TIME"`
+
+	// RingBufferSize overrides the size, in bytes, of the ring buffer that each eBPF tracer uses
+	// to send the events to user space. It must be a power of 2 and a multiple of the page size.
+	// If unset, the size defined in the eBPF programs is used (16MB).
+	RingBufferSize int `yaml:"ring_buffer_size" env:"BPF_RING_BUFFER_SIZE"`
+	// RingBufferSizes overrides the ring buffer size of the tracers whose package name
+	// (nethttp, grpc, gosql, goruntime, httpfltr) is used as a key. It has priority over RingBufferSize.
+	RingBufferSizes map[string]int `yaml:"ring_buffer_sizes"`
+	// SamplingFillThreshold is the fill level of the ring buffer, in percentage, from which
+	// the eBPF programs start sampling the events. The sampling rate grows exponentially as
+	// the ring buffer gets fuller, up to 1 out of 1024 events. Zero disables sampling.
+	SamplingFillThreshold int `yaml:"sampling_fill_threshold" env:"BPF_SAMPLING_FILL_THRESHOLD"`
+
+	// RequestCaptureSize is the number of bytes, from the beginning of each request, that the
+	// generic HTTP tracer sends to user space to read the full URL and the request headers.
+	// Larger values allow capturing longer URLs and headers at the cost of a higher ring
+	// buffer usage. It can't be larger than MaxRequestCaptureSize.
+	RequestCaptureSize int `yaml:"request_capture_size" env:"BPF_REQUEST_CAPTURE_SIZE"`
+
+	// HTTPHeaders selects the HTTP headers that are captured as span attributes
+	HTTPHeaders HTTPHeadersConfig `yaml:"http_headers"`
+}
+
+// RingBufferSizeFor returns the ring buffer size of the tracer with the given name (as returned by
+// ebpf.TracerName, e.g. nethttp.Tracer), or zero if the size defined in the eBPF programs must be kept.
+func (c *TracerConfig) RingBufferSizeFor(tracerName string) int {
+	if c == nil {
+		return 0
+	}
+	pkg, _, _ := strings.Cut(tracerName, ".")
+	if size, ok := c.RingBufferSizes[pkg]; ok {
+		return size
+	}
+	return c.RingBufferSize
 }
 
+// MaxRequestCaptureSize is the maximum number of bytes of a request that the eBPF programs can
+// capture, as the product of MAX_CAPTURE_CHUNKS and TRACE_BUF_SIZE in bpf/http_capture.h
+const MaxRequestCaptureSize = 16 * 1024
+
+// MaxAggregatedHistogramBounds is the maximum number of histogram bucket boundaries that
+// are supported when the metrics are aggregated in the kernel
+const MaxAggregatedHistogramBounds = 16
+
 // Probe holds the information of the instrumentation points of a given function: its start and end offsets and
 // eBPF programs
 type Probe struct {
@@ -56,6 +132,28 @@ type FunctionPrograms struct {
 	End      *ebpf.Program
 }
 
+// TracepointProgram is an eBPF program that is attached to a kernel tracepoint
+type TracepointProgram struct {
+	// Required, if true, will cancel the execution of the eBPF Tracer
+	// if the tracepoint can't be attached and there isn't any kprobe to fall back to
+	Required bool
+	Program  *ebpf.Program
+	// Replaces contains the names of the kernel functions whose kprobes are not
+	// attached when the tracepoint is successfully attached
+	Replaces []string
+}
+
+// USDTProgram is an eBPF program that is attached to a User Statically-Defined Tracing probe
+type USDTProgram struct {
+	// Required, if true, will cancel the execution of the eBPF Tracer
+	// if the probe has not been found in the executable or library
+	Required bool
+	Program  *ebpf.Program
+	// Specs is the usdt_specs map of the program, if it reads the probe arguments with the
+	// helpers from bpf/usdt.h. The location of the arguments of each probe is stored there.
+	Specs *ebpf.Map
+}
+
 type Filter struct {
 	io.Closer
 	Fd int
@@ -0,0 +1,92 @@
+package ebpfcommon
+
+import (
+	"fmt"
+	"strings"
+)
+
+// These need to line up with the definitions in bpf/go_headers.h
+const (
+	// MaxCapturedHeaders is the maximum number of request and response headers that can be captured
+	MaxCapturedHeaders = 8
+	// MaxHeaderNameLen is the maximum length of the names of the captured headers
+	MaxHeaderNameLen = 32
+	// MaxHeaderValueLen is the maximum length of the values of the captured headers. Longer values are truncated.
+	MaxHeaderValueLen = 64
+)
+
+// HTTPHeadersConfig selects the HTTP headers that are captured by the tracers and added
+// as attributes of the spans, and optionally, of the metrics.
+type HTTPHeadersConfig struct {
+	// Request is the list of names of the request headers to capture
+	Request []string `yaml:"request" env:"BPF_HTTP_REQUEST_HEADERS"`
+	// Response is the list of names of the response headers to capture
+	Response []string `yaml:"response" env:"BPF_HTTP_RESPONSE_HEADERS"`
+	// MetricLabels is the list of names of the captured headers that are also reported as
+	// metric attributes. Each header increases the cardinality of the metrics, so it should only
+	// contain headers with a few distinct values.
+	MetricLabels []string `yaml:"metric_labels" env:"BPF_HTTP_HEADERS_METRIC_LABELS"`
+}
+
+func (c *HTTPHeadersConfig) Enabled() bool {
+	return len(c.Request) > 0 || len(c.Response) > 0
+}
+
+func (c *HTTPHeadersConfig) Validate() error {
+	if len(c.Request)+len(c.Response) > MaxCapturedHeaders {
+		return fmt.Errorf("can't capture more than %d request and response headers", MaxCapturedHeaders)
+	}
+	for _, h := range append(c.Request, c.Response...) {
+		if len(h) == 0 || len(h) > MaxHeaderNameLen || strings.ContainsAny(h, ": \t") {
+			return fmt.Errorf("invalid header name %q: it must have between 1 and %d characters, without spaces nor colons",
+				h, MaxHeaderNameLen)
+		}
+	}
+	for _, h := range c.MetricLabels {
+		if !containsHeader(c.Request, h) && !containsHeader(c.Response, h) {
+			return fmt.Errorf("header %q can't be a metric label because it's not captured", h)
+		}
+	}
+	return nil
+}
+
+// MetricRequestHeaders returns the lowercase names of the request headers that are reported as metric attributes
+func (c *HTTPHeadersConfig) MetricRequestHeaders() []string {
+	return metricHeaders(c.Request, c.MetricLabels)
+}
+
+// MetricResponseHeaders returns the lowercase names of the response headers that are reported as metric attributes
+func (c *HTTPHeadersConfig) MetricResponseHeaders() []string {
+	return metricHeaders(c.Response, c.MetricLabels)
+}
+
+// HeaderSlots returns the lowercase names of the captured headers by the position in which the eBPF
+// programs report their values: first the request headers, followed by the response headers.
+func (c *HTTPHeadersConfig) HeaderSlots() (request, response []string) {
+	for _, h := range c.Request {
+		request = append(request, strings.ToLower(h))
+	}
+	for _, h := range c.Response {
+		response = append(response, strings.ToLower(h))
+	}
+	return request, response
+}
+
+func metricHeaders(captured, labels []string) []string {
+	var names []string
+	for _, h := range labels {
+		if containsHeader(captured, h) {
+			names = append(names, strings.ToLower(h))
+		}
+	}
+	return names
+}
+
+func containsHeader(headers []string, name string) bool {
+	for _, h := range headers {
+		if strings.EqualFold(h, name) {
+			return true
+		}
+	}
+	return false
+}
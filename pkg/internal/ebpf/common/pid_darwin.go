@@ -1,6 +1,6 @@
-package httpfltr
+package ebpfcommon
 
-func findNamespace(_ int32) (uint32, error) {
+func FindNamespace(_ int32) (uint32, error) {
 	// convenience method to allow unit tests compiling in Darwin
 	return 0, nil
 }
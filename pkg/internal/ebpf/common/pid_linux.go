@@ -1,4 +1,4 @@
-package httpfltr
+package ebpfcommon
 
 import (
 	"fmt"
@@ -9,7 +9,9 @@ import (
 	"syscall"
 )
 
-func findNamespace(pid int32) (uint32, error) {
+// FindNamespace returns the ID of the PID namespace of the process, which is used by the
+// eBPF programs to match the processes that run in containers
+func FindNamespace(pid int32) (uint32, error) {
 	pidPath := fmt.Sprintf("/proc/%d/ns/pid", pid)
 	f, err := os.Open(pidPath)
 
@@ -26,7 +28,7 @@ func findNamespace(pid int32) (uint32, error) {
 		return 0, fmt.Errorf("failed to read symlink(/proc/%d/ns/pid): %w", pid, err)
 	}
 
-	logger := slog.With("component", "httpfltr.Tracer")
+	logger := slog.With("component", "ebpfcommon.FindNamespace")
 
 	nsPid := string(buf[:n])
 	// extract u32 from the format pid:[nnnnn]
@@ -0,0 +1,67 @@
+package ebpfcommon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+
+	"github.com/cilium/ebpf"
+)
+
+// PinMaps pins, in the provided directory, the maps of a bpf2go-generated maps struct
+// (e.g. &bpfObjects.bpfMaps), so they can be loaded later from another process.
+// Maps that are already pinned (e.g. maps shared by all the Go tracers) are ignored.
+func PinMaps(dir string, maps any) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("creating directory %s: %w", dir, err)
+	}
+	return forEachMap(maps, func(name string, m **ebpf.Map) error {
+		if *m == nil || (*m).IsPinned() {
+			return nil
+		}
+		if err := (*m).Pin(filepath.Join(dir, name)); err != nil {
+			return fmt.Errorf("pinning map %s: %w", name, err)
+		}
+		return nil
+	})
+}
+
+// LoadPinnedMaps loads, into a bpf2go-generated maps struct, the maps that were pinned in
+// the provided directory by PinMaps. Maps that were not pinned are left unset.
+func LoadPinnedMaps(dir string, maps any) error {
+	return forEachMap(maps, func(name string, m **ebpf.Map) error {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return nil
+		}
+		loaded, err := ebpf.LoadPinnedMap(path, nil)
+		if err != nil {
+			return fmt.Errorf("loading pinned map %s: %w", path, err)
+		}
+		*m = loaded
+		return nil
+	})
+}
+
+// forEachMap invokes the provided function for each *ebpf.Map field of a struct that
+// is tagged with its eBPF name, as generated by bpf2go
+func forEachMap(maps any, fn func(name string, m **ebpf.Map) error) error {
+	val := reflect.ValueOf(maps)
+	if val.Kind() != reflect.Pointer || val.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("expecting a pointer to a struct of maps. Got %T", maps)
+	}
+	val = val.Elem()
+	mapType := reflect.TypeOf((*ebpf.Map)(nil))
+	for i := 0; i < val.NumField(); i++ {
+		field := val.Type().Field(i)
+		name, ok := field.Tag.Lookup("ebpf")
+		if !ok || field.Type != mapType {
+			continue
+		}
+		if err := fn(name, val.Field(i).Addr().Interface().(**ebpf.Map)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
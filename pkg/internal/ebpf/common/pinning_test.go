@@ -0,0 +1,38 @@
+package ebpfcommon
+
+import (
+	"testing"
+
+	"github.com/cilium/ebpf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testMaps struct {
+	Events   *ebpf.Map `ebpf:"events"`
+	DeadPids *ebpf.Map `ebpf:"dead_pids"`
+	notAMap  int       //nolint:unused
+	Untagged *ebpf.Map
+}
+
+func TestForEachMap(t *testing.T) {
+	var names []string
+	require.NoError(t, forEachMap(&testMaps{}, func(name string, _ **ebpf.Map) error {
+		names = append(names, name)
+		return nil
+	}))
+	assert.Equal(t, []string{"events", "dead_pids"}, names)
+
+	assert.Error(t, forEachMap(testMaps{}, func(string, **ebpf.Map) error { return nil }))
+}
+
+func TestPinnedMaps_NotPinned(t *testing.T) {
+	dir := t.TempDir()
+	// nil maps are not pinned
+	require.NoError(t, PinMaps(dir, &testMaps{}))
+	// maps that are not pinned are left unset
+	maps := testMaps{}
+	require.NoError(t, LoadPinnedMaps(dir, &maps))
+	assert.Nil(t, maps.Events)
+	assert.Nil(t, maps.DeadPids)
+}
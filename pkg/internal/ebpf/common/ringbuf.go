@@ -23,18 +23,31 @@ type ringBufReader interface {
 	Read() (ringbuf.Record, error)
 }
 
-// readerFactory instantiates a ringBufReader from a ring buffer. In unit tests, we can
-// replace this function by a mock/dummy.
-var readerFactory = func(rb *ebpf.Map) (ringBufReader, error) {
-	return ringbuf.NewReader(rb)
-}
+// readerFactory instantiates a ringBufReader from a ring buffer, which checks for pending
+// records at least once per timeout. In unit tests, we can replace this function by a mock/dummy.
+var readerFactory = newTimedRingBufReader
+
+// must coincide with the RINGBUF_STAT_* definitions in bpf/ringbuf.h
+const (
+	ringBufStatDropped = iota
+	ringBufStatSampledOut
+	ringBufStatSamplingRate
+	ringBufStatFillPct
+	ringBufStatLastWakeup
+	ringBufStatsLen
+)
+
+// ringBufStatsInterval specifies how often the ring buffer statistics are read from the kernel
+const ringBufStatsInterval = 5 * time.Second
 
 type ringBufForwarder[T any] struct {
 	service svc.ID
+	name    string
 
 	cfg        *TracerConfig
 	logger     *slog.Logger
 	ringbuffer *ebpf.Map
+	stats      *ebpf.Map
 	closers    []io.Closer
 	spans      []request.Span
 	spansLen   int
@@ -47,17 +60,21 @@ type ringBufForwarder[T any] struct {
 // ForwardRingbuf returns a function reads HTTPRequestTraces from an input ring buffer, accumulates them into an
 // internal buffer, and forwards them to an output events channel, previously converted to request.Span
 // instances.
+// The name of the tracer labels its internal metrics. If the stats map is not nil, the dropped events, the fill level and the sampling rate of the ring
+// buffer, as accounted by the eBPF programs in the ringbuf_stats map, are periodically reported.
 func ForwardRingbuf[T any](
 	service svc.ID,
+	name string,
 	cfg *TracerConfig,
 	logger *slog.Logger,
 	ringbuffer *ebpf.Map,
+	stats *ebpf.Map,
 	reader func(*ringbuf.Record) (request.Span, bool, error),
 	metrics imetrics.Reporter,
 	closers ...io.Closer,
 ) func(context.Context, chan<- []request.Span) {
 	rbf := ringBufForwarder[T]{
-		service: service, cfg: cfg, logger: logger, ringbuffer: ringbuffer,
+		service: service, name: name, cfg: cfg, logger: logger, ringbuffer: ringbuffer, stats: stats,
 		closers: closers, reader: reader, metrics: metrics,
 	}
 	return rbf.readAndForward
@@ -67,7 +84,7 @@ func (rbf *ringBufForwarder[T]) readAndForward(ctx context.Context, spansChan ch
 	rbf.logger.Debug("start reading and forwarding")
 	// BPF will send each measured trace via Ring Buffer, so we listen for them from the
 	// user space.
-	eventsReader, err := readerFactory(rbf.ringbuffer)
+	eventsReader, err := readerFactory(rbf.ringbuffer, rbf.cfg.WakeupTimeout)
 	if err != nil {
 		rbf.logger.Error("creating perf reader. Exiting", err)
 		return
@@ -82,6 +99,10 @@ func (rbf *ringBufForwarder[T]) readAndForward(ctx context.Context, spansChan ch
 	// so the function can exit.
 	go rbf.bgListenContextCancelation(ctx, eventsReader)
 
+	if rbf.stats != nil {
+		go rbf.bgReportStats(ctx)
+	}
+
 	// Forwards periodically on timeout, if the batch is not full
 	if rbf.cfg.BatchTimeout > 0 {
 		rbf.ticker = time.NewTicker(rbf.cfg.BatchTimeout)
@@ -117,10 +138,12 @@ func (rbf *ringBufForwarder[T]) readAndForward(ctx context.Context, spansChan ch
 			rbf.access.Unlock()
 			continue
 		}
-		s.ServiceID = rbf.service
-		rbf.spans[rbf.spansLen] = s
 		// we need to decorate each span with the tracer's service name
 		// if this information is not forwarded from eBPF
+		if s.ServiceID.Name == "" {
+			s.ServiceID = rbf.service
+		}
+		rbf.spans[rbf.spansLen] = s
 		rbf.spansLen++
 		if rbf.spansLen == rbf.cfg.BatchLength {
 			rbf.logger.Debug("submitting traces after batch is full", "len", rbf.spansLen)
@@ -152,6 +175,55 @@ func (rbf *ringBufForwarder[T]) bgFlushOnTimeout(spansChan chan<- []request.Span
 	}
 }
 
+// bgReportStats periodically reports the ring buffer statistics that are accounted by the eBPF programs
+func (rbf *ringBufForwarder[T]) bgReportStats(ctx context.Context) {
+	ticker := time.NewTicker(ringBufStatsInterval)
+	defer ticker.Stop()
+	var last [ringBufStatsLen]uint64
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			current, err := rbf.readStats()
+			if err != nil {
+				rbf.logger.Debug("can't read ring buffer stats", "error", err)
+				continue
+			}
+			reportStats(rbf.metrics, rbf.name, &last, &current)
+			last = current
+		}
+	}
+}
+
+func (rbf *ringBufForwarder[T]) readStats() ([ringBufStatsLen]uint64, error) {
+	var stats [ringBufStatsLen]uint64
+	for i := uint32(0); i < ringBufStatsLen; i++ {
+		if err := rbf.stats.Lookup(i, &stats[i]); err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
+
+// reportStats forwards the increment of the counters since the last read, as well as the current
+// values of the gauges
+func reportStats(metrics imetrics.Reporter, tracer string, last, current *[ringBufStatsLen]uint64) {
+	if drops := current[ringBufStatDropped] - last[ringBufStatDropped]; drops > 0 {
+		metrics.TracerRingBufDrops(tracer, int(drops))
+	}
+	if sampledOut := current[ringBufStatSampledOut] - last[ringBufStatSampledOut]; sampledOut > 0 {
+		metrics.TracerSampledOut(tracer, int(sampledOut))
+	}
+	metrics.TracerRingBufFill(tracer, float64(current[ringBufStatFillPct])/100)
+	// the sampling rate is zero until the first event is reserved
+	if rate := current[ringBufStatSamplingRate]; rate > 0 {
+		metrics.TracerSamplingRate(tracer, int(rate))
+	} else {
+		metrics.TracerSamplingRate(tracer, 1)
+	}
+}
+
 func (rbf *ringBufForwarder[T]) bgListenContextCancelation(ctx context.Context, eventsReader ringBufReader) {
 	<-ctx.Done()
 	rbf.logger.Debug("context is cancelled. Closing events reader")
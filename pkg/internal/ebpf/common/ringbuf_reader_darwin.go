@@ -0,0 +1,12 @@
+package ebpfcommon
+
+import (
+	"time"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/ringbuf"
+)
+
+func newTimedRingBufReader(rb *ebpf.Map, _ time.Duration) (ringBufReader, error) {
+	return ringbuf.NewReader(rb)
+}
@@ -0,0 +1,206 @@
+package ebpfcommon
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/ringbuf"
+	"golang.org/x/sys/unix"
+)
+
+// from 'struct bpf_ringbuf_hdr' in kernel/bpf/ringbuf.c
+const (
+	ringBufHeaderSize  = 8
+	ringBufBusyBit     = 1 << 31
+	ringBufDiscardBit  = 1 << 30
+	ringBufLenMask     = ^uint32(ringBufBusyBit | ringBufDiscardBit)
+	ringBufRecordAlign = 8
+)
+
+// busyRecordBackoff is the time that Read waits for the eBPF program that is writing the next
+// record to commit it. The ring buffer is not empty meanwhile, so epoll would return immediately.
+const busyRecordBackoff = 50 * time.Microsecond
+
+// recordState is the state of the next record of the ring buffer
+type recordState int
+
+const (
+	recordNone  recordState = iota // the ring buffer is empty
+	recordBusy                     // the record is still being written by the eBPF program
+	recordReady                    // the record has been committed
+)
+
+// timedRingBufReader reads the records of a BPF ring buffer. Unlike ringbuf.Reader, it checks
+// the ring buffer for pending records after each wait timeout, so the records that have been
+// submitted without waking up user space (BPF_RB_NO_WAKEUP) are read with a bounded delay.
+type timedRingBufReader struct {
+	mu      sync.Mutex
+	epollFd int
+	eventFd int
+	closed  atomic.Bool
+	timeout time.Duration
+
+	cons []byte
+	prod []byte
+	// consPos and prodPos point into the mmap'ed memory and must be accessed atomically
+	consPos *uint64
+	prodPos *uint64
+	data    []byte
+	mask    uint64
+	events  []unix.EpollEvent
+}
+
+func newTimedRingBufReader(rb *ebpf.Map, timeout time.Duration) (ringBufReader, error) {
+	size := int(rb.MaxEntries())
+	if rb.Type() != ebpf.RingBuf || size == 0 || size&(size-1) != 0 {
+		return nil, fmt.Errorf("invalid ring buffer map: %s of size %d", rb.Type(), size)
+	}
+	r := &timedRingBufReader{timeout: timeout, epollFd: -1, eventFd: -1, events: make([]unix.EpollEvent, 2)}
+	if err := r.init(rb.FD(), size); err != nil {
+		r.release()
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *timedRingBufReader) init(mapFD, size int) error {
+	var err error
+	if r.epollFd, err = unix.EpollCreate1(unix.EPOLL_CLOEXEC); err != nil {
+		return fmt.Errorf("creating epoll fd: %w", err)
+	}
+	// the eventfd interrupts the waits when the reader is closed
+	if r.eventFd, err = unix.Eventfd(0, unix.EFD_CLOEXEC|unix.EFD_NONBLOCK); err != nil {
+		return fmt.Errorf("creating eventfd: %w", err)
+	}
+	for _, fd := range []int{mapFD, r.eventFd} {
+		if err := unix.EpollCtl(r.epollFd, unix.EPOLL_CTL_ADD, fd,
+			&unix.EpollEvent{Events: unix.EPOLLIN, Fd: int32(fd)}); err != nil {
+			return fmt.Errorf("adding fd to epoll: %w", err)
+		}
+	}
+	pageSize := os.Getpagesize()
+	if r.cons, err = unix.Mmap(mapFD, 0, pageSize, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED); err != nil {
+		return fmt.Errorf("can't mmap consumer page: %w", err)
+	}
+	// the data pages are mapped twice, so the records that wrap around can be read contiguously
+	if r.prod, err = unix.Mmap(mapFD, int64(pageSize), pageSize+2*size, unix.PROT_READ, unix.MAP_SHARED); err != nil {
+		return fmt.Errorf("can't mmap data pages: %w", err)
+	}
+	r.consPos = (*uint64)(unsafe.Pointer(&r.cons[0]))
+	r.prodPos = (*uint64)(unsafe.Pointer(&r.prod[0]))
+	r.data = r.prod[pageSize:]
+	r.mask = uint64(size - 1)
+	return nil
+}
+
+// Read returns the next record of the ring buffer. If the ring buffer is empty, it waits
+// for a wakeup notification, or for the timeout, whatever happens first, and checks again.
+func (r *timedRingBufReader) Read() (ringbuf.Record, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for {
+		if r.closed.Load() {
+			return ringbuf.Record{}, ringbuf.ErrClosed
+		}
+		record, state := r.readRecord()
+		switch state {
+		case recordReady:
+			return record, nil
+		case recordBusy:
+			time.Sleep(busyRecordBackoff)
+		default:
+			if err := r.wait(); err != nil {
+				return ringbuf.Record{}, err
+			}
+		}
+	}
+}
+
+func (r *timedRingBufReader) wait() error {
+	if _, err := unix.EpollWait(r.epollFd, r.events, epollTimeout(r.timeout)); err != nil &&
+		!errors.Is(err, unix.EINTR) {
+		return fmt.Errorf("waiting for ring buffer: %w", err)
+	}
+	return nil
+}
+
+// epollTimeout returns the epoll timeout, in milliseconds, for the passed timeout. The timeouts
+// are rounded up, as a zero epoll timeout would return immediately.
+func epollTimeout(timeout time.Duration) int {
+	if timeout <= 0 {
+		return -1 // blocks until the eBPF programs wake up user space
+	}
+	return int((timeout + time.Millisecond - 1) / time.Millisecond)
+}
+
+// readRecord returns the next committed record, skipping the discarded ones
+func (r *timedRingBufReader) readRecord() (ringbuf.Record, recordState) {
+	cons := atomic.LoadUint64(r.consPos)
+	for {
+		prod := atomic.LoadUint64(r.prodPos)
+		if prod-cons < ringBufHeaderSize {
+			return ringbuf.Record{}, recordNone
+		}
+		start := cons & r.mask
+		header := atomic.LoadUint32((*uint32)(unsafe.Pointer(&r.data[start])))
+		if header&ringBufBusyBit != 0 {
+			return ringbuf.Record{}, recordBusy
+		}
+		dataLen := uint64(header & ringBufLenMask)
+		next := cons + alignRecord(ringBufHeaderSize+dataLen)
+		if header&ringBufDiscardBit != 0 {
+			cons = next
+			atomic.StoreUint64(r.consPos, cons)
+			continue
+		}
+		sample := make([]byte, dataLen)
+		copy(sample, r.data[start+ringBufHeaderSize:start+ringBufHeaderSize+dataLen])
+		atomic.StoreUint64(r.consPos, next)
+		return ringbuf.Record{RawSample: sample}, recordReady
+	}
+}
+
+func alignRecord(n uint64) uint64 {
+	return (n + ringBufRecordAlign - 1) &^ (ringBufRecordAlign - 1)
+}
+
+// Close interrupts any ongoing Read and frees the resources of the reader
+func (r *timedRingBufReader) Close() error {
+	if r.closed.Swap(true) {
+		return nil
+	}
+	var one [8]byte
+	binary.NativeEndian.PutUint64(one[:], 1)
+	_, _ = unix.Write(r.eventFd, one[:])
+	// waits for any ongoing Read to finish
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.release()
+	return nil
+}
+
+func (r *timedRingBufReader) release() {
+	if r.prod != nil {
+		_ = unix.Munmap(r.prod)
+		r.prod = nil
+	}
+	if r.cons != nil {
+		_ = unix.Munmap(r.cons)
+		r.cons = nil
+	}
+	if r.eventFd >= 0 {
+		_ = unix.Close(r.eventFd)
+		r.eventFd = -1
+	}
+	if r.epollFd >= 0 {
+		_ = unix.Close(r.epollFd)
+		r.epollFd = -1
+	}
+}
@@ -0,0 +1,70 @@
+package ebpfcommon
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimedRingBufReader_ReadRecord(t *testing.T) {
+	const size = 64
+	// the data pages are mapped twice in the kernel
+	data := make([]byte, 2*size)
+	var cons, prod uint64
+	r := &timedRingBufReader{consPos: &cons, prodPos: &prod, data: data, mask: size - 1}
+	write := func(header uint32, payload string) {
+		for _, off := range []uint64{prod & (size - 1), prod&(size-1) + size} {
+			if off+ringBufHeaderSize+uint64(len(payload)) > 2*size {
+				continue
+			}
+			binary.NativeEndian.PutUint32(data[off:], header|uint32(len(payload)))
+			copy(data[off+ringBufHeaderSize:], payload)
+		}
+		prod += alignRecord(ringBufHeaderSize + uint64(len(payload)))
+	}
+
+	// empty ring buffer
+	_, state := r.readRecord()
+	assert.Equal(t, recordNone, state)
+
+	write(0, "hello")
+	write(ringBufDiscardBit, "discarded")
+	write(0, "world")
+	record, state := r.readRecord()
+	require.Equal(t, recordReady, state)
+	assert.Equal(t, "hello", string(record.RawSample))
+	// the discarded records are skipped
+	record, state = r.readRecord()
+	require.Equal(t, recordReady, state)
+	assert.Equal(t, "world", string(record.RawSample))
+	assert.Equal(t, prod, cons)
+
+	// the records that are still being written are not read
+	busyPos := prod
+	write(ringBufBusyBit, "busy")
+	_, state = r.readRecord()
+	assert.Equal(t, recordBusy, state)
+	assert.Equal(t, busyPos, cons)
+
+	// once committed, the record, which wraps around the end of the ring buffer, is read contiguously
+	binary.NativeEndian.PutUint32(data[busyPos&(size-1):], 4)
+	write(0, "next")
+	record, state = r.readRecord()
+	require.Equal(t, recordReady, state)
+	assert.Equal(t, "busy", string(record.RawSample))
+	record, state = r.readRecord()
+	require.Equal(t, recordReady, state)
+	assert.Equal(t, "next", string(record.RawSample))
+}
+
+func TestEpollTimeout(t *testing.T) {
+	assert.Equal(t, -1, epollTimeout(0))
+	// the timeouts below one millisecond don't make epoll return immediately
+	assert.Equal(t, 1, epollTimeout(time.Microsecond))
+	assert.Equal(t, 1, epollTimeout(time.Millisecond))
+	assert.Equal(t, 2, epollTimeout(1500*time.Microsecond))
+	assert.Equal(t, 200, epollTimeout(200*time.Millisecond))
+}
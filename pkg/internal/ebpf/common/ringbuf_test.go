@@ -31,10 +31,11 @@ func TestForwardRingbuf_CapacityFull(t *testing.T) {
 	metrics := &metricsReporter{}
 	forwardedMessages := make(chan []request.Span, 100)
 	go ForwardRingbuf[HTTPRequestTrace](
-		svc.ID{Name: "myService"},
+		svc.ID{Name: "myService"}, "test.Tracer",
 		&TracerConfig{BatchLength: 10},
 		slog.With("test", "TestForwardRingbuf_CapacityFull"),
 		nil, // the source ring buffer can be null
+		nil, // the ring buffer stats are not reported if null
 		ReadHTTPRequestTraceAsSpan,
 		metrics,
 	)(context.Background(), forwardedMessages)
@@ -78,10 +79,11 @@ func TestForwardRingbuf_Deadline(t *testing.T) {
 	metrics := &metricsReporter{}
 	forwardedMessages := make(chan []request.Span, 100)
 	go ForwardRingbuf[HTTPRequestTrace](
-		svc.ID{Name: "myService"},
+		svc.ID{Name: "myService"}, "test.Tracer",
 		&TracerConfig{BatchLength: 10, BatchTimeout: 20 * time.Millisecond},
 		slog.With("test", "TestForwardRingbuf_Deadline"),
 		nil, // the source ring buffer can be null
+		nil, // the ring buffer stats are not reported if null
 		ReadHTTPRequestTraceAsSpan,
 		metrics,
 	)(context.Background(), forwardedMessages)
@@ -115,10 +117,11 @@ func TestForwardRingbuf_Close(t *testing.T) {
 	metrics := &metricsReporter{}
 	closable := closableObject{}
 	go ForwardRingbuf[HTTPRequestTrace](
-		svc.ID{Name: "myService"},
+		svc.ID{Name: "myService"}, "test.Tracer",
 		&TracerConfig{BatchLength: 10},
 		slog.With("test", "TestForwardRingbuf_Close"),
 		nil, // the source ring buffer can be null
+		nil, // the ring buffer stats are not reported if null
 		ReadHTTPRequestTraceAsSpan,
 		metrics,
 		&closable,
@@ -150,7 +153,7 @@ func replaceTestRingBuf() (ringBuf *fakeRingBufReader, restorer func()) {
 	mt.Lock()
 	defer mt.Unlock()
 	oldReaderFactory := readerFactory
-	readerFactory = func(_ *ebpf.Map) (ringBufReader, error) {
+	readerFactory = func(_ *ebpf.Map, _ time.Duration) (ringBufReader, error) {
 		return &rb, nil
 	}
 	return &rb, func() {
@@ -196,11 +199,46 @@ func (c *closableObject) Close() error {
 
 type metricsReporter struct {
 	imetrics.NoopReporter
-	flushes    int
-	flushedLen int
+	flushes      int
+	flushedLen   int
+	drops        int
+	sampledOut   int
+	fill         float64
+	samplingRate int
 }
 
+func (m *metricsReporter) TracerRingBufDrops(_ string, drops int)    { m.drops += drops }
+func (m *metricsReporter) TracerSampledOut(_ string, events int)     { m.sampledOut += events }
+func (m *metricsReporter) TracerRingBufFill(_ string, ratio float64) { m.fill = ratio }
+func (m *metricsReporter) TracerSamplingRate(_ string, rate int)     { m.samplingRate = rate }
+
 func (m *metricsReporter) TracerFlush(len int) {
 	m.flushes++
 	m.flushedLen += len
 }
+
+func TestReportStats(t *testing.T) {
+	metrics := &metricsReporter{}
+	var last [ringBufStatsLen]uint64
+	// before any event is reserved, the sampling rate is still zero
+	reportStats(metrics, "test.Tracer", &last, &last)
+	assert.Equal(t, 1, metrics.samplingRate)
+
+	current := [ringBufStatsLen]uint64{
+		ringBufStatDropped:      10,
+		ringBufStatSampledOut:   3,
+		ringBufStatSamplingRate: 4,
+		ringBufStatFillPct:      75,
+	}
+	reportStats(metrics, "test.Tracer", &last, &current)
+	last = current
+	current[ringBufStatDropped] = 15
+	current[ringBufStatFillPct] = 20
+	reportStats(metrics, "test.Tracer", &last, &current)
+
+	// counters are reported as the increment since the last read
+	assert.Equal(t, 15, metrics.drops)
+	assert.Equal(t, 3, metrics.sampledOut)
+	assert.Equal(t, 0.2, metrics.fill)
+	assert.Equal(t, 4, metrics.samplingRate)
+}
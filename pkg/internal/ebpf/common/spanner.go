@@ -28,12 +28,15 @@ func HTTPRequestTraceToSpan(trace *HTTPRequestTrace) request.Span {
 	peer := ""
 	hostname := ""
 	hostPort := 0
+	responseLength := int64(0)
 	traceparent := extractTraceparent(trace.Traceparent)
 
 	switch request.EventType(trace.Type) {
 	case request.EventTypeHTTPClient, request.EventTypeHTTP:
 		peer, _ = extractHostPort(trace.RemoteAddr[:])
 		hostname, hostPort = extractHostPort(trace.Host[:])
+		// the client response length is -1 if it's unknown
+		responseLength = trace.ResponseLength
 	case request.EventTypeGRPC:
 		hostPort = int(trace.HostPort)
 		peer = extractIP(trace.RemoteAddr[:], int(trace.RemoteAddrLen))
@@ -48,19 +51,21 @@ func HTTPRequestTraceToSpan(trace *HTTPRequestTrace) request.Span {
 	}
 
 	return request.Span{
-		Type:          request.EventType(trace.Type),
-		ID:            trace.Id,
-		Method:        method,
-		Path:          path,
-		Peer:          peer,
-		Host:          hostname,
-		HostPort:      hostPort,
-		ContentLength: trace.ContentLength,
-		RequestStart:  int64(trace.GoStartMonotimeNs),
-		Start:         int64(trace.StartMonotimeNs),
-		End:           int64(trace.EndMonotimeNs),
-		Status:        int(trace.Status),
-		Traceparent:   traceparent,
+		Type:           request.EventType(trace.Type),
+		ID:             trace.Id,
+		Method:         method,
+		Path:           path,
+		Peer:           peer,
+		Host:           hostname,
+		HostPort:       hostPort,
+		ContentLength:  trace.ContentLength,
+		ResponseLength: responseLength,
+		RequestStart:   int64(trace.GoStartMonotimeNs),
+		Start:          int64(trace.StartMonotimeNs),
+		End:            int64(trace.EndMonotimeNs),
+		Status:         int(trace.Status),
+		Traceparent:    traceparent,
+		CPUTime:        request.NewCPUTime(trace.OnCpuNs, trace.OffCpuNs),
 	}
 }
 
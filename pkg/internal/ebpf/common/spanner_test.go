@@ -2,6 +2,7 @@ package ebpfcommon
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -90,6 +91,29 @@ func TestRequestTraceParsing(t *testing.T) {
 		s := HTTPRequestTraceToSpan(&tr)
 		assertMatches(t, &s, "", "/posts/1/1", "127.0.0.1", 2, 1)
 	})
+
+	t.Run("Test response length", func(t *testing.T) {
+		tr := makeHTTPRequestTrace("GET", "/posts/1/1", "127.0.0.1:1234", 200, 1)
+		tr.ResponseLength = 1234
+		s := HTTPRequestTraceToSpan(&tr)
+		assert.Equal(t, int64(1234), s.ResponseLength)
+
+		// unknown response lengths are reported as -1 by the Go client, and kept as unknown
+		tr.ResponseLength = -1
+		s = HTTPRequestTraceToSpan(&tr)
+		assert.Equal(t, int64(-1), s.ResponseLength)
+	})
+
+	t.Run("Test CPU time", func(t *testing.T) {
+		tr := makeHTTPRequestTrace("GET", "/posts/1/1", "127.0.0.1:1234", 200, 1)
+		s := HTTPRequestTraceToSpan(&tr)
+		assert.Nil(t, s.CPUTime)
+
+		tr.OnCpuNs = 300_000
+		tr.OffCpuNs = 700_000
+		s = HTTPRequestTraceToSpan(&tr)
+		assert.Equal(t, &request.CPUTime{OnCPU: 300 * time.Microsecond, OffCPU: 700 * time.Microsecond}, s.CPUTime)
+	})
 }
 
 func makeSpanWithTimings(goStart, start, end uint64) request.Span {
@@ -0,0 +1,239 @@
+// Package dns provides the DNS tracer, which reports the DNS queries over UDP of the
+// instrumented services as client spans, including the IP addresses that they resolved.
+package dns
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"unsafe"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/ringbuf"
+	"golang.org/x/net/dns/dnsmessage"
+
+	ebpfcommon "github.com/grafana/beyla/pkg/internal/ebpf/common"
+	"github.com/grafana/beyla/pkg/internal/exec"
+	"github.com/grafana/beyla/pkg/internal/goexec"
+	"github.com/grafana/beyla/pkg/internal/imetrics"
+	"github.com/grafana/beyla/pkg/internal/pipe"
+	"github.com/grafana/beyla/pkg/internal/request"
+	"github.com/grafana/beyla/pkg/internal/svc"
+)
+
+//go:generate $BPF2GO -cc $BPF_CLANG -cflags $BPF_CFLAGS -type dns_event_t -target amd64,arm64 bpf ../../../../bpf/dns_trace.c -- -I../../../../bpf/headers
+//go:generate $BPF2GO -cc $BPF_CLANG -cflags $BPF_CFLAGS -type dns_event_t -target amd64,arm64 bpf_debug ../../../../bpf/dns_trace.c -- -I../../../../bpf/headers -DBPF_DEBUG
+
+type Tracer struct {
+	Cfg        *pipe.Config
+	Metrics    imetrics.Reporter
+	bpfObjects bpfObjects
+	closers    []io.Closer
+	logger     *slog.Logger
+}
+
+func (p *Tracer) log() *slog.Logger {
+	if p.logger == nil {
+		p.logger = slog.With("component", "dns.Tracer")
+	}
+	return p.logger
+}
+
+func (p *Tracer) Load() (*ebpf.CollectionSpec, error) {
+	loader := loadBpf
+	if p.Cfg.EBPF.BpfDebug {
+		loader = loadBpf_debug
+	}
+	return loader()
+}
+
+func (p *Tracer) Constants(finfo *exec.FileInfo, _ *goexec.Offsets) map[string]any {
+	m := map[string]any{
+		"wakeup_data_bytes": uint32(p.Cfg.EBPF.WakeupLen) * uint32(unsafe.Sizeof(bpfDnsEventT{})),
+	}
+
+	// The eBPF side does some basic filtering of events that do not belong to
+	// processes which we monitor. We filter more accurately in the userspace, but
+	// for performance reasons we enable the PID based filtering in eBPF.
+	if !p.Cfg.Discovery.SystemWide {
+		m["current_pid"] = finfo.Pid
+		npid, err := ebpfcommon.FindNamespace(finfo.Pid)
+		if err != nil {
+			p.log().Warn("error while looking up namespace pid, namespace pid matching will not work", "error", err)
+		}
+		m["current_pid_ns_id"] = npid
+	}
+
+	return m
+}
+
+func (p *Tracer) BpfObjects() any {
+	return &p.bpfObjects
+}
+
+func (p *Tracer) AddCloser(c ...io.Closer) {
+	p.closers = append(p.closers, c...)
+}
+
+func (p *Tracer) GoProbes() map[string]ebpfcommon.FunctionPrograms {
+	return nil
+}
+
+func (p *Tracer) KProbes() map[string]ebpfcommon.FunctionPrograms {
+	return map[string]ebpfcommon.FunctionPrograms{
+		// the queries are sent, and the responses received, from the process context
+		"udp_sendmsg": {
+			Required: true,
+			Start:    p.bpfObjects.KprobeUdpSendmsg,
+		},
+		"udp_recvmsg": {
+			Required: true,
+			Start:    p.bpfObjects.KprobeUdpRecvmsg,
+			End:      p.bpfObjects.KretprobeUdpRecvmsg,
+		},
+	}
+}
+
+func (p *Tracer) FentryProbes() map[string]ebpfcommon.FunctionPrograms {
+	return nil
+}
+
+func (p *Tracer) Tracepoints() map[string]ebpfcommon.TracepointProgram {
+	return nil
+}
+
+func (p *Tracer) UProbes() map[string]map[string]ebpfcommon.FunctionPrograms {
+	return nil
+}
+
+func (p *Tracer) USDTProbes() map[string]map[string]ebpfcommon.USDTProgram {
+	return nil
+}
+
+func (p *Tracer) SetupMaps() error {
+	return nil
+}
+
+func (p *Tracer) SocketFilters() []*ebpf.Program {
+	return nil
+}
+
+func (p *Tracer) PinMaps(dir string) error {
+	return ebpfcommon.PinMaps(dir, &p.bpfObjects.bpfMaps)
+}
+
+func (p *Tracer) LoadPinnedMaps(dir string) error {
+	return ebpfcommon.LoadPinnedMaps(dir, &p.bpfObjects.bpfMaps)
+}
+
+func (p *Tracer) Run(ctx context.Context, eventsChan chan<- []request.Span, service svc.ID) {
+	ebpfcommon.ForwardRingbuf[bpfDnsEventT](
+		service, "dns.Tracer",
+		&p.Cfg.EBPF, p.log(), p.bpfObjects.Events, p.bpfObjects.RingbufStats,
+		p.readDNSEventIntoSpan,
+		p.Metrics,
+		append(p.closers, &p.bpfObjects)...,
+	)(ctx, eventsChan)
+}
+
+func (p *Tracer) readDNSEventIntoSpan(record *ringbuf.Record) (request.Span, bool, error) {
+	var event bpfDnsEventT
+	if err := binary.Read(bytes.NewBuffer(record.RawSample), binary.LittleEndian, &event); err != nil {
+		return request.Span{}, true, err
+	}
+	span, err := eventToSpan(&event)
+	if err != nil {
+		// the application might have sent non-DNS traffic to port 53
+		p.log().Debug("can't parse DNS response. Ignoring it", "error", err)
+		return request.Span{}, true, nil
+	}
+	if p.Cfg.Discovery.SystemWide {
+		span.ServiceID = svc.ID{Name: ebpfcommon.ProcessName(event.Pid), ProcPID: int32(event.Pid)}
+	}
+	return span, false, nil
+}
+
+func eventToSpan(event *bpfDnsEventT) (request.Span, error) {
+	span := request.Span{
+		Type:         request.EventTypeDNSClient,
+		Peer:         net.IP(event.ConnInfo.S_addr[:]).String(),
+		HostPort:     int(event.ConnInfo.D_port),
+		RequestStart: int64(event.StartMonotimeNs),
+		Start:        int64(event.StartMonotimeNs),
+		End:          int64(event.EndMonotimeNs),
+	}
+	// the address of the DNS server is unknown if the socket is not connected
+	if server := net.IP(event.ConnInfo.D_addr[:]); !server.IsUnspecified() {
+		span.Host = server.String()
+	}
+	msgLen := int(event.Len)
+	if msgLen > len(event.Buf) {
+		msgLen = len(event.Buf)
+	}
+	if err := parseResponse(&span, event.Buf[:msgLen]); err != nil {
+		return request.Span{}, err
+	}
+	return span, nil
+}
+
+// parseResponse reads the question, response code and resolved IP addresses of a DNS
+// response message. The responses that are longer than the captured buffer are
+// truncated, so the answers beyond it are ignored.
+func parseResponse(span *request.Span, msg []byte) error {
+	var parser dnsmessage.Parser
+	hdr, err := parser.Start(msg)
+	if err != nil {
+		return err
+	}
+	if !hdr.Response {
+		return errors.New("the message is not a DNS response")
+	}
+	span.Status = int(hdr.RCode)
+	question, err := parser.Question()
+	if err != nil {
+		return err
+	}
+	span.Path = strings.TrimSuffix(question.Name.String(), ".")
+	span.Method = strings.TrimPrefix(question.Type.String(), "Type")
+	if err := parser.SkipAllQuestions(); err != nil {
+		return err
+	}
+	span.ResolvedAddresses = resolvedAddresses(&parser)
+	return nil
+}
+
+// resolvedAddresses returns the addresses of the A and AAAA answers. If the message is
+// truncated, it returns the addresses that could be read.
+func resolvedAddresses(parser *dnsmessage.Parser) []string {
+	var addrs []string
+	for {
+		answer, err := parser.AnswerHeader()
+		if err != nil {
+			// no more answers, or the message is truncated
+			return addrs
+		}
+		switch answer.Type {
+		case dnsmessage.TypeA:
+			a, err := parser.AResource()
+			if err != nil {
+				return addrs
+			}
+			addrs = append(addrs, net.IP(a.A[:]).String())
+		case dnsmessage.TypeAAAA:
+			aaaa, err := parser.AAAAResource()
+			if err != nil {
+				return addrs
+			}
+			addrs = append(addrs, net.IP(aaaa.AAAA[:]).String())
+		default:
+			if err := parser.SkipAnswer(); err != nil {
+				return addrs
+			}
+		}
+	}
+}
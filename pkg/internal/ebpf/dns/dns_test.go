@@ -0,0 +1,98 @@
+package dns
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/grafana/beyla/pkg/internal/request"
+)
+
+func dnsResponse(t *testing.T, rcode dnsmessage.RCode, answers ...dnsmessage.Resource) []byte {
+	name := dnsmessage.MustNewName("grafana.com.")
+	msg := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: 1234, Response: true, RCode: rcode},
+		Questions: []dnsmessage.Question{{Name: name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET}},
+		Answers:   answers,
+	}
+	packed, err := msg.Pack()
+	require.NoError(t, err)
+	return packed
+}
+
+func aRecord(name string, ip net.IP) dnsmessage.Resource {
+	a := dnsmessage.AResource{}
+	copy(a.A[:], ip.To4())
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(name), Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET},
+		Body:   &a,
+	}
+}
+
+func dnsEvent(msg []byte) *bpfDnsEventT {
+	event := bpfDnsEventT{StartMonotimeNs: 100, EndMonotimeNs: 300, Len: uint32(len(msg))}
+	copy(event.ConnInfo.S_addr[:], net.ParseIP("10.0.0.1").To16())
+	copy(event.ConnInfo.D_addr[:], net.ParseIP("10.0.0.53").To16())
+	event.ConnInfo.D_port = 53
+	copy(event.Buf[:], msg)
+	return &event
+}
+
+func TestEventToSpan(t *testing.T) {
+	msg := dnsResponse(t, dnsmessage.RCodeSuccess,
+		dnsmessage.Resource{
+			Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName("grafana.com."), Type: dnsmessage.TypeCNAME, Class: dnsmessage.ClassINET},
+			Body:   &dnsmessage.CNAMEResource{CNAME: dnsmessage.MustNewName("lb.grafana.net.")},
+		},
+		aRecord("lb.grafana.net.", net.ParseIP("34.120.177.193")),
+		aRecord("lb.grafana.net.", net.ParseIP("34.120.177.194")),
+	)
+
+	span, err := eventToSpan(dnsEvent(msg))
+	require.NoError(t, err)
+	assert.Equal(t, request.Span{
+		Type:              request.EventTypeDNSClient,
+		Method:            "A",
+		Path:              "grafana.com",
+		Peer:              "10.0.0.1",
+		Host:              "10.0.0.53",
+		HostPort:          53,
+		RequestStart:      100,
+		Start:             100,
+		End:               300,
+		ResolvedAddresses: []string{"34.120.177.193", "34.120.177.194"},
+	}, span)
+}
+
+func TestEventToSpan_NXDomain(t *testing.T) {
+	event := dnsEvent(dnsResponse(t, dnsmessage.RCodeNameError))
+	event.ConnInfo.D_addr = [16]uint8{}
+
+	span, err := eventToSpan(event)
+	require.NoError(t, err)
+	assert.Equal(t, int(dnsmessage.RCodeNameError), span.Status)
+	assert.Empty(t, span.Host)
+	assert.Empty(t, span.ResolvedAddresses)
+}
+
+func TestEventToSpan_Truncated(t *testing.T) {
+	msg := dnsResponse(t, dnsmessage.RCodeSuccess,
+		aRecord("grafana.com.", net.ParseIP("34.120.177.193")),
+		aRecord("grafana.com.", net.ParseIP("34.120.177.194")),
+	)
+	// the last answer is not complete
+	event := dnsEvent(msg)
+	event.Len -= 2
+
+	span, err := eventToSpan(event)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"34.120.177.193"}, span.ResolvedAddresses)
+}
+
+func TestEventToSpan_NotDNS(t *testing.T) {
+	_, err := eventToSpan(dnsEvent([]byte("GET / HTTP/1.1\r\nHost: foo\r\n\r\n")))
+	assert.Error(t, err)
+}
@@ -13,6 +13,15 @@ import (
 	"github.com/cilium/ebpf"
 )
 
+type bpfCpuTimeT struct {
+	StartNs      uint64
+	LastSwitchNs uint64
+	OnCpuNs      uint64
+	OffCpuNs     uint64
+	OnCpu        uint8
+	_            [7]byte
+}
+
 type bpfFuncInvocation struct {
 	StartMonotimeNs uint64
 	Regs            struct {
@@ -79,9 +88,12 @@ type bpfSpecs struct {
 //
 // It can be passed ebpf.CollectionSpec.Assign.
 type bpfProgramSpecs struct {
+	TracepointSchedSwitch *ebpf.ProgramSpec `ebpf:"tracepoint_sched_switch"`
 	UprobeProcGoexit1     *ebpf.ProgramSpec `ebpf:"uprobe_proc_goexit1"`
 	UprobeProcNewproc1    *ebpf.ProgramSpec `ebpf:"uprobe_proc_newproc1"`
 	UprobeProcNewproc1Ret *ebpf.ProgramSpec `ebpf:"uprobe_proc_newproc1_ret"`
+	UprobeRuntimeExecute  *ebpf.ProgramSpec `ebpf:"uprobe_runtime_execute"`
+	UprobeRuntimeGopark   *ebpf.ProgramSpec `ebpf:"uprobe_runtime_gopark"`
 }
 
 // bpfMapSpecs contains maps before they are loaded into the kernel.
@@ -89,9 +101,13 @@ type bpfProgramSpecs struct {
 // It can be passed ebpf.CollectionSpec.Assign.
 type bpfMapSpecs struct {
 	Events                *ebpf.MapSpec `ebpf:"events"`
+	GoroutineThreads      *ebpf.MapSpec `ebpf:"goroutine_threads"`
 	Newproc1              *ebpf.MapSpec `ebpf:"newproc1"`
 	OngoingGoroutines     *ebpf.MapSpec `ebpf:"ongoing_goroutines"`
 	OngoingServerRequests *ebpf.MapSpec `ebpf:"ongoing_server_requests"`
+	RequestCpuTime        *ebpf.MapSpec `ebpf:"request_cpu_time"`
+	RingbufStats          *ebpf.MapSpec `ebpf:"ringbuf_stats"`
+	RunningGoroutines     *ebpf.MapSpec `ebpf:"running_goroutines"`
 }
 
 // bpfObjects contains all objects after they have been loaded into the kernel.
@@ -114,17 +130,25 @@ func (o *bpfObjects) Close() error {
 // It can be passed to loadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
 type bpfMaps struct {
 	Events                *ebpf.Map `ebpf:"events"`
+	GoroutineThreads      *ebpf.Map `ebpf:"goroutine_threads"`
 	Newproc1              *ebpf.Map `ebpf:"newproc1"`
 	OngoingGoroutines     *ebpf.Map `ebpf:"ongoing_goroutines"`
 	OngoingServerRequests *ebpf.Map `ebpf:"ongoing_server_requests"`
+	RequestCpuTime        *ebpf.Map `ebpf:"request_cpu_time"`
+	RingbufStats          *ebpf.Map `ebpf:"ringbuf_stats"`
+	RunningGoroutines     *ebpf.Map `ebpf:"running_goroutines"`
 }
 
 func (m *bpfMaps) Close() error {
 	return _BpfClose(
 		m.Events,
+		m.GoroutineThreads,
 		m.Newproc1,
 		m.OngoingGoroutines,
 		m.OngoingServerRequests,
+		m.RequestCpuTime,
+		m.RingbufStats,
+		m.RunningGoroutines,
 	)
 }
 
@@ -132,16 +156,22 @@ func (m *bpfMaps) Close() error {
 //
 // It can be passed to loadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
 type bpfPrograms struct {
+	TracepointSchedSwitch *ebpf.Program `ebpf:"tracepoint_sched_switch"`
 	UprobeProcGoexit1     *ebpf.Program `ebpf:"uprobe_proc_goexit1"`
 	UprobeProcNewproc1    *ebpf.Program `ebpf:"uprobe_proc_newproc1"`
 	UprobeProcNewproc1Ret *ebpf.Program `ebpf:"uprobe_proc_newproc1_ret"`
+	UprobeRuntimeExecute  *ebpf.Program `ebpf:"uprobe_runtime_execute"`
+	UprobeRuntimeGopark   *ebpf.Program `ebpf:"uprobe_runtime_gopark"`
 }
 
 func (p *bpfPrograms) Close() error {
 	return _BpfClose(
+		p.TracepointSchedSwitch,
 		p.UprobeProcGoexit1,
 		p.UprobeProcNewproc1,
 		p.UprobeProcNewproc1Ret,
+		p.UprobeRuntimeExecute,
+		p.UprobeRuntimeGopark,
 	)
 }
 
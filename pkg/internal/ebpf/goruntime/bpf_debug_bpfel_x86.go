@@ -13,6 +13,15 @@ import (
 	"github.com/cilium/ebpf"
 )
 
+type bpf_debugCpuTimeT struct {
+	StartNs      uint64
+	LastSwitchNs uint64
+	OnCpuNs      uint64
+	OffCpuNs     uint64
+	OnCpu        uint8
+	_            [7]byte
+}
+
 type bpf_debugFuncInvocation struct {
 	StartMonotimeNs uint64
 	Regs            struct {
@@ -86,9 +95,12 @@ type bpf_debugSpecs struct {
 //
 // It can be passed ebpf.CollectionSpec.Assign.
 type bpf_debugProgramSpecs struct {
+	TracepointSchedSwitch *ebpf.ProgramSpec `ebpf:"tracepoint_sched_switch"`
 	UprobeProcGoexit1     *ebpf.ProgramSpec `ebpf:"uprobe_proc_goexit1"`
 	UprobeProcNewproc1    *ebpf.ProgramSpec `ebpf:"uprobe_proc_newproc1"`
 	UprobeProcNewproc1Ret *ebpf.ProgramSpec `ebpf:"uprobe_proc_newproc1_ret"`
+	UprobeRuntimeExecute  *ebpf.ProgramSpec `ebpf:"uprobe_runtime_execute"`
+	UprobeRuntimeGopark   *ebpf.ProgramSpec `ebpf:"uprobe_runtime_gopark"`
 }
 
 // bpf_debugMapSpecs contains maps before they are loaded into the kernel.
@@ -96,9 +108,13 @@ type bpf_debugProgramSpecs struct {
 // It can be passed ebpf.CollectionSpec.Assign.
 type bpf_debugMapSpecs struct {
 	Events                *ebpf.MapSpec `ebpf:"events"`
+	GoroutineThreads      *ebpf.MapSpec `ebpf:"goroutine_threads"`
 	Newproc1              *ebpf.MapSpec `ebpf:"newproc1"`
 	OngoingGoroutines     *ebpf.MapSpec `ebpf:"ongoing_goroutines"`
 	OngoingServerRequests *ebpf.MapSpec `ebpf:"ongoing_server_requests"`
+	RequestCpuTime        *ebpf.MapSpec `ebpf:"request_cpu_time"`
+	RingbufStats          *ebpf.MapSpec `ebpf:"ringbuf_stats"`
+	RunningGoroutines     *ebpf.MapSpec `ebpf:"running_goroutines"`
 }
 
 // bpf_debugObjects contains all objects after they have been loaded into the kernel.
@@ -121,17 +137,25 @@ func (o *bpf_debugObjects) Close() error {
 // It can be passed to loadBpf_debugObjects or ebpf.CollectionSpec.LoadAndAssign.
 type bpf_debugMaps struct {
 	Events                *ebpf.Map `ebpf:"events"`
+	GoroutineThreads      *ebpf.Map `ebpf:"goroutine_threads"`
 	Newproc1              *ebpf.Map `ebpf:"newproc1"`
 	OngoingGoroutines     *ebpf.Map `ebpf:"ongoing_goroutines"`
 	OngoingServerRequests *ebpf.Map `ebpf:"ongoing_server_requests"`
+	RequestCpuTime        *ebpf.Map `ebpf:"request_cpu_time"`
+	RingbufStats          *ebpf.Map `ebpf:"ringbuf_stats"`
+	RunningGoroutines     *ebpf.Map `ebpf:"running_goroutines"`
 }
 
 func (m *bpf_debugMaps) Close() error {
 	return _Bpf_debugClose(
 		m.Events,
+		m.GoroutineThreads,
 		m.Newproc1,
 		m.OngoingGoroutines,
 		m.OngoingServerRequests,
+		m.RequestCpuTime,
+		m.RingbufStats,
+		m.RunningGoroutines,
 	)
 }
 
@@ -139,16 +163,22 @@ func (m *bpf_debugMaps) Close() error {
 //
 // It can be passed to loadBpf_debugObjects or ebpf.CollectionSpec.LoadAndAssign.
 type bpf_debugPrograms struct {
+	TracepointSchedSwitch *ebpf.Program `ebpf:"tracepoint_sched_switch"`
 	UprobeProcGoexit1     *ebpf.Program `ebpf:"uprobe_proc_goexit1"`
 	UprobeProcNewproc1    *ebpf.Program `ebpf:"uprobe_proc_newproc1"`
 	UprobeProcNewproc1Ret *ebpf.Program `ebpf:"uprobe_proc_newproc1_ret"`
+	UprobeRuntimeExecute  *ebpf.Program `ebpf:"uprobe_runtime_execute"`
+	UprobeRuntimeGopark   *ebpf.Program `ebpf:"uprobe_runtime_gopark"`
 }
 
 func (p *bpf_debugPrograms) Close() error {
 	return _Bpf_debugClose(
+		p.TracepointSchedSwitch,
 		p.UprobeProcGoexit1,
 		p.UprobeProcNewproc1,
 		p.UprobeProcNewproc1Ret,
+		p.UprobeRuntimeExecute,
+		p.UprobeRuntimeGopark,
 	)
 }
 
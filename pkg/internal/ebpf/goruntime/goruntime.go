@@ -46,7 +46,11 @@ func (p *Tracer) Load() (*ebpf.CollectionSpec, error) {
 }
 
 func (p *Tracer) Constants(_ *exec.FileInfo, _ *goexec.Offsets) map[string]any {
-	return make(map[string]any)
+	constants := make(map[string]any)
+	if p.Cfg.CPUTime {
+		constants["cpu_time_enabled"] = uint8(1)
+	}
+	return constants
 }
 
 func (p *Tracer) BpfObjects() any {
@@ -58,7 +62,7 @@ func (p *Tracer) AddCloser(c ...io.Closer) {
 }
 
 func (p *Tracer) GoProbes() map[string]ebpfcommon.FunctionPrograms {
-	return map[string]ebpfcommon.FunctionPrograms{
+	probes := map[string]ebpfcommon.FunctionPrograms{
 		"runtime.newproc1": {
 			Start: p.bpfObjects.UprobeProcNewproc1,
 			End:   p.bpfObjects.UprobeProcNewproc1Ret,
@@ -67,25 +71,69 @@ func (p *Tracer) GoProbes() map[string]ebpfcommon.FunctionPrograms {
 			Start: p.bpfObjects.UprobeProcGoexit1,
 		},
 	}
+	if p.Cfg.CPUTime {
+		// the goroutines that are scheduled on each thread
+		probes["runtime.execute"] = ebpfcommon.FunctionPrograms{
+			Start: p.bpfObjects.UprobeRuntimeExecute,
+		}
+		// the goroutines that stop running on their thread until they are ready again
+		probes["runtime.gopark"] = ebpfcommon.FunctionPrograms{
+			Start: p.bpfObjects.UprobeRuntimeGopark,
+		}
+	}
+	return probes
 }
 
 func (p *Tracer) KProbes() map[string]ebpfcommon.FunctionPrograms {
 	return nil
 }
 
+func (p *Tracer) FentryProbes() map[string]ebpfcommon.FunctionPrograms {
+	return nil
+}
+
+func (p *Tracer) Tracepoints() map[string]ebpfcommon.TracepointProgram {
+	if !p.Cfg.CPUTime {
+		return nil
+	}
+	return map[string]ebpfcommon.TracepointProgram{
+		// context switches of the threads that run the goroutines
+		"sched/sched_switch": {
+			Required: true,
+			Program:  p.bpfObjects.TracepointSchedSwitch,
+		},
+	}
+}
+
 func (p *Tracer) UProbes() map[string]map[string]ebpfcommon.FunctionPrograms {
 	return nil
 }
 
+func (p *Tracer) USDTProbes() map[string]map[string]ebpfcommon.USDTProgram {
+	return nil
+}
+
+func (p *Tracer) SetupMaps() error {
+	return nil
+}
+
 func (p *Tracer) SocketFilters() []*ebpf.Program {
 	return nil
 }
 
+func (p *Tracer) PinMaps(dir string) error {
+	return ebpfcommon.PinMaps(dir, &p.bpfObjects.bpfMaps)
+}
+
+func (p *Tracer) LoadPinnedMaps(dir string) error {
+	return ebpfcommon.LoadPinnedMaps(dir, &p.bpfObjects.bpfMaps)
+}
+
 func (p *Tracer) Run(ctx context.Context, eventsChan chan<- []request.Span, service svc.ID) {
 	logger := slog.With("component", "goruntime.Tracer")
 	ebpfcommon.ForwardRingbuf[ebpfcommon.HTTPRequestTrace](
-		service,
-		p.Cfg, logger, p.bpfObjects.Events,
+		service, "goruntime.Tracer",
+		p.Cfg, logger, p.bpfObjects.Events, p.bpfObjects.RingbufStats,
 		ebpfcommon.ReadHTTPRequestTraceAsSpan,
 		p.Metrics,
 		append(p.closers, &p.bpfObjects)...,
@@ -58,19 +58,43 @@ func (p *Tracer) KProbes() map[string]ebpfcommon.FunctionPrograms {
 	return nil
 }
 
+func (p *Tracer) FentryProbes() map[string]ebpfcommon.FunctionPrograms {
+	return nil
+}
+
+func (p *Tracer) Tracepoints() map[string]ebpfcommon.TracepointProgram {
+	return nil
+}
+
 func (p *Tracer) UProbes() map[string]map[string]ebpfcommon.FunctionPrograms {
 	return nil
 }
 
+func (p *Tracer) USDTProbes() map[string]map[string]ebpfcommon.USDTProgram {
+	return nil
+}
+
+func (p *Tracer) SetupMaps() error {
+	return nil
+}
+
 func (p *Tracer) SocketFilters() []*ebpf.Program {
 	return nil
 }
 
+func (p *Tracer) PinMaps(dir string) error {
+	return ebpfcommon.PinMaps(dir, &p.bpfObjects.bpfMaps)
+}
+
+func (p *Tracer) LoadPinnedMaps(dir string) error {
+	return ebpfcommon.LoadPinnedMaps(dir, &p.bpfObjects.bpfMaps)
+}
+
 func (p *Tracer) Run(ctx context.Context, eventsChan chan<- []request.Span, service svc.ID) {
 	logger := slog.With("component", "gosql.Tracer")
 	ebpfcommon.ForwardRingbuf[ebpfcommon.HTTPRequestTrace](
-		service,
-		p.Cfg, logger, p.bpfObjects.Events,
+		service, "gosql.Tracer",
+		p.Cfg, logger, p.bpfObjects.Events, p.bpfObjects.RingbufStats,
 		ebpfcommon.ReadHTTPRequestTraceAsSpan,
 		p.Metrics,
 		append(p.closers, &p.bpfObjects)...,
@@ -104,6 +104,7 @@ type bpfMapSpecs struct {
 	OngoingGrpcClientRequests *ebpf.MapSpec `ebpf:"ongoing_grpc_client_requests"`
 	OngoingGrpcRequestStatus  *ebpf.MapSpec `ebpf:"ongoing_grpc_request_status"`
 	OngoingServerRequests     *ebpf.MapSpec `ebpf:"ongoing_server_requests"`
+	RingbufStats              *ebpf.MapSpec `ebpf:"ringbuf_stats"`
 }
 
 // bpfObjects contains all objects after they have been loaded into the kernel.
@@ -132,6 +133,7 @@ type bpfMaps struct {
 	OngoingGrpcClientRequests *ebpf.Map `ebpf:"ongoing_grpc_client_requests"`
 	OngoingGrpcRequestStatus  *ebpf.Map `ebpf:"ongoing_grpc_request_status"`
 	OngoingServerRequests     *ebpf.Map `ebpf:"ongoing_server_requests"`
+	RingbufStats              *ebpf.Map `ebpf:"ringbuf_stats"`
 }
 
 func (m *bpfMaps) Close() error {
@@ -143,6 +145,7 @@ func (m *bpfMaps) Close() error {
 		m.OngoingGrpcClientRequests,
 		m.OngoingGrpcRequestStatus,
 		m.OngoingServerRequests,
+		m.RingbufStats,
 	)
 }
 
@@ -20,9 +20,20 @@ type bpfConnectionInfoT struct {
 	D_port uint16
 }
 
+type bpfCpuTimeT struct {
+	StartNs      uint64
+	LastSwitchNs uint64
+	OnCpuNs      uint64
+	OffCpuNs     uint64
+	OnCpu        uint8
+	_            [7]byte
+}
+
 type bpfHttpBufT struct {
 	Flags    uint64
 	ConnInfo bpfConnectionInfoT
+	Offset   uint32
+	Len      uint32
 	Buf      [1024]uint8
 	_        [4]byte
 }
@@ -45,7 +56,49 @@ type bpfHttpInfoT struct {
 	Status          uint16
 	Type            uint8
 	Ssl             uint8
-	_               [4]byte
+	RespLen         uint32
+	RespCounting    uint8
+	Sampling        uint8
+	RespUnknown     uint8
+	_               [1]byte
+	ServingTid      uint32
+	OnCpuNs         uint64
+	OffCpuNs        uint64
+}
+
+type bpfHttpMetricsKeyT struct {
+	PathId uint64
+	Pid    uint32
+	Status uint16
+	Method uint8
+	Type   uint8
+}
+
+type bpfHttpMetricsPathT struct{ Path [96]uint8 }
+
+type bpfHttpMetricsValueT struct {
+	Count           uint64
+	DurationSumNs   uint64
+	SizeSum         uint64
+	DurationBuckets [17]uint64
+	SizeBuckets     [17]uint64
+}
+
+type bpfIgnoreMemT struct {
+	Key bpfIgnorePrefixKeyT
+	Buf [224]uint8
+}
+
+type bpfIgnorePeerKeyT struct {
+	Prefixlen uint32
+	Addr      [16]uint8
+}
+
+type bpfIgnorePrefixKeyT struct {
+	Prefixlen uint32
+	Kind      uint8
+	Prefix    [64]uint8
+	_         [3]byte
 }
 
 type bpfRecvArgsT struct {
@@ -105,27 +158,34 @@ type bpfSpecs struct {
 //
 // It can be passed ebpf.CollectionSpec.Assign.
 type bpfProgramSpecs struct {
-	KprobeSysExit           *ebpf.ProgramSpec `ebpf:"kprobe_sys_exit"`
-	KprobeTcpConnect        *ebpf.ProgramSpec `ebpf:"kprobe_tcp_connect"`
-	KprobeTcpRcvEstablished *ebpf.ProgramSpec `ebpf:"kprobe_tcp_rcv_established"`
-	KprobeTcpRecvmsg        *ebpf.ProgramSpec `ebpf:"kprobe_tcp_recvmsg"`
-	KprobeTcpSendmsg        *ebpf.ProgramSpec `ebpf:"kprobe_tcp_sendmsg"`
-	KretprobeSockAlloc      *ebpf.ProgramSpec `ebpf:"kretprobe_sock_alloc"`
-	KretprobeSysAccept4     *ebpf.ProgramSpec `ebpf:"kretprobe_sys_accept4"`
-	KretprobeSysConnect     *ebpf.ProgramSpec `ebpf:"kretprobe_sys_connect"`
-	KretprobeTcpRecvmsg     *ebpf.ProgramSpec `ebpf:"kretprobe_tcp_recvmsg"`
-	SocketHttpFilter        *ebpf.ProgramSpec `ebpf:"socket__http_filter"`
-	UprobeSslDoHandshake    *ebpf.ProgramSpec `ebpf:"uprobe_ssl_do_handshake"`
-	UprobeSslRead           *ebpf.ProgramSpec `ebpf:"uprobe_ssl_read"`
-	UprobeSslReadEx         *ebpf.ProgramSpec `ebpf:"uprobe_ssl_read_ex"`
-	UprobeSslShutdown       *ebpf.ProgramSpec `ebpf:"uprobe_ssl_shutdown"`
-	UprobeSslWrite          *ebpf.ProgramSpec `ebpf:"uprobe_ssl_write"`
-	UprobeSslWriteEx        *ebpf.ProgramSpec `ebpf:"uprobe_ssl_write_ex"`
-	UretprobeSslDoHandshake *ebpf.ProgramSpec `ebpf:"uretprobe_ssl_do_handshake"`
-	UretprobeSslRead        *ebpf.ProgramSpec `ebpf:"uretprobe_ssl_read"`
-	UretprobeSslReadEx      *ebpf.ProgramSpec `ebpf:"uretprobe_ssl_read_ex"`
-	UretprobeSslWrite       *ebpf.ProgramSpec `ebpf:"uretprobe_ssl_write"`
-	UretprobeSslWriteEx     *ebpf.ProgramSpec `ebpf:"uretprobe_ssl_write_ex"`
+	FentryTcpConnect         *ebpf.ProgramSpec `ebpf:"fentry_tcp_connect"`
+	FentryTcpRcvEstablished  *ebpf.ProgramSpec `ebpf:"fentry_tcp_rcv_established"`
+	FentryTcpSendmsg         *ebpf.ProgramSpec `ebpf:"fentry_tcp_sendmsg"`
+	FexitSockAlloc           *ebpf.ProgramSpec `ebpf:"fexit_sock_alloc"`
+	KprobeSysExit            *ebpf.ProgramSpec `ebpf:"kprobe_sys_exit"`
+	KprobeTcpConnect         *ebpf.ProgramSpec `ebpf:"kprobe_tcp_connect"`
+	KprobeTcpRcvEstablished  *ebpf.ProgramSpec `ebpf:"kprobe_tcp_rcv_established"`
+	KprobeTcpRecvmsg         *ebpf.ProgramSpec `ebpf:"kprobe_tcp_recvmsg"`
+	KprobeTcpSendmsg         *ebpf.ProgramSpec `ebpf:"kprobe_tcp_sendmsg"`
+	KretprobeSockAlloc       *ebpf.ProgramSpec `ebpf:"kretprobe_sock_alloc"`
+	KretprobeSysAccept4      *ebpf.ProgramSpec `ebpf:"kretprobe_sys_accept4"`
+	KretprobeSysConnect      *ebpf.ProgramSpec `ebpf:"kretprobe_sys_connect"`
+	KretprobeTcpRecvmsg      *ebpf.ProgramSpec `ebpf:"kretprobe_tcp_recvmsg"`
+	SocketHttpFilter         *ebpf.ProgramSpec `ebpf:"socket__http_filter"`
+	TracepointSchedSwitch    *ebpf.ProgramSpec `ebpf:"tracepoint_sched_switch"`
+	TracepointSysExitAccept  *ebpf.ProgramSpec `ebpf:"tracepoint_sys_exit_accept"`
+	TracepointSysExitConnect *ebpf.ProgramSpec `ebpf:"tracepoint_sys_exit_connect"`
+	UprobeSslDoHandshake     *ebpf.ProgramSpec `ebpf:"uprobe_ssl_do_handshake"`
+	UprobeSslRead            *ebpf.ProgramSpec `ebpf:"uprobe_ssl_read"`
+	UprobeSslReadEx          *ebpf.ProgramSpec `ebpf:"uprobe_ssl_read_ex"`
+	UprobeSslShutdown        *ebpf.ProgramSpec `ebpf:"uprobe_ssl_shutdown"`
+	UprobeSslWrite           *ebpf.ProgramSpec `ebpf:"uprobe_ssl_write"`
+	UprobeSslWriteEx         *ebpf.ProgramSpec `ebpf:"uprobe_ssl_write_ex"`
+	UretprobeSslDoHandshake  *ebpf.ProgramSpec `ebpf:"uretprobe_ssl_do_handshake"`
+	UretprobeSslRead         *ebpf.ProgramSpec `ebpf:"uretprobe_ssl_read"`
+	UretprobeSslReadEx       *ebpf.ProgramSpec `ebpf:"uretprobe_ssl_read_ex"`
+	UretprobeSslWrite        *ebpf.ProgramSpec `ebpf:"uretprobe_ssl_write"`
+	UretprobeSslWriteEx      *ebpf.ProgramSpec `ebpf:"uretprobe_ssl_write_ex"`
 }
 
 // bpfMapSpecs contains maps before they are loaded into the kernel.
@@ -138,12 +198,27 @@ type bpfMapSpecs struct {
 	ActiveSslHandshakes *ebpf.MapSpec `ebpf:"active_ssl_handshakes"`
 	ActiveSslReadArgs   *ebpf.MapSpec `ebpf:"active_ssl_read_args"`
 	ActiveSslWriteArgs  *ebpf.MapSpec `ebpf:"active_ssl_write_args"`
+	CaptureChunkMem     *ebpf.MapSpec `ebpf:"capture_chunk_mem"`
 	DeadPids            *ebpf.MapSpec `ebpf:"dead_pids"`
 	Events              *ebpf.MapSpec `ebpf:"events"`
 	FilteredConnections *ebpf.MapSpec `ebpf:"filtered_connections"`
+	GoroutineThreads    *ebpf.MapSpec `ebpf:"goroutine_threads"`
+	HttpMetrics         *ebpf.MapSpec `ebpf:"http_metrics"`
+	HttpMetricsDropped  *ebpf.MapSpec `ebpf:"http_metrics_dropped"`
+	HttpMetricsPathMem  *ebpf.MapSpec `ebpf:"http_metrics_path_mem"`
+	HttpMetricsPathSeq  *ebpf.MapSpec `ebpf:"http_metrics_path_seq"`
+	HttpMetricsPaths    *ebpf.MapSpec `ebpf:"http_metrics_paths"`
+	HttpMetricsValueMem *ebpf.MapSpec `ebpf:"http_metrics_value_mem"`
 	HttpTcpSeq          *ebpf.MapSpec `ebpf:"http_tcp_seq"`
+	IgnoreMem           *ebpf.MapSpec `ebpf:"ignore_mem"`
+	IgnorePeers         *ebpf.MapSpec `ebpf:"ignore_peers"`
+	IgnorePrefixes      *ebpf.MapSpec `ebpf:"ignore_prefixes"`
 	OngoingHttp         *ebpf.MapSpec `ebpf:"ongoing_http"`
 	PidTidToConn        *ebpf.MapSpec `ebpf:"pid_tid_to_conn"`
+	RequestCpuTime      *ebpf.MapSpec `ebpf:"request_cpu_time"`
+	RingbufStats        *ebpf.MapSpec `ebpf:"ringbuf_stats"`
+	RunningGoroutines   *ebpf.MapSpec `ebpf:"running_goroutines"`
+	SslBufMem           *ebpf.MapSpec `ebpf:"ssl_buf_mem"`
 	SslToConn           *ebpf.MapSpec `ebpf:"ssl_to_conn"`
 	SslToPidTid         *ebpf.MapSpec `ebpf:"ssl_to_pid_tid"`
 }
@@ -173,12 +248,27 @@ type bpfMaps struct {
 	ActiveSslHandshakes *ebpf.Map `ebpf:"active_ssl_handshakes"`
 	ActiveSslReadArgs   *ebpf.Map `ebpf:"active_ssl_read_args"`
 	ActiveSslWriteArgs  *ebpf.Map `ebpf:"active_ssl_write_args"`
+	CaptureChunkMem     *ebpf.Map `ebpf:"capture_chunk_mem"`
 	DeadPids            *ebpf.Map `ebpf:"dead_pids"`
 	Events              *ebpf.Map `ebpf:"events"`
 	FilteredConnections *ebpf.Map `ebpf:"filtered_connections"`
+	GoroutineThreads    *ebpf.Map `ebpf:"goroutine_threads"`
+	HttpMetrics         *ebpf.Map `ebpf:"http_metrics"`
+	HttpMetricsDropped  *ebpf.Map `ebpf:"http_metrics_dropped"`
+	HttpMetricsPathMem  *ebpf.Map `ebpf:"http_metrics_path_mem"`
+	HttpMetricsPathSeq  *ebpf.Map `ebpf:"http_metrics_path_seq"`
+	HttpMetricsPaths    *ebpf.Map `ebpf:"http_metrics_paths"`
+	HttpMetricsValueMem *ebpf.Map `ebpf:"http_metrics_value_mem"`
 	HttpTcpSeq          *ebpf.Map `ebpf:"http_tcp_seq"`
+	IgnoreMem           *ebpf.Map `ebpf:"ignore_mem"`
+	IgnorePeers         *ebpf.Map `ebpf:"ignore_peers"`
+	IgnorePrefixes      *ebpf.Map `ebpf:"ignore_prefixes"`
 	OngoingHttp         *ebpf.Map `ebpf:"ongoing_http"`
 	PidTidToConn        *ebpf.Map `ebpf:"pid_tid_to_conn"`
+	RequestCpuTime      *ebpf.Map `ebpf:"request_cpu_time"`
+	RingbufStats        *ebpf.Map `ebpf:"ringbuf_stats"`
+	RunningGoroutines   *ebpf.Map `ebpf:"running_goroutines"`
+	SslBufMem           *ebpf.Map `ebpf:"ssl_buf_mem"`
 	SslToConn           *ebpf.Map `ebpf:"ssl_to_conn"`
 	SslToPidTid         *ebpf.Map `ebpf:"ssl_to_pid_tid"`
 }
@@ -191,12 +281,27 @@ func (m *bpfMaps) Close() error {
 		m.ActiveSslHandshakes,
 		m.ActiveSslReadArgs,
 		m.ActiveSslWriteArgs,
+		m.CaptureChunkMem,
 		m.DeadPids,
 		m.Events,
 		m.FilteredConnections,
+		m.GoroutineThreads,
+		m.HttpMetrics,
+		m.HttpMetricsDropped,
+		m.HttpMetricsPathMem,
+		m.HttpMetricsPathSeq,
+		m.HttpMetricsPaths,
+		m.HttpMetricsValueMem,
 		m.HttpTcpSeq,
+		m.IgnoreMem,
+		m.IgnorePeers,
+		m.IgnorePrefixes,
 		m.OngoingHttp,
 		m.PidTidToConn,
+		m.RequestCpuTime,
+		m.RingbufStats,
+		m.RunningGoroutines,
+		m.SslBufMem,
 		m.SslToConn,
 		m.SslToPidTid,
 	)
@@ -206,31 +311,42 @@ func (m *bpfMaps) Close() error {
 //
 // It can be passed to loadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
 type bpfPrograms struct {
-	KprobeSysExit           *ebpf.Program `ebpf:"kprobe_sys_exit"`
-	KprobeTcpConnect        *ebpf.Program `ebpf:"kprobe_tcp_connect"`
-	KprobeTcpRcvEstablished *ebpf.Program `ebpf:"kprobe_tcp_rcv_established"`
-	KprobeTcpRecvmsg        *ebpf.Program `ebpf:"kprobe_tcp_recvmsg"`
-	KprobeTcpSendmsg        *ebpf.Program `ebpf:"kprobe_tcp_sendmsg"`
-	KretprobeSockAlloc      *ebpf.Program `ebpf:"kretprobe_sock_alloc"`
-	KretprobeSysAccept4     *ebpf.Program `ebpf:"kretprobe_sys_accept4"`
-	KretprobeSysConnect     *ebpf.Program `ebpf:"kretprobe_sys_connect"`
-	KretprobeTcpRecvmsg     *ebpf.Program `ebpf:"kretprobe_tcp_recvmsg"`
-	SocketHttpFilter        *ebpf.Program `ebpf:"socket__http_filter"`
-	UprobeSslDoHandshake    *ebpf.Program `ebpf:"uprobe_ssl_do_handshake"`
-	UprobeSslRead           *ebpf.Program `ebpf:"uprobe_ssl_read"`
-	UprobeSslReadEx         *ebpf.Program `ebpf:"uprobe_ssl_read_ex"`
-	UprobeSslShutdown       *ebpf.Program `ebpf:"uprobe_ssl_shutdown"`
-	UprobeSslWrite          *ebpf.Program `ebpf:"uprobe_ssl_write"`
-	UprobeSslWriteEx        *ebpf.Program `ebpf:"uprobe_ssl_write_ex"`
-	UretprobeSslDoHandshake *ebpf.Program `ebpf:"uretprobe_ssl_do_handshake"`
-	UretprobeSslRead        *ebpf.Program `ebpf:"uretprobe_ssl_read"`
-	UretprobeSslReadEx      *ebpf.Program `ebpf:"uretprobe_ssl_read_ex"`
-	UretprobeSslWrite       *ebpf.Program `ebpf:"uretprobe_ssl_write"`
-	UretprobeSslWriteEx     *ebpf.Program `ebpf:"uretprobe_ssl_write_ex"`
+	FentryTcpConnect         *ebpf.Program `ebpf:"fentry_tcp_connect"`
+	FentryTcpRcvEstablished  *ebpf.Program `ebpf:"fentry_tcp_rcv_established"`
+	FentryTcpSendmsg         *ebpf.Program `ebpf:"fentry_tcp_sendmsg"`
+	FexitSockAlloc           *ebpf.Program `ebpf:"fexit_sock_alloc"`
+	KprobeSysExit            *ebpf.Program `ebpf:"kprobe_sys_exit"`
+	KprobeTcpConnect         *ebpf.Program `ebpf:"kprobe_tcp_connect"`
+	KprobeTcpRcvEstablished  *ebpf.Program `ebpf:"kprobe_tcp_rcv_established"`
+	KprobeTcpRecvmsg         *ebpf.Program `ebpf:"kprobe_tcp_recvmsg"`
+	KprobeTcpSendmsg         *ebpf.Program `ebpf:"kprobe_tcp_sendmsg"`
+	KretprobeSockAlloc       *ebpf.Program `ebpf:"kretprobe_sock_alloc"`
+	KretprobeSysAccept4      *ebpf.Program `ebpf:"kretprobe_sys_accept4"`
+	KretprobeSysConnect      *ebpf.Program `ebpf:"kretprobe_sys_connect"`
+	KretprobeTcpRecvmsg      *ebpf.Program `ebpf:"kretprobe_tcp_recvmsg"`
+	SocketHttpFilter         *ebpf.Program `ebpf:"socket__http_filter"`
+	TracepointSchedSwitch    *ebpf.Program `ebpf:"tracepoint_sched_switch"`
+	TracepointSysExitAccept  *ebpf.Program `ebpf:"tracepoint_sys_exit_accept"`
+	TracepointSysExitConnect *ebpf.Program `ebpf:"tracepoint_sys_exit_connect"`
+	UprobeSslDoHandshake     *ebpf.Program `ebpf:"uprobe_ssl_do_handshake"`
+	UprobeSslRead            *ebpf.Program `ebpf:"uprobe_ssl_read"`
+	UprobeSslReadEx          *ebpf.Program `ebpf:"uprobe_ssl_read_ex"`
+	UprobeSslShutdown        *ebpf.Program `ebpf:"uprobe_ssl_shutdown"`
+	UprobeSslWrite           *ebpf.Program `ebpf:"uprobe_ssl_write"`
+	UprobeSslWriteEx         *ebpf.Program `ebpf:"uprobe_ssl_write_ex"`
+	UretprobeSslDoHandshake  *ebpf.Program `ebpf:"uretprobe_ssl_do_handshake"`
+	UretprobeSslRead         *ebpf.Program `ebpf:"uretprobe_ssl_read"`
+	UretprobeSslReadEx       *ebpf.Program `ebpf:"uretprobe_ssl_read_ex"`
+	UretprobeSslWrite        *ebpf.Program `ebpf:"uretprobe_ssl_write"`
+	UretprobeSslWriteEx      *ebpf.Program `ebpf:"uretprobe_ssl_write_ex"`
 }
 
 func (p *bpfPrograms) Close() error {
 	return _BpfClose(
+		p.FentryTcpConnect,
+		p.FentryTcpRcvEstablished,
+		p.FentryTcpSendmsg,
+		p.FexitSockAlloc,
 		p.KprobeSysExit,
 		p.KprobeTcpConnect,
 		p.KprobeTcpRcvEstablished,
@@ -241,6 +357,9 @@ func (p *bpfPrograms) Close() error {
 		p.KretprobeSysConnect,
 		p.KretprobeTcpRecvmsg,
 		p.SocketHttpFilter,
+		p.TracepointSchedSwitch,
+		p.TracepointSysExitAccept,
+		p.TracepointSysExitConnect,
 		p.UprobeSslDoHandshake,
 		p.UprobeSslRead,
 		p.UprobeSslReadEx,
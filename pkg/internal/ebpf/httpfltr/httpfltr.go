@@ -29,9 +29,10 @@ import (
 //go:generate $BPF2GO -cc $BPF_CLANG -cflags $BPF_CFLAGS -type http_buf_t -target amd64,arm64 bpf_debug ../../../../bpf/http_sock.c -- -I../../../../bpf/headers -DBPF_DEBUG
 
 var activePids, _ = lru.New[uint32, string](64)
-var recvBufs, _ = lru.New[bpfConnectionInfoT, string](8192)
 
-const tpLookup = "traceparent: "
+// recvBufs and respBufs store the captured requests and responses until their span is received
+var recvBufs, _ = lru.New[bpfConnectionInfoT, *capturedBuffer](8192)
+var respBufs, _ = lru.New[bpfConnectionInfoT, *capturedBuffer](8192)
 
 type BPFHTTPInfo bpfHttpInfoT
 type BPFConnInfo bpfConnectionInfoT
@@ -44,6 +45,9 @@ type HTTPInfo struct {
 	Host        string
 	Peer        string
 	Traceparent string
+	// RequestHeaders and ResponseHeaders contain the captured headers, by their lowercase names
+	RequestHeaders  map[string]string
+	ResponseHeaders map[string]string
 }
 
 type Tracer struct {
@@ -52,6 +56,12 @@ type Tracer struct {
 	bpfObjects bpfObjects
 	closers    []io.Closer
 	logger     *slog.Logger
+	// names of the headers that are read from the captured requests and responses
+	headers struct {
+		parsed, request, response map[string]struct{}
+	}
+	// paths of the kernel-aggregated metrics that were read in the previous aggregation interval
+	lastAggregatedPaths map[uint64]string
 }
 
 func (p *Tracer) Load() (*ebpf.CollectionSpec, error) {
@@ -73,13 +83,26 @@ func (p *Tracer) log() *slog.Logger {
 }
 
 func (p *Tracer) Constants(finfo *exec.FileInfo, _ *goexec.Offsets) map[string]any {
+	m := map[string]any{}
+	for k, v := range p.aggregationConstants() {
+		m[k] = v
+	}
+	for k, v := range p.ignoreConstants() {
+		m[k] = v
+	}
+	for k, v := range p.captureConstants() {
+		m[k] = v
+	}
+	if p.Cfg.EBPF.CPUTime {
+		m["cpu_time_enabled"] = uint8(1)
+	}
 	if p.Cfg.Discovery.SystemWide {
-		return nil
+		return m
 	}
 
-	m := map[string]any{"current_pid": finfo.Pid}
+	m["current_pid"] = finfo.Pid
 
-	npid, err := findNamespace(finfo.Pid)
+	npid, err := ebpfcommon.FindNamespace(finfo.Pid)
 	if err != nil {
 		p.log().Warn("error while looking up namespace pid, namespace pid matching will not work", err)
 	}
@@ -159,6 +182,59 @@ func (p *Tracer) KProbes() map[string]ebpfcommon.FunctionPrograms {
 	return kprobes
 }
 
+// FentryProbes are preferred over the kprobes of the same functions, since they
+// have lower overhead. They are only available in kernels supporting BPF trampolines.
+func (p *Tracer) FentryProbes() map[string]ebpfcommon.FunctionPrograms {
+	return map[string]ebpfcommon.FunctionPrograms{
+		"sock_alloc": {
+			Required: true,
+			End:      p.bpfObjects.FexitSockAlloc,
+		},
+		"tcp_rcv_established": {
+			Required: true,
+			Start:    p.bpfObjects.FentryTcpRcvEstablished,
+		},
+		"tcp_connect": {
+			Required: true,
+			Start:    p.bpfObjects.FentryTcpConnect,
+		},
+		"tcp_sendmsg": {
+			Required: true,
+			Start:    p.bpfObjects.FentryTcpSendmsg,
+		},
+	}
+}
+
+// Tracepoints are a stable interface across kernel versions, so they are preferred
+// over the kprobes of the functions they replace.
+func (p *Tracer) Tracepoints() map[string]ebpfcommon.TracepointProgram {
+	tracepoints := map[string]ebpfcommon.TracepointProgram{
+		"syscalls/sys_exit_accept": {
+			Required: true,
+			Program:  p.bpfObjects.TracepointSysExitAccept,
+			Replaces: []string{"sys_accept"},
+		},
+		"syscalls/sys_exit_accept4": {
+			Required: true,
+			Program:  p.bpfObjects.TracepointSysExitAccept,
+			Replaces: []string{"sys_accept4"},
+		},
+		"syscalls/sys_exit_connect": {
+			Required: true,
+			Program:  p.bpfObjects.TracepointSysExitConnect,
+			Replaces: []string{"sys_connect"},
+		},
+	}
+	if p.Cfg.EBPF.CPUTime {
+		// context switches of the threads that serve the requests
+		tracepoints["sched/sched_switch"] = ebpfcommon.TracepointProgram{
+			Required: true,
+			Program:  p.bpfObjects.TracepointSchedSwitch,
+		}
+	}
+	return tracepoints
+}
+
 func (p *Tracer) UProbes() map[string]map[string]ebpfcommon.FunctionPrograms {
 	return map[string]map[string]ebpfcommon.FunctionPrograms{
 		"libssl.so": {
@@ -216,59 +292,44 @@ func (p *Tracer) UProbes() map[string]map[string]ebpfcommon.FunctionPrograms {
 	}
 }
 
+func (p *Tracer) USDTProbes() map[string]map[string]ebpfcommon.USDTProgram {
+	return nil
+}
+
 func (p *Tracer) SocketFilters() []*ebpf.Program {
 	return []*ebpf.Program{p.bpfObjects.SocketHttpFilter}
 }
 
+func (p *Tracer) PinMaps(dir string) error {
+	return ebpfcommon.PinMaps(dir, &p.bpfObjects.bpfMaps)
+}
+
+func (p *Tracer) LoadPinnedMaps(dir string) error {
+	return ebpfcommon.LoadPinnedMaps(dir, &p.bpfObjects.bpfMaps)
+}
+
 func (p *Tracer) Run(ctx context.Context, eventsChan chan<- []request.Span, service svc.ID) {
+	if p.Cfg.EBPF.AggregateMetrics {
+		// the requests are not sent through the ring buffer, so it's not read
+		defer p.close()
+		p.forwardAggregatedMetrics(ctx, eventsChan, service)
+		return
+	}
 	ebpfcommon.ForwardRingbuf[HTTPInfo](
-		service,
-		&p.Cfg.EBPF, p.log(), p.bpfObjects.Events,
+		service, "httpfltr.Tracer",
+		&p.Cfg.EBPF, p.log(), p.bpfObjects.Events, p.bpfObjects.RingbufStats,
 		p.readHTTPInfoIntoSpan,
 		p.Metrics,
 		append(p.closers, &p.bpfObjects)...,
 	)(ctx, eventsChan)
 }
 
-func (p *Tracer) extractTraceParent(b []uint8) string {
-	sLen := bytes.IndexByte(b, 0)
-	if sLen < 0 {
-		sLen = len(b)
-	}
-
-	s := string(b[:sLen])
-
-	hdrEnd := strings.Index(s, "\r\n\r\n")
-
-	if hdrEnd < 0 {
-		return ""
-	}
-
-	s = s[:hdrEnd]
-
-	ls := strings.ToLower(s)
-	keyIdx := strings.Index(ls, tpLookup)
-	if keyIdx >= 0 {
-		end := strings.Index(ls[keyIdx:], "\r\n")
-		if end < 0 {
-			end = hdrEnd
-		} else {
-			end += keyIdx
-		}
-		tp := s[keyIdx+len(tpLookup) : end]
-		return tp
-	}
-
-	return ""
-}
-
-func (p *Tracer) processHTTPBuf(event *bpfHttpBufT) {
-	b := event.Buf[:]
-	tp := p.extractTraceParent(b)
-	if tp != "" {
-		p.log().Debug("Found traceparent in buffer", "Traceparent", tp)
-		recvBufs.Add(event.ConnInfo, tp)
+func (p *Tracer) close() {
+	p.log().Debug("closing eBPF resources")
+	for _, c := range p.closers {
+		_ = c.Close()
 	}
+	_ = p.bpfObjects.Close()
 }
 
 func (p *Tracer) readHTTPInfoIntoSpan(record *ringbuf.Record) (request.Span, bool, error) {
@@ -282,13 +343,7 @@ func (p *Tracer) readHTTPInfoIntoSpan(record *ringbuf.Record) (request.Span, boo
 	}
 
 	if flags != 0 {
-		var buf bpfHttpBufT
-		err = binary.Read(bytes.NewBuffer(record.RawSample), binary.LittleEndian, &buf)
-		if err != nil {
-			return request.Span{}, true, err
-		}
-		p.processHTTPBuf(&buf)
-		return request.Span{}, true, nil
+		return request.Span{}, true, p.processHTTPBuf(record.RawSample)
 	}
 
 	err = binary.Read(bytes.NewBuffer(record.RawSample), binary.LittleEndian, &event)
@@ -318,18 +373,70 @@ func (p *Tracer) readHTTPInfoIntoSpan(record *ringbuf.Record) (request.Span, boo
 		result.Comm = p.serviceName(event.Pid)
 	}
 
-	tp, ok := recvBufs.Get(event.ConnInfo)
-
-	if ok {
-		p.log().Debug("Found traceparent for request", "Traceparent", tp)
-		result.Traceparent = tp
+	if captured, ok := recvBufs.Get(event.ConnInfo); ok {
+		p.readCapturedRequest(&result, captured)
 		// Clean up the LRU map once we know we have what we need
 		recvBufs.Remove(event.ConnInfo)
 	}
+	if captured, ok := respBufs.Get(event.ConnInfo); ok {
+		p.readCapturedResponse(&result, captured)
+		respBufs.Remove(event.ConnInfo)
+	}
 
 	return httpInfoToSpan(&result), false, nil
 }
 
+// readCapturedRequest completes the request information with the captured request, which
+// isn't limited by the size of the buffer of the HTTP info event
+func (p *Tracer) readCapturedRequest(result *HTTPInfo, captured *capturedBuffer) {
+	parsed, selected, _ := p.capturedHeaders()
+	req, ok := parseRequest(captured.buf, parsed)
+	if !ok || req.Method != result.Method {
+		return
+	}
+	// the URL of the event is empty if it's truncated
+	if result.URL == "" || strings.HasPrefix(req.URL, result.URL) {
+		result.URL = req.URL
+	}
+	if tp := req.Headers[headerTraceparent]; tp != "" {
+		p.log().Debug("Found traceparent for request", "Traceparent", tp)
+		result.Traceparent = tp
+	}
+	if result.Host == "" {
+		if host, portStr, err := net.SplitHostPort(req.Headers[headerHost]); err == nil {
+			if port, err := strconv.Atoi(portStr); err == nil {
+				result.Host = host
+				result.ConnInfo.D_port = uint16(port)
+			}
+		}
+	}
+	result.RequestHeaders = selectHeaders(req.Headers, selected)
+}
+
+// readCapturedResponse reads the selected headers from the captured response of a server request
+func (p *Tracer) readCapturedResponse(result *HTTPInfo, captured *capturedBuffer) {
+	_, _, selected := p.capturedHeaders()
+	if len(selected) == 0 || request.EventType(result.Type) != request.EventTypeHTTP {
+		return
+	}
+	if headers, ok := parseResponseHeaders(captured.buf, selected); ok && len(headers) > 0 {
+		result.ResponseHeaders = headers
+	}
+}
+
+func selectHeaders(headers map[string]string, selected map[string]struct{}) map[string]string {
+	var result map[string]string
+	for name, value := range headers {
+		if _, ok := selected[name]; ok {
+			if result == nil {
+				result = map[string]string{}
+			}
+			result[name] = value
+		}
+	}
+	return result
+}
+
 func (event *BPFHTTPInfo) url() string {
 	buf := string(event.Buf[:])
 	space := strings.Index(buf, " ")
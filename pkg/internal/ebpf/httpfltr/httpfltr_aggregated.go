@@ -0,0 +1,227 @@
+package httpfltr
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/cilium/ebpf"
+	"github.com/gavv/monotime"
+
+	ebpfcommon "github.com/grafana/beyla/pkg/internal/ebpf/common"
+	"github.com/grafana/beyla/pkg/internal/request"
+	"github.com/grafana/beyla/pkg/internal/svc"
+)
+
+// must coincide with the HTTP_METHOD_* definitions in bpf/http_metrics.h
+var httpMethods = []string{"", "GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS", "CONNECT", "TRACE"}
+
+// aggregationConstants returns the eBPF constants that enable the in-kernel aggregation of metrics
+func (p *Tracer) aggregationConstants() map[string]any {
+	if !p.Cfg.EBPF.AggregateMetrics {
+		return nil
+	}
+	buckets := p.Cfg.AggregatedBuckets()
+	durations := boundsConstant(buckets.DurationHistogram, float64(time.Second))
+	sizes := boundsConstant(buckets.RequestSizeHistogram, 1)
+	return map[string]any{
+		"aggregate_metrics":   uint8(1),
+		"duration_bounds_ns":  durations,
+		"duration_bounds_len": uint32(len(buckets.DurationHistogram)),
+		"size_bounds":         sizes,
+		"size_bounds_len":     uint32(len(buckets.RequestSizeHistogram)),
+	}
+}
+
+func boundsConstant(bounds []float64, multiplier float64) [ebpfcommon.MaxAggregatedHistogramBounds]uint64 {
+	var c [ebpfcommon.MaxAggregatedHistogramBounds]uint64
+	for i := 0; i < len(bounds) && i < len(c); i++ {
+		c[i] = uint64(math.Max(0, bounds[i]*multiplier))
+	}
+	return c
+}
+
+// forwardAggregatedMetrics periodically reads and resets the metrics that are aggregated in the
+// kernel, and forwards them as aggregated spans
+func (p *Tracer) forwardAggregatedMetrics(ctx context.Context, eventsChan chan<- []request.Span, service svc.ID) {
+	ticker := time.NewTicker(p.Cfg.EBPF.AggregationInterval)
+	defer ticker.Stop()
+	var lastDropped uint64
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			spans, err := p.readAggregatedMetrics(service)
+			if err != nil {
+				p.log().Error("reading aggregated metrics", "error", err)
+			}
+			if dropped, err := p.readAggregationDrops(); err != nil {
+				p.log().Debug("can't read the aggregation drops", "error", err)
+			} else {
+				if dropped > lastDropped {
+					p.Metrics.TracerAggregationDrops("httpfltr.Tracer", int(dropped-lastDropped))
+				}
+				lastDropped = dropped
+			}
+			if len(spans) > 0 {
+				p.Metrics.TracerFlush(len(spans))
+				eventsChan <- spans
+			}
+		}
+	}
+}
+
+func (p *Tracer) readAggregatedMetrics(service svc.ID) ([]request.Span, error) {
+	// the entries can't be safely deleted while iterating the map
+	var keys []bpfHttpMetricsKeyT
+	var key bpfHttpMetricsKeyT
+	var perCPU []bpfHttpMetricsValueT
+	iter := p.bpfObjects.HttpMetrics.Iterate()
+	for iter.Next(&key, &perCPU) {
+		keys = append(keys, key)
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	buckets := p.Cfg.AggregatedBuckets()
+	now := int64(monotime.Now())
+	spans := make([]request.Span, 0, len(keys))
+	values := make([]*request.AggregatedMetrics, 0, len(keys))
+	for i := range keys {
+		if err := p.lookupAndDeleteMetrics(&keys[i], &perCPU); err != nil {
+			if !errors.Is(err, ebpf.ErrKeyNotExist) {
+				return spans, err
+			}
+			values = append(values, nil)
+			continue
+		}
+		values = append(values, sumMetrics(perCPU, buckets.DurationHistogram, buckets.RequestSizeHistogram))
+	}
+	// the paths are read after the metrics, so the identifiers of all the read metrics are known
+	paths, err := p.readAggregatedPaths()
+	if err != nil {
+		return spans, err
+	}
+	for i, aggregated := range values {
+		if aggregated == nil || aggregated.Count == 0 {
+			continue
+		}
+		span := request.Span{
+			Type:         request.EventType(keys[i].Type),
+			Method:       httpMethod(keys[i].Method),
+			Path:         p.aggregatedPath(paths, keys[i].PathId),
+			Status:       int(keys[i].Status),
+			RequestStart: now,
+			Start:        now,
+			End:          now,
+			ServiceID:    service,
+			Aggregated:   aggregated,
+		}
+		if p.Cfg.Discovery.SystemWide {
+			span.ServiceID = svc.ID{Name: p.serviceName(keys[i].Pid), ProcPID: int32(keys[i].Pid)}
+		}
+		spans = append(spans, span)
+	}
+	p.lastAggregatedPaths = paths
+	return spans, nil
+}
+
+// readAggregationDrops returns the number of requests that couldn't be aggregated since the
+// tracer started, because the eBPF maps were full
+func (p *Tracer) readAggregationDrops() (uint64, error) {
+	var perCPU []uint64
+	if err := p.bpfObjects.HttpMetricsDropped.Lookup(uint32(0), &perCPU); err != nil {
+		return 0, err
+	}
+	var dropped uint64
+	for _, d := range perCPU {
+		dropped += d
+	}
+	return dropped, nil
+}
+
+// lookupAndDeleteMetrics atomically reads and removes the entry, if the kernel supports it
+func (p *Tracer) lookupAndDeleteMetrics(key *bpfHttpMetricsKeyT, perCPU *[]bpfHttpMetricsValueT) error {
+	err := p.bpfObjects.HttpMetrics.LookupAndDelete(key, perCPU)
+	if err == nil || !errors.Is(err, ebpf.ErrNotSupported) {
+		return err
+	}
+	// older kernels: the requests that are aggregated between both calls are lost
+	if err := p.bpfObjects.HttpMetrics.Lookup(key, perCPU); err != nil {
+		return err
+	}
+	return p.bpfObjects.HttpMetrics.Delete(key)
+}
+
+// readAggregatedPaths reads and removes the paths that were assigned an identifier in the kernel.
+// The removed paths get a new identifier the next time they are aggregated.
+func (p *Tracer) readAggregatedPaths() (map[uint64]string, error) {
+	paths := map[uint64]string{}
+	var keys []bpfHttpMetricsPathT
+	var key bpfHttpMetricsPathT
+	var id uint64
+	iter := p.bpfObjects.HttpMetricsPaths.Iterate()
+	for iter.Next(&key, &id) {
+		keys = append(keys, key)
+		paths[id] = cstr(key.Path[:])
+	}
+	if err := iter.Err(); err != nil {
+		return paths, err
+	}
+	for i := range keys {
+		if err := p.bpfObjects.HttpMetricsPaths.Delete(&keys[i]); err != nil && !errors.Is(err, ebpf.ErrKeyNotExist) {
+			return paths, err
+		}
+	}
+	return paths, nil
+}
+
+// aggregatedPath returns the path of the passed identifier. The paths that were read in the previous
+// aggregation interval are also looked up, as the kernel might have aggregated a request with a path
+// identifier right before its removal.
+func (p *Tracer) aggregatedPath(paths map[uint64]string, pathID uint64) string {
+	path, ok := paths[pathID]
+	if !ok {
+		path = p.lastAggregatedPaths[pathID]
+	}
+	return path
+}
+
+func httpMethod(method uint8) string {
+	if int(method) < len(httpMethods) {
+		return httpMethods[method]
+	}
+	return ""
+}
+
+// sumMetrics sums the metrics of each CPU
+func sumMetrics(perCPU []bpfHttpMetricsValueT, durationBounds, sizeBounds []float64) *request.AggregatedMetrics {
+	aggregated := &request.AggregatedMetrics{
+		Duration: request.Histogram{
+			Bounds: durationBounds,
+			Counts: make([]uint64, len(durationBounds)+1),
+		},
+		ContentLength: request.Histogram{
+			Bounds: sizeBounds,
+			Counts: make([]uint64, len(sizeBounds)+1),
+		},
+	}
+	var durationSumNs, sizeSum uint64
+	for i := range perCPU {
+		v := &perCPU[i]
+		aggregated.Count += v.Count
+		durationSumNs += v.DurationSumNs
+		sizeSum += v.SizeSum
+		for b := range aggregated.Duration.Counts {
+			aggregated.Duration.Counts[b] += v.DurationBuckets[b]
+		}
+		for b := range aggregated.ContentLength.Counts {
+			aggregated.ContentLength.Counts[b] += v.SizeBuckets[b]
+		}
+	}
+	aggregated.Duration.Sum = float64(durationSumNs) / float64(time.Second)
+	aggregated.ContentLength.Sum = float64(sizeSum)
+	return aggregated
+}
@@ -0,0 +1,44 @@
+package httpfltr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/grafana/beyla/pkg/internal/request"
+)
+
+func TestSumMetrics(t *testing.T) {
+	cpu0 := bpfHttpMetricsValueT{Count: 3, DurationSumNs: 2_500_000_000, SizeSum: 100}
+	cpu0.DurationBuckets[0], cpu0.DurationBuckets[2] = 1, 2
+	cpu0.SizeBuckets[1] = 3
+	cpu1 := bpfHttpMetricsValueT{Count: 1, DurationSumNs: 500_000_000, SizeSum: 20}
+	cpu1.DurationBuckets[1] = 1
+	cpu1.SizeBuckets[0] = 1
+
+	assert.Equal(t, &request.AggregatedMetrics{
+		Count: 4,
+		Duration: request.Histogram{
+			Bounds: []float64{0.1, 1},
+			Counts: []uint64{1, 1, 2},
+			Sum:    3,
+		},
+		ContentLength: request.Histogram{
+			Bounds: []float64{10},
+			Counts: []uint64{1, 3},
+			Sum:    120,
+		},
+	}, sumMetrics([]bpfHttpMetricsValueT{cpu0, cpu1}, []float64{0.1, 1}, []float64{10}))
+}
+
+func TestBoundsConstant(t *testing.T) {
+	c := boundsConstant([]float64{0, 0.005, 2.5}, 1e9)
+	assert.Equal(t, []uint64{0, 5_000_000, 2_500_000_000, 0}, c[:4])
+}
+
+func TestHTTPMethod(t *testing.T) {
+	assert.Equal(t, "GET", httpMethod(1))
+	assert.Equal(t, "TRACE", httpMethod(9))
+	assert.Equal(t, "", httpMethod(0))
+	assert.Equal(t, "", httpMethod(200))
+}
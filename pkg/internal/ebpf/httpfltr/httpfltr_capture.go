@@ -0,0 +1,222 @@
+package httpfltr
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"unsafe"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+)
+
+// names of the headers that are always parsed from the captured requests
+const (
+	headerHost        = "host"
+	headerUserAgent   = "user-agent"
+	headerTraceparent = "traceparent"
+)
+
+var defaultCapturedHeaders = map[string]struct{}{
+	headerHost:        {},
+	headerUserAgent:   {},
+	headerTraceparent: {},
+}
+
+// must coincide with CONN_INFO_FLAG_RESPONSE in bpf/http_types.h
+const connInfoFlagResponse = 0x2
+
+// captureChunkHeader mirrors the fields of the http_buf_t struct that precede the variable-length buffer
+type captureChunkHeader struct {
+	Flags    uint64
+	ConnInfo bpfConnectionInfoT
+	Offset   uint32
+	Len      uint32
+}
+
+var captureChunkHeaderLen = int(unsafe.Offsetof(bpfHttpBufT{}.Buf))
+
+// capturedBuffer accumulates the chunks of a request or a response, as sent by the eBPF programs
+type capturedBuffer struct {
+	buf []byte
+	// discarding is set when the chunks that are being received don't belong to a new request
+	// or response, e.g. when they are the body of a request that has been already captured
+	discarding bool
+}
+
+// httpRequest holds the request line and the selected headers of a captured request
+type httpRequest struct {
+	Method string
+	URL    string
+	// Headers contains the selected headers, by their lowercase names
+	Headers map[string]string
+}
+
+// captureConstants returns the eBPF constants that limit the size of the captured requests
+// and enable the capture of the responses
+func (p *Tracer) captureConstants() map[string]any {
+	m := map[string]any{"max_capture_bytes": uint32(p.Cfg.EBPF.RequestCaptureSize)}
+	if len(p.Cfg.EBPF.HTTPHeaders.Response) > 0 {
+		m["capture_responses"] = uint8(1)
+	}
+	return m
+}
+
+// capturedHeaders returns the lowercase names of the request headers that are parsed from the
+// captured requests, and the names of the request and response headers to add to the spans
+func (p *Tracer) capturedHeaders() (parsed, request, response map[string]struct{}) {
+	if p.headers.parsed != nil {
+		return p.headers.parsed, p.headers.request, p.headers.response
+	}
+	reqNames, respNames := p.Cfg.EBPF.HTTPHeaders.HeaderSlots()
+	p.headers.parsed = map[string]struct{}{}
+	for name := range defaultCapturedHeaders {
+		p.headers.parsed[name] = struct{}{}
+	}
+	p.headers.request = map[string]struct{}{}
+	for _, name := range reqNames {
+		p.headers.request[name] = struct{}{}
+		p.headers.parsed[name] = struct{}{}
+	}
+	p.headers.response = map[string]struct{}{}
+	for _, name := range respNames {
+		p.headers.response[name] = struct{}{}
+	}
+	return p.headers.parsed, p.headers.request, p.headers.response
+}
+
+// processHTTPBuf stores the chunk of a captured request or response, to be read when the span of
+// the request is received. The chunks of each connection are sent in order, starting from offset zero.
+func (p *Tracer) processHTTPBuf(raw []byte) error {
+	var hdr captureChunkHeader
+	if err := binary.Read(bytes.NewReader(raw), binary.LittleEndian, &hdr); err != nil {
+		return err
+	}
+	end := captureChunkHeaderLen + int(hdr.Len)
+	if end > len(raw) {
+		return fmt.Errorf("invalid chunk length %d for a record of %d bytes", hdr.Len, len(raw))
+	}
+	data := raw[captureChunkHeaderLen:end]
+	if hdr.Flags&connInfoFlagResponse != 0 {
+		addCaptureChunk(respBufs, isResponseStart, hdr.ConnInfo, hdr.Offset, data)
+	} else {
+		addCaptureChunk(recvBufs, isRequestStart, hdr.ConnInfo, hdr.Offset, data)
+	}
+	return nil
+}
+
+func addCaptureChunk(
+	bufs *lru.Cache[bpfConnectionInfoT, *capturedBuffer],
+	isStart func([]byte) bool,
+	conn bpfConnectionInfoT, offset uint32, data []byte,
+) {
+	req, ok := bufs.Get(conn)
+	if offset == 0 {
+		// a subsequent read or write from the same connection only replaces the
+		// captured request or response if it's the beginning of a new one
+		if ok && !isStart(data) {
+			req.discarding = true
+			return
+		}
+		bufs.Add(conn, &capturedBuffer{buf: append([]byte(nil), data...)})
+		return
+	}
+	if !ok || req.discarding || int(offset) != len(req.buf) {
+		// a chunk has been lost, so we keep what we have
+		return
+	}
+	req.buf = append(req.buf, data...)
+}
+
+func isRequestStart(buf []byte) bool {
+	for _, m := range httpMethods {
+		if m != "" && len(buf) > len(m) && string(buf[:len(m)]) == m && buf[len(m)] == ' ' {
+			return true
+		}
+	}
+	return false
+}
+
+func isResponseStart(buf []byte) bool {
+	return bytes.HasPrefix(buf, []byte("HTTP/"))
+}
+
+// parseRequest parses the request line and the headers of a captured request. Only the headers
+// whose lowercase names are in the selected set are returned. It returns false if the request
+// line can't be parsed.
+func parseRequest(buf []byte, selected map[string]struct{}) (httpRequest, bool) {
+	line, rest, complete := nextLine(buf)
+	if !complete {
+		return httpRequest{}, false
+	}
+	method, target, ok := strings.Cut(line, " ")
+	if !ok || method == "" {
+		return httpRequest{}, false
+	}
+	url, proto, ok := strings.Cut(target, " ")
+	if !ok || url == "" || !strings.HasPrefix(proto, "HTTP/") {
+		return httpRequest{}, false
+	}
+	req := httpRequest{Method: method, URL: url, Headers: map[string]string{}}
+	parseHeaders(rest, selected, req.Headers)
+	return req, true
+}
+
+// parseResponseHeaders parses the selected headers of a captured response. It returns false if
+// the buffer doesn't start with a valid status line.
+func parseResponseHeaders(buf []byte, selected map[string]struct{}) (map[string]string, bool) {
+	line, rest, complete := nextLine(buf)
+	if !complete || !strings.HasPrefix(line, "HTTP/") {
+		return nil, false
+	}
+	headers := map[string]string{}
+	parseHeaders(rest, selected, headers)
+	return headers, true
+}
+
+// parseHeaders adds to the headers map the selected headers of the passed headers section.
+// Since the captured buffer might be truncated, the last line is ignored if it's not complete.
+func parseHeaders(buf []byte, selected map[string]struct{}, headers map[string]string) {
+	lastName := ""
+	for {
+		line, rest, complete := nextLine(buf)
+		if !complete || line == "" {
+			// truncated buffer, or end of the headers section
+			return
+		}
+		buf = rest
+		// obsolete line folding: the value continues from the previous line
+		if line[0] == ' ' || line[0] == '\t' {
+			if lastName != "" {
+				headers[lastName] += " " + strings.TrimSpace(line)
+			}
+			continue
+		}
+		lastName = ""
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, ok := selected[name]; !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if prev, ok := headers[name]; ok {
+			// repeated headers are equivalent to a comma-separated list
+			value = prev + ", " + value
+		}
+		headers[name] = value
+		lastName = name
+	}
+}
+
+// nextLine returns the next CRLF (or LF) terminated line of the buffer, without the line terminator,
+// and the rest of the buffer. It returns false if the line is not terminated.
+func nextLine(buf []byte) (string, []byte, bool) {
+	end := bytes.IndexByte(buf, '\n')
+	if end < 0 {
+		return "", nil, false
+	}
+	return string(bytes.TrimSuffix(buf[:end], []byte{'\r'})), buf[end+1:], true
+}
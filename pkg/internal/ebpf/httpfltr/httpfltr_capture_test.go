@@ -0,0 +1,235 @@
+package httpfltr
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/cilium/ebpf/ringbuf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ebpfcommon "github.com/grafana/beyla/pkg/internal/ebpf/common"
+	"github.com/grafana/beyla/pkg/internal/pipe"
+)
+
+func traceparentOf(buf string) string {
+	req, _ := parseRequest([]byte(buf), defaultCapturedHeaders)
+	return req.Headers[headerTraceparent]
+}
+
+func TestParseRequest_Traceparent(t *testing.T) {
+	// normal formulated request
+	assert.Equal(t, "ABBA", traceparentOf(
+		"POST /smoke HTTP/1.1\r\n"+
+			"Host: localhost:3030\r\n"+
+			"User-Agent: curl/7.81.0\r\n"+
+			"Accept: */*\r\n"+
+			"Content-Type:application/json\r\n"+
+			"TraceParent: ABBA\r\n"+
+			"Content-Length: 33\r\n"+
+			"\r\n"+
+			"{\"name\": \"Joe\", \"number\": 123}"))
+
+	// normal formulated request, weird casing
+	assert.Equal(t, "AbbA", traceparentOf(
+		"POST /smoke HTTP/1.1\r\n"+
+			"Host: localhost:3030\r\n"+
+			"TrAcEpArEnT: AbbA\r\n"+
+			"\r\n"))
+
+	// we only look up to the end of the headers section
+	assert.Equal(t, "", traceparentOf(
+		"POST /smoke HTTP/1.1\r\n"+
+			"Host: localhost:3030\r\n"+
+			"Content-Length: 33\r\n"+
+			"\r\n"+
+			"Traceparent: ABBA\r\n"))
+
+	// we find the traceparent in truncated requests, if its line is complete
+	assert.Equal(t, "ABBA", traceparentOf(
+		"POST /smoke HTTP/1.1\r\n"+
+			"Host: localhost:3030\r\n"+
+			"TraceParent: ABBA\r\n"+
+			"Content-Le"))
+
+	// empty buffer
+	assert.Equal(t, "", traceparentOf(""))
+
+	// we find the traceparent but it's empty
+	assert.Equal(t, "", traceparentOf(
+		"POST /smoke HTTP/1.1\r\n"+
+			"TraceParent: \r\n"+
+			"Content-Length: 33\r\n\r\n"))
+
+	// Cut off
+	assert.Equal(t, "", traceparentOf(
+		"POST /smoke HTTP/1.1\r\n"+
+			"Host: localhost:3030\r\n"+
+			"TraceParent: "))
+	assert.Equal(t, "", traceparentOf(
+		"POST /smoke HTTP/1.1\r\n"+
+			"Host: localhost:3030\r\n"+
+			"TraceParent: 00-0af7651916cd43dd8448eb211c80319c"))
+}
+
+func TestParseRequest(t *testing.T) {
+	selected := map[string]struct{}{headerHost: {}, headerUserAgent: {}, "x-tenant-id": {}}
+	req, ok := parseRequest([]byte(
+		"GET /api/v1/users/1234?fields=name HTTP/1.1\r\n"+
+			"host: example.com:8080\r\n"+
+			"User-Agent:   Mozilla/5.0\r\n"+
+			"\t(X11; Linux x86_64)\r\n"+
+			"X-Tenant-ID: a\r\n"+
+			"X-Ignored: b\r\n"+
+			"x-tenant-id: c\r\n"+
+			"\r\n"), selected)
+	require.True(t, ok)
+	assert.Equal(t, httpRequest{
+		Method: "GET",
+		URL:    "/api/v1/users/1234?fields=name",
+		Headers: map[string]string{
+			headerHost:      "example.com:8080",
+			headerUserAgent: "Mozilla/5.0 (X11; Linux x86_64)",
+			"x-tenant-id":   "a, c",
+		},
+	}, req)
+
+	// bare LF line terminators are accepted
+	req, ok = parseRequest([]byte("DELETE /item HTTP/1.0\nHost: foo\n\n"), selected)
+	require.True(t, ok)
+	assert.Equal(t, "DELETE", req.Method)
+	assert.Equal(t, "foo", req.Headers[headerHost])
+
+	// truncated or invalid request lines
+	_, ok = parseRequest([]byte("GET /very/long/path/that/is/trunc"), selected)
+	assert.False(t, ok)
+	_, ok = parseRequest([]byte("HTTP/1.1 200 OK\r\n\r\n"), selected)
+	assert.False(t, ok)
+	_, ok = parseRequest([]byte("GET /foo\r\n\r\n"), selected)
+	assert.False(t, ok)
+}
+
+func captureChunkRecord(t *testing.T, conn bpfConnectionInfoT, offset int, data string) *ringbuf.Record {
+	buf := new(bytes.Buffer)
+	require.NoError(t, binary.Write(buf, binary.LittleEndian, &captureChunkHeader{
+		Flags: 1, ConnInfo: conn, Offset: uint32(offset), Len: uint32(len(data)),
+	}))
+	buf.WriteString(data)
+	return &ringbuf.Record{RawSample: buf.Bytes()}
+}
+
+func TestLongURLCapture(t *testing.T) {
+	tracer := Tracer{Cfg: &pipe.Config{}}
+	conn := bpfConnectionInfoT{S_port: 44000, D_port: 8080}
+	conn.S_addr = [16]byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 192, 168, 0, 1}
+	conn.D_addr = [16]byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 8, 8, 8, 8}
+
+	longPath := "/api/" + strings.Repeat("segment/", 200) + "end"
+	request := "GET " + longPath + "?q=1 HTTP/1.1\r\n" +
+		"Host: example.com\r\n" +
+		"User-Agent: test\r\n" +
+		"Traceparent: 00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01\r\n\r\n"
+	const chunkSize = 1024
+	require.Greater(t, len(request), chunkSize)
+
+	// the chunks are reassembled from the ring buffer records
+	for off := 0; off < len(request); off += chunkSize {
+		end := min(off+chunkSize, len(request))
+		_, ignore, err := tracer.readHTTPInfoIntoSpan(captureChunkRecord(t, conn, off, request[off:end]))
+		require.NoError(t, err)
+		assert.True(t, ignore)
+	}
+	// a read of the request body doesn't replace the captured request
+	_, _, err := tracer.readHTTPInfoIntoSpan(captureChunkRecord(t, conn, 0, `{"some":"body"}`))
+	require.NoError(t, err)
+
+	// the HTTP info event only contains the truncated beginning of the request
+	var event BPFHTTPInfo
+	event.Type = 1
+	event.Status = 200
+	event.ConnInfo = conn
+	copy(event.Buf[:], request)
+	buf := new(bytes.Buffer)
+	require.NoError(t, binary.Write(buf, binary.LittleEndian, &event))
+
+	span, ignore, err := tracer.readHTTPInfoIntoSpan(&ringbuf.Record{RawSample: buf.Bytes()})
+	require.NoError(t, err)
+	require.False(t, ignore)
+	assert.Equal(t, longPath, span.Path)
+	assert.Equal(t, "GET", span.Method)
+	assert.Equal(t, "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01", span.Traceparent)
+
+	// the headers are only added to the spans if they are selected
+	assert.Empty(t, span.RequestHeaders)
+
+	// the captured request is removed once it's used
+	_, ok := recvBufs.Get(conn)
+	assert.False(t, ok)
+}
+
+func TestCaptureChunks_LostChunk(t *testing.T) {
+	conn := bpfConnectionInfoT{S_port: 44001, D_port: 8080}
+	defer recvBufs.Remove(conn)
+
+	addCaptureChunk(recvBufs, isRequestStart, conn, 0, []byte("GET /a"))
+	// the chunk at offset 6 is lost
+	addCaptureChunk(recvBufs, isRequestStart, conn, 12, []byte("bcdef"))
+	req, ok := recvBufs.Get(conn)
+	require.True(t, ok)
+	assert.Equal(t, "GET /a", string(req.buf))
+
+	// a new request replaces the previous one
+	addCaptureChunk(recvBufs, isRequestStart, conn, 0, []byte("POST /b"))
+	addCaptureChunk(recvBufs, isRequestStart, conn, 7, []byte(" HTTP/1.1"))
+	req, ok = recvBufs.Get(conn)
+	require.True(t, ok)
+	assert.Equal(t, "POST /b HTTP/1.1", string(req.buf))
+}
+
+func TestProcessHTTPBuf_InvalidLength(t *testing.T) {
+	tracer := Tracer{Cfg: &pipe.Config{}}
+	record := captureChunkRecord(t, bpfConnectionInfoT{S_port: 44002}, 0, "GET / HTTP/1.1")
+	_, _, err := tracer.readHTTPInfoIntoSpan(&ringbuf.Record{RawSample: record.RawSample[:captureChunkHeaderLen+2]})
+	assert.Error(t, err)
+}
+
+func TestHeadersCapture(t *testing.T) {
+	tracer := Tracer{Cfg: &pipe.Config{EBPF: ebpfcommon.TracerConfig{
+		HTTPHeaders: ebpfcommon.HTTPHeadersConfig{
+			Request:  []string{"X-Tenant-ID", "User-Agent"},
+			Response: []string{"Content-Type"},
+		},
+	}}}
+	assert.Equal(t, uint8(1), tracer.captureConstants()["capture_responses"])
+
+	conn := bpfConnectionInfoT{S_port: 44003, D_port: 8080}
+	request := "GET /tenant HTTP/1.1\r\n" +
+		"Host: example.com\r\n" +
+		"User-Agent: test\r\n" +
+		"X-Tenant-Id: tenant1\r\n" +
+		"X-Request-Id: 1234\r\n\r\n"
+	response := "HTTP/1.1 200 OK\r\n" +
+		"Content-Type: text/plain\r\n" +
+		"Content-Length: 2\r\n\r\nOK"
+	_, _, err := tracer.readHTTPInfoIntoSpan(captureChunkRecord(t, conn, 0, request))
+	require.NoError(t, err)
+	record := captureChunkRecord(t, conn, 0, response)
+	record.RawSample[0] |= connInfoFlagResponse
+	_, _, err = tracer.readHTTPInfoIntoSpan(record)
+	require.NoError(t, err)
+
+	var event BPFHTTPInfo
+	event.Type = 1
+	event.Status = 200
+	event.ConnInfo = conn
+	copy(event.Buf[:], request)
+	buf := new(bytes.Buffer)
+	require.NoError(t, binary.Write(buf, binary.LittleEndian, &event))
+
+	span, _, err := tracer.readHTTPInfoIntoSpan(&ringbuf.Record{RawSample: buf.Bytes()})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"x-tenant-id": "tenant1", "user-agent": "test"}, span.RequestHeaders)
+	assert.Equal(t, map[string]string{"content-type": "text/plain"}, span.ResponseHeaders)
+}
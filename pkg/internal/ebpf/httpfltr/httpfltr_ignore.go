@@ -0,0 +1,102 @@
+package httpfltr
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/grafana/beyla/pkg/internal/transform"
+)
+
+// must coincide with the IGNORE_KIND_* definitions in bpf/http_ignore.h
+const (
+	ignoreKindMethod = iota + 1
+	ignoreKindPath
+	ignoreKindUserAgent
+)
+
+// ipv4 addresses are stored in the eBPF maps as IPv4-mapped IPv6 addresses
+const ipv4MappedPrefixLen = 96
+
+// ignoreConstants returns the eBPF constants that enable the in-kernel filtering of requests
+func (p *Tracer) ignoreConstants() map[string]any {
+	if !p.Cfg.Ignore.Enabled() {
+		return nil
+	}
+	return map[string]any{"ignore_requests": uint8(1)}
+}
+
+// SetupMaps populates the ignore_prefixes and ignore_peers maps with the ignore criteria
+func (p *Tracer) SetupMaps() error {
+	if !p.Cfg.Ignore.Enabled() {
+		return nil
+	}
+	prefixes, err := ignorePrefixKeys(&p.Cfg.Ignore)
+	if err != nil {
+		return err
+	}
+	for i := range prefixes {
+		if err := p.bpfObjects.IgnorePrefixes.Put(&prefixes[i], uint8(1)); err != nil {
+			return fmt.Errorf("adding ignored prefix: %w", err)
+		}
+	}
+	peers, err := ignorePeerKeys(&p.Cfg.Ignore)
+	if err != nil {
+		return err
+	}
+	for i := range peers {
+		if err := p.bpfObjects.IgnorePeers.Put(&peers[i], uint8(1)); err != nil {
+			return fmt.Errorf("adding ignored peer: %w", err)
+		}
+	}
+	return nil
+}
+
+func ignorePrefixKeys(cfg *transform.IgnoreConfig) ([]bpfIgnorePrefixKeyT, error) {
+	var keys []bpfIgnorePrefixKeyT
+	add := func(kind uint8, prefix string) error {
+		key := bpfIgnorePrefixKeyT{Kind: kind}
+		if len(prefix) > len(key.Prefix) {
+			return fmt.Errorf("ignored prefix %q is longer than %d characters", prefix, len(key.Prefix))
+		}
+		// the prefix length is in bits and includes the kind
+		key.Prefixlen = uint32(1+len(prefix)) * 8
+		copy(key.Prefix[:], prefix)
+		keys = append(keys, key)
+		return nil
+	}
+	for _, m := range cfg.Methods {
+		// the trailing space makes the match exact
+		if err := add(ignoreKindMethod, strings.ToUpper(m)+" "); err != nil {
+			return nil, err
+		}
+	}
+	for _, path := range cfg.Paths {
+		if err := add(ignoreKindPath, path); err != nil {
+			return nil, err
+		}
+	}
+	for _, ua := range cfg.UserAgents {
+		if err := add(ignoreKindUserAgent, ua); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+func ignorePeerKeys(cfg *transform.IgnoreConfig) ([]bpfIgnorePeerKeyT, error) {
+	nets, err := cfg.ParsePeerCIDRs()
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]bpfIgnorePeerKeyT, 0, len(nets))
+	for _, n := range nets {
+		ones, _ := n.Mask.Size()
+		if n.IP.To4() != nil {
+			ones += ipv4MappedPrefixLen
+		}
+		key := bpfIgnorePeerKeyT{Prefixlen: uint32(ones)}
+		copy(key.Addr[:], n.IP.To16())
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
@@ -0,0 +1,45 @@
+package httpfltr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/beyla/pkg/internal/transform"
+)
+
+func TestIgnorePrefixKeys(t *testing.T) {
+	keys, err := ignorePrefixKeys(&transform.IgnoreConfig{
+		Methods:    []string{"options"},
+		Paths:      []string{"/health"},
+		UserAgents: []string{"kube-probe/"},
+	})
+	require.NoError(t, err)
+	require.Len(t, keys, 3)
+
+	assert.EqualValues(t, ignoreKindMethod, keys[0].Kind)
+	assert.EqualValues(t, 9*8, keys[0].Prefixlen)
+	assert.Equal(t, "OPTIONS ", cstr(keys[0].Prefix[:]))
+
+	assert.EqualValues(t, ignoreKindPath, keys[1].Kind)
+	assert.EqualValues(t, 8*8, keys[1].Prefixlen)
+	assert.Equal(t, "/health", cstr(keys[1].Prefix[:]))
+
+	assert.EqualValues(t, ignoreKindUserAgent, keys[2].Kind)
+	assert.EqualValues(t, 12*8, keys[2].Prefixlen)
+	assert.Equal(t, "kube-probe/", cstr(keys[2].Prefix[:]))
+}
+
+func TestIgnorePeerKeys(t *testing.T) {
+	keys, err := ignorePeerKeys(&transform.IgnoreConfig{PeerCIDRs: []string{"10.0.0.0/8", "fd00::/16"}})
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+
+	// IPv4 networks are stored as IPv4-mapped IPv6 networks
+	assert.EqualValues(t, 104, keys[0].Prefixlen)
+	assert.Equal(t, [16]uint8{10: 0xff, 11: 0xff, 12: 10}, keys[0].Addr)
+
+	assert.EqualValues(t, 16, keys[1].Prefixlen)
+	assert.Equal(t, [16]uint8{0: 0xfd}, keys[1].Addr)
+}
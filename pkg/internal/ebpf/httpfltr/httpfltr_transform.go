@@ -8,21 +8,30 @@ import (
 )
 
 func httpInfoToSpan(info *HTTPInfo) request.Span {
+	responseLength := int64(info.RespLen)
+	if info.RespUnknown != 0 {
+		responseLength = -1
+	}
 	return request.Span{
-		Type:          request.EventType(info.Type),
-		ID:            0,
-		Method:        info.Method,
-		Path:          removeQuery(info.URL),
-		Peer:          info.Peer,
-		Host:          info.Host,
-		HostPort:      int(info.ConnInfo.D_port),
-		ContentLength: int64(info.Len),
-		RequestStart:  int64(info.StartMonotimeNs),
-		Start:         int64(info.StartMonotimeNs),
-		End:           int64(info.EndMonotimeNs),
-		Status:        int(info.Status),
-		ServiceID:     svc.ID{Name: info.Comm},
-		Traceparent:   info.Traceparent,
+		Type:           request.EventType(info.Type),
+		ID:             0,
+		Method:         info.Method,
+		Path:           removeQuery(info.URL),
+		Peer:           info.Peer,
+		Host:           info.Host,
+		HostPort:       int(info.ConnInfo.D_port),
+		ContentLength:  int64(info.Len),
+		ResponseLength: responseLength,
+		RequestStart:   int64(info.StartMonotimeNs),
+		Start:          int64(info.StartMonotimeNs),
+		End:            int64(info.EndMonotimeNs),
+		Status:         int(info.Status),
+		ServiceID:      svc.ID{Name: info.Comm, ProcPID: int32(info.Pid)},
+		Traceparent:    info.Traceparent,
+		CPUTime:        request.NewCPUTime(info.OnCpuNs, info.OffCpuNs),
+
+		RequestHeaders:  info.RequestHeaders,
+		ResponseHeaders: info.ResponseHeaders,
 	}
 }
 
@@ -2,6 +2,7 @@ package httpfltr
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -26,6 +27,30 @@ func TestHTTPInfoParsing(t *testing.T) {
 		s := httpInfoToSpan(&tr)
 		assertMatchesInfo(t, &s, "POST", "/users", "127.0.0.1", "127.0.0.2", "curl", 8080, 200, 5)
 	})
+
+	t.Run("Test response length", func(t *testing.T) {
+		tr := makeHTTPInfo("GET", "/users", "127.0.0.1", "127.0.0.2", "curl", 12345, 8080, 200, 5)
+		tr.RespLen = 4096
+		s := httpInfoToSpan(&tr)
+		assert.Equal(t, int64(4096), s.ResponseLength)
+
+		// neither the Content-Length header nor the end of the headers were found
+		tr.RespLen = 0
+		tr.RespUnknown = 1
+		s = httpInfoToSpan(&tr)
+		assert.Equal(t, int64(-1), s.ResponseLength)
+	})
+
+	t.Run("Test CPU time", func(t *testing.T) {
+		tr := makeHTTPInfo("GET", "/users", "127.0.0.1", "127.0.0.2", "curl", 12345, 8080, 200, 5)
+		s := httpInfoToSpan(&tr)
+		assert.Nil(t, s.CPUTime)
+
+		tr.OnCpuNs = 1_500_000
+		tr.OffCpuNs = 3_500_000
+		s = httpInfoToSpan(&tr)
+		assert.Equal(t, &request.CPUTime{OnCPU: 1500 * time.Microsecond, OffCPU: 3500 * time.Microsecond}, s.CPUTime)
+	})
 }
 
 func makeHTTPInfo(method, path, peer, host, comm string, peerPort, hostPort uint32, status uint16, durationMs uint64) HTTPInfo {
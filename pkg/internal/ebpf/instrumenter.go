@@ -3,10 +3,12 @@
 package ebpf
 
 import (
+	"debug/elf"
 	"encoding/binary"
 	"fmt"
 	"io"
 	"log/slog"
+	"strings"
 	"syscall"
 	"unsafe"
 
@@ -82,9 +84,102 @@ func (i *instrumenter) goprobe(probe ebpfcommon.Probe) error {
 	return nil
 }
 
-func (i *instrumenter) kprobes(p Tracer) error {
+// kernelProbes attaches the fentry/fexit programs and the tracepoints of the tracer, falling
+// back to kprobes for the kernel functions that couldn't be instrumented that way.
+func (i *instrumenter) kernelProbes(p Tracer) error {
+	replaced := i.fentryProbes(p)
+	if err := i.tracepoints(p, replaced); err != nil {
+		return err
+	}
+	return i.kprobes(p, replaced)
+}
+
+// fentryProbes attaches the fentry/fexit programs of the tracer and returns the
+// kernel functions that have been successfully instrumented
+func (i *instrumenter) fentryProbes(p Tracer) map[string]struct{} {
+	log := ilog().With("probes", "fentry")
+	replaced := map[string]struct{}{}
+	for kfunc, programs := range p.FentryProbes() {
+		log.Debug("going to add fentry/fexit to function", "function", kfunc, "probes", programs)
+		links, err := fentry(programs)
+		if err != nil {
+			// e.g. the kernel does not support BPF trampolines
+			log.Debug("can't attach fentry/fexit. Falling back to kprobes", "function", kfunc, "error", err)
+			continue
+		}
+		p.AddCloser(links...)
+		replaced[kfunc] = struct{}{}
+	}
+	return replaced
+}
+
+func fentry(programs ebpfcommon.FunctionPrograms) ([]io.Closer, error) {
+	var links []io.Closer
+	for _, prog := range []*ebpf.Program{programs.Start, programs.End} {
+		if prog == nil {
+			continue
+		}
+		l, err := link.AttachTracing(link.TracingOptions{Program: prog})
+		if err != nil {
+			for _, l := range links {
+				_ = l.Close()
+			}
+			return nil, err
+		}
+		links = append(links, l)
+	}
+	return links, nil
+}
+
+// tracepoints attaches the tracepoints of the tracer and adds to the replaced set
+// the kernel functions that don't need to be tapped with kprobes
+func (i *instrumenter) tracepoints(p Tracer, replaced map[string]struct{}) error {
+	log := ilog().With("probes", "tracepoints")
+	for name, tp := range p.Tracepoints() {
+		if allReplaced(tp.Replaces, replaced) {
+			log.Debug("kernel functions already instrumented. Ignoring tracepoint", "tracepoint", name)
+			continue
+		}
+		group, event, ok := strings.Cut(name, "/")
+		if !ok {
+			return fmt.Errorf("invalid tracepoint %q. Expected group/name", name)
+		}
+		log.Debug("going to attach tracepoint", "tracepoint", name)
+		l, err := link.Tracepoint(group, event, tp.Program, nil)
+		if err != nil {
+			if tp.Required && len(tp.Replaces) == 0 {
+				return fmt.Errorf("attaching tracepoint %q: %w", name, err)
+			}
+			log.Debug("can't attach tracepoint. Falling back to kprobes", "tracepoint", name, "error", err)
+			continue
+		}
+		p.AddCloser(l)
+		for _, kfunc := range tp.Replaces {
+			replaced[kfunc] = struct{}{}
+		}
+	}
+	return nil
+}
+
+func allReplaced(kfuncs []string, replaced map[string]struct{}) bool {
+	if len(kfuncs) == 0 {
+		return false
+	}
+	for _, kfunc := range kfuncs {
+		if _, ok := replaced[kfunc]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (i *instrumenter) kprobes(p Tracer, replaced map[string]struct{}) error {
 	log := ilog().With("probes", "kprobes")
 	for kfunc, kprobes := range p.KProbes() {
+		if _, ok := replaced[kfunc]; ok {
+			log.Debug("function already instrumented with fentry or tracepoints. Ignoring kprobe", "function", kfunc)
+			continue
+		}
 		log.Debug("going to add kprobe to function", "function", kfunc, "probes", kprobes)
 
 		if err := i.kprobe(kfunc, kprobes); err != nil {
@@ -128,18 +223,7 @@ func (i *instrumenter) uprobes(pid int32, p Tracer) error {
 	}
 
 	for lib, pMap := range p.UProbes() {
-		log.Debug("finding library", "lib", lib)
-		libMap := exec.LibPath(lib, maps)
-		instrPath := fmt.Sprintf("/proc/%d/exe", pid)
-
-		if libMap != nil {
-			log.Debug("instrumenting library", "lib", lib, "path", libMap.Pathname)
-			// we do this to make sure instrumenting something like libssl.so works with Docker
-			instrPath = fmt.Sprintf("/proc/%d/map_files/%x-%x", pid, libMap.StartAddr, libMap.EndAddr)
-		} else {
-			// E.g. NodeJS uses OpenSSL but they ship it as statically linked in the node binary
-			log.Debug(fmt.Sprintf("%s not linked, attempting to instrument executable", lib), "path", instrPath)
-		}
+		instrPath := modulePath(log, pid, lib, maps)
 
 		libExe, err := link.OpenExecutable(instrPath)
 
@@ -164,6 +248,133 @@ func (i *instrumenter) uprobes(pid int32, p Tracer) error {
 	return nil
 }
 
+// modulePath returns the path of the mapped library, or the path of the process executable if
+// the library is not loaded by the process
+func modulePath(log *slog.Logger, pid int32, lib string, maps []*procfs.ProcMap) string {
+	log.Debug("finding library", "lib", lib)
+	libMap := exec.LibPath(lib, maps)
+	instrPath := fmt.Sprintf("/proc/%d/exe", pid)
+
+	if libMap != nil {
+		log.Debug("instrumenting library", "lib", lib, "path", libMap.Pathname)
+		// we do this to make sure instrumenting something like libssl.so works with Docker
+		instrPath = fmt.Sprintf("/proc/%d/map_files/%x-%x", pid, libMap.StartAddr, libMap.EndAddr)
+	} else {
+		// E.g. NodeJS uses OpenSSL but they ship it as statically linked in the node binary
+		log.Debug(fmt.Sprintf("%s not linked, attempting to instrument executable", lib), "path", instrPath)
+	}
+	return instrPath
+}
+
+func (i *instrumenter) usdtprobes(pid int32, p Tracer) error {
+	usdtProbes := p.USDTProbes()
+	if len(usdtProbes) == 0 {
+		return nil
+	}
+	maps, err := processMaps(pid)
+	if err != nil {
+		return err
+	}
+	log := ilog().With("probes", "usdt")
+	if len(maps) == 0 {
+		log.Info("didn't find any process maps, not instrumenting USDT probes", "pid", pid)
+		return nil
+	}
+
+	for lib, pMap := range usdtProbes {
+		err := i.usdtprobesInFile(log, modulePath(log, pid, lib, maps), lib, pMap)
+		p.AddCloser(i.closables...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// usdtprobesInFile attaches the programs to the USDT probes of the executable or library file
+func (i *instrumenter) usdtprobesInFile(log *slog.Logger, instrPath, lib string, pMap map[string]ebpfcommon.USDTProgram) error {
+	found, err := usdtProbesByName(instrPath)
+	if err != nil {
+		return err
+	}
+	libExe, err := link.OpenExecutable(instrPath)
+	if err != nil {
+		return err
+	}
+
+	for probeName, program := range pMap {
+		locations, ok := found[probeName]
+		if !ok {
+			if program.Required {
+				return fmt.Errorf("USDT probe %q not found in %s", probeName, lib)
+			}
+			log.Debug("USDT probe not found. Ignoring", "probe", probeName, "lib", lib)
+			continue
+		}
+		log.Debug("going to instrument USDT probe", "probe", probeName, "locations", len(locations))
+		if err := i.usdtprobe(libExe, program, locations); err != nil {
+			if program.Required {
+				return fmt.Errorf("instrumenting USDT probe %q: %w", probeName, err)
+			}
+			log.Debug("error instrumenting USDT probe", "probe", probeName, "error", err)
+		}
+	}
+	return nil
+}
+
+// usdtProbesByName returns the USDT probes of the ELF file, grouped by provider:name. A
+// probe can be defined in multiple locations (e.g. if the function defining it is inlined).
+func usdtProbesByName(path string) (map[string][]exec.USDTProbe, error) {
+	f, err := elf.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening ELF file %s: %w", path, err)
+	}
+	defer f.Close()
+	probes, err := exec.FindUSDTProbes(f)
+	if err != nil {
+		return nil, fmt.Errorf("reading USDT probes of %s: %w", path, err)
+	}
+	byName := map[string][]exec.USDTProbe{}
+	for _, probe := range probes {
+		name := probe.Provider + ":" + probe.Name
+		byName[name] = append(byName[name], probe)
+	}
+	return byName, nil
+}
+
+func (i *instrumenter) usdtprobe(exe *link.Executable, program ebpfcommon.USDTProgram, locations []exec.USDTProbe) error {
+	for n := range locations {
+		loc := &locations[n]
+		opts := &link.UprobeOptions{
+			Address: loc.Offset,
+			// the kernel increments the semaphore while the probe is attached
+			RefCtrOffset: loc.SemaphoreOffset,
+		}
+		if program.Specs != nil {
+			// the program reads the location of the probe arguments from the spec with the cookie id
+			if id, err := i.storeUSDTSpec(program.Specs, loc); err != nil {
+				ilog().Debug("can't provide the USDT probe arguments", "probe", loc.Provider+":"+loc.Name, "error", err)
+			} else {
+				opts.Cookie = uint64(id)
+			}
+		}
+		up, err := exe.Uprobe("", program.Program, opts)
+		if err != nil && opts.Cookie != 0 {
+			// the attach cookies require kernel 5.15+. The probe is attached without its arguments
+			ilog().Debug("can't attach USDT probe with cookie. Retrying without probe arguments",
+				"probe", loc.Provider+":"+loc.Name, "error", err)
+			opts.Cookie = 0
+			up, err = exe.Uprobe("", program.Program, opts)
+		}
+		if err != nil {
+			return fmt.Errorf("setting USDT uprobe: %w", err)
+		}
+		i.closables = append(i.closables, up)
+	}
+	return nil
+}
+
 func (i *instrumenter) uprobe(funcName string, exe *link.Executable, probe ebpfcommon.FunctionPrograms) error {
 	if probe.Start != nil {
 		up, err := exe.Uprobe(funcName, probe.Start, nil)
@@ -0,0 +1,122 @@
+//go:build linux
+
+package ebpf
+
+import (
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ebpfcommon "github.com/grafana/beyla/pkg/internal/ebpf/common"
+	beylaexec "github.com/grafana/beyla/pkg/internal/exec"
+)
+
+// testdata/usdt_x86_64 is built from testdata/usdt.c
+const usdtFixture = "testdata/usdt_x86_64"
+
+func TestUSDTProbesByName(t *testing.T) {
+	found, err := usdtProbesByName(usdtFixture)
+	require.NoError(t, err)
+	require.Len(t, found, 2)
+
+	require.Len(t, found["beyla:request"], 1)
+	request := found["beyla:request"][0]
+	assert.Equal(t, uint64(0x1135), request.Offset)
+	assert.Equal(t, uint64(0x3018), request.SemaphoreOffset)
+	assert.Equal(t, "-4@%edi 8@-8(%rsp) 4@$5", request.Args)
+
+	// the inlined probe is defined in each of its locations
+	require.Len(t, found["beyla:query"], 2)
+	assert.Equal(t, uint64(0x1141), found["beyla:query"][0].Offset)
+	assert.Equal(t, uint64(0x1142), found["beyla:query"][1].Offset)
+	assert.Zero(t, found["beyla:query"][0].SemaphoreOffset)
+
+	spec, err := newUSDTSpec(&request)
+	require.NoError(t, err)
+	assert.Equal(t, uint16(3), spec.ArgCnt)
+	assert.Equal(t, usdtArgSpec{ArgType: uint8(beylaexec.USDTArgReg), RegOff: 112, Signed: 1, Bitshift: 32}, spec.Args[0])
+	assert.Equal(t, usdtArgSpec{ArgType: uint8(beylaexec.USDTArgRegDeref), RegOff: 152, ValOff: uint64(0xfffffffffffffff8)}, spec.Args[1])
+	assert.Equal(t, usdtArgSpec{ArgType: uint8(beylaexec.USDTArgConst), ValOff: 5, Bitshift: 32}, spec.Args[2])
+
+	// probes without arguments
+	spec, err = newUSDTSpec(&found["beyla:query"][0])
+	require.NoError(t, err)
+	assert.Zero(t, spec.ArgCnt)
+}
+
+func TestUSDTProbesInFile(t *testing.T) {
+	if runtime.GOARCH != "amd64" {
+		t.Skip("the USDT fixture is an x86-64 executable")
+	}
+	// a program that counts its invocations
+	counter, err := ebpf.NewMap(&ebpf.MapSpec{Type: ebpf.Array, KeySize: 4, ValueSize: 8, MaxEntries: 1})
+	if err != nil {
+		t.Skipf("can't create eBPF maps: %v", err)
+	}
+	defer counter.Close()
+	prog, err := ebpf.NewProgram(&ebpf.ProgramSpec{
+		Type:    ebpf.Kprobe,
+		License: "MIT",
+		Instructions: asm.Instructions{
+			asm.StoreImm(asm.RFP, -4, 0, asm.Word),
+			asm.LoadMapPtr(asm.R1, counter.FD()),
+			asm.Mov.Reg(asm.R2, asm.RFP),
+			asm.Add.Imm(asm.R2, -4),
+			asm.FnMapLookupElem.Call(),
+			asm.JEq.Imm(asm.R0, 0, "exit"),
+			asm.Mov.Imm(asm.R1, 1),
+			asm.StoreXAdd(asm.R0, asm.R1, asm.DWord),
+			asm.Mov.Imm(asm.R0, 0).WithSymbol("exit"),
+			asm.Return(),
+		},
+	})
+	require.NoError(t, err)
+	defer prog.Close()
+	specs, err := ebpf.NewMap(&ebpf.MapSpec{Type: ebpf.Hash, KeySize: 4, ValueSize: 200, MaxEntries: 16})
+	require.NoError(t, err)
+	defer specs.Close()
+
+	fixture, err := filepath.Abs(usdtFixture)
+	require.NoError(t, err)
+	i := &instrumenter{}
+	err = i.usdtprobesInFile(ilog(), fixture, "usdt", map[string]ebpfcommon.USDTProgram{
+		"beyla:request": {Required: true, Program: prog, Specs: specs},
+		"beyla:query":   {Required: true, Program: prog},
+		"beyla:missing": {Program: prog},
+	})
+	if err != nil {
+		t.Skipf("can't attach uprobes: %v", err)
+	}
+	defer func() {
+		for _, c := range i.closables {
+			assert.NoError(t, c.Close())
+		}
+	}()
+
+	// THEN the location of the arguments is stored for the probe that reads them
+	var id uint32
+	var spec usdtSpec
+	require.True(t, specs.Iterate().Next(&id, &spec))
+	assert.NotZero(t, id)
+	assert.Equal(t, uint16(3), spec.ArgCnt)
+
+	// AND the probe semaphores are incremented while the probes are attached, so the
+	// fixture invokes the request probe besides the two locations of the query probe
+	require.NoError(t, exec.Command(fixture).Run())
+	var count uint64
+	require.NoError(t, counter.Lookup(uint32(0), &count))
+	assert.Equal(t, uint64(3), count)
+
+	// AND the specs are removed when the probes are detached
+	for _, c := range i.closables {
+		require.NoError(t, c.Close())
+	}
+	i.closables = nil
+	assert.False(t, specs.Iterate().Next(&id, &spec))
+}
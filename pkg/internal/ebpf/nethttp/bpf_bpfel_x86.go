@@ -13,6 +13,15 @@ import (
 	"github.com/cilium/ebpf"
 )
 
+type bpfCpuTimeT struct {
+	StartNs      uint64
+	LastSwitchNs uint64
+	OnCpuNs      uint64
+	OffCpuNs     uint64
+	OnCpu        uint8
+	_            [7]byte
+}
+
 type bpfFuncInvocation struct {
 	StartMonotimeNs uint64
 	Regs            struct {
@@ -45,6 +54,44 @@ type bpfGoroutineMetadata struct {
 	Timestamp uint64
 }
 
+type bpfHeaderKeyT struct {
+	Kind uint8
+	Name [32]uint8
+}
+
+type bpfHttpHeadersT struct {
+	Type            uint8
+	Id              uint64
+	StartMonotimeNs uint64
+	Values          [8][64]uint8
+}
+
+type bpfServerHttpTraceT struct {
+	RespPtr uint64
+	ReqPtr  uint64
+	Trace   struct {
+		Type              uint8
+		Id                uint64
+		GoStartMonotimeNs uint64
+		StartMonotimeNs   uint64
+		EndMonotimeNs     uint64
+		Method            [7]uint8
+		Path              [100]uint8
+		Status            uint16
+		RemoteAddr        [50]uint8
+		RemoteAddrLen     uint64
+		Host              [256]uint8
+		HostLen           uint64
+		HostPort          uint32
+		ContentLength     int64
+		Traceparent       [55]uint8
+		ResponseLength    int64
+		OnCpuNs           uint64
+		OffCpuNs          uint64
+	}
+	_ [5]byte
+}
+
 // loadBpf returns the embedded CollectionSpec for bpf.
 func loadBpf() (*ebpf.CollectionSpec, error) {
 	reader := bytes.NewReader(_BpfBytes)
@@ -87,6 +134,7 @@ type bpfSpecs struct {
 // It can be passed ebpf.CollectionSpec.Assign.
 type bpfProgramSpecs struct {
 	UprobeServeHTTP           *ebpf.ProgramSpec `ebpf:"uprobe_ServeHTTP"`
+	UprobeServeHTTPReturns    *ebpf.ProgramSpec `ebpf:"uprobe_ServeHTTPReturns"`
 	UprobeWriteHeader         *ebpf.ProgramSpec `ebpf:"uprobe_WriteHeader"`
 	UprobeRoundTrip           *ebpf.ProgramSpec `ebpf:"uprobe_roundTrip"`
 	UprobeRoundTripReturn     *ebpf.ProgramSpec `ebpf:"uprobe_roundTripReturn"`
@@ -97,12 +145,20 @@ type bpfProgramSpecs struct {
 //
 // It can be passed ebpf.CollectionSpec.Assign.
 type bpfMapSpecs struct {
+	CapturedHeaders           *ebpf.MapSpec `ebpf:"captured_headers"`
 	Events                    *ebpf.MapSpec `ebpf:"events"`
 	GolangMapbucketStorageMap *ebpf.MapSpec `ebpf:"golang_mapbucket_storage_map"`
+	GoroutineThreads          *ebpf.MapSpec `ebpf:"goroutine_threads"`
+	HeaderKeyMem              *ebpf.MapSpec `ebpf:"header_key_mem"`
 	Newproc1                  *ebpf.MapSpec `ebpf:"newproc1"`
 	OngoingGoroutines         *ebpf.MapSpec `ebpf:"ongoing_goroutines"`
 	OngoingHttpClientRequests *ebpf.MapSpec `ebpf:"ongoing_http_client_requests"`
 	OngoingServerRequests     *ebpf.MapSpec `ebpf:"ongoing_server_requests"`
+	OngoingServerTraces       *ebpf.MapSpec `ebpf:"ongoing_server_traces"`
+	RequestCpuTime            *ebpf.MapSpec `ebpf:"request_cpu_time"`
+	RingbufStats              *ebpf.MapSpec `ebpf:"ringbuf_stats"`
+	RunningGoroutines         *ebpf.MapSpec `ebpf:"running_goroutines"`
+	ServerTraceMem            *ebpf.MapSpec `ebpf:"server_trace_mem"`
 }
 
 // bpfObjects contains all objects after they have been loaded into the kernel.
@@ -124,22 +180,38 @@ func (o *bpfObjects) Close() error {
 //
 // It can be passed to loadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
 type bpfMaps struct {
+	CapturedHeaders           *ebpf.Map `ebpf:"captured_headers"`
 	Events                    *ebpf.Map `ebpf:"events"`
 	GolangMapbucketStorageMap *ebpf.Map `ebpf:"golang_mapbucket_storage_map"`
+	GoroutineThreads          *ebpf.Map `ebpf:"goroutine_threads"`
+	HeaderKeyMem              *ebpf.Map `ebpf:"header_key_mem"`
 	Newproc1                  *ebpf.Map `ebpf:"newproc1"`
 	OngoingGoroutines         *ebpf.Map `ebpf:"ongoing_goroutines"`
 	OngoingHttpClientRequests *ebpf.Map `ebpf:"ongoing_http_client_requests"`
 	OngoingServerRequests     *ebpf.Map `ebpf:"ongoing_server_requests"`
+	OngoingServerTraces       *ebpf.Map `ebpf:"ongoing_server_traces"`
+	RequestCpuTime            *ebpf.Map `ebpf:"request_cpu_time"`
+	RingbufStats              *ebpf.Map `ebpf:"ringbuf_stats"`
+	RunningGoroutines         *ebpf.Map `ebpf:"running_goroutines"`
+	ServerTraceMem            *ebpf.Map `ebpf:"server_trace_mem"`
 }
 
 func (m *bpfMaps) Close() error {
 	return _BpfClose(
+		m.CapturedHeaders,
 		m.Events,
 		m.GolangMapbucketStorageMap,
+		m.GoroutineThreads,
+		m.HeaderKeyMem,
 		m.Newproc1,
 		m.OngoingGoroutines,
 		m.OngoingHttpClientRequests,
 		m.OngoingServerRequests,
+		m.OngoingServerTraces,
+		m.RequestCpuTime,
+		m.RingbufStats,
+		m.RunningGoroutines,
+		m.ServerTraceMem,
 	)
 }
 
@@ -148,6 +220,7 @@ func (m *bpfMaps) Close() error {
 // It can be passed to loadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
 type bpfPrograms struct {
 	UprobeServeHTTP           *ebpf.Program `ebpf:"uprobe_ServeHTTP"`
+	UprobeServeHTTPReturns    *ebpf.Program `ebpf:"uprobe_ServeHTTPReturns"`
 	UprobeWriteHeader         *ebpf.Program `ebpf:"uprobe_WriteHeader"`
 	UprobeRoundTrip           *ebpf.Program `ebpf:"uprobe_roundTrip"`
 	UprobeRoundTripReturn     *ebpf.Program `ebpf:"uprobe_roundTripReturn"`
@@ -157,6 +230,7 @@ type bpfPrograms struct {
 func (p *bpfPrograms) Close() error {
 	return _BpfClose(
 		p.UprobeServeHTTP,
+		p.UprobeServeHTTPReturns,
 		p.UprobeWriteHeader,
 		p.UprobeRoundTrip,
 		p.UprobeRoundTripReturn,
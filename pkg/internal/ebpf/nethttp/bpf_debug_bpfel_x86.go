@@ -13,6 +13,15 @@ import (
 	"github.com/cilium/ebpf"
 )
 
+type bpf_debugCpuTimeT struct {
+	StartNs      uint64
+	LastSwitchNs uint64
+	OnCpuNs      uint64
+	OffCpuNs     uint64
+	OnCpu        uint8
+	_            [7]byte
+}
+
 type bpf_debugFuncInvocation struct {
 	StartMonotimeNs uint64
 	Regs            struct {
@@ -45,6 +54,44 @@ type bpf_debugGoroutineMetadata struct {
 	Timestamp uint64
 }
 
+type bpf_debugHeaderKeyT struct {
+	Kind uint8
+	Name [32]uint8
+}
+
+type bpf_debugHttpHeadersT struct {
+	Type            uint8
+	Id              uint64
+	StartMonotimeNs uint64
+	Values          [8][64]uint8
+}
+
+type bpf_debugServerHttpTraceT struct {
+	RespPtr uint64
+	ReqPtr  uint64
+	Trace   struct {
+		Type              uint8
+		Id                uint64
+		GoStartMonotimeNs uint64
+		StartMonotimeNs   uint64
+		EndMonotimeNs     uint64
+		Method            [7]uint8
+		Path              [100]uint8
+		Status            uint16
+		RemoteAddr        [50]uint8
+		RemoteAddrLen     uint64
+		Host              [256]uint8
+		HostLen           uint64
+		HostPort          uint32
+		ContentLength     int64
+		Traceparent       [55]uint8
+		ResponseLength    int64
+		OnCpuNs           uint64
+		OffCpuNs          uint64
+	}
+	_ [5]byte
+}
+
 // loadBpf_debug returns the embedded CollectionSpec for bpf_debug.
 func loadBpf_debug() (*ebpf.CollectionSpec, error) {
 	reader := bytes.NewReader(_Bpf_debugBytes)
@@ -87,6 +134,7 @@ type bpf_debugSpecs struct {
 // It can be passed ebpf.CollectionSpec.Assign.
 type bpf_debugProgramSpecs struct {
 	UprobeServeHTTP           *ebpf.ProgramSpec `ebpf:"uprobe_ServeHTTP"`
+	UprobeServeHTTPReturns    *ebpf.ProgramSpec `ebpf:"uprobe_ServeHTTPReturns"`
 	UprobeWriteHeader         *ebpf.ProgramSpec `ebpf:"uprobe_WriteHeader"`
 	UprobeRoundTrip           *ebpf.ProgramSpec `ebpf:"uprobe_roundTrip"`
 	UprobeRoundTripReturn     *ebpf.ProgramSpec `ebpf:"uprobe_roundTripReturn"`
@@ -97,12 +145,20 @@ type bpf_debugProgramSpecs struct {
 //
 // It can be passed ebpf.CollectionSpec.Assign.
 type bpf_debugMapSpecs struct {
+	CapturedHeaders           *ebpf.MapSpec `ebpf:"captured_headers"`
 	Events                    *ebpf.MapSpec `ebpf:"events"`
 	GolangMapbucketStorageMap *ebpf.MapSpec `ebpf:"golang_mapbucket_storage_map"`
+	GoroutineThreads          *ebpf.MapSpec `ebpf:"goroutine_threads"`
+	HeaderKeyMem              *ebpf.MapSpec `ebpf:"header_key_mem"`
 	Newproc1                  *ebpf.MapSpec `ebpf:"newproc1"`
 	OngoingGoroutines         *ebpf.MapSpec `ebpf:"ongoing_goroutines"`
 	OngoingHttpClientRequests *ebpf.MapSpec `ebpf:"ongoing_http_client_requests"`
 	OngoingServerRequests     *ebpf.MapSpec `ebpf:"ongoing_server_requests"`
+	OngoingServerTraces       *ebpf.MapSpec `ebpf:"ongoing_server_traces"`
+	RequestCpuTime            *ebpf.MapSpec `ebpf:"request_cpu_time"`
+	RingbufStats              *ebpf.MapSpec `ebpf:"ringbuf_stats"`
+	RunningGoroutines         *ebpf.MapSpec `ebpf:"running_goroutines"`
+	ServerTraceMem            *ebpf.MapSpec `ebpf:"server_trace_mem"`
 }
 
 // bpf_debugObjects contains all objects after they have been loaded into the kernel.
@@ -124,22 +180,38 @@ func (o *bpf_debugObjects) Close() error {
 //
 // It can be passed to loadBpf_debugObjects or ebpf.CollectionSpec.LoadAndAssign.
 type bpf_debugMaps struct {
+	CapturedHeaders           *ebpf.Map `ebpf:"captured_headers"`
 	Events                    *ebpf.Map `ebpf:"events"`
 	GolangMapbucketStorageMap *ebpf.Map `ebpf:"golang_mapbucket_storage_map"`
+	GoroutineThreads          *ebpf.Map `ebpf:"goroutine_threads"`
+	HeaderKeyMem              *ebpf.Map `ebpf:"header_key_mem"`
 	Newproc1                  *ebpf.Map `ebpf:"newproc1"`
 	OngoingGoroutines         *ebpf.Map `ebpf:"ongoing_goroutines"`
 	OngoingHttpClientRequests *ebpf.Map `ebpf:"ongoing_http_client_requests"`
 	OngoingServerRequests     *ebpf.Map `ebpf:"ongoing_server_requests"`
+	OngoingServerTraces       *ebpf.Map `ebpf:"ongoing_server_traces"`
+	RequestCpuTime            *ebpf.Map `ebpf:"request_cpu_time"`
+	RingbufStats              *ebpf.Map `ebpf:"ringbuf_stats"`
+	RunningGoroutines         *ebpf.Map `ebpf:"running_goroutines"`
+	ServerTraceMem            *ebpf.Map `ebpf:"server_trace_mem"`
 }
 
 func (m *bpf_debugMaps) Close() error {
 	return _Bpf_debugClose(
+		m.CapturedHeaders,
 		m.Events,
 		m.GolangMapbucketStorageMap,
+		m.GoroutineThreads,
+		m.HeaderKeyMem,
 		m.Newproc1,
 		m.OngoingGoroutines,
 		m.OngoingHttpClientRequests,
 		m.OngoingServerRequests,
+		m.OngoingServerTraces,
+		m.RequestCpuTime,
+		m.RingbufStats,
+		m.RunningGoroutines,
+		m.ServerTraceMem,
 	)
 }
 
@@ -148,6 +220,7 @@ func (m *bpf_debugMaps) Close() error {
 // It can be passed to loadBpf_debugObjects or ebpf.CollectionSpec.LoadAndAssign.
 type bpf_debugPrograms struct {
 	UprobeServeHTTP           *ebpf.Program `ebpf:"uprobe_ServeHTTP"`
+	UprobeServeHTTPReturns    *ebpf.Program `ebpf:"uprobe_ServeHTTPReturns"`
 	UprobeWriteHeader         *ebpf.Program `ebpf:"uprobe_WriteHeader"`
 	UprobeRoundTrip           *ebpf.Program `ebpf:"uprobe_roundTrip"`
 	UprobeRoundTripReturn     *ebpf.Program `ebpf:"uprobe_roundTripReturn"`
@@ -157,6 +230,7 @@ type bpf_debugPrograms struct {
 func (p *bpf_debugPrograms) Close() error {
 	return _Bpf_debugClose(
 		p.UprobeServeHTTP,
+		p.UprobeServeHTTPReturns,
 		p.UprobeWriteHeader,
 		p.UprobeRoundTrip,
 		p.UprobeRoundTripReturn,
@@ -0,0 +1,127 @@
+package nethttp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/ringbuf"
+	lru "github.com/hashicorp/golang-lru/v2"
+
+	ebpfcommon "github.com/grafana/beyla/pkg/internal/ebpf/common"
+	"github.com/grafana/beyla/pkg/internal/request"
+)
+
+// must coincide with EVENT_HTTP_HEADERS in bpf/go_headers.h
+const eventTypeHTTPHeaders = 16
+
+// must coincide with the HEADER_KIND_* definitions in bpf/go_headers.h
+const (
+	headerKindRequest  = 1
+	headerKindResponse = 2
+)
+
+// the headers record of a span is sent right before it, so only a few records
+// should be pending at the same time
+const maxPendingHeaders = 1024
+
+// capturedHeaders keeps the headers records that are sent by the eBPF programs until the
+// span they belong to is received
+type capturedHeaders struct {
+	request  []string
+	response []string
+	pending  *lru.Cache[headersKey, *bpfHttpHeadersT]
+}
+
+// headersKey identifies the span of a headers record. The goroutine ID alone can't be used, as
+// it is shared by sibling client requests and by the successive server requests of a connection.
+type headersKey struct {
+	id    uint64
+	start int64
+}
+
+func newCapturedHeaders(cfg *ebpfcommon.HTTPHeadersConfig) *capturedHeaders {
+	pending, _ := lru.New[headersKey, *bpfHttpHeadersT](maxPendingHeaders)
+	ch := &capturedHeaders{pending: pending}
+	ch.request, ch.response = cfg.HeaderSlots()
+	return ch
+}
+
+// setupHeadersMap stores, for each captured header, the position of its value in the headers records
+func setupHeadersMap(m *ebpf.Map, cfg *ebpfcommon.HTTPHeadersConfig) error {
+	request, response := cfg.HeaderSlots()
+	slot := uint8(0)
+	for _, h := range []struct {
+		kind  uint8
+		names []string
+	}{{kind: headerKindRequest, names: request}, {kind: headerKindResponse, names: response}} {
+		for _, name := range h.names {
+			key := bpfHeaderKeyT{Kind: h.kind}
+			copy(key.Name[:], name)
+			if err := m.Put(key, slot); err != nil {
+				return fmt.Errorf("adding header %q: %w", name, err)
+			}
+			slot++
+		}
+	}
+	return nil
+}
+
+func (ch *capturedHeaders) store(raw []byte) error {
+	var event bpfHttpHeadersT
+	if err := binary.Read(bytes.NewReader(raw), binary.LittleEndian, &event); err != nil {
+		return err
+	}
+	ch.pending.Add(headersKey{id: event.Id, start: int64(event.StartMonotimeNs)}, &event)
+	return nil
+}
+
+// addTo adds to the span the headers that were captured for it, if any. The records whose
+// span is never received (e.g. because the ring buffer was full) are eventually evicted.
+func (ch *capturedHeaders) addTo(span *request.Span) {
+	key := headersKey{id: span.ID, start: span.Start}
+	event, ok := ch.pending.Get(key)
+	if !ok {
+		return
+	}
+	ch.pending.Remove(key)
+	span.RequestHeaders = headerValues(ch.request, event.Values[:len(ch.request)])
+	span.ResponseHeaders = headerValues(ch.response, event.Values[len(ch.request):])
+}
+
+func headerValues(names []string, values [][ebpfcommon.MaxHeaderValueLen]uint8) map[string]string {
+	var headers map[string]string
+	for i, name := range names {
+		value := values[i][:]
+		if end := bytes.IndexByte(value, 0); end >= 0 {
+			value = value[:end]
+		}
+		if len(value) == 0 {
+			// missing header
+			continue
+		}
+		if headers == nil {
+			headers = map[string]string{}
+		}
+		headers[name] = string(value)
+	}
+	return headers
+}
+
+// readHTTPRequestTrace reads the spans of the HTTP requests and attaches them the headers that
+// were captured in their preceding headers records
+func (p *Tracer) readHTTPRequestTrace(record *ringbuf.Record) (request.Span, bool, error) {
+	if len(record.RawSample) > 0 && record.RawSample[0] == eventTypeHTTPHeaders {
+		if p.headers == nil {
+			p.headers = newCapturedHeaders(&p.Cfg.HTTPHeaders)
+		}
+		return request.Span{}, true, p.headers.store(record.RawSample)
+	}
+	span, ignore, err := ebpfcommon.ReadHTTPRequestTraceAsSpan(record)
+	if err != nil || ignore || p.headers == nil {
+		return span, ignore, err
+	}
+	p.headers.addTo(&span)
+	return span, ignore, err
+}
@@ -0,0 +1,86 @@
+package nethttp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/cilium/ebpf/ringbuf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ebpfcommon "github.com/grafana/beyla/pkg/internal/ebpf/common"
+	"github.com/grafana/beyla/pkg/internal/request"
+)
+
+func record(t *testing.T, event any) *ringbuf.Record {
+	buf := new(bytes.Buffer)
+	require.NoError(t, binary.Write(buf, binary.LittleEndian, event))
+	return &ringbuf.Record{RawSample: buf.Bytes()}
+}
+
+func TestReadHTTPRequestTrace_Headers(t *testing.T) {
+	tracer := Tracer{Cfg: &ebpfcommon.TracerConfig{HTTPHeaders: ebpfcommon.HTTPHeadersConfig{
+		Request:  []string{"X-Tenant-ID", "User-Agent"},
+		Response: []string{"Content-Type"},
+	}}}
+
+	headers := bpfHttpHeadersT{Type: eventTypeHTTPHeaders, Id: 0xc000123, StartMonotimeNs: 1000}
+	copy(headers.Values[0][:], "tenant1")
+	// the User-Agent header is missing
+	copy(headers.Values[2][:], "text/plain")
+	_, ignore, err := tracer.readHTTPRequestTrace(record(t, &headers))
+	require.NoError(t, err)
+	assert.True(t, ignore)
+
+	trace := ebpfcommon.HTTPRequestTrace{
+		Type: uint8(request.EventTypeHTTP), Id: 0xc000123, StartMonotimeNs: 1000, EndMonotimeNs: 2000, Status: 200,
+	}
+	copy(trace.Method[:], "GET")
+	copy(trace.Path[:], "/tenant")
+	span, ignore, err := tracer.readHTTPRequestTrace(record(t, &trace))
+	require.NoError(t, err)
+	assert.False(t, ignore)
+	assert.Equal(t, "/tenant", span.Path)
+	assert.Equal(t, map[string]string{"x-tenant-id": "tenant1"}, span.RequestHeaders)
+	assert.Equal(t, map[string]string{"content-type": "text/plain"}, span.ResponseHeaders)
+
+	// the headers are removed once they are used
+	span, _, err = tracer.readHTTPRequestTrace(record(t, &trace))
+	require.NoError(t, err)
+	assert.Empty(t, span.RequestHeaders)
+	assert.Empty(t, span.ResponseHeaders)
+}
+
+func TestReadHTTPRequestTrace_SiblingHeaders(t *testing.T) {
+	tracer := Tracer{Cfg: &ebpfcommon.TracerConfig{HTTPHeaders: ebpfcommon.HTTPHeadersConfig{
+		Request: []string{"X-Tenant-ID"},
+	}}}
+	// client requests from sibling goroutines share the ID of their parent goroutine
+	for i, tenant := range []string{"tenant1", "tenant2"} {
+		headers := bpfHttpHeadersT{Type: eventTypeHTTPHeaders, Id: 0xc000123, StartMonotimeNs: uint64(1000 * (i + 1))}
+		copy(headers.Values[0][:], tenant)
+		_, _, err := tracer.readHTTPRequestTrace(record(t, &headers))
+		require.NoError(t, err)
+	}
+
+	clientTrace := func(start uint64) *ebpfcommon.HTTPRequestTrace {
+		trace := ebpfcommon.HTTPRequestTrace{
+			Type: uint8(request.EventTypeHTTPClient), Id: 0xc000123, StartMonotimeNs: start, EndMonotimeNs: start + 500,
+		}
+		copy(trace.Method[:], "GET")
+		return &trace
+	}
+	span, _, err := tracer.readHTTPRequestTrace(record(t, clientTrace(2000)))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"x-tenant-id": "tenant2"}, span.RequestHeaders)
+
+	// a span whose headers record was not received doesn't get the headers of a sibling
+	span, _, err = tracer.readHTTPRequestTrace(record(t, clientTrace(3000)))
+	require.NoError(t, err)
+	assert.Empty(t, span.RequestHeaders)
+
+	span, _, err = tracer.readHTTPRequestTrace(record(t, clientTrace(1000)))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"x-tenant-id": "tenant1"}, span.RequestHeaders)
+}
@@ -30,14 +30,15 @@ import (
 	"github.com/grafana/beyla/pkg/internal/svc"
 )
 
-//go:generate $BPF2GO -cc $BPF_CLANG -cflags $BPF_CFLAGS -target amd64,arm64 bpf ../../../../bpf/go_nethttp.c -- -I../../../../bpf/headers
-//go:generate $BPF2GO -cc $BPF_CLANG -cflags $BPF_CFLAGS -target amd64,arm64 bpf_debug ../../../../bpf/go_nethttp.c -- -I../../../../bpf/headers -DBPF_DEBUG
+//go:generate $BPF2GO -cc $BPF_CLANG -cflags $BPF_CFLAGS -type http_headers_t -target amd64,arm64 bpf ../../../../bpf/go_nethttp.c -- -I../../../../bpf/headers
+//go:generate $BPF2GO -cc $BPF_CLANG -cflags $BPF_CFLAGS -type http_headers_t -target amd64,arm64 bpf_debug ../../../../bpf/go_nethttp.c -- -I../../../../bpf/headers -DBPF_DEBUG
 
 type Tracer struct {
 	Cfg        *ebpfcommon.TracerConfig
 	Metrics    imetrics.Reporter
 	bpfObjects bpfObjects
 	closers    []io.Closer
+	headers    *capturedHeaders
 }
 
 func (p *Tracer) Load() (*ebpf.CollectionSpec, error) {
@@ -65,9 +66,19 @@ func (p *Tracer) Constants(_ *exec.FileInfo, offsets *goexec.Offsets) map[string
 		"content_length_ptr_pos",
 		"resp_req_pos",
 		"req_header_ptr_pos",
+		"handler_header_ptr_pos",
+		"resp_header_ptr_pos",
+		"resp_written_pos",
+		"resp_content_length_ptr_pos",
 	} {
 		constants[s] = offsets.Field[s]
 	}
+	if p.Cfg.HTTPHeaders.Enabled() {
+		constants["capture_headers"] = uint8(1)
+	}
+	if p.Cfg.CPUTime {
+		constants["cpu_time_enabled"] = uint8(1)
+	}
 	return constants
 }
 
@@ -83,6 +94,7 @@ func (p *Tracer) GoProbes() map[string]ebpfcommon.FunctionPrograms {
 	return map[string]ebpfcommon.FunctionPrograms{
 		"net/http.HandlerFunc.ServeHTTP": {
 			Start: p.bpfObjects.UprobeServeHTTP,
+			End:   p.bpfObjects.UprobeServeHTTPReturns,
 		},
 		"net/http.(*connReader).startBackgroundRead": {
 			Start: p.bpfObjects.UprobeStartBackgroundRead,
@@ -101,20 +113,47 @@ func (p *Tracer) KProbes() map[string]ebpfcommon.FunctionPrograms {
 	return nil
 }
 
+func (p *Tracer) FentryProbes() map[string]ebpfcommon.FunctionPrograms {
+	return nil
+}
+
+func (p *Tracer) Tracepoints() map[string]ebpfcommon.TracepointProgram {
+	return nil
+}
+
 func (p *Tracer) UProbes() map[string]map[string]ebpfcommon.FunctionPrograms {
 	return nil
 }
 
+func (p *Tracer) USDTProbes() map[string]map[string]ebpfcommon.USDTProgram {
+	return nil
+}
+
+func (p *Tracer) SetupMaps() error {
+	if !p.Cfg.HTTPHeaders.Enabled() {
+		return nil
+	}
+	return setupHeadersMap(p.bpfObjects.CapturedHeaders, &p.Cfg.HTTPHeaders)
+}
+
 func (p *Tracer) SocketFilters() []*ebpf.Program {
 	return nil
 }
 
+func (p *Tracer) PinMaps(dir string) error {
+	return ebpfcommon.PinMaps(dir, &p.bpfObjects.bpfMaps)
+}
+
+func (p *Tracer) LoadPinnedMaps(dir string) error {
+	return ebpfcommon.LoadPinnedMaps(dir, &p.bpfObjects.bpfMaps)
+}
+
 func (p *Tracer) Run(ctx context.Context, eventsChan chan<- []request.Span, service svc.ID) {
 	logger := slog.With("component", "nethttp.Tracer")
 	ebpfcommon.ForwardRingbuf[ebpfcommon.HTTPRequestTrace](
-		service,
-		p.Cfg, logger, p.bpfObjects.Events,
-		ebpfcommon.ReadHTTPRequestTraceAsSpan,
+		service, "nethttp.Tracer",
+		p.Cfg, logger, p.bpfObjects.Events, p.bpfObjects.RingbufStats,
+		p.readHTTPRequestTrace,
 		p.Metrics,
 		append(p.closers, &p.bpfObjects)...,
 	)(ctx, eventsChan)
@@ -130,6 +169,7 @@ func (p *GinTracer) GoProbes() map[string]ebpfcommon.FunctionPrograms {
 		"github.com/gin-gonic/gin.(*Engine).ServeHTTP": {
 			Required: true,
 			Start:    p.bpfObjects.UprobeServeHTTP,
+			End:      p.bpfObjects.UprobeServeHTTPReturns,
 		},
 		"net/http.(*response).WriteHeader": {
 			Start: p.bpfObjects.UprobeWriteHeader,
@@ -140,9 +180,9 @@ func (p *GinTracer) GoProbes() map[string]ebpfcommon.FunctionPrograms {
 func (p *GinTracer) Run(ctx context.Context, eventsChan chan<- []request.Span, service svc.ID) {
 	logger := slog.With("component", "nethttp.GinTracer")
 	ebpfcommon.ForwardRingbuf[ebpfcommon.HTTPRequestTrace](
-		service,
-		p.Cfg, logger, p.bpfObjects.Events,
-		ebpfcommon.ReadHTTPRequestTraceAsSpan,
+		service, "nethttp.GinTracer",
+		p.Cfg, logger, p.bpfObjects.Events, p.bpfObjects.RingbufStats,
+		p.readHTTPRequestTrace,
 		p.Metrics,
 		append(p.closers, &p.bpfObjects)...,
 	)(ctx, eventsChan)
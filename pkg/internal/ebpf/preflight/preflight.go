@@ -0,0 +1,140 @@
+// Package preflight checks that the host provides the kernel features and privileges
+// that are required by the eBPF tracers, and reports actionable diagnostics for the
+// missing ones.
+package preflight
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/grafana/beyla/pkg/internal/ebpf"
+)
+
+func plog() *slog.Logger {
+	return slog.With("component", "preflight.Checker")
+}
+
+// Status of a preflight check
+type Status string
+
+const (
+	StatusPass = Status("PASS")
+	// StatusWarn means that Beyla can run but some features might not work
+	StatusWarn = Status("WARN")
+	StatusFail = Status("FAIL")
+)
+
+// Check is the result of a single preflight check
+type Check struct {
+	Name    string
+	Status  Status
+	Details string
+	// Hint suggests how to fix a failed check
+	Hint string
+}
+
+// Report of all the preflight checks. It also decides which tracers are supported by the host.
+type Report struct {
+	Checks []Check
+
+	btf     bool
+	ringBuf bool
+	kprobes bool
+	uprobes bool
+	// failedKprobes maps each kernel function that can't be probed to the attach error
+	failedKprobes map[string]string
+}
+
+// Failed returns true if any of the checks failed
+func (r *Report) Failed() bool {
+	for i := range r.Checks {
+		if r.Checks[i].Status == StatusFail {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Report) add(c Check) {
+	r.Checks = append(r.Checks, c)
+}
+
+// Log the failed checks as warnings, with their remediation hints, and the passed checks as debug
+func (r *Report) Log() {
+	log := plog()
+	for _, c := range r.Checks {
+		if c.Status == StatusPass {
+			log.Debug("preflight check passed", "check", c.Name, "details", c.Details)
+		} else {
+			log.Warn("preflight check failed", "check", c.Name, "status", c.Status,
+				"details", c.Details, "hint", c.Hint)
+		}
+	}
+}
+
+// Supports returns whether the host provides all the features that are required by the tracer.
+// If not, it returns the reason. A nil report supports all the tracers.
+func (r *Report) Supports(t ebpf.Tracer) (bool, string) {
+	if r == nil {
+		return true, ""
+	}
+	if !r.btf {
+		return false, "the kernel does not provide BTF information"
+	}
+	if !r.ringBuf {
+		return false, "can't create ring buffer maps"
+	}
+	if len(t.GoProbes()) > 0 && !r.uprobes {
+		return false, "uprobes are not supported"
+	}
+	for lib, probes := range t.UProbes() {
+		for fn, p := range probes {
+			if p.Required && !r.uprobes {
+				return false, fmt.Sprintf("uprobes are not supported and %s in %s is required", fn, lib)
+			}
+		}
+	}
+	for lib, probes := range t.USDTProbes() {
+		for name, p := range probes {
+			if p.Required && !r.uprobes {
+				return false, fmt.Sprintf("uprobes are not supported and USDT probe %s in %s is required", name, lib)
+			}
+		}
+	}
+	alternatives := map[string]struct{}{}
+	for fn := range t.FentryProbes() {
+		alternatives[fn] = struct{}{}
+	}
+	for _, tp := range t.Tracepoints() {
+		for _, fn := range tp.Replaces {
+			alternatives[fn] = struct{}{}
+		}
+	}
+	for fn, p := range t.KProbes() {
+		// kprobes are a fallback for the functions that can be instrumented with
+		// fentry/fexit or tracepoints, so we let the instrumenter decide
+		if _, ok := alternatives[fn]; !p.Required || ok {
+			continue
+		}
+		if !r.kprobes {
+			return false, "kprobes are not supported"
+		}
+		if err, ok := r.failedKprobes[fn]; ok {
+			return false, fmt.Sprintf("can't attach kprobe to %s: %s", fn, err)
+		}
+	}
+	return true, ""
+}
+
+// Filter returns the tracers that are supported by the host, logging the discarded ones
+func (r *Report) Filter(tracers []ebpf.Tracer) []ebpf.Tracer {
+	supported := make([]ebpf.Tracer, 0, len(tracers))
+	for _, t := range tracers {
+		if ok, reason := r.Supports(t); ok {
+			supported = append(supported, t)
+		} else {
+			plog().Warn("disabling tracer not supported by the host", "tracer", ebpf.TracerName(t), "reason", reason)
+		}
+	}
+	return supported
+}
@@ -0,0 +1,15 @@
+package preflight
+
+import (
+	"github.com/grafana/beyla/pkg/internal/ebpf"
+)
+
+// Run returns a failed report, as the eBPF tracers are only supported in Linux
+func Run(_ string, _ []ebpf.Tracer) *Report {
+	return &Report{Checks: []Check{{
+		Name:    "Operating system",
+		Status:  StatusFail,
+		Details: "eBPF is only supported in Linux",
+		Hint:    "run Beyla in a Linux host",
+	}}}
+}
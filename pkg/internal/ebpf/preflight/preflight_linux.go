@@ -0,0 +1,303 @@
+package preflight
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	cebpf "github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+	"github.com/cilium/ebpf/link"
+	"github.com/cilium/ebpf/rlimit"
+	"golang.org/x/sys/unix"
+
+	"github.com/grafana/beyla/pkg/internal/ebpf"
+)
+
+// minimum kernel version, which introduced BPF ring buffers
+const minKernelMajor, minKernelMinor = 5, 8
+
+var kernelVersionRegexp = regexp.MustCompile(`^(\d+)\.(\d+)`)
+
+// tracefs directories, where kprobes and uprobes can be attached in old kernels
+var tracefsDirs = []string{"/sys/kernel/tracing", "/sys/kernel/debug/tracing"}
+
+// Run all the preflight checks. The tracers are inspected to check that all the kernel
+// functions that they require can be probed.
+func Run(bpfBaseDir string, tracers []ebpf.Tracer) *Report {
+	r := &Report{failedKprobes: map[string]string{}}
+	r.add(checkKernelVersion())
+	r.add(r.checkBTF())
+	r.add(checkCapabilities())
+	r.add(checkBpfFS(bpfBaseDir))
+	r.add(checkLockdown())
+	// required for kernels older than 5.11, which account BPF memory in RLIMIT_MEMLOCK
+	_ = rlimit.RemoveMemlock()
+	r.add(r.checkRingBuf())
+	prog, check := loadKprobeProgram()
+	if prog != nil {
+		defer prog.Close()
+	}
+	r.add(check)
+	r.add(r.checkProbeSupport(prog, "kprobe"))
+	r.add(r.checkProbeSupport(prog, "uprobe"))
+	r.checkKprobes(prog, tracers)
+	return r
+}
+
+func checkKernelVersion() Check {
+	c := Check{Name: "Kernel version"}
+	var uname unix.Utsname
+	if err := unix.Uname(&uname); err != nil {
+		c.Status, c.Details = StatusWarn, "can't get kernel version: "+err.Error()
+		return c
+	}
+	release := unix.ByteSliceToString(uname.Release[:])
+	c.Details = release
+	matches := kernelVersionRegexp.FindStringSubmatch(release)
+	if matches == nil {
+		c.Status, c.Hint = StatusWarn, "can't parse kernel version"
+		return c
+	}
+	major, _ := strconv.Atoi(matches[1])
+	minor, _ := strconv.Atoi(matches[2])
+	switch {
+	case major > minKernelMajor || major == minKernelMajor && minor >= minKernelMinor:
+		c.Status = StatusPass
+	case strings.Contains(release, ".el8"):
+		// RHEL 8 kernels backport most of the eBPF features from newer kernels
+		c.Status = StatusWarn
+		c.Hint = "RHEL 8 kernels backport the required eBPF features, but some of them might not work"
+	default:
+		c.Status = StatusFail
+		c.Hint = fmt.Sprintf("upgrade to Linux %d.%d or newer", minKernelMajor, minKernelMinor)
+	}
+	return c
+}
+
+func (r *Report) checkBTF() Check {
+	c := Check{Name: "BTF information"}
+	if _, err := os.Stat("/sys/kernel/btf/vmlinux"); err != nil {
+		c.Status, c.Details = StatusFail, err.Error()
+		c.Hint = "use a kernel compiled with CONFIG_DEBUG_INFO_BTF=y, which is the default in most distributions"
+		return c
+	}
+	r.btf = true
+	c.Status, c.Details = StatusPass, "/sys/kernel/btf/vmlinux"
+	return c
+}
+
+func checkCapabilities() Check {
+	c := Check{Name: "Capabilities"}
+	effective, err := effectiveCapabilities()
+	if err != nil {
+		c.Status, c.Details = StatusWarn, "can't read capabilities: "+err.Error()
+		return c
+	}
+	has := func(capability int) bool { return effective&(1<<capability) != 0 }
+	var missing []string
+	// CAP_SYS_ADMIN is required to mount the BPF filesystem, and to load eBPF programs
+	// in kernels that don't support CAP_BPF
+	if !has(unix.CAP_SYS_ADMIN) {
+		missing = append(missing, "CAP_SYS_ADMIN")
+	}
+	if len(missing) > 0 {
+		c.Status, c.Details = StatusFail, "missing "+strings.Join(missing, ", ")
+		c.Hint = "run Beyla as root, or grant it the missing capabilities (e.g. privileged: true or capabilities.add in Kubernetes)"
+		return c
+	}
+	// CAP_SYS_PTRACE is required to inspect the executables of processes owned by other users
+	if !has(unix.CAP_SYS_PTRACE) {
+		c.Status, c.Details = StatusWarn, "missing CAP_SYS_PTRACE"
+		c.Hint = "grant the CAP_SYS_PTRACE capability to instrument processes that are owned by other users"
+		return c
+	}
+	c.Status, c.Details = StatusPass, "CAP_SYS_ADMIN, CAP_SYS_PTRACE"
+	return c
+}
+
+// effectiveCapabilities reads the effective capabilities bitmask of the current process
+func effectiveCapabilities() (uint64, error) {
+	status, err := os.Open("/proc/self/status")
+	if err != nil {
+		return 0, err
+	}
+	defer status.Close()
+	scanner := bufio.NewScanner(status)
+	for scanner.Scan() {
+		if hex, ok := strings.CutPrefix(scanner.Text(), "CapEff:"); ok {
+			return strconv.ParseUint(strings.TrimSpace(hex), 16, 64)
+		}
+	}
+	return 0, errors.New("CapEff not found in /proc/self/status")
+}
+
+func checkBpfFS(bpfBaseDir string) Check {
+	c := Check{Name: "BPF filesystem"}
+	filesystems, err := os.ReadFile("/proc/filesystems")
+	if err != nil {
+		c.Status, c.Details = StatusWarn, "can't read supported filesystems: "+err.Error()
+		return c
+	}
+	if !slices.Contains(strings.Fields(string(filesystems)), "bpf") {
+		c.Status, c.Details = StatusFail, "the bpf filesystem is not supported by the kernel"
+		c.Hint = "use a kernel compiled with CONFIG_BPF_SYSCALL=y"
+		return c
+	}
+	// the base dir will be created if it does not exist, so we check the nearest existing parent
+	dir := bpfBaseDir
+	for {
+		if _, err := os.Stat(dir); err == nil || dir == filepath.Dir(dir) {
+			break
+		}
+		dir = filepath.Dir(dir)
+	}
+	if err := unix.Access(dir, unix.W_OK); err != nil {
+		c.Status, c.Details = StatusFail, fmt.Sprintf("%s is not writable: %s", dir, err)
+		c.Hint = "set the BPF_FS_BASE_DIR property to a writable directory"
+		return c
+	}
+	c.Status, c.Details = StatusPass, "maps are pinned under "+bpfBaseDir
+	return c
+}
+
+func checkLockdown() Check {
+	c := Check{Name: "Kernel lockdown"}
+	content, err := os.ReadFile("/sys/kernel/security/lockdown")
+	if err != nil {
+		c.Status, c.Details = StatusPass, "lockdown not available"
+		return c
+	}
+	// the active mode is enclosed in brackets. E.g.: none [integrity] confidentiality
+	mode := "none"
+	for _, m := range strings.Fields(string(content)) {
+		if strings.HasPrefix(m, "[") {
+			mode = strings.Trim(m, "[]")
+		}
+	}
+	c.Details = mode
+	if mode == "confidentiality" {
+		c.Status = StatusFail
+		c.Hint = "the confidentiality lockdown mode forbids reading kernel memory from eBPF programs. " +
+			"Boot with lockdown=integrity or lockdown=none"
+		return c
+	}
+	c.Status = StatusPass
+	return c
+}
+
+func (r *Report) checkRingBuf() Check {
+	c := Check{Name: "Ring buffer maps"}
+	rb, err := cebpf.NewMap(&cebpf.MapSpec{Type: cebpf.RingBuf, MaxEntries: uint32(os.Getpagesize())})
+	if err != nil {
+		c.Status, c.Details = StatusFail, err.Error()
+		c.Hint = privilegesHint(err, fmt.Sprintf("ring buffers require Linux %d.%d or newer", minKernelMajor, minKernelMinor))
+		return c
+	}
+	_ = rb.Close()
+	r.ringBuf = true
+	c.Status = StatusPass
+	return c
+}
+
+// loadKprobeProgram loads a minimal kprobe program, which is used to check that kprobes
+// and uprobes can be attached
+func loadKprobeProgram() (*cebpf.Program, Check) {
+	c := Check{Name: "Kprobe programs"}
+	prog, err := cebpf.NewProgram(&cebpf.ProgramSpec{
+		Type:    cebpf.Kprobe,
+		License: "GPL",
+		Instructions: asm.Instructions{
+			asm.LoadImm(asm.R0, 0, asm.DWord),
+			asm.Return(),
+		},
+	})
+	if err != nil {
+		c.Status, c.Details = StatusFail, err.Error()
+		c.Hint = privilegesHint(err, "use a kernel compiled with CONFIG_BPF_EVENTS=y")
+		return nil, c
+	}
+	c.Status = StatusPass
+	return prog, c
+}
+
+// checkProbeSupport checks that kprobes or uprobes can be attached, either through the
+// perf PMU or the tracefs interface
+func (r *Report) checkProbeSupport(prog *cebpf.Program, kind string) Check {
+	c := Check{Name: strings.ToUpper(kind[:1]) + kind[1:] + "s"}
+	if prog == nil {
+		c.Status, c.Details = StatusFail, "can't load kprobe programs"
+		return c
+	}
+	supported := false
+	if _, err := os.Stat(filepath.Join("/sys/bus/event_source/devices", kind)); err == nil {
+		supported, c.Details = true, "perf PMU"
+	} else {
+		for _, dir := range tracefsDirs {
+			if _, err := os.Stat(filepath.Join(dir, kind+"_events")); err == nil {
+				supported, c.Details = true, "tracefs"
+				break
+			}
+		}
+	}
+	if !supported {
+		c.Status, c.Details = StatusFail, kind+"s are not supported by the kernel"
+		c.Hint = fmt.Sprintf("use a kernel compiled with CONFIG_%s_EVENTS=y, or mount tracefs in /sys/kernel/tracing",
+			strings.ToUpper(kind))
+		return c
+	}
+	if kind == "kprobe" {
+		r.kprobes = true
+	} else {
+		r.uprobes = true
+	}
+	c.Status = StatusPass
+	return c
+}
+
+// checkKprobes attaches, for a while, a kprobe to each kernel function that is required by the tracers
+func (r *Report) checkKprobes(prog *cebpf.Program, tracers []ebpf.Tracer) {
+	var functions []string
+	for _, t := range tracers {
+		for fn := range t.KProbes() {
+			if !slices.Contains(functions, fn) {
+				functions = append(functions, fn)
+			}
+		}
+	}
+	slices.Sort(functions)
+	for _, fn := range functions {
+		c := Check{Name: "Kprobe " + fn}
+		if prog == nil || !r.kprobes {
+			c.Status, c.Details = StatusFail, "kprobes are not supported"
+			r.failedKprobes[fn] = c.Details
+			r.add(c)
+			continue
+		}
+		// the tracers are not loaded, so we can't know whether they use a kprobe or
+		// a kretprobe. Anyway, both can be attached to the same functions.
+		l, err := link.Kprobe(fn, prog, nil)
+		if err != nil {
+			c.Status, c.Details = StatusFail, err.Error()
+			c.Hint = "the kernel function might not exist in this kernel version, or be inlined or blacklisted for probing"
+			r.failedKprobes[fn] = err.Error()
+		} else {
+			_ = l.Close()
+			c.Status = StatusPass
+		}
+		r.add(c)
+	}
+}
+
+func privilegesHint(err error, otherwise string) string {
+	if errors.Is(err, unix.EPERM) {
+		return "run Beyla as root or with the CAP_SYS_ADMIN capability"
+	}
+	return otherwise
+}
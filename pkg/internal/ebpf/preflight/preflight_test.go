@@ -0,0 +1,106 @@
+package preflight
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	cebpf "github.com/cilium/ebpf"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/grafana/beyla/pkg/internal/ebpf"
+	ebpfcommon "github.com/grafana/beyla/pkg/internal/ebpf/common"
+	"github.com/grafana/beyla/pkg/internal/exec"
+	"github.com/grafana/beyla/pkg/internal/goexec"
+	"github.com/grafana/beyla/pkg/internal/request"
+	"github.com/grafana/beyla/pkg/internal/svc"
+)
+
+func TestSupports(t *testing.T) {
+	goTracer := &fakeTracer{goProbes: map[string]ebpfcommon.FunctionPrograms{"net/http.HandlerFunc.ServeHTTP": {}}}
+	kprobeTracer := &fakeTracer{kProbes: map[string]ebpfcommon.FunctionPrograms{
+		"tcp_rcv_established": {Required: true},
+		"optional_function":   {},
+	}}
+	all := []ebpf.Tracer{goTracer, kprobeTracer}
+
+	full := &Report{btf: true, ringBuf: true, kprobes: true, uprobes: true}
+	assert.Equal(t, all, full.Filter(all))
+
+	// a nil report supports everything
+	var nilReport *Report
+	assert.Equal(t, all, nilReport.Filter(all))
+
+	noUprobes := &Report{btf: true, ringBuf: true, kprobes: true}
+	assert.Equal(t, []ebpf.Tracer{kprobeTracer}, noUprobes.Filter(all))
+
+	noKprobes := &Report{btf: true, ringBuf: true, uprobes: true}
+	assert.Equal(t, []ebpf.Tracer{goTracer}, noKprobes.Filter(all))
+
+	failedRequired := &Report{btf: true, ringBuf: true, kprobes: true, uprobes: true,
+		failedKprobes: map[string]string{"tcp_rcv_established": "not found"}}
+	ok, reason := failedRequired.Supports(kprobeTracer)
+	assert.False(t, ok)
+	assert.Contains(t, reason, "tcp_rcv_established")
+
+	// optional kprobes don't disable the tracer
+	failedOptional := &Report{btf: true, ringBuf: true, kprobes: true, uprobes: true,
+		failedKprobes: map[string]string{"optional_function": "not found"}}
+	assert.Equal(t, all, failedOptional.Filter(all))
+
+	// failed kprobes are ignored if the functions can be instrumented by other means
+	alternativesTracer := &fakeTracer{
+		kProbes: map[string]ebpfcommon.FunctionPrograms{
+			"tcp_rcv_established": {Required: true},
+			"tcp_connect":         {Required: true},
+		},
+		fentryProbes: map[string]ebpfcommon.FunctionPrograms{"tcp_rcv_established": {Required: true}},
+		tracepoints: map[string]ebpfcommon.TracepointProgram{
+			"sock/inet_sock_set_state": {Required: true, Replaces: []string{"tcp_connect"}},
+		},
+	}
+	ok, _ = noKprobes.Supports(alternativesTracer)
+	assert.True(t, ok)
+
+	noRingBuf := &Report{btf: true, kprobes: true, uprobes: true}
+	assert.Empty(t, noRingBuf.Filter(all))
+
+	noBTF := &Report{ringBuf: true, kprobes: true, uprobes: true}
+	assert.Empty(t, noBTF.Filter(all))
+}
+
+func TestFailed(t *testing.T) {
+	r := &Report{}
+	r.add(Check{Name: "foo", Status: StatusPass})
+	r.add(Check{Name: "bar", Status: StatusWarn})
+	assert.False(t, r.Failed())
+	r.add(Check{Name: "baz", Status: StatusFail})
+	assert.True(t, r.Failed())
+}
+
+type fakeTracer struct {
+	goProbes     map[string]ebpfcommon.FunctionPrograms
+	kProbes      map[string]ebpfcommon.FunctionPrograms
+	fentryProbes map[string]ebpfcommon.FunctionPrograms
+	tracepoints  map[string]ebpfcommon.TracepointProgram
+}
+
+func (f *fakeTracer) Load() (*cebpf.CollectionSpec, error)                     { return nil, nil }
+func (f *fakeTracer) Constants(*exec.FileInfo, *goexec.Offsets) map[string]any { return nil }
+func (f *fakeTracer) BpfObjects() any                                          { return nil }
+func (f *fakeTracer) GoProbes() map[string]ebpfcommon.FunctionPrograms         { return f.goProbes }
+func (f *fakeTracer) KProbes() map[string]ebpfcommon.FunctionPrograms          { return f.kProbes }
+func (f *fakeTracer) FentryProbes() map[string]ebpfcommon.FunctionPrograms     { return f.fentryProbes }
+func (f *fakeTracer) Tracepoints() map[string]ebpfcommon.TracepointProgram     { return f.tracepoints }
+func (f *fakeTracer) UProbes() map[string]map[string]ebpfcommon.FunctionPrograms {
+	return nil
+}
+func (f *fakeTracer) USDTProbes() map[string]map[string]ebpfcommon.USDTProgram {
+	return nil
+}
+func (f *fakeTracer) SetupMaps() error                                   { return nil }
+func (f *fakeTracer) SocketFilters() []*cebpf.Program                    { return nil }
+func (f *fakeTracer) PinMaps(string) error                               { return nil }
+func (f *fakeTracer) LoadPinnedMaps(string) error                        { return nil }
+func (f *fakeTracer) Run(context.Context, chan<- []request.Span, svc.ID) {}
+func (f *fakeTracer) AddCloser(...io.Closer)                             {}
@@ -0,0 +1,281 @@
+// Package tcpstats provides the network metrics tracer, which aggregates in the kernel
+// the metrics of the TCP connections that are accepted or started by the instrumented
+// services, by source and destination.
+package tcpstats
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"math"
+	"net"
+	"time"
+
+	"github.com/cilium/ebpf"
+	"github.com/gavv/monotime"
+
+	ebpfcommon "github.com/grafana/beyla/pkg/internal/ebpf/common"
+	"github.com/grafana/beyla/pkg/internal/exec"
+	"github.com/grafana/beyla/pkg/internal/goexec"
+	"github.com/grafana/beyla/pkg/internal/imetrics"
+	"github.com/grafana/beyla/pkg/internal/pipe"
+	"github.com/grafana/beyla/pkg/internal/request"
+	"github.com/grafana/beyla/pkg/internal/svc"
+)
+
+//go:generate $BPF2GO -cc $BPF_CLANG -cflags $BPF_CFLAGS -target amd64,arm64 bpf ../../../../bpf/tcp_stats.c -- -I../../../../bpf/headers
+//go:generate $BPF2GO -cc $BPF_CLANG -cflags $BPF_CFLAGS -target amd64,arm64 bpf_debug ../../../../bpf/tcp_stats.c -- -I../../../../bpf/headers -DBPF_DEBUG
+
+type Tracer struct {
+	Cfg        *pipe.Config
+	Metrics    imetrics.Reporter
+	bpfObjects bpfObjects
+	closers    []io.Closer
+	logger     *slog.Logger
+}
+
+func (p *Tracer) log() *slog.Logger {
+	if p.logger == nil {
+		p.logger = slog.With("component", "tcpstats.Tracer")
+	}
+	return p.logger
+}
+
+func (p *Tracer) Load() (*ebpf.CollectionSpec, error) {
+	loader := loadBpf
+	if p.Cfg.EBPF.BpfDebug {
+		loader = loadBpf_debug
+	}
+	return loader()
+}
+
+func (p *Tracer) Constants(finfo *exec.FileInfo, _ *goexec.Offsets) map[string]any {
+	buckets := p.Cfg.AggregatedBuckets()
+	m := map[string]any{
+		"conn_duration_bounds_ns":  boundsConstant(buckets.ConnectionDurationHistogram, float64(time.Second)),
+		"conn_duration_bounds_len": uint32(len(buckets.ConnectionDurationHistogram)),
+		"srtt_bounds_us":           boundsConstant(buckets.SRTTHistogram, float64(time.Second/time.Microsecond)),
+		"srtt_bounds_len":          uint32(len(buckets.SRTTHistogram)),
+	}
+	if p.Cfg.Discovery.SystemWide {
+		return m
+	}
+
+	m["current_pid"] = finfo.Pid
+
+	npid, err := ebpfcommon.FindNamespace(finfo.Pid)
+	if err != nil {
+		p.log().Warn("error while looking up namespace pid, namespace pid matching will not work", "error", err)
+	}
+
+	m["current_pid_ns_id"] = npid
+
+	return m
+}
+
+func boundsConstant(bounds []float64, multiplier float64) [ebpfcommon.MaxAggregatedHistogramBounds]uint64 {
+	var c [ebpfcommon.MaxAggregatedHistogramBounds]uint64
+	for i := 0; i < len(bounds) && i < len(c); i++ {
+		c[i] = uint64(math.Max(0, bounds[i]*multiplier))
+	}
+	return c
+}
+
+func (p *Tracer) BpfObjects() any {
+	return &p.bpfObjects
+}
+
+func (p *Tracer) AddCloser(c ...io.Closer) {
+	p.closers = append(p.closers, c...)
+}
+
+func (p *Tracer) GoProbes() map[string]ebpfcommon.FunctionPrograms {
+	return nil
+}
+
+func (p *Tracer) KProbes() map[string]ebpfcommon.FunctionPrograms {
+	return map[string]ebpfcommon.FunctionPrograms{
+		// the accepted connections are registered from the process context
+		"inet_csk_accept": {
+			Required: true,
+			End:      p.bpfObjects.KretprobeInetCskAccept,
+		},
+		"tcp_connect": {
+			Required: true,
+			Start:    p.bpfObjects.KprobeTcpConnect,
+		},
+		"tcp_sendmsg": {
+			Required: true,
+			Start:    p.bpfObjects.KprobeTcpSendmsg,
+		},
+		"tcp_cleanup_rbuf": {
+			Required: true,
+			Start:    p.bpfObjects.KprobeTcpCleanupRbuf,
+		},
+	}
+}
+
+func (p *Tracer) FentryProbes() map[string]ebpfcommon.FunctionPrograms {
+	return nil
+}
+
+func (p *Tracer) Tracepoints() map[string]ebpfcommon.TracepointProgram {
+	return map[string]ebpfcommon.TracepointProgram{
+		// opened, refused and closed connections
+		"sock/inet_sock_set_state": {
+			Required: true,
+			Program:  p.bpfObjects.TracepointTcpSetState,
+		},
+	}
+}
+
+func (p *Tracer) UProbes() map[string]map[string]ebpfcommon.FunctionPrograms {
+	return nil
+}
+
+func (p *Tracer) USDTProbes() map[string]map[string]ebpfcommon.USDTProgram {
+	return nil
+}
+
+func (p *Tracer) SetupMaps() error {
+	return nil
+}
+
+func (p *Tracer) SocketFilters() []*ebpf.Program {
+	return nil
+}
+
+func (p *Tracer) PinMaps(dir string) error {
+	return ebpfcommon.PinMaps(dir, &p.bpfObjects.bpfMaps)
+}
+
+func (p *Tracer) LoadPinnedMaps(dir string) error {
+	return ebpfcommon.LoadPinnedMaps(dir, &p.bpfObjects.bpfMaps)
+}
+
+// Run periodically reads and resets the metrics that are aggregated in the kernel, and
+// forwards them as TCP server and client spans
+func (p *Tracer) Run(ctx context.Context, eventsChan chan<- []request.Span, service svc.ID) {
+	defer p.close()
+	ticker := time.NewTicker(p.Cfg.EBPF.AggregationInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			spans, err := p.readMetrics(service)
+			if err != nil {
+				p.log().Error("reading TCP metrics", "error", err)
+			}
+			if len(spans) > 0 {
+				p.Metrics.TracerFlush(len(spans))
+				eventsChan <- spans
+			}
+		}
+	}
+}
+
+func (p *Tracer) close() {
+	p.log().Debug("closing eBPF resources")
+	for _, c := range p.closers {
+		_ = c.Close()
+	}
+	_ = p.bpfObjects.Close()
+}
+
+func (p *Tracer) readMetrics(service svc.ID) ([]request.Span, error) {
+	// the entries can't be safely deleted while iterating the map
+	var keys []bpfTcpMetricsKeyT
+	var key bpfTcpMetricsKeyT
+	var perCPU []bpfTcpMetricsValueT
+	iter := p.bpfObjects.TcpMetrics.Iterate()
+	for iter.Next(&key, &perCPU) {
+		keys = append(keys, key)
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	buckets := p.Cfg.AggregatedBuckets()
+	now := int64(monotime.Now())
+	spans := make([]request.Span, 0, len(keys))
+	for i := range keys {
+		if err := p.lookupAndDeleteMetrics(&keys[i], &perCPU); err != nil {
+			if !errors.Is(err, ebpf.ErrKeyNotExist) {
+				return spans, err
+			}
+			continue
+		}
+		span := toSpan(&keys[i], sumMetrics(perCPU, buckets.ConnectionDurationHistogram, buckets.SRTTHistogram))
+		span.RequestStart, span.Start, span.End = now, now, now
+		span.ServiceID = service
+		if p.Cfg.Discovery.SystemWide {
+			span.ServiceID = svc.ID{Name: ebpfcommon.ProcessName(keys[i].Pid), ProcPID: int32(keys[i].Pid)}
+		}
+		spans = append(spans, span)
+	}
+	return spans, nil
+}
+
+// lookupAndDeleteMetrics atomically reads and removes the entry, if the kernel supports it
+func (p *Tracer) lookupAndDeleteMetrics(key *bpfTcpMetricsKeyT, perCPU *[]bpfTcpMetricsValueT) error {
+	err := p.bpfObjects.TcpMetrics.LookupAndDelete(key, perCPU)
+	if err == nil || !errors.Is(err, ebpf.ErrNotSupported) {
+		return err
+	}
+	// older kernels: the metrics that are aggregated between both calls are lost
+	if err := p.bpfObjects.TcpMetrics.Lookup(key, perCPU); err != nil {
+		return err
+	}
+	return p.bpfObjects.TcpMetrics.Delete(key)
+}
+
+func toSpan(key *bpfTcpMetricsKeyT, metrics *request.NetworkMetrics) request.Span {
+	span := request.Span{
+		Type:     request.EventTypeTCPServer,
+		Peer:     net.IP(key.SrcAddr[:]).String(),
+		Host:     net.IP(key.DstAddr[:]).String(),
+		HostPort: int(key.DstPort),
+		Network:  metrics,
+	}
+	if key.Client != 0 {
+		span.Type = request.EventTypeTCPClient
+	}
+	return span
+}
+
+// sumMetrics sums the metrics of each CPU
+func sumMetrics(perCPU []bpfTcpMetricsValueT, durationBounds, srttBounds []float64) *request.NetworkMetrics {
+	metrics := &request.NetworkMetrics{
+		Duration: request.Histogram{
+			Bounds: durationBounds,
+			Counts: make([]uint64, len(durationBounds)+1),
+		},
+		SRTT: request.Histogram{
+			Bounds: srttBounds,
+			Counts: make([]uint64, len(srttBounds)+1),
+		},
+	}
+	var durationSumNs, srttSumUs uint64
+	for i := range perCPU {
+		v := &perCPU[i]
+		metrics.BytesSent += v.BytesSent
+		metrics.BytesReceived += v.BytesReceived
+		metrics.Opened += v.Opened
+		metrics.Closed += v.Closed
+		metrics.Refused += v.Refused
+		metrics.Retransmits += v.Retransmits
+		durationSumNs += v.DurationSumNs
+		srttSumUs += v.SrttSumUs
+		for b := range metrics.Duration.Counts {
+			metrics.Duration.Counts[b] += v.DurationBuckets[b]
+		}
+		for b := range metrics.SRTT.Counts {
+			metrics.SRTT.Counts[b] += v.SrttBuckets[b]
+		}
+	}
+	metrics.Duration.Sum = float64(durationSumNs) / float64(time.Second)
+	metrics.SRTT.Sum = float64(srttSumUs) / float64(time.Second/time.Microsecond)
+	return metrics
+}
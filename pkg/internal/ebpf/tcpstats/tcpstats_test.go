@@ -0,0 +1,56 @@
+package tcpstats
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/grafana/beyla/pkg/internal/request"
+)
+
+func TestSumMetrics(t *testing.T) {
+	cpu0 := bpfTcpMetricsValueT{BytesSent: 100, BytesReceived: 2000, Opened: 2, Closed: 1,
+		Retransmits: 3, DurationSumNs: 1_500_000_000, SrttSumUs: 250}
+	cpu0.DurationBuckets[1] = 1
+	cpu0.SrttBuckets[0] = 1
+	cpu1 := bpfTcpMetricsValueT{BytesSent: 10, Closed: 2, Refused: 1, DurationSumNs: 500_000_000, SrttSumUs: 1750}
+	cpu1.DurationBuckets[0] = 2
+	cpu1.SrttBuckets[1] = 2
+
+	assert.Equal(t, &request.NetworkMetrics{
+		BytesSent:     110,
+		BytesReceived: 2000,
+		Opened:        2,
+		Closed:        3,
+		Refused:       1,
+		Retransmits:   3,
+		Duration: request.Histogram{
+			Bounds: []float64{1},
+			Counts: []uint64{2, 1},
+			Sum:    2,
+		},
+		SRTT: request.Histogram{
+			Bounds: []float64{0.0005, 0.01},
+			Counts: []uint64{1, 2, 0},
+			Sum:    0.002,
+		},
+	}, sumMetrics([]bpfTcpMetricsValueT{cpu0, cpu1}, []float64{1}, []float64{0.0005, 0.01}))
+}
+
+func TestToSpan(t *testing.T) {
+	key := bpfTcpMetricsKeyT{DstPort: 8080}
+	copy(key.SrcAddr[:], net.ParseIP("10.0.0.1").To16())
+	copy(key.DstAddr[:], net.ParseIP("10.0.0.2").To16())
+	metrics := &request.NetworkMetrics{Opened: 1}
+
+	span := toSpan(&key, metrics)
+	assert.Equal(t, request.EventTypeTCPServer, span.Type)
+	assert.Equal(t, "10.0.0.1", span.Peer)
+	assert.Equal(t, "10.0.0.2", span.Host)
+	assert.Equal(t, 8080, span.HostPort)
+	assert.Same(t, metrics, span.Network)
+
+	key.Client = 1
+	assert.Equal(t, request.EventTypeTCPClient, toSpan(&key, metrics).Type)
+}
@@ -0,0 +1,53 @@
+// USDT probes, with the same .note.stapsdt layout as the ones from <sys/sdt.h>, to test
+// the USDT probes support. Build it with:
+//   gcc -O1 -o usdt_x86_64 usdt.c
+// The arguments of each probe are: a signed int in a register, an unsigned long in memory
+// and a constant. The query probe is inlined, so it is defined in two locations.
+
+#define USDT_NOTE(provider, name, semaphore, args)                           \
+    "990: nop\n"                                                             \
+    ".pushsection .note.stapsdt,\"?\",\"note\"\n"                            \
+    ".balign 4\n"                                                            \
+    ".4byte 992f-991f, 994f-993f, 3\n"                                       \
+    "991: .asciz \"stapsdt\"\n"                                              \
+    "992: .balign 4\n"                                                       \
+    "993: .8byte 990b\n"                                                     \
+    ".8byte _.stapsdt.base\n"                                                \
+    ".8byte " semaphore "\n"                                                 \
+    ".asciz \"" provider "\"\n"                                              \
+    ".asciz \"" name "\"\n"                                                  \
+    ".asciz \"" args "\"\n"                                                  \
+    "994: .balign 4\n"                                                       \
+    ".popsection\n"                                                          \
+    ".ifndef _.stapsdt.base\n"                                               \
+    ".pushsection .stapsdt.base,\"aG\",\"progbits\",.stapsdt.base,comdat\n" \
+    ".weak _.stapsdt.base\n"                                                 \
+    ".hidden _.stapsdt.base\n"                                               \
+    "_.stapsdt.base: .space 1\n"                                             \
+    ".size _.stapsdt.base, 1\n"                                              \
+    ".popsection\n"                                                          \
+    ".endif\n"
+
+unsigned short beyla_request_semaphore __attribute__((section(".probes"))) = 0;
+
+volatile unsigned long counter = 42;
+
+__attribute__((noinline)) void request(int status) {
+    unsigned long size = counter;
+    __asm__ __volatile__(USDT_NOTE("beyla", "request", "beyla_request_semaphore",
+                                   "-4@%[status] 8@%[size] 4@%[code]")
+                         :: [status] "r"(status), [size] "m"(size), [code] "i"(5));
+}
+
+static inline __attribute__((always_inline)) void query(void) {
+    __asm__ __volatile__(USDT_NOTE("beyla", "query", "0", ""));
+}
+
+int main(int argc, char **argv) {
+    if (beyla_request_semaphore) {
+        request(argc);
+    }
+    query();
+    query();
+    return 0;
+}
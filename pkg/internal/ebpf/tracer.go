@@ -2,8 +2,10 @@ package ebpf
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"log/slog"
+	"strings"
 
 	"github.com/cilium/ebpf"
 	"github.com/cilium/ebpf/link"
@@ -31,12 +33,33 @@ type Tracer interface {
 	// KProbes returns a map with the name of the kernel probes that need to be
 	// tapped into. Start matches kprobe, End matches kretprobe
 	KProbes() map[string]ebpfcommon.FunctionPrograms
+	// FentryProbes returns a map with the name of the kernel functions that can be tapped
+	// with fentry (Start) and fexit (End) programs. They are preferred over the kprobes of
+	// the same functions, which are only attached if the kernel does not support fentry.
+	FentryProbes() map[string]ebpfcommon.FunctionPrograms
+	// Tracepoints returns a map with the tracepoints to attach, in the form "group/name"
+	// (e.g. "syscalls/sys_exit_accept4"). If a tracepoint is attached, the kprobes of the
+	// kernel functions that it replaces are not attached.
+	Tracepoints() map[string]ebpfcommon.TracepointProgram
 	// UProbes returns a map with the module name mapping to the uprobes that need to be
 	// tapped into. Start matches uprobe, End matches uretprobe
 	UProbes() map[string]map[string]ebpfcommon.FunctionPrograms
+	// USDTProbes returns a map with the module name mapping to the USDT probes that need to be
+	// tapped into, in the form "provider:name" (e.g. "node:http__server__request"). If the module
+	// is not loaded by the process, the probes are looked up in the executable.
+	USDTProbes() map[string]map[string]ebpfcommon.USDTProgram
+	// SetupMaps populates the eBPF maps whose contents depend on the configuration. It is
+	// invoked once the eBPF programs and maps are loaded.
+	SetupMaps() error
 	// SocketFilters  returns a list of programs that need to be loaded as a
 	// generic eBPF socket filter
 	SocketFilters() []*ebpf.Program
+	// PinMaps pins the loaded eBPF maps of the tracer into the provided directory, so
+	// they can be read by another process (e.g. an unprivileged Beyla processor)
+	PinMaps(dir string) error
+	// LoadPinnedMaps loads the maps that were pinned by PinMaps in another process. It allows
+	// invoking Run without loading the eBPF programs, which requires extra privileges.
+	LoadPinnedMaps(dir string) error
 	// Run will do the action of listening for eBPF traces and forward them
 	// periodically to the output channel.
 	// It optionally receives the service svc.ID, to
@@ -60,4 +83,24 @@ type ProcessTracer struct {
 	PinPath  string
 
 	SystemWide bool
+	// EBPFConfig provides the ring buffer settings that are common to all the tracers
+	EBPFConfig *ebpfcommon.TracerConfig
+}
+
+// PinnedProcess describes a process that has been instrumented by a privileged loader,
+// which pinned the maps of its tracers so their events can be read and forwarded by
+// an unprivileged processor.
+type PinnedProcess struct {
+	Service    svc.ID `json:"service"`
+	SystemWide bool   `json:"system_wide,omitempty"`
+	// PinPath is the BPF filesystem mount point of the instrumented process
+	PinPath string `json:"pin_path"`
+	// Tracers maps the name of each tracer (as returned by TracerName) to the directory
+	// where its maps are pinned
+	Tracers map[string]string `json:"tracers"`
+}
+
+// TracerName returns the type name of the tracer without pointer prefix (e.g. nethttp.Tracer)
+func TracerName(t Tracer) string {
+	return strings.TrimPrefix(fmt.Sprintf("%T", t), "*")
 }
@@ -2,6 +2,7 @@ package ebpf
 
 import (
 	"context"
+	"errors"
 
 	"github.com/grafana/beyla/pkg/internal/request"
 )
@@ -9,3 +10,7 @@ import (
 // dummy implementations to avoid compilation errors in Darwin.
 // The tracer component is only usable in Linux.
 func (pt *ProcessTracer) Run(_ context.Context, _ chan<- []request.Span) {}
+
+func (pt *ProcessTracer) Pin(_ context.Context) (*PinnedProcess, error) {
+	return nil, errors.New("eBPF tracers are only supported on Linux")
+}
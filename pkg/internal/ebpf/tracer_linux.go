@@ -6,47 +6,94 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"path"
 	"reflect"
 	"strings"
 
 	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
 	"github.com/cilium/ebpf/rlimit"
 	"golang.org/x/sys/unix"
 
 	"github.com/grafana/beyla/pkg/internal/request"
+	"github.com/grafana/beyla/pkg/internal/svc"
 )
 
 func ptlog() *slog.Logger { return slog.With("component", "ebpf.ProcessTracer") }
 
 func (pt *ProcessTracer) Run(ctx context.Context, out chan<- []request.Span) {
+	trcrs, service, ok := pt.load()
+	if !ok {
+		return
+	}
+	// run each tracer program
+	for _, t := range trcrs {
+		go t.Run(ctx, out, service)
+	}
+	go func() {
+		<-ctx.Done()
+		pt.close()
+	}()
+}
+
+// Pin loads the eBPF programs of the process tracer and pins their maps in a subfolder
+// of the PinPath, without reading their events. The maps are unpinned when the passed
+// context is cancelled.
+func (pt *ProcessTracer) Pin(ctx context.Context) (*PinnedProcess, error) {
+	trcrs, service, ok := pt.load()
+	if !ok {
+		return nil, fmt.Errorf("can't load the tracers of process %d", pt.ELFInfo.Pid)
+	}
+	go func() {
+		<-ctx.Done()
+		pt.close()
+	}()
+	pinned := &PinnedProcess{
+		Service:    service,
+		SystemWide: pt.SystemWide,
+		PinPath:    pt.PinPath,
+		Tracers:    map[string]string{},
+	}
+	for _, t := range trcrs {
+		name := TracerName(t)
+		dir := path.Join(pt.PinPath, name)
+		if err := t.PinMaps(dir); err != nil {
+			return nil, fmt.Errorf("pinning %s maps: %w", name, err)
+		}
+		pinned.Tracers[name] = dir
+	}
+	pt.log.Debug("pinned tracers' maps", "tracers", len(pinned.Tracers))
+	return pinned, nil
+}
+
+// load the eBPF programs of the process tracer and returns the service ID that will
+// decorate the traces. If it fails, the error is logged and ok is false.
+func (pt *ProcessTracer) load() (trcrs []Tracer, service svc.ID, ok bool) {
 	if err := pt.init(); err != nil {
 		pt.log.Error("cant start process tracer. Stopping it", "error", err)
-		return
+		return nil, service, false
 	}
 	pt.log.Debug("starting process tracer")
 	// Searches for traceable functions
 	trcrs, err := pt.tracers()
 	if err != nil {
 		pt.log.Error("couldn't trace process. Stopping process tracer", "error", err)
-		return
+		return nil, service, false
 	}
 
-	service := pt.ELFInfo.Service
+	service = pt.ELFInfo.Service
 	// If the user does not override the service name via configuration
 	// the service name is the name of the found executable
 	// Unless the case of system-wide tracing, where the name of the
 	// executable will be dynamically set for each traced http request call.
-	if service.Name == "" && !pt.SystemWide {
-		service.Name = pt.ELFInfo.ExecutableName()
-	}
-	// run each tracer program
-	for _, t := range trcrs {
-		go t.Run(ctx, out, service)
+	// The same applies to the PID of the service process.
+	if !pt.SystemWide {
+		if service.Name == "" {
+			service.Name = pt.ELFInfo.ExecutableName()
+		}
+		service.ProcPID = pt.ELFInfo.Pid
 	}
-	go func() {
-		<-ctx.Done()
-		pt.close()
-	}()
+	return trcrs, service, true
 }
 
 func (pt *ProcessTracer) init() error {
@@ -92,6 +139,37 @@ func (pt *ProcessTracer) unmountBpfPinPath() {
 	}
 }
 
+// configureRingBuffer overrides the size of the events ring buffer and sets the
+// fill threshold of the adaptive sampling, as well as the wakeup timeout
+func (pt *ProcessTracer) configureRingBuffer(spec *ebpf.CollectionSpec, tracerName string) error {
+	if pt.EBPFConfig == nil {
+		return nil
+	}
+	events, ok := spec.Maps["events"]
+	if !ok {
+		// the tracer does not send its events through a ring buffer (e.g. tcpstats)
+		return nil
+	}
+	if size := pt.EBPFConfig.RingBufferSizeFor(tracerName); size > 0 {
+		events.MaxEntries = uint32(size)
+	}
+	if pt.EBPFConfig.WakeupTimeout > 0 {
+		if err := spec.RewriteConstants(map[string]any{
+			"wakeup_timeout_ns": uint64(pt.EBPFConfig.WakeupTimeout.Nanoseconds()),
+		}); err != nil {
+			return fmt.Errorf("setting the ring buffer wakeup timeout: %w", err)
+		}
+	}
+	if pt.EBPFConfig.SamplingFillThreshold > 0 {
+		if err := spec.RewriteConstants(map[string]any{
+			"sampling_fill_threshold": uint32(pt.EBPFConfig.SamplingFillThreshold),
+		}); err != nil {
+			return fmt.Errorf("setting the ring buffer sampling threshold: %w", err)
+		}
+	}
+	return nil
+}
+
 // tracers returns Tracer implementer for each discovered eBPF traceable source: GRPC, HTTP...
 func (pt *ProcessTracer) tracers() ([]Tracer, error) {
 	var log = ptlog()
@@ -109,12 +187,29 @@ func (pt *ProcessTracer) tracers() ([]Tracer, error) {
 		if err := spec.RewriteConstants(p.Constants(pt.ELFInfo, pt.Goffsets)); err != nil {
 			return nil, fmt.Errorf("rewriting BPF constants definition: %w", err)
 		}
-		if err := spec.LoadAndAssign(p.BpfObjects(), &ebpf.CollectionOptions{
+		if err := pt.configureRingBuffer(spec, TracerName(p)); err != nil {
+			return nil, err
+		}
+		opts := &ebpf.CollectionOptions{
 			Maps: ebpf.MapOptions{
 				PinPath: pt.PinPath,
-			}}); err != nil {
-			printVerifierErrorInfo(err)
-			return nil, fmt.Errorf("loading and assigning BPF objects: %w", err)
+			}}
+		if err := spec.LoadAndAssign(p.BpfObjects(), opts); err != nil {
+			// fentry/fexit programs can't be loaded in kernels without BTF or BPF trampolines,
+			// so we retry without them and the instrumenter will fall back to kprobes
+			if !fentryUnsupported(err) || !stubTracingPrograms(spec) {
+				printVerifierErrorInfo(err)
+				return nil, fmt.Errorf("loading and assigning BPF objects: %w", err)
+			}
+			plog.Info("can't load fentry/fexit programs. Falling back to kprobes", "error", err)
+			if retryErr := spec.LoadAndAssign(p.BpfObjects(), opts); retryErr != nil {
+				printVerifierErrorInfo(retryErr)
+				return nil, fmt.Errorf("loading and assigning BPF objects without fentry/fexit programs: %w",
+					errors.Join(retryErr, err))
+			}
+		}
+		if err := p.SetupMaps(); err != nil {
+			return nil, fmt.Errorf("setting up BPF maps: %w", err)
 		}
 		i := instrumenter{
 			exe:     pt.Exe,
@@ -127,8 +222,8 @@ func (pt *ProcessTracer) tracers() ([]Tracer, error) {
 			return nil, err
 		}
 
-		//Kprobes to be used for native instrumentation points
-		if err := i.kprobes(p); err != nil {
+		//Fentry, tracepoints and kprobes to be used for native instrumentation points
+		if err := i.kernelProbes(p); err != nil {
 			printVerifierErrorInfo(err)
 			return nil, err
 		}
@@ -139,6 +234,12 @@ func (pt *ProcessTracer) tracers() ([]Tracer, error) {
 			return nil, err
 		}
 
+		//USDT probes to be used for runtimes exposing static tracepoints
+		if err := i.usdtprobes(pt.ELFInfo.Pid, p); err != nil {
+			printVerifierErrorInfo(err)
+			return nil, err
+		}
+
 		//Sock filters support
 		if err := i.sockfilters(p); err != nil {
 			printVerifierErrorInfo(err)
@@ -151,6 +252,40 @@ func (pt *ProcessTracer) tracers() ([]Tracer, error) {
 	return tracers, nil
 }
 
+// enotsupp is the kernel-internal errno that is returned when loading fentry/fexit programs
+// in architectures without BPF trampolines
+const enotsupp = unix.Errno(524)
+
+// fentryUnsupported returns whether the error was caused by a kernel that can't load fentry/fexit
+// programs: because it does not provide BTF information, it does not define the traced function,
+// or it does not support BPF trampolines.
+func fentryUnsupported(err error) bool {
+	return errors.Is(err, ebpf.ErrNotSupported) || errors.Is(err, enotsupp)
+}
+
+// stubTracingPrograms replaces the fentry/fexit programs of the spec by programs that do nothing,
+// so the rest of the programs can be loaded. The stubs can't be attached as fentry/fexit, so the
+// instrumenter ignores them. It returns false if the spec does not contain any fentry/fexit program.
+func stubTracingPrograms(spec *ebpf.CollectionSpec) bool {
+	stubbed := false
+	for name, prog := range spec.Programs {
+		if prog.Type != ebpf.Tracing {
+			continue
+		}
+		spec.Programs[name] = &ebpf.ProgramSpec{
+			Name:    prog.Name,
+			Type:    ebpf.SocketFilter,
+			License: prog.License,
+			Instructions: asm.Instructions{
+				asm.LoadImm(asm.R0, 0, asm.DWord),
+				asm.Return(),
+			},
+		}
+		stubbed = true
+	}
+	return stubbed
+}
+
 func printVerifierErrorInfo(err error) {
 	var ve *ebpf.VerifierError
 	if errors.As(err, &ve) {
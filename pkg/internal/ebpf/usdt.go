@@ -0,0 +1,110 @@
+//go:build linux
+
+package ebpf
+
+import (
+	"debug/elf"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/cilium/ebpf"
+
+	"github.com/grafana/beyla/pkg/internal/exec"
+)
+
+// usdtMaxArgs must line up with the USDT_MAX_ARGS definition in bpf/usdt.h
+const usdtMaxArgs = 12
+
+// usdtArgSpec and usdtSpec must line up with the usdt_arg_spec_t and usdt_spec_t types of bpf/usdt.h
+type usdtArgSpec struct {
+	ValOff   uint64
+	RegOff   uint32
+	ArgType  uint8
+	Signed   uint8
+	Bitshift uint8
+	_        uint8
+}
+
+type usdtSpec struct {
+	Args   [usdtMaxArgs]usdtArgSpec
+	ArgCnt uint16
+	_      [6]uint8
+}
+
+// offsets of the registers in the struct pt_regs of each architecture
+var ptRegsOffsets = map[elf.Machine]map[string]uint32{
+	elf.EM_X86_64: {
+		"r15": 0, "r14": 8, "r13": 16, "r12": 24, "rbp": 32, "rbx": 40, "r11": 48, "r10": 56,
+		"r9": 64, "r8": 72, "rax": 80, "rcx": 88, "rdx": 96, "rsi": 104, "rdi": 112,
+		"rip": 128, "rsp": 152,
+	},
+	elf.EM_AARCH64: func() map[string]uint32 {
+		regs := map[string]uint32{"sp": 31 * 8}
+		for i := 0; i <= 30; i++ {
+			regs[fmt.Sprintf("x%d", i)] = uint32(i * 8)
+		}
+		return regs
+	}(),
+}
+
+// usdtSpecIDs provides the ids of the specs, which are unique across all the usdt_specs maps.
+// Zero is never used, as it's the attach cookie of the programs without spec.
+var usdtSpecIDs atomic.Uint32
+
+// newUSDTSpec returns the location of the arguments of the probe, as read by bpf/usdt.h
+func newUSDTSpec(probe *exec.USDTProbe) (*usdtSpec, error) {
+	args, err := probe.ParseArgs()
+	if err != nil {
+		return nil, err
+	}
+	if len(args) > usdtMaxArgs {
+		return nil, fmt.Errorf("probe %s:%s has %d arguments. Only %d are supported",
+			probe.Provider, probe.Name, len(args), usdtMaxArgs)
+	}
+	regs, ok := ptRegsOffsets[probe.Machine]
+	if !ok {
+		return nil, fmt.Errorf("unsupported architecture %s", probe.Machine)
+	}
+	spec := &usdtSpec{ArgCnt: uint16(len(args))}
+	for n, arg := range args {
+		as := &spec.Args[n]
+		as.ArgType = uint8(arg.Kind)
+		as.ValOff = uint64(arg.Value)
+		as.Bitshift = uint8(64 - arg.Size*8)
+		if arg.Signed {
+			as.Signed = 1
+		}
+		if arg.Kind != exec.USDTArgConst {
+			if as.RegOff, ok = regs[arg.Register]; !ok {
+				return nil, fmt.Errorf("probe %s:%s: unsupported register %q",
+					probe.Provider, probe.Name, arg.Register)
+			}
+		}
+	}
+	return spec, nil
+}
+
+// usdtSpecEntry removes the spec of a probe from the usdt_specs map when the probe is detached
+type usdtSpecEntry struct {
+	specs *ebpf.Map
+	id    uint32
+}
+
+func (e *usdtSpecEntry) Close() error {
+	return e.specs.Delete(e.id)
+}
+
+// storeUSDTSpec stores the location of the arguments of the probe in the usdt_specs map,
+// and returns the id of the spec, to be used as attach cookie of the program.
+func (i *instrumenter) storeUSDTSpec(specs *ebpf.Map, probe *exec.USDTProbe) (uint32, error) {
+	spec, err := newUSDTSpec(probe)
+	if err != nil {
+		return 0, err
+	}
+	id := usdtSpecIDs.Add(1)
+	if err := specs.Put(id, spec); err != nil {
+		return 0, fmt.Errorf("storing USDT spec: %w", err)
+	}
+	i.closables = append(i.closables, &usdtSpecEntry{specs: specs, id: id})
+	return id, nil
+}
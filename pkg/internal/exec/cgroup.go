@@ -0,0 +1,62 @@
+package exec
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/prometheus/procfs"
+)
+
+const systemdServiceSuffix = ".service"
+
+// CgroupPath returns the path of the control group of the process with the given PID.
+// For cgroups v1, it returns the path in the systemd hierarchy, which is the one that
+// reflects the systemd units.
+func CgroupPath(pid int32) (string, error) {
+	proc, err := procfs.NewProc(int(pid))
+	if err != nil {
+		return "", err
+	}
+	cgroups, err := proc.Cgroups()
+	if err != nil {
+		return "", err
+	}
+	return cgroupPath(cgroups), nil
+}
+
+func cgroupPath(cgroups []procfs.Cgroup) string {
+	for _, cg := range cgroups {
+		// cgroups v2 unified hierarchy
+		if cg.HierarchyID == 0 {
+			return cg.Path
+		}
+	}
+	for _, cg := range cgroups {
+		if slices.Contains(cg.Controllers, "name=systemd") {
+			return cg.Path
+		}
+	}
+	if len(cgroups) > 0 {
+		return cgroups[0].Path
+	}
+	return ""
+}
+
+// SystemdUnit returns the name of the systemd service unit from a control group path
+// (e.g. /system.slice/billing-api.service -> billing-api.service), or an empty string
+// if the control group does not belong to a service unit.
+func SystemdUnit(cgroupPath string) string {
+	folders := strings.Split(cgroupPath, "/")
+	// looking from the deepest folder, as services might have nested control groups
+	for i := len(folders) - 1; i >= 0; i-- {
+		if strings.HasSuffix(folders[i], systemdServiceSuffix) {
+			return folders[i]
+		}
+	}
+	return ""
+}
+
+// SystemdServiceName returns the name of a systemd service unit without the .service suffix
+func SystemdServiceName(unit string) string {
+	return strings.TrimSuffix(unit, systemdServiceSuffix)
+}
@@ -0,0 +1,31 @@
+package exec
+
+import (
+	"testing"
+
+	"github.com/prometheus/procfs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCgroupPath(t *testing.T) {
+	// cgroups v2
+	assert.Equal(t, "/system.slice/billing-api.service", cgroupPath([]procfs.Cgroup{
+		{HierarchyID: 0, Path: "/system.slice/billing-api.service"},
+	}))
+	// cgroups v1
+	assert.Equal(t, "/system.slice/billing-api.service", cgroupPath([]procfs.Cgroup{
+		{HierarchyID: 12, Controllers: []string{"cpu", "cpuacct"}, Path: "/"},
+		{HierarchyID: 1, Controllers: []string{"name=systemd"}, Path: "/system.slice/billing-api.service"},
+	}))
+	assert.Empty(t, cgroupPath(nil))
+}
+
+func TestSystemdUnit(t *testing.T) {
+	assert.Equal(t, "billing-api.service", SystemdUnit("/system.slice/billing-api.service"))
+	assert.Equal(t, "getty@tty1.service", SystemdUnit("/system.slice/system-getty.slice/getty@tty1.service"))
+	assert.Equal(t, "worker.service", SystemdUnit("/system.slice/worker.service/payload"))
+	assert.Empty(t, SystemdUnit("/user.slice/user-1000.slice/session-2.scope"))
+	assert.Empty(t, SystemdUnit("/"))
+
+	assert.Equal(t, "billing-api", SystemdServiceName("billing-api.service"))
+}
@@ -0,0 +1,42 @@
+package exec
+
+import (
+	"errors"
+	"regexp"
+
+	"github.com/prometheus/procfs"
+)
+
+// ErrNoContainer is returned when a process does not run inside a container
+var ErrNoContainer = errors.New("process is not running in a container")
+
+// container runtimes name the cgroups after the container ID, with different
+// prefixes and suffixes, e.g.:
+// /kubepods/besteffort/pod<uid>/<id>
+// /system.slice/docker-<id>.scope
+// /kubepods.slice/kubepods-burstable.slice/cri-containerd-<id>.scope
+var containerIDRegexp = regexp.MustCompile(`[0-9a-f]{64}`)
+
+// ContainerID returns the ID of the container where the process with the given PID
+// is running, as read from its /proc/<pid>/cgroup file.
+func ContainerID(pid int32) (string, error) {
+	proc, err := procfs.NewProc(int(pid))
+	if err != nil {
+		return "", err
+	}
+	cgroups, err := proc.Cgroups()
+	if err != nil {
+		return "", err
+	}
+	return containerIDFromCgroups(cgroups)
+}
+
+func containerIDFromCgroups(cgroups []procfs.Cgroup) (string, error) {
+	for _, cg := range cgroups {
+		if ids := containerIDRegexp.FindAllString(cg.Path, -1); len(ids) > 0 {
+			// nested containers would have many IDs in the path. We take the innermost
+			return ids[len(ids)-1], nil
+		}
+	}
+	return "", ErrNoContainer
+}
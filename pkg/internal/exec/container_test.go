@@ -0,0 +1,42 @@
+package exec
+
+import (
+	"testing"
+
+	"github.com/prometheus/procfs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	containerID1 = "40c03570b6f4c30bc8d69923d37ee698f5cfcced92c7b7df1c47f6f7887378a9"
+	containerID2 = "2b1b5ae5f0bf8b9c0e71d0d6a8e2e2bcd4ad7d0c7e7e0f6a1ae5b1e2b26d1c3a"
+)
+
+func TestContainerIDFromCgroups(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		path string
+	}{
+		{name: "cgroups v1", path: "/kubepods/besteffort/pod7b9e2f0a-5d8c-4cd1-9d3e-bf1a3b2c4d5e/" + containerID1},
+		{name: "docker", path: "/system.slice/docker-" + containerID1 + ".scope"},
+		{name: "containerd", path: "/kubepods.slice/kubepods-burstable.slice/cri-containerd-" + containerID1 + ".scope"},
+		{name: "nested", path: "/docker/" + containerID2 + "/kubepods/" + containerID1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			id, err := containerIDFromCgroups([]procfs.Cgroup{
+				{HierarchyID: 1, Path: "/"},
+				{HierarchyID: 0, Path: tc.path},
+			})
+			require.NoError(t, err)
+			assert.Equal(t, containerID1, id)
+		})
+	}
+}
+
+func TestContainerIDFromCgroups_NoContainer(t *testing.T) {
+	_, err := containerIDFromCgroups([]procfs.Cgroup{
+		{HierarchyID: 0, Path: "/user.slice/user-1000.slice/session-2.scope"},
+	})
+	assert.ErrorIs(t, err, ErrNoContainer)
+}
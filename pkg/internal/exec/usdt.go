@@ -0,0 +1,362 @@
+package exec
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	stapsdtSection  = ".note.stapsdt"
+	stapsdtBase     = ".stapsdt.base"
+	stapsdtNoteName = "stapsdt"
+	stapsdtNoteType = 3
+)
+
+// USDTProbe is a User Statically-Defined Tracing marker, as defined in the .note.stapsdt
+// section of an ELF file. See https://sourceware.org/systemtap/wiki/UserSpaceProbeImplementation
+type USDTProbe struct {
+	Provider string
+	Name     string
+	// Offset of the probe in the ELF file, which can be used as uprobe address
+	Offset uint64
+	// SemaphoreOffset is the offset of the probe semaphore in the ELF file, or zero if the
+	// probe does not have a semaphore. The kernel increments it while the probe is attached,
+	// so the instrumented program can skip the computation of the probe arguments otherwise.
+	SemaphoreOffset uint64
+	// Args describes the location of the probe arguments, as space-separated
+	// size@location specifiers. E.g. "-4@%edi 8@-8(%rbp)"
+	Args string
+	// Machine is the architecture of the ELF file, which defines the syntax of Args
+	Machine elf.Machine
+}
+
+// USDTArgKind is the location of a USDT probe argument. The values match the USDT_ARG_*
+// constants of bpf/usdt.h
+type USDTArgKind uint8
+
+const (
+	// USDTArgConst is a constant value
+	USDTArgConst USDTArgKind = iota
+	// USDTArgReg is the value of a register
+	USDTArgReg
+	// USDTArgRegDeref is the value in the memory address of a register, plus an offset
+	USDTArgRegDeref
+)
+
+// USDTArg describes the location of a USDT probe argument
+type USDTArg struct {
+	Kind USDTArgKind
+	// Size of the argument, in bytes: 1, 2, 4 or 8
+	Size   int
+	Signed bool
+	// Register that holds the argument, or its address, by the name of the whole 64-bit
+	// register (e.g. rdi for %edi in x86-64, or x1 for w1 in arm64)
+	Register string
+	// Value of a constant argument, or offset of the argument from the register address
+	Value int64
+}
+
+// FindUSDTProbes returns the USDT probes of the ELF file. It returns an empty slice if the
+// file does not contain any USDT probe.
+func FindUSDTProbes(f *elf.File) ([]USDTProbe, error) {
+	notes := f.Section(stapsdtSection)
+	if notes == nil {
+		return nil, nil
+	}
+	data, err := notes.Data()
+	if err != nil {
+		return nil, fmt.Errorf("reading %s section: %w", stapsdtSection, err)
+	}
+	addrSize := 8
+	if f.Class == elf.ELFCLASS32 {
+		addrSize = 4
+	}
+	// the probe addresses need to be adjusted if the file has been prelinked
+	var baseAddr uint64
+	if base := f.Section(stapsdtBase); base != nil {
+		baseAddr = base.Addr
+	}
+	notesList, err := parseStapsdtNotes(data, f.ByteOrder, addrSize)
+	if err != nil {
+		return nil, err
+	}
+	probes := make([]USDTProbe, 0, len(notesList))
+	for _, n := range notesList {
+		pc, semaphore := n.pc, n.semaphore
+		if baseAddr != 0 {
+			pc = pc + baseAddr - n.base
+			if semaphore != 0 {
+				semaphore = semaphore + baseAddr - n.base
+			}
+		}
+		probe := USDTProbe{Provider: n.provider, Name: n.name, Args: n.args, Machine: f.Machine}
+		var ok bool
+		if probe.Offset, ok = fileOffset(f, pc, true); !ok {
+			return nil, fmt.Errorf("probe %s:%s: address %#x is not in an executable segment",
+				n.provider, n.name, pc)
+		}
+		if semaphore != 0 {
+			if probe.SemaphoreOffset, ok = fileOffset(f, semaphore, false); !ok {
+				return nil, fmt.Errorf("probe %s:%s: semaphore address %#x is not in a loadable segment",
+					n.provider, n.name, semaphore)
+			}
+		}
+		probes = append(probes, probe)
+	}
+	return probes, nil
+}
+
+// ParseArgs returns the location of the probe arguments. It returns an error if the
+// syntax of any argument is not supported (e.g. x86-64 scaled index addressing).
+func (p *USDTProbe) ParseArgs() ([]USDTArg, error) {
+	specs := splitUSDTArgs(p.Args)
+	args := make([]USDTArg, 0, len(specs))
+	for _, spec := range specs {
+		arg, err := parseUSDTArg(p.Machine, spec)
+		if err != nil {
+			return nil, fmt.Errorf("probe %s:%s: argument %q: %w", p.Provider, p.Name, spec, err)
+		}
+		args = append(args, arg)
+	}
+	return args, nil
+}
+
+// splitUSDTArgs splits the space-separated argument specifiers. The arm64 memory locations
+// contain spaces between brackets (e.g. 4@[sp, 16]).
+func splitUSDTArgs(args string) []string {
+	var specs []string
+	start, brackets := -1, 0
+	for i, c := range args {
+		switch {
+		case c == '[':
+			brackets++
+		case c == ']':
+			brackets--
+		case c == ' ' && brackets == 0:
+			if start >= 0 {
+				specs = append(specs, args[start:i])
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		specs = append(specs, args[start:])
+	}
+	return specs
+}
+
+// parseUSDTArg parses a size@location argument specifier
+func parseUSDTArg(machine elf.Machine, spec string) (USDTArg, error) {
+	sizeStr, loc, ok := strings.Cut(spec, "@")
+	if !ok {
+		return USDTArg{}, errors.New("missing size")
+	}
+	size, err := strconv.Atoi(sizeStr)
+	if err != nil {
+		return USDTArg{}, fmt.Errorf("invalid size: %w", err)
+	}
+	arg := USDTArg{Size: size, Signed: size < 0}
+	if arg.Signed {
+		arg.Size = -size
+	}
+	switch arg.Size {
+	case 1, 2, 4, 8:
+	default:
+		return USDTArg{}, fmt.Errorf("invalid size %d", size)
+	}
+	switch machine {
+	case elf.EM_X86_64:
+		err = parseUSDTLocationX86(loc, &arg)
+	case elf.EM_AARCH64:
+		err = parseUSDTLocationARM64(loc, &arg)
+	default:
+		err = fmt.Errorf("unsupported architecture %s", machine)
+	}
+	return arg, err
+}
+
+// x86-64 registers, by the name of any of their parts
+var x86Registers = func() map[string]string {
+	regs := map[string]string{"rip": "rip"}
+	for _, r := range []string{"a", "b", "c", "d"} {
+		for _, name := range []string{"r" + r + "x", "e" + r + "x", r + "x", r + "l"} {
+			regs[name] = "r" + r + "x"
+		}
+	}
+	for _, r := range []string{"si", "di", "bp", "sp"} {
+		for _, name := range []string{"r" + r, "e" + r, r, r + "l"} {
+			regs[name] = "r" + r
+		}
+	}
+	for i := 8; i <= 15; i++ {
+		r := "r" + strconv.Itoa(i)
+		for _, name := range []string{r, r + "d", r + "w", r + "b"} {
+			regs[name] = r
+		}
+	}
+	return regs
+}()
+
+// parseUSDTLocationX86 parses the AT&T syntax locations: $5 (constant), %edi (register),
+// -8(%rbp) and (%rax) (memory)
+func parseUSDTLocationX86(loc string, arg *USDTArg) error {
+	var err error
+	switch {
+	case strings.HasPrefix(loc, "$"):
+		arg.Kind = USDTArgConst
+		arg.Value, err = strconv.ParseInt(loc[1:], 0, 64)
+		return err
+	case strings.HasPrefix(loc, "%"):
+		arg.Kind = USDTArgReg
+		return x86Register(loc, arg)
+	case strings.HasSuffix(loc, ")"):
+		offset, reg, ok := strings.Cut(strings.TrimSuffix(loc, ")"), "(")
+		if !ok {
+			return errors.New("unsupported location")
+		}
+		arg.Kind = USDTArgRegDeref
+		if offset != "" {
+			if arg.Value, err = strconv.ParseInt(offset, 0, 64); err != nil {
+				return err
+			}
+		}
+		return x86Register(reg, arg)
+	}
+	return errors.New("unsupported location")
+}
+
+func x86Register(name string, arg *USDTArg) error {
+	reg, ok := x86Registers[strings.TrimPrefix(name, "%")]
+	if !ok {
+		return fmt.Errorf("unsupported register %q", name)
+	}
+	arg.Register = reg
+	return nil
+}
+
+// parseUSDTLocationARM64 parses the arm64 locations: 5 (constant), x0 or w0 (register),
+// [sp, 16] and [x1] (memory)
+func parseUSDTLocationARM64(loc string, arg *USDTArg) error {
+	var err error
+	if strings.HasPrefix(loc, "[") && strings.HasSuffix(loc, "]") {
+		reg, offset, hasOffset := strings.Cut(loc[1:len(loc)-1], ",")
+		arg.Kind = USDTArgRegDeref
+		if hasOffset {
+			if arg.Value, err = strconv.ParseInt(strings.TrimSpace(offset), 0, 64); err != nil {
+				return err
+			}
+		}
+		return arm64Register(strings.TrimSpace(reg), arg)
+	}
+	if arg.Value, err = strconv.ParseInt(loc, 0, 64); err == nil {
+		arg.Kind = USDTArgConst
+		return nil
+	}
+	arg.Kind = USDTArgReg
+	return arm64Register(loc, arg)
+}
+
+func arm64Register(name string, arg *USDTArg) error {
+	if name == "sp" {
+		arg.Register = name
+		return nil
+	}
+	if len(name) > 1 && (name[0] == 'x' || name[0] == 'w') {
+		if n, err := strconv.Atoi(name[1:]); err == nil && n >= 0 && n <= 30 {
+			arg.Register = "x" + name[1:]
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported register %q", name)
+}
+
+type stapsdtNote struct {
+	pc, base, semaphore  uint64
+	provider, name, args string
+}
+
+// parseStapsdtNotes parses the contents of the .note.stapsdt section. Each note has
+// the following format, where each field is aligned to 4 bytes:
+// namesz(4) descsz(4) type(4) name("stapsdt\0") desc
+// The desc contains the pc, base and semaphore addresses, followed by the
+// null-terminated provider, name and arguments strings.
+func parseStapsdtNotes(data []byte, order binary.ByteOrder, addrSize int) ([]stapsdtNote, error) {
+	var notes []stapsdtNote
+	for len(data) > 0 {
+		if len(data) < 12 {
+			return nil, errors.New("truncated note header")
+		}
+		nameSize := int(order.Uint32(data[0:4]))
+		descSize := int(order.Uint32(data[4:8]))
+		noteType := order.Uint32(data[8:12])
+		data = data[12:]
+		nameEnd, descEnd := align4(nameSize), align4(nameSize)+align4(descSize)
+		if len(data) < nameEnd+descSize {
+			return nil, errors.New("truncated note")
+		}
+		name := string(bytes.TrimRight(data[:nameSize], "\x00"))
+		desc := data[nameEnd : nameEnd+descSize]
+		if descEnd > len(data) {
+			descEnd = len(data)
+		}
+		data = data[descEnd:]
+		if name != stapsdtNoteName || noteType != stapsdtNoteType {
+			continue
+		}
+		note, err := parseStapsdtDesc(desc, order, addrSize)
+		if err != nil {
+			return nil, err
+		}
+		notes = append(notes, note)
+	}
+	return notes, nil
+}
+
+func parseStapsdtDesc(desc []byte, order binary.ByteOrder, addrSize int) (stapsdtNote, error) {
+	var note stapsdtNote
+	if len(desc) < 3*addrSize {
+		return note, errors.New("truncated stapsdt note")
+	}
+	addr := func(b []byte) uint64 {
+		if addrSize == 4 {
+			return uint64(order.Uint32(b))
+		}
+		return order.Uint64(b)
+	}
+	note.pc = addr(desc[0:])
+	note.base = addr(desc[addrSize:])
+	note.semaphore = addr(desc[2*addrSize:])
+	strs := bytes.SplitN(desc[3*addrSize:], []byte{0}, 4)
+	if len(strs) < 3 {
+		return note, errors.New("malformed stapsdt note strings")
+	}
+	note.provider, note.name, note.args = string(strs[0]), string(strs[1]), string(strs[2])
+	return note, nil
+}
+
+// fileOffset converts a virtual address to an offset in the ELF file, looking
+// for the loadable segment that contains it
+func fileOffset(f *elf.File, addr uint64, executable bool) (uint64, bool) {
+	for _, prog := range f.Progs {
+		if prog.Type != elf.PT_LOAD || executable && prog.Flags&elf.PF_X == 0 {
+			continue
+		}
+		if prog.Vaddr <= addr && addr < prog.Vaddr+prog.Memsz {
+			return addr - prog.Vaddr + prog.Off, true
+		}
+	}
+	return 0, false
+}
+
+func align4(n int) int {
+	return (n + 3) &^ 3
+}
@@ -0,0 +1,100 @@
+package exec
+
+import (
+	"debug/elf"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func stapsdtNoteBytes(name string, noteType uint32, pc, base, sem uint64, strs string) []byte {
+	desc := binary.LittleEndian.AppendUint64(nil, pc)
+	desc = binary.LittleEndian.AppendUint64(desc, base)
+	desc = binary.LittleEndian.AppendUint64(desc, sem)
+	desc = append(desc, strs...)
+
+	nameBytes := append([]byte(name), 0)
+	note := binary.LittleEndian.AppendUint32(nil, uint32(len(nameBytes)))
+	note = binary.LittleEndian.AppendUint32(note, uint32(len(desc)))
+	note = binary.LittleEndian.AppendUint32(note, noteType)
+	note = append(note, nameBytes...)
+	for len(note)%4 != 0 {
+		note = append(note, 0)
+	}
+	note = append(note, desc...)
+	for len(note)%4 != 0 {
+		note = append(note, 0)
+	}
+	return note
+}
+
+func TestParseStapsdtNotes(t *testing.T) {
+	data := stapsdtNoteBytes("stapsdt", 3, 0x1234, 0x5000, 0x6000, "node\x00http__server__request\x008@%rdi -4@%esi\x00")
+	// other notes are ignored
+	data = append(data, stapsdtNoteBytes("GNU", 3, 1, 2, 3, "a\x00b\x00c\x00")...)
+	data = append(data, stapsdtNoteBytes("stapsdt", 3, 0x4321, 0x5000, 0, "postgresql\x00query__start\x00\x00")...)
+
+	notes, err := parseStapsdtNotes(data, binary.LittleEndian, 8)
+	require.NoError(t, err)
+	assert.Equal(t, []stapsdtNote{{
+		pc: 0x1234, base: 0x5000, semaphore: 0x6000,
+		provider: "node", name: "http__server__request", args: "8@%rdi -4@%esi",
+	}, {
+		pc: 0x4321, base: 0x5000,
+		provider: "postgresql", name: "query__start",
+	}}, notes)
+}
+
+func TestParseStapsdtNotes_Malformed(t *testing.T) {
+	data := stapsdtNoteBytes("stapsdt", 3, 0x1234, 0x5000, 0x6000, "node\x00http__server__request\x00")
+	_, err := parseStapsdtNotes(data[:len(data)-12], binary.LittleEndian, 8)
+	assert.Error(t, err)
+
+	// missing strings
+	_, err = parseStapsdtNotes(stapsdtNoteBytes("stapsdt", 3, 1, 2, 3, "node"), binary.LittleEndian, 8)
+	assert.Error(t, err)
+}
+
+func TestUSDTProbe_ParseArgs(t *testing.T) {
+	probe := USDTProbe{Machine: elf.EM_X86_64, Args: "-4@%edi 8@-8(%rbp) 8@(%rax) 1@$5 2@%r9w"}
+	args, err := probe.ParseArgs()
+	require.NoError(t, err)
+	assert.Equal(t, []USDTArg{
+		{Kind: USDTArgReg, Size: 4, Signed: true, Register: "rdi"},
+		{Kind: USDTArgRegDeref, Size: 8, Register: "rbp", Value: -8},
+		{Kind: USDTArgRegDeref, Size: 8, Register: "rax"},
+		{Kind: USDTArgConst, Size: 1, Value: 5},
+		{Kind: USDTArgReg, Size: 2, Register: "r9"},
+	}, args)
+
+	probe = USDTProbe{Machine: elf.EM_AARCH64, Args: "8@x0 -4@w1 4@[sp, 16] 8@[x2] -8@-3"}
+	args, err = probe.ParseArgs()
+	require.NoError(t, err)
+	assert.Equal(t, []USDTArg{
+		{Kind: USDTArgReg, Size: 8, Register: "x0"},
+		{Kind: USDTArgReg, Size: 4, Signed: true, Register: "x1"},
+		{Kind: USDTArgRegDeref, Size: 4, Register: "sp", Value: 16},
+		{Kind: USDTArgRegDeref, Size: 8, Register: "x2"},
+		{Kind: USDTArgConst, Size: 8, Signed: true, Value: -3},
+	}, args)
+
+	// probes without arguments
+	probe = USDTProbe{Machine: elf.EM_X86_64}
+	args, err = probe.ParseArgs()
+	require.NoError(t, err)
+	assert.Empty(t, args)
+
+	for _, probe := range []USDTProbe{
+		{Machine: elf.EM_X86_64, Args: "8@(%rax,%rbx,8)"},
+		{Machine: elf.EM_X86_64, Args: "3@%eax"},
+		{Machine: elf.EM_X86_64, Args: "%eax"},
+		{Machine: elf.EM_X86_64, Args: "4@%xmm0"},
+		{Machine: elf.EM_AARCH64, Args: "8@v0"},
+		{Machine: elf.EM_386, Args: "4@%eax"},
+	} {
+		_, err := probe.ParseArgs()
+		assert.Error(t, err, probe.Args)
+	}
+}
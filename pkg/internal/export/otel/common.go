@@ -3,6 +3,13 @@ package otel
 import (
 	"crypto/tls"
 	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
 
 	"github.com/hashicorp/golang-lru/v2/simplelru"
 	"go.opentelemetry.io/otel/attribute"
@@ -14,6 +21,7 @@ import (
 	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
 	"google.golang.org/grpc/credentials"
 
+	"github.com/grafana/beyla/pkg/buildinfo"
 	"github.com/grafana/beyla/pkg/internal/svc"
 )
 
@@ -38,8 +46,14 @@ const (
 // Buckets defines the histograms bucket boundaries, and allows users to
 // redefine them
 type Buckets struct {
-	DurationHistogram    []float64 `yaml:"duration_histogram"`
-	RequestSizeHistogram []float64 `yaml:"request_size_histogram"`
+	DurationHistogram     []float64 `yaml:"duration_histogram"`
+	RequestSizeHistogram  []float64 `yaml:"request_size_histogram"`
+	ResponseSizeHistogram []float64 `yaml:"response_size_histogram"`
+	// ConnectionDurationHistogram and SRTTHistogram are used by the TCP network metrics
+	ConnectionDurationHistogram []float64 `yaml:"connection_duration_histogram"`
+	SRTTHistogram               []float64 `yaml:"srtt_histogram"`
+	// CPUTimeHistogram is used by the on-CPU time of the HTTP server requests
+	CPUTimeHistogram []float64 `yaml:"cpu_time_histogram"`
 }
 
 var DefaultBuckets = Buckets{
@@ -48,19 +62,104 @@ var DefaultBuckets = Buckets{
 	DurationHistogram: []float64{0, 0.005, 0.01, 0.025, 0.05, 0.075, 0.1, 0.25, 0.5, 0.75, 1, 2.5, 5, 7.5, 10},
 
 	RequestSizeHistogram: []float64{0, 32, 64, 128, 256, 512, 1024, 2048, 4096, 8192},
+
+	ResponseSizeHistogram: []float64{0, 32, 64, 128, 256, 512, 1024, 2048, 4096, 8192, 32768, 131072, 524288, 1048576},
+
+	ConnectionDurationHistogram: []float64{0, 0.01, 0.1, 0.5, 1, 5, 10, 30, 60, 300, 900, 1800, 3600},
+
+	SRTTHistogram: []float64{0, 0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1},
+
+	CPUTimeHistogram: []float64{0, 0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1},
 }
 
+const (
+	envResourceAttrs = "OTEL_RESOURCE_ATTRIBUTES"
+	sdkName          = "beyla"
+)
+
+// hostIDFiles contain the machine ID of the host, as generated by systemd or D-Bus
+var hostIDFiles = []string{"/etc/machine-id", "/var/lib/dbus/machine-id"}
+
+// hostAttributes are resolved once, as they are added to the resource of each service
+var hostAttributes = sync.OnceValue(func() []attribute.KeyValue {
+	attrs := []attribute.KeyValue{semconv.OSTypeKey.String(runtime.GOOS)}
+	if name, err := os.Hostname(); err != nil {
+		slog.Warn("can't get host name. Leaving host.name resource attribute unset", "error", err)
+	} else {
+		attrs = append(attrs, semconv.HostName(name))
+	}
+	for _, file := range hostIDFiles {
+		if id, err := os.ReadFile(file); err == nil {
+			attrs = append(attrs, semconv.HostID(strings.TrimSpace(string(id))))
+			break
+		}
+	}
+	return attrs
+})
+
+// userAttributes are the resource attributes provided by the user in the standard
+// OTEL_RESOURCE_ATTRIBUTES environment variable
+var userAttributes = sync.OnceValue(func() []attribute.KeyValue {
+	return parseResourceAttributes(os.Getenv(envResourceAttrs))
+})
+
+// parseResourceAttributes parses a comma-separated list of key=value pairs, whose values
+// can be URL-encoded, as defined by the OpenTelemetry specification.
+func parseResourceAttributes(envVar string) []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	for _, pair := range strings.Split(envVar, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			slog.Warn("ignoring malformed resource attribute", "var", envResourceAttrs, "attribute", pair)
+			continue
+		}
+		decoded, err := url.PathUnescape(strings.TrimSpace(value))
+		if err != nil {
+			slog.Warn("ignoring malformed resource attribute", "var", envResourceAttrs,
+				"attribute", pair, "error", err)
+			continue
+		}
+		attrs = append(attrs, attribute.String(key, decoded))
+	}
+	return attrs
+}
+
+// otelResource returns the OTEL resource of a service instance. Later attributes override
+// the previous attributes with the same key, so the attributes provided by the user override
+// the automatically detected attributes, but not the service name and namespace, which have
+// their own configuration options.
 func otelResource(service svc.ID) *resource.Resource {
 	attrs := []attribute.KeyValue{
-		semconv.ServiceName(service.Name),
 		// SpanMetrics requires an extra attribute besides service name
 		// to generate the traces_target_info metric,
 		// so the service is visible in the ServicesList
-		// This attribute also allows that App O11y plugin shows this app as a Go application.
-		// TODO: detect the runtime of the target executable and set this value accordingly
-		semconv.TelemetrySDKLanguageGo,
+		semconv.TelemetrySDKName(sdkName),
+		semconv.TelemetrySDKVersion(buildinfo.Version),
+	}
+	// This attribute also allows that App O11y plugin shows the language of the application
+	if service.SDKLanguage != "" {
+		attrs = append(attrs, semconv.TelemetrySDKLanguageKey.String(service.SDKLanguage))
+	}
+	attrs = append(attrs, hostAttributes()...)
+
+	if service.ProcPID != 0 {
+		attrs = append(attrs, semconv.ProcessPID(int(service.ProcPID)))
+	}
+	if len(service.CommandArgs) > 0 {
+		attrs = append(attrs, semconv.ProcessCommandArgs(service.CommandArgs...))
 	}
 
+	for k, v := range service.Metadata {
+		attrs = append(attrs, attribute.String(k, v))
+	}
+
+	attrs = append(attrs, userAttributes()...)
+
+	attrs = append(attrs, semconv.ServiceName(service.Name))
 	if service.Namespace != "" {
 		attrs = append(attrs, semconv.ServiceNamespace(service.Namespace))
 	}
@@ -68,10 +167,50 @@ func otelResource(service svc.ID) *resource.Resource {
 	return resource.NewWithAttributes(semconv.SchemaURL, attrs...)
 }
 
-// ReporterPool keeps an LRU cache of different OTEL reporters given a service name.
+// prefixes of the attributes of the captured HTTP headers
+const (
+	httpRequestHeaderPrefix  = "http.request.header."
+	httpResponseHeaderPrefix = "http.response.header."
+)
+
+// attributes of the DNS client spans, which are not defined in the semantic conventions version
+// that is used by Beyla
+const (
+	dnsQuestionNameKey = attribute.Key("dns.question.name")
+	dnsQuestionTypeKey = attribute.Key("dns.question.type")
+	dnsResponseCodeKey = attribute.Key("dns.response_code")
+	dnsAnswersKey      = attribute.Key("dns.answers")
+)
+
+// attributes of the HTTP server spans whose CPU time has been accounted, in seconds
+const (
+	cpuOnTimeKey  = attribute.Key("process.cpu.on_time")
+	cpuOffTimeKey = attribute.Key("process.cpu.off_time")
+)
+
+// headerAttr returns the attribute of a captured HTTP header. Following the OTEL semantic
+// conventions, the header name is lowercase and its dashes are replaced by underscores.
+func headerAttr(prefix, name, value string) attribute.KeyValue {
+	return attribute.String(prefix+strings.ReplaceAll(strings.ToLower(name), "-", "_"), value)
+}
+
+// appendHeaderAttrs appends the attributes of the passed captured headers, sorted by name
+func appendHeaderAttrs(attrs []attribute.KeyValue, prefix string, headers map[string]string) []attribute.KeyValue {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		attrs = append(attrs, headerAttr(prefix, name, headers[name]))
+	}
+	return attrs
+}
+
+// ReporterPool keeps an LRU cache of different OTEL reporters given a service instance.
 // TODO: evict reporters after a time without being accessed
 type ReporterPool[T any] struct {
-	pool *simplelru.LRU[svc.ID, T]
+	pool *simplelru.LRU[svc.UID, T]
 
 	itemConstructor func(svc.ID) (T, error)
 }
@@ -82,25 +221,26 @@ type ReporterPool[T any] struct {
 // instantiate the generic OTEL metrics/traces reporter.
 func NewReporterPool[T any](
 	cacheLen int,
-	callback simplelru.EvictCallback[svc.ID, T],
+	callback simplelru.EvictCallback[svc.UID, T],
 	itemConstructor func(id svc.ID) (T, error),
 ) ReporterPool[T] {
-	pool, _ := simplelru.NewLRU[svc.ID, T](cacheLen, callback)
+	pool, _ := simplelru.NewLRU[svc.UID, T](cacheLen, callback)
 	return ReporterPool[T]{pool: pool, itemConstructor: itemConstructor}
 }
 
-// For retrieves the associated item for the given service name, or
+// For retrieves the associated item for the given service instance, or
 // creates a new one if it does not exist
-func (rp *ReporterPool[T]) For(service svc.ID) (T, error) {
-	if m, ok := rp.pool.Get(service); ok {
+func (rp *ReporterPool[T]) For(service *svc.ID) (T, error) {
+	uid := service.UID()
+	if m, ok := rp.pool.Get(uid); ok {
 		return m, nil
 	}
-	m, err := rp.itemConstructor(service)
+	m, err := rp.itemConstructor(*service)
 	if err != nil {
 		var t T
 		return t, fmt.Errorf("creating resource for service %q: %w", service, err)
 	}
-	rp.pool.Add(service, m)
+	rp.pool.Add(uid, m)
 	return m, nil
 }
 
@@ -5,6 +5,9 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/grafana/beyla/pkg/internal/svc"
 )
 
 func TestOtlpOptions_AsMetricHTTP(t *testing.T) {
@@ -84,3 +87,37 @@ func TestOtlpOptions_AsTraceGRPC(t *testing.T) {
 		})
 	}
 }
+
+func TestParseResourceAttributes(t *testing.T) {
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.String("deployment.environment", "production"),
+		attribute.String("team", "billing & payments"),
+	}, parseResourceAttributes(" deployment.environment=production, ,team=billing%20%26%20payments,malformed,=foo"))
+	assert.Empty(t, parseResourceAttributes(""))
+}
+
+func TestOtelResource(t *testing.T) {
+	res := otelResource(svc.ID{
+		Name: "billing", Namespace: "shop", ProcPID: 1234, SDKLanguage: "java",
+		CommandArgs: []string{"java", "-jar", "billing.jar"},
+		Metadata:    map[string]string{"process.runtime.name": "java"},
+	})
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, kv := range res.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	assert.Equal(t, "billing", attrs["service.name"].AsString())
+	assert.Equal(t, "shop", attrs["service.namespace"].AsString())
+	assert.Equal(t, "beyla", attrs["telemetry.sdk.name"].AsString())
+	assert.Equal(t, "java", attrs["telemetry.sdk.language"].AsString())
+	assert.Equal(t, "linux", attrs["os.type"].AsString())
+	assert.Equal(t, int64(1234), attrs["process.pid"].AsInt64())
+	assert.Equal(t, []string{"java", "-jar", "billing.jar"}, attrs["process.command_args"].AsStringSlice())
+	assert.Equal(t, "java", attrs["process.runtime.name"].AsString())
+	assert.Contains(t, attrs, attribute.Key("host.name"))
+
+	// unknown languages are not reported
+	res = otelResource(svc.ID{Name: "foo"})
+	_, ok := res.Set().Value("telemetry.sdk.language")
+	assert.False(t, ok)
+}
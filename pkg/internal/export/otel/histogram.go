@@ -0,0 +1,101 @@
+package otel
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/grafana/beyla/pkg/internal/request"
+)
+
+// aggregatedHistograms is a metric.Producer of the histograms whose observations were already
+// aggregated (e.g. in the kernel). They are accumulated and exported as cumulative histograms,
+// without recording each of their observations through the OTEL instruments.
+type aggregatedHistograms struct {
+	start time.Time
+
+	mt         sync.Mutex
+	histograms []*aggregatedHistogram
+}
+
+// aggregatedHistogram accumulates the aggregated observations of a metric, by attribute set
+type aggregatedHistogram struct {
+	name   string
+	unit   string
+	bounds []float64
+	points map[attribute.Distinct]*aggregatedPoint
+}
+
+type aggregatedPoint struct {
+	attrs     attribute.Set
+	histogram *request.Histogram
+}
+
+func newAggregatedHistograms() *aggregatedHistograms {
+	return &aggregatedHistograms{start: time.Now()}
+}
+
+// histogram registers a new histogram metric. It must be invoked before any metric is produced.
+func (ah *aggregatedHistograms) histogram(name, unit string, bounds []float64) *aggregatedHistogram {
+	h := &aggregatedHistogram{name: name, unit: unit, bounds: bounds, points: map[attribute.Distinct]*aggregatedPoint{}}
+	ah.histograms = append(ah.histograms, h)
+	return h
+}
+
+// merge adds the observations of an aggregated histogram to the metric with the passed attributes
+func (ah *aggregatedHistograms) merge(h *aggregatedHistogram, attrs attribute.Set, o *request.Histogram) {
+	if o.Count() == 0 {
+		return
+	}
+	ah.mt.Lock()
+	defer ah.mt.Unlock()
+	p, ok := h.points[attrs.Equivalent()]
+	if !ok {
+		p = &aggregatedPoint{attrs: attrs, histogram: request.NewHistogram(h.bounds)}
+		h.points[attrs.Equivalent()] = p
+	}
+	p.histogram.Merge(o)
+}
+
+func (ah *aggregatedHistograms) Produce(context.Context) ([]metricdata.ScopeMetrics, error) {
+	ah.mt.Lock()
+	defer ah.mt.Unlock()
+	now := time.Now()
+	var metrics []metricdata.Metrics
+	for _, h := range ah.histograms {
+		if len(h.points) == 0 {
+			continue
+		}
+		points := make([]metricdata.HistogramDataPoint[float64], 0, len(h.points))
+		for _, p := range h.points {
+			points = append(points, metricdata.HistogramDataPoint[float64]{
+				Attributes:   p.attrs,
+				StartTime:    ah.start,
+				Time:         now,
+				Count:        p.histogram.Count(),
+				Bounds:       p.histogram.Bounds,
+				BucketCounts: append([]uint64(nil), p.histogram.Counts...),
+				Sum:          p.histogram.Sum,
+			})
+		}
+		metrics = append(metrics, metricdata.Metrics{
+			Name: h.name,
+			Unit: h.unit,
+			Data: metricdata.Histogram[float64]{
+				DataPoints:  points,
+				Temporality: metricdata.CumulativeTemporality,
+			},
+		})
+	}
+	if len(metrics) == 0 {
+		return nil, nil
+	}
+	return []metricdata.ScopeMetrics{{
+		Scope:   instrumentation.Scope{Name: reporterName},
+		Metrics: metrics,
+	}}, nil
+}
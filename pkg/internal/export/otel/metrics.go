@@ -29,13 +29,29 @@ func mlog() *slog.Logger {
 }
 
 const (
-	HTTPServerDuration    = "http.server.duration"
-	HTTPClientDuration    = "http.client.duration"
-	RPCServerDuration     = "rpc.server.duration"
-	RPCClientDuration     = "rpc.client.duration"
-	SQLClientDuration     = "sql.client.duration"
-	HTTPServerRequestSize = "http.server.request.size"
-	HTTPClientRequestSize = "http.client.request.size"
+	HTTPServerDuration     = "http.server.duration"
+	HTTPClientDuration     = "http.client.duration"
+	RPCServerDuration      = "rpc.server.duration"
+	RPCClientDuration      = "rpc.client.duration"
+	SQLClientDuration      = "sql.client.duration"
+	HTTPServerRequestSize  = "http.server.request.size"
+	HTTPClientRequestSize  = "http.client.request.size"
+	HTTPServerResponseSize = "http.server.response.size"
+	HTTPClientResponseSize = "http.client.response.size"
+	HTTPServerCPUTime      = "http.server.cpu_time"
+	TCPSentBytes           = "tcp.sent.bytes"
+	TCPReceivedBytes       = "tcp.received.bytes"
+	TCPConnectionsOpened   = "tcp.connections.opened"
+	TCPConnectionsClosed   = "tcp.connections.closed"
+	TCPConnectionsRefused  = "tcp.connections.refused"
+	TCPRetransmits         = "tcp.retransmits"
+	TCPSRTT                = "tcp.srtt"
+	TCPConnectionDuration  = "tcp.connection.duration"
+
+	// attributes of the TCP metrics
+	sourceAddressKey      = attribute.Key("source.address")
+	destinationAddressKey = attribute.Key("destination.address")
+	destinationPortKey    = attribute.Key("destination.port")
 
 	UsualPortGRPC = "4317"
 	UsualPortHTTP = "4318"
@@ -107,20 +123,42 @@ type MetricsReporter struct {
 	cfg       *MetricsConfig
 	exporter  metric.Exporter
 	reporters ReporterPool[*Metrics]
+	// lowercase names of the captured HTTP headers that are reported as metric attributes
+	requestHeaders  []string
+	responseHeaders []string
 }
 
 // Metrics is a set of metrics associated to a given OTEL MeterProvider.
 // There is a Metrics instance for each service/process instrumented by Beyla.
 type Metrics struct {
-	ctx                   context.Context
-	provider              *metric.MeterProvider
-	httpDuration          instrument.Float64Histogram
-	httpClientDuration    instrument.Float64Histogram
-	grpcDuration          instrument.Float64Histogram
-	grpcClientDuration    instrument.Float64Histogram
-	sqlClientDuration     instrument.Float64Histogram
-	httpRequestSize       instrument.Float64Histogram
-	httpClientRequestSize instrument.Float64Histogram
+	ctx                    context.Context
+	provider               *metric.MeterProvider
+	httpDuration           instrument.Float64Histogram
+	httpClientDuration     instrument.Float64Histogram
+	grpcDuration           instrument.Float64Histogram
+	grpcClientDuration     instrument.Float64Histogram
+	sqlClientDuration      instrument.Float64Histogram
+	httpRequestSize        instrument.Float64Histogram
+	httpClientRequestSize  instrument.Float64Histogram
+	httpResponseSize       instrument.Float64Histogram
+	httpClientResponseSize instrument.Float64Histogram
+	httpCPUTime            instrument.Float64Histogram
+	tcpSentBytes           instrument.Int64Counter
+	tcpReceivedBytes       instrument.Int64Counter
+	tcpOpened              instrument.Int64Counter
+	tcpClosed              instrument.Int64Counter
+	tcpRefused             instrument.Int64Counter
+	tcpRetransmits         instrument.Int64Counter
+
+	// histograms that were aggregated in the kernel. The HTTP histograms are exported with the same
+	// names as the above instruments, which don't record any observation for the same service.
+	aggregated                      *aggregatedHistograms
+	httpDurationAggregated          *aggregatedHistogram
+	httpClientDurationAggregated    *aggregatedHistogram
+	httpRequestSizeAggregated       *aggregatedHistogram
+	httpClientRequestSizeAggregated *aggregatedHistogram
+	tcpSRTTAggregated               *aggregatedHistogram
+	tcpConnectionDurationAggregated *aggregatedHistogram
 }
 
 func ReportMetrics(
@@ -137,11 +175,13 @@ func ReportMetrics(
 func newMetricsReporter(ctx context.Context, cfg *MetricsConfig, ctxInfo *global.ContextInfo) (*MetricsReporter, error) {
 	log := mlog()
 	mr := MetricsReporter{
-		ctx: ctx,
-		cfg: cfg,
+		ctx:             ctx,
+		cfg:             cfg,
+		requestHeaders:  ctxInfo.MetricRequestHeaders,
+		responseHeaders: ctxInfo.MetricResponseHeaders,
 	}
 	mr.reporters = NewReporterPool[*Metrics](cfg.ReportersCacheLen,
-		func(id svc.ID, v *Metrics) {
+		func(id svc.UID, v *Metrics) {
 			llog := log.With("service", id)
 			llog.Debug("evicting metrics reporter from cache")
 			go func() {
@@ -163,12 +203,14 @@ func newMetricsReporter(ctx context.Context, cfg *MetricsConfig, ctxInfo *global
 func (mr *MetricsReporter) newMetricSet(service svc.ID) (*Metrics, error) {
 	mlog().Debug("creating new Metrics reporter", "service", service)
 	resources := otelResource(service)
+	aggregated := newAggregatedHistograms()
 	m := Metrics{
 		ctx: mr.ctx,
 		provider: metric.NewMeterProvider(
 			metric.WithResource(resources),
 			metric.WithReader(metric.NewPeriodicReader(mr.exporter,
-				metric.WithInterval(mr.cfg.Interval))),
+				metric.WithInterval(mr.cfg.Interval),
+				metric.WithProducer(aggregated))),
 			metric.WithView(otelHistogramBuckets(HTTPServerDuration, mr.cfg.Buckets.DurationHistogram)),
 			metric.WithView(otelHistogramBuckets(HTTPClientDuration, mr.cfg.Buckets.DurationHistogram)),
 			metric.WithView(otelHistogramBuckets(RPCServerDuration, mr.cfg.Buckets.DurationHistogram)),
@@ -176,7 +218,23 @@ func (mr *MetricsReporter) newMetricSet(service svc.ID) (*Metrics, error) {
 			metric.WithView(otelHistogramBuckets(SQLClientDuration, mr.cfg.Buckets.DurationHistogram)),
 			metric.WithView(otelHistogramBuckets(HTTPServerRequestSize, mr.cfg.Buckets.RequestSizeHistogram)),
 			metric.WithView(otelHistogramBuckets(HTTPClientRequestSize, mr.cfg.Buckets.RequestSizeHistogram)),
+			metric.WithView(otelHistogramBuckets(HTTPServerResponseSize, mr.cfg.Buckets.ResponseSizeHistogram)),
+			metric.WithView(otelHistogramBuckets(HTTPClientResponseSize, mr.cfg.Buckets.ResponseSizeHistogram)),
+			metric.WithView(otelHistogramBuckets(HTTPServerCPUTime, mr.cfg.Buckets.CPUTimeHistogram)),
 		),
+		aggregated: aggregated,
+		httpDurationAggregated: aggregated.histogram(
+			HTTPServerDuration, "s", mr.cfg.Buckets.DurationHistogram),
+		httpClientDurationAggregated: aggregated.histogram(
+			HTTPClientDuration, "s", mr.cfg.Buckets.DurationHistogram),
+		httpRequestSizeAggregated: aggregated.histogram(
+			HTTPServerRequestSize, "By", mr.cfg.Buckets.RequestSizeHistogram),
+		httpClientRequestSizeAggregated: aggregated.histogram(
+			HTTPClientRequestSize, "By", mr.cfg.Buckets.RequestSizeHistogram),
+		tcpSRTTAggregated: aggregated.histogram(
+			TCPSRTT, "s", mr.cfg.Buckets.SRTTHistogram),
+		tcpConnectionDurationAggregated: aggregated.histogram(
+			TCPConnectionDuration, "s", mr.cfg.Buckets.ConnectionDurationHistogram),
 	}
 	// time units for HTTP and GRPC durations are in seconds, according to the OTEL specification:
 	// https://github.com/open-telemetry/opentelemetry-specification/tree/main/specification/metrics/semantic_conventions
@@ -211,9 +269,46 @@ func (mr *MetricsReporter) newMetricSet(service svc.ID) (*Metrics, error) {
 	if err != nil {
 		return nil, fmt.Errorf("creating http size histogram metric: %w", err)
 	}
+	m.httpResponseSize, err = meter.Float64Histogram(HTTPServerResponseSize, instrument.WithUnit("By"))
+	if err != nil {
+		return nil, fmt.Errorf("creating http response size histogram metric: %w", err)
+	}
+	m.httpClientResponseSize, err = meter.Float64Histogram(HTTPClientResponseSize, instrument.WithUnit("By"))
+	if err != nil {
+		return nil, fmt.Errorf("creating http response size histogram metric: %w", err)
+	}
+	m.httpCPUTime, err = meter.Float64Histogram(HTTPServerCPUTime, instrument.WithUnit("s"))
+	if err != nil {
+		return nil, fmt.Errorf("creating http cpu time histogram metric: %w", err)
+	}
+	if err := m.createTCPInstruments(meter); err != nil {
+		return nil, err
+	}
 	return &m, nil
 }
 
+func (m *Metrics) createTCPInstruments(meter instrument.Meter) error {
+	var err error
+	counters := []struct {
+		counter *instrument.Int64Counter
+		name    string
+		unit    string
+	}{
+		{counter: &m.tcpSentBytes, name: TCPSentBytes, unit: "By"},
+		{counter: &m.tcpReceivedBytes, name: TCPReceivedBytes, unit: "By"},
+		{counter: &m.tcpOpened, name: TCPConnectionsOpened, unit: "{connection}"},
+		{counter: &m.tcpClosed, name: TCPConnectionsClosed, unit: "{connection}"},
+		{counter: &m.tcpRefused, name: TCPConnectionsRefused, unit: "{connection}"},
+		{counter: &m.tcpRetransmits, name: TCPRetransmits, unit: "{segment}"},
+	}
+	for _, c := range counters {
+		if *c.counter, err = meter.Int64Counter(c.name, instrument.WithUnit(c.unit)); err != nil {
+			return fmt.Errorf("creating %s counter metric: %w", c.name, err)
+		}
+	}
+	return nil
+}
+
 func instantiateMetricsExporter(ctx context.Context, cfg *MetricsConfig, log *slog.Logger) (metric.Exporter, error) {
 	var err error
 	var exporter metric.Exporter
@@ -311,6 +406,7 @@ func (mr *MetricsReporter) metricAttributes(span *request.Span) attribute.Set {
 		if span.Route != "" {
 			attrs = append(attrs, semconv.HTTPRoute(span.Route))
 		}
+		attrs = mr.appendMetricHeaderAttrs(attrs, span)
 	case request.EventTypeGRPC, request.EventTypeGRPCClient:
 		attrs = []attribute.KeyValue{
 			semconv.RPCMethod(span.Path),
@@ -329,10 +425,17 @@ func (mr *MetricsReporter) metricAttributes(span *request.Span) attribute.Set {
 			attrs = append(attrs, semconv.NetSockPeerName(span.Host))
 			attrs = append(attrs, semconv.NetSockPeerPort(span.HostPort))
 		}
+		attrs = mr.appendMetricHeaderAttrs(attrs, span)
 	case request.EventTypeSQLClient:
 		attrs = []attribute.KeyValue{
 			semconv.DBOperation(span.Method),
 		}
+	case request.EventTypeTCPServer, request.EventTypeTCPClient:
+		attrs = []attribute.KeyValue{
+			sourceAddressKey.String(span.Peer),
+			destinationAddressKey.String(span.Host),
+			destinationPortKey.Int(span.HostPort),
+		}
 	}
 
 	if span.ServiceID.Name != "" { // we don't have service name set, system wide instrumentation
@@ -346,29 +449,96 @@ func (mr *MetricsReporter) metricAttributes(span *request.Span) attribute.Set {
 	return attribute.NewSet(attrs...)
 }
 
+// appendMetricHeaderAttrs appends the captured headers that have been selected as metric attributes
+func (mr *MetricsReporter) appendMetricHeaderAttrs(attrs []attribute.KeyValue, span *request.Span) []attribute.KeyValue {
+	for _, name := range mr.requestHeaders {
+		if value, ok := span.RequestHeaders[name]; ok {
+			attrs = append(attrs, headerAttr(httpRequestHeaderPrefix, name, value))
+		}
+	}
+	for _, name := range mr.responseHeaders {
+		if value, ok := span.ResponseHeaders[name]; ok {
+			attrs = append(attrs, headerAttr(httpResponseHeaderPrefix, name, value))
+		}
+	}
+	return attrs
+}
+
 func (r *Metrics) record(span *request.Span, attrs attribute.Set) {
+	attrOpt := instrument.WithAttributeSet(attrs)
+	if span.Aggregated != nil {
+		r.recordAggregated(span, attrs)
+		return
+	}
+	if span.Network != nil {
+		r.recordNetwork(span.Network, attrs)
+		return
+	}
 	t := span.Timings()
 	duration := t.End.Sub(t.RequestStart).Seconds()
-	attrOpt := instrument.WithAttributeSet(attrs)
 	switch span.Type {
 	case request.EventTypeHTTP:
 		// TODO: for more accuracy, there must be a way to set the metric time from the actual span end time
 		r.httpDuration.Record(r.ctx, duration, attrOpt)
-		r.httpRequestSize.Record(r.ctx, float64(span.ContentLength), attrOpt)
+		// the sizes are -1 if they are unknown
+		if span.ContentLength >= 0 {
+			r.httpRequestSize.Record(r.ctx, float64(span.ContentLength), attrOpt)
+		}
+		if span.ResponseLength >= 0 {
+			r.httpResponseSize.Record(r.ctx, float64(span.ResponseLength), attrOpt)
+		}
+		if span.CPUTime != nil {
+			r.httpCPUTime.Record(r.ctx, span.CPUTime.OnCPU.Seconds(), attrOpt)
+		}
 	case request.EventTypeGRPC:
 		r.grpcDuration.Record(r.ctx, duration, attrOpt)
 	case request.EventTypeGRPCClient:
 		r.grpcClientDuration.Record(r.ctx, duration, attrOpt)
 	case request.EventTypeHTTPClient:
 		r.httpClientDuration.Record(r.ctx, duration, attrOpt)
-		r.httpClientRequestSize.Record(r.ctx, float64(span.ContentLength), attrOpt)
+		if span.ContentLength >= 0 {
+			r.httpClientRequestSize.Record(r.ctx, float64(span.ContentLength), attrOpt)
+		}
+		if span.ResponseLength >= 0 {
+			r.httpClientResponseSize.Record(r.ctx, float64(span.ResponseLength), attrOpt)
+		}
 	case request.EventTypeSQLClient:
 		r.sqlClientDuration.Record(r.ctx, duration, attrOpt)
 	}
 }
 
+// recordAggregated records the metrics that were aggregated in the kernel
+func (r *Metrics) recordAggregated(span *request.Span, attrs attribute.Set) {
+	switch span.Type {
+	case request.EventTypeHTTP:
+		r.aggregated.merge(r.httpDurationAggregated, attrs, &span.Aggregated.Duration)
+		r.aggregated.merge(r.httpRequestSizeAggregated, attrs, &span.Aggregated.ContentLength)
+	case request.EventTypeHTTPClient:
+		r.aggregated.merge(r.httpClientDurationAggregated, attrs, &span.Aggregated.Duration)
+		r.aggregated.merge(r.httpClientRequestSizeAggregated, attrs, &span.Aggregated.ContentLength)
+	}
+}
+
+// recordNetwork records the metrics of the TCP connections that were aggregated in the kernel
+func (r *Metrics) recordNetwork(nm *request.NetworkMetrics, attrs attribute.Set) {
+	attrOpt := instrument.WithAttributeSet(attrs)
+	add := func(c instrument.Int64Counter, v uint64) {
+		if v > 0 {
+			c.Add(r.ctx, int64(v), attrOpt)
+		}
+	}
+	add(r.tcpSentBytes, nm.BytesSent)
+	add(r.tcpReceivedBytes, nm.BytesReceived)
+	add(r.tcpOpened, nm.Opened)
+	add(r.tcpClosed, nm.Closed)
+	add(r.tcpRefused, nm.Refused)
+	add(r.tcpRetransmits, nm.Retransmits)
+	r.aggregated.merge(r.tcpSRTTAggregated, attrs, &nm.SRTT)
+	r.aggregated.merge(r.tcpConnectionDurationAggregated, attrs, &nm.Duration)
+}
+
 func (mr *MetricsReporter) reportMetrics(input <-chan []request.Span) {
-	var lastSvc svc.ID
+	var lastSvc svc.UID
 	var reporter *Metrics
 	for spans := range input {
 		for i := range spans {
@@ -380,14 +550,14 @@ func (mr *MetricsReporter) reportMetrics(input <-chan []request.Span) {
 			// only a single instrumented process.
 			// In multi-process tracing, this is likely to happen as most
 			// tracers group traces belonging to the same service in the same slice.
-			if s.ServiceID != lastSvc || reporter == nil {
-				lm, err := mr.reporters.For(s.ServiceID)
+			if s.ServiceID.UID() != lastSvc || reporter == nil {
+				lm, err := mr.reporters.For(&s.ServiceID)
 				if err != nil {
 					mlog().Error("unexpected error creating OTEL resource. Ignoring metric",
 						err, "service", s.ServiceID)
 					continue
 				}
-				lastSvc = s.ServiceID
+				lastSvc = s.ServiceID.UID()
 				reporter = lm
 			}
 			reporter.record(s, mr.metricAttributes(s))
@@ -13,6 +13,9 @@ import (
 	"github.com/mariomac/pipes/pkg/node"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
 
 	"github.com/grafana/beyla/pkg/internal/imetrics"
 	"github.com/grafana/beyla/pkg/internal/pipe/global"
@@ -307,3 +310,50 @@ func (f *fakeInternalMetrics) Errors() int {
 func (f *fakeInternalMetrics) SumCount() (sum, count int) {
 	return int(f.sum.Load()), int(f.cnt.Load())
 }
+
+func TestMetrics_HeaderAttributes(t *testing.T) {
+	mr := MetricsReporter{cfg: &MetricsConfig{}, requestHeaders: []string{"x-tenant-id"}}
+	attrs := mr.metricAttributes(&request.Span{
+		Type:           request.EventTypeHTTP,
+		Method:         "GET",
+		Status:         200,
+		RequestHeaders: map[string]string{"x-tenant-id": "tenant1", "x-request-id": "1234"},
+	})
+	val, ok := attrs.Value("http.request.header.x_tenant_id")
+	require.True(t, ok)
+	assert.Equal(t, "tenant1", val.AsString())
+	// only the selected headers are metric attributes
+	_, ok = attrs.Value("http.request.header.x_request_id")
+	assert.False(t, ok)
+}
+
+func TestAggregatedHistograms_Produce(t *testing.T) {
+	ah := newAggregatedHistograms()
+	duration := ah.histogram(HTTPServerDuration, "s", []float64{0.1, 1})
+	ah.histogram(HTTPServerRequestSize, "By", []float64{10})
+
+	empty, err := ah.Produce(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+
+	attrs := attribute.NewSet(semconv.HTTPMethod("GET"))
+	ah.merge(duration, attrs, &request.Histogram{Bounds: []float64{0.1, 1}, Counts: []uint64{1, 2, 0}, Sum: 1.5})
+	ah.merge(duration, attribute.NewSet(semconv.HTTPMethod("GET")),
+		&request.Histogram{Bounds: []float64{0.1, 1}, Counts: []uint64{0, 0, 1}, Sum: 2})
+
+	sm, err := ah.Produce(context.Background())
+	require.NoError(t, err)
+	require.Len(t, sm, 1)
+	assert.Equal(t, reporterName, sm[0].Scope.Name)
+	require.Len(t, sm[0].Metrics, 1)
+	assert.Equal(t, HTTPServerDuration, sm[0].Metrics[0].Name)
+	h, ok := sm[0].Metrics[0].Data.(metricdata.Histogram[float64])
+	require.True(t, ok)
+	assert.Equal(t, metricdata.CumulativeTemporality, h.Temporality)
+	require.Len(t, h.DataPoints, 1)
+	dp := h.DataPoints[0]
+	assert.Equal(t, attrs, dp.Attributes)
+	assert.Equal(t, uint64(4), dp.Count)
+	assert.Equal(t, []uint64{1, 2, 1}, dp.BucketCounts)
+	assert.Equal(t, 3.5, dp.Sum)
+}
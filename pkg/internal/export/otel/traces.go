@@ -127,7 +127,7 @@ func newTracesReporter(ctx context.Context, cfg *TracesConfig, ctxInfo *global.C
 	log := tlog()
 	r := TracesReporter{ctx: ctx, cfg: cfg}
 	r.reporters = NewReporterPool[*Tracers](cfg.ReportersCacheLen,
-		func(k svc.ID, v *Tracers) {
+		func(k svc.UID, v *Tracers) {
 			llog := log.With("service", k)
 			llog.Debug("evicting metrics reporter from cache")
 			go func() {
@@ -274,6 +274,11 @@ func spanStatusCode(span *request.Span) codes.Code {
 		return httpSpanStatusCode(span)
 	case request.EventTypeGRPC, request.EventTypeGRPCClient:
 		return grpcSpanStatusCode(span)
+	case request.EventTypeDNSClient:
+		// any response code other than NOERROR
+		if span.Status != 0 {
+			return codes.Error
+		}
 	}
 	return codes.Unset
 }
@@ -290,11 +295,11 @@ func (r *TracesReporter) traceAttributes(span *request.Span) []attribute.KeyValu
 			semconv.NetSockPeerAddr(span.Peer),
 			semconv.NetHostName(span.Host),
 			semconv.NetHostPort(span.HostPort),
-			semconv.HTTPRequestContentLength(int(span.ContentLength)),
 		}
 		if span.Route != "" {
 			attrs = append(attrs, semconv.HTTPRoute(span.Route))
 		}
+		attrs = appendContentLengths(attrs, span)
 	case request.EventTypeGRPC:
 		attrs = []attribute.KeyValue{
 			semconv.RPCMethod(span.Path),
@@ -311,8 +316,8 @@ func (r *TracesReporter) traceAttributes(span *request.Span) []attribute.KeyValu
 			semconv.HTTPURL(span.Path),
 			semconv.NetPeerName(span.Host),
 			semconv.NetPeerPort(span.HostPort),
-			semconv.HTTPRequestContentLength(int(span.ContentLength)),
 		}
+		attrs = appendContentLengths(attrs, span)
 	case request.EventTypeGRPCClient:
 		attrs = []attribute.KeyValue{
 			semconv.RPCMethod(span.Path),
@@ -332,8 +337,14 @@ func (r *TracesReporter) traceAttributes(span *request.Span) []attribute.KeyValu
 				attrs = append(attrs, semconv.DBSQLTable(table))
 			}
 		}
+	case request.EventTypeDNSClient:
+		attrs = dnsAttributes(span)
 	}
 
+	attrs = appendHeaderAttrs(attrs, httpRequestHeaderPrefix, span.RequestHeaders)
+	attrs = appendHeaderAttrs(attrs, httpResponseHeaderPrefix, span.ResponseHeaders)
+	attrs = appendCPUTimeAttrs(attrs, span.CPUTime)
+
 	if span.ServiceID.Name != "" { // we don't have service name set, system wide instrumentation
 		attrs = append(attrs, semconv.ServiceName(span.ServiceID.Name))
 	}
@@ -346,6 +357,41 @@ func (r *TracesReporter) traceAttributes(span *request.Span) []attribute.KeyValu
 	return attrs
 }
 
+// appendContentLengths appends the sizes of the request and response bodies, if they are known
+func appendContentLengths(attrs []attribute.KeyValue, span *request.Span) []attribute.KeyValue {
+	if span.ContentLength >= 0 {
+		attrs = append(attrs, semconv.HTTPRequestContentLength(int(span.ContentLength)))
+	}
+	if span.ResponseLength >= 0 {
+		attrs = append(attrs, semconv.HTTPResponseContentLength(int(span.ResponseLength)))
+	}
+	return attrs
+}
+
+// appendCPUTimeAttrs appends the on-CPU and off-CPU time of the request, if it was accounted
+func appendCPUTimeAttrs(attrs []attribute.KeyValue, cpuTime *request.CPUTime) []attribute.KeyValue {
+	if cpuTime == nil {
+		return attrs
+	}
+	return append(attrs,
+		cpuOnTimeKey.Float64(cpuTime.OnCPU.Seconds()),
+		cpuOffTimeKey.Float64(cpuTime.OffCPU.Seconds()))
+}
+
+func dnsAttributes(span *request.Span) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		dnsQuestionNameKey.String(span.Path),
+		dnsQuestionTypeKey.String(span.Method),
+		dnsResponseCodeKey.Int(span.Status),
+		semconv.NetPeerName(span.Host),
+		semconv.NetPeerPort(span.HostPort),
+	}
+	if len(span.ResolvedAddresses) > 0 {
+		attrs = append(attrs, dnsAnswersKey.StringSlice(span.ResolvedAddresses))
+	}
+	return attrs
+}
+
 func traceName(span *request.Span) string {
 	switch span.Type {
 	case request.EventTypeHTTP:
@@ -370,6 +416,8 @@ func traceName(span *request.Span) string {
 			operation += " ." + table
 		}
 		return operation
+	case request.EventTypeDNSClient:
+		return "DNS " + span.Method
 	}
 	return ""
 }
@@ -378,7 +426,8 @@ func spanKind(span *request.Span) trace2.SpanKind {
 	switch span.Type {
 	case request.EventTypeHTTP, request.EventTypeGRPC:
 		return trace2.SpanKindServer
-	case request.EventTypeHTTPClient, request.EventTypeGRPCClient, request.EventTypeSQLClient:
+	case request.EventTypeHTTPClient, request.EventTypeGRPCClient, request.EventTypeSQLClient,
+		request.EventTypeDNSClient:
 		return trace2.SpanKindClient
 	}
 	return trace2.SpanKindInternal
@@ -520,26 +569,27 @@ func (r *TracesReporter) reportServerSpan(span *request.Span, tracer trace2.Trac
 }
 
 func (r *TracesReporter) reportTraces(input <-chan []request.Span) {
-	var lastSvc svc.ID
+	var lastSvc svc.UID
 	var reporter trace2.Tracer
 	for spans := range input {
 		for i := range spans {
 			span := &spans[i]
 
 			// small optimization: read explanation in MetricsReporter.reportMetrics
-			if span.ServiceID != lastSvc || reporter == nil {
-				lm, err := r.reporters.For(span.ServiceID)
+			if span.ServiceID.UID() != lastSvc || reporter == nil {
+				lm, err := r.reporters.For(&span.ServiceID)
 				if err != nil {
 					mlog().Error("unexpected error creating OTEL resource. Ignoring trace",
 						err, "service", span.ServiceID)
 					continue
 				}
-				lastSvc = span.ServiceID
+				lastSvc = span.ServiceID.UID()
 				reporter = lm.tracer
 			}
 
 			switch span.Type {
-			case request.EventTypeHTTPClient, request.EventTypeGRPCClient, request.EventTypeSQLClient:
+			case request.EventTypeHTTPClient, request.EventTypeGRPCClient, request.EventTypeSQLClient,
+				request.EventTypeDNSClient:
 				r.reportClientSpan(span, reporter)
 			case request.EventTypeHTTP, request.EventTypeGRPC:
 				r.reportServerSpan(span, reporter)
@@ -574,3 +574,67 @@ func TestTraces_GRPCStatus(t *testing.T) {
 		}
 	})
 }
+
+func TestTraces_HeaderAttributes(t *testing.T) {
+	r := TracesReporter{}
+	attrs := r.traceAttributes(&request.Span{
+		Type:            request.EventTypeHTTP,
+		Method:          "GET",
+		RequestHeaders:  map[string]string{"x-tenant-id": "tenant1", "user-agent": "curl"},
+		ResponseHeaders: map[string]string{"content-type": "text/plain"},
+	})
+	assert.Contains(t, attrs, attribute.String("http.request.header.x_tenant_id", "tenant1"))
+	assert.Contains(t, attrs, attribute.String("http.request.header.user_agent", "curl"))
+	assert.Contains(t, attrs, attribute.String("http.response.header.content_type", "text/plain"))
+}
+
+func TestTraces_UnknownContentLength(t *testing.T) {
+	r := TracesReporter{}
+	attrs := r.traceAttributes(&request.Span{
+		Type:           request.EventTypeHTTPClient,
+		Method:         "GET",
+		ContentLength:  0,
+		ResponseLength: -1,
+	})
+	assert.Contains(t, attrs, semconv.HTTPRequestContentLength(0))
+	for _, attr := range attrs {
+		assert.NotEqual(t, semconv.HTTPResponseContentLengthKey, attr.Key)
+	}
+}
+
+func TestTraces_DNSClient(t *testing.T) {
+	r := TracesReporter{}
+	span := &request.Span{
+		Type:              request.EventTypeDNSClient,
+		Method:            "A",
+		Path:              "grafana.com",
+		Host:              "10.0.0.53",
+		HostPort:          53,
+		ResolvedAddresses: []string{"34.120.177.193"},
+	}
+	assert.Equal(t, "DNS A", traceName(span))
+	assert.Equal(t, trace2.SpanKindClient, spanKind(span))
+	assert.Equal(t, codes.Unset, spanStatusCode(span))
+	attrs := r.traceAttributes(span)
+	assert.Contains(t, attrs, attribute.String("dns.question.name", "grafana.com"))
+	assert.Contains(t, attrs, attribute.String("dns.question.type", "A"))
+	assert.Contains(t, attrs, attribute.Int("dns.response_code", 0))
+	assert.Contains(t, attrs, attribute.StringSlice("dns.answers", []string{"34.120.177.193"}))
+
+	// NXDOMAIN
+	span.Status = 3
+	assert.Equal(t, codes.Error, spanStatusCode(span))
+}
+
+func TestTraces_CPUTime(t *testing.T) {
+	r := TracesReporter{}
+	span := &request.Span{Type: request.EventTypeHTTP, Method: "GET", Path: "/foo", Status: 200}
+	for _, attr := range r.traceAttributes(span) {
+		assert.NotEqual(t, "process.cpu.on_time", string(attr.Key))
+	}
+
+	span.CPUTime = &request.CPUTime{OnCPU: 2 * time.Millisecond, OffCPU: 8 * time.Millisecond}
+	attrs := r.traceAttributes(span)
+	assert.Contains(t, attrs, attribute.Float64("process.cpu.on_time", 0.002))
+	assert.Contains(t, attrs, attribute.Float64("process.cpu.off_time", 0.008))
+}
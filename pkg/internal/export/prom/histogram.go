@@ -0,0 +1,85 @@
+package prom
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/grafana/beyla/pkg/internal/request"
+)
+
+// histogramVec is a Prometheus collector of histograms, partitioned by label values, that accepts
+// both single observations and histograms whose observations were already aggregated (e.g. in
+// the kernel). The aggregated histograms are merged without replaying each of their observations.
+type histogramVec struct {
+	desc    *prometheus.Desc
+	buckets []float64
+
+	mt     sync.Mutex
+	series map[string]*histogramSeries
+}
+
+// histogramSeries is the histogram of a given set of label values
+type histogramSeries struct {
+	vec         *histogramVec
+	labelValues []string
+	histogram   *request.Histogram
+}
+
+func newHistogramVec(opts prometheus.HistogramOpts, labelNames []string) *histogramVec {
+	return &histogramVec{
+		desc:    prometheus.NewDesc(opts.Name, opts.Help, labelNames, opts.ConstLabels),
+		buckets: opts.Buckets,
+		series:  map[string]*histogramSeries{},
+	}
+}
+
+// WithLabelValues returns the histogram for the passed label values, creating it if it doesn't exist
+func (hv *histogramVec) WithLabelValues(lv ...string) *histogramSeries {
+	// label values are valid UTF-8 strings, so they can't contain the separator
+	key := strings.Join(lv, "\xff")
+	hv.mt.Lock()
+	defer hv.mt.Unlock()
+	hs, ok := hv.series[key]
+	if !ok {
+		hs = &histogramSeries{
+			vec:         hv,
+			labelValues: append([]string(nil), lv...),
+			histogram:   request.NewHistogram(hv.buckets),
+		}
+		hv.series[key] = hs
+	}
+	return hs
+}
+
+// Observe adds a single observation
+func (hs *histogramSeries) Observe(v float64) {
+	hs.vec.mt.Lock()
+	hs.histogram.Record(v)
+	hs.vec.mt.Unlock()
+}
+
+// Merge adds all the observations of an aggregated histogram
+func (hs *histogramSeries) Merge(h *request.Histogram) {
+	hs.vec.mt.Lock()
+	hs.histogram.Merge(h)
+	hs.vec.mt.Unlock()
+}
+
+func (hv *histogramVec) Describe(ch chan<- *prometheus.Desc) {
+	ch <- hv.desc
+}
+
+func (hv *histogramVec) Collect(ch chan<- prometheus.Metric) {
+	hv.mt.Lock()
+	defer hv.mt.Unlock()
+	for _, hs := range hv.series {
+		h := hs.histogram
+		m, err := prometheus.NewConstHistogram(hv.desc, h.Count(), h.Sum, h.CumulativeCounts(), hs.labelValues...)
+		if err != nil {
+			m = prometheus.NewInvalidMetric(hv.desc, err)
+		}
+		ch <- m
+	}
+}
@@ -3,6 +3,7 @@ package prom
 import (
 	"context"
 	"strconv"
+	"strings"
 
 	"github.com/mariomac/pipes/pkg/node"
 	"github.com/prometheus/client_golang/prometheus"
@@ -17,13 +18,24 @@ import (
 // using labels and names that are equivalent names to the OTEL attributes
 // but following the different naming conventions
 const (
-	HTTPServerDuration    = "http_server_duration_seconds"
-	HTTPClientDuration    = "http_client_duration_seconds"
-	RPCServerDuration     = "rpc_server_duration_seconds"
-	RPCClientDuration     = "rpc_client_duration_seconds"
-	SQLClientDuration     = "sql_client_duration_seconds"
-	HTTPServerRequestSize = "http_server_request_size_bytes"
-	HTTPClientRequestSize = "http_client_request_size_bytes"
+	HTTPServerDuration     = "http_server_duration_seconds"
+	HTTPClientDuration     = "http_client_duration_seconds"
+	RPCServerDuration      = "rpc_server_duration_seconds"
+	RPCClientDuration      = "rpc_client_duration_seconds"
+	SQLClientDuration      = "sql_client_duration_seconds"
+	HTTPServerRequestSize  = "http_server_request_size_bytes"
+	HTTPClientRequestSize  = "http_client_request_size_bytes"
+	HTTPServerResponseSize = "http_server_response_size_bytes"
+	HTTPClientResponseSize = "http_client_response_size_bytes"
+	HTTPServerCPUTime      = "http_server_cpu_time_seconds"
+	TCPSentBytes           = "tcp_sent_bytes_total"
+	TCPReceivedBytes       = "tcp_received_bytes_total"
+	TCPConnectionsOpened   = "tcp_connections_opened_total"
+	TCPConnectionsClosed   = "tcp_connections_closed_total"
+	TCPConnectionsRefused  = "tcp_connections_refused_total"
+	TCPRetransmits         = "tcp_retransmits_total"
+	TCPSRTT                = "tcp_srtt_seconds"
+	TCPConnectionDuration  = "tcp_connection_duration_seconds"
 
 	serviceNameKey       = "service_name"
 	serviceNamespaceKey  = "service_namespace"
@@ -38,12 +50,30 @@ const (
 	rpcMethodKey         = "rpc_method"
 	rpcSystemGRPC        = "rpc_system"
 	DBOperationKey       = "db_operation"
+	sourceAddressKey     = "source_address"
+	destAddressKey       = "destination_address"
+	destPortKey          = "destination_port"
 
 	k8sSrcNameKey      = "k8s_src_name"
 	k8sSrcNamespaceKey = "k8s_src_namespace"
 	k8sDstNameKey      = "k8s_dst_name"
 	k8sDstNamespaceKey = "k8s_dst_namespace"
 	k8sDstTypeKey      = "k8s_dst_type"
+
+	k8sNamespaceNameKey    = "k8s_namespace_name"
+	k8sPodNameKey          = "k8s_pod_name"
+	k8sPodUIDKey           = "k8s_pod_uid"
+	k8sNodeNameKey         = "k8s_node_name"
+	k8sContainerNameKey    = "k8s_container_name"
+	k8sDeploymentNameKey   = "k8s_deployment_name"
+	k8sReplicaSetNameKey   = "k8s_replicaset_name"
+	k8sStatefulSetNameKey  = "k8s_statefulset_name"
+	k8sDaemonSetNameKey    = "k8s_daemonset_name"
+	k8sPodLabelPrefix      = "k8s_pod_label_"
+	k8sPodAnnotationPrefix = "k8s_pod_annotation_"
+
+	httpRequestHeaderPrefix  = "http_request_header_"
+	httpResponseHeaderPrefix = "http_response_header_"
 )
 
 // TODO: TLS
@@ -52,6 +82,9 @@ type PrometheusConfig struct {
 	Path           string `yaml:"path" env:"PROMETHEUS_PATH"`
 	ReportTarget   bool   `yaml:"report_target" env:"METRICS_REPORT_TARGET"`
 	ReportPeerInfo bool   `yaml:"report_peer" env:"METRICS_REPORT_PEER"`
+	// ReportPodMetadata specifies whether the metadata of the Pod where the instrumented service
+	// runs (Pod name and UID, node, container and owner workload) is reported as metric labels
+	ReportPodMetadata bool `yaml:"report_pod_metadata" env:"METRICS_REPORT_POD_METADATA"`
 
 	Buckets otel.Buckets `yaml:"buckets"`
 }
@@ -64,13 +97,24 @@ func (p PrometheusConfig) Enabled() bool {
 type metricsReporter struct {
 	cfg *PrometheusConfig
 
-	httpDuration          *prometheus.HistogramVec
-	httpClientDuration    *prometheus.HistogramVec
-	grpcDuration          *prometheus.HistogramVec
-	grpcClientDuration    *prometheus.HistogramVec
-	sqlClientDuration     *prometheus.HistogramVec
-	httpRequestSize       *prometheus.HistogramVec
-	httpClientRequestSize *prometheus.HistogramVec
+	httpDuration           *histogramVec
+	httpClientDuration     *histogramVec
+	grpcDuration           *prometheus.HistogramVec
+	grpcClientDuration     *prometheus.HistogramVec
+	sqlClientDuration      *prometheus.HistogramVec
+	httpRequestSize        *histogramVec
+	httpClientRequestSize  *histogramVec
+	httpResponseSize       *prometheus.HistogramVec
+	httpClientResponseSize *prometheus.HistogramVec
+	httpCPUTime            *prometheus.HistogramVec
+	tcpSentBytes           *prometheus.CounterVec
+	tcpReceivedBytes       *prometheus.CounterVec
+	tcpOpened              *prometheus.CounterVec
+	tcpClosed              *prometheus.CounterVec
+	tcpRefused             *prometheus.CounterVec
+	tcpRetransmits         *prometheus.CounterVec
+	tcpSRTT                *histogramVec
+	tcpConnectionDuration  *histogramVec
 
 	promConnect *connector.PrometheusManager
 
@@ -91,12 +135,12 @@ func newReporter(ctx context.Context, cfg *PrometheusConfig, ctxInfo *global.Con
 		ctxInfo:     ctxInfo,
 		cfg:         cfg,
 		promConnect: ctxInfo.Prometheus,
-		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		httpDuration: newHistogramVec(prometheus.HistogramOpts{
 			Name:    HTTPServerDuration,
 			Help:    "duration of HTTP service calls from the server side, in seconds",
 			Buckets: cfg.Buckets.DurationHistogram,
 		}, labelNamesHTTP(cfg, ctxInfo)),
-		httpClientDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		httpClientDuration: newHistogramVec(prometheus.HistogramOpts{
 			Name:    HTTPClientDuration,
 			Help:    "duration of HTTP service calls from the client side, in seconds",
 			Buckets: cfg.Buckets.DurationHistogram,
@@ -115,26 +159,86 @@ func newReporter(ctx context.Context, cfg *PrometheusConfig, ctxInfo *global.Con
 			Name:    SQLClientDuration,
 			Help:    "duration of SQL client operations, in seconds",
 			Buckets: cfg.Buckets.DurationHistogram,
-		}, labelNamesSQL(ctxInfo)),
-		httpRequestSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		}, labelNamesSQL(cfg, ctxInfo)),
+		httpRequestSize: newHistogramVec(prometheus.HistogramOpts{
 			Name:    HTTPServerRequestSize,
 			Help:    "size, in bytes, of the HTTP request body as received at the server side",
 			Buckets: cfg.Buckets.RequestSizeHistogram,
 		}, labelNamesHTTP(cfg, ctxInfo)),
-		httpClientRequestSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		httpClientRequestSize: newHistogramVec(prometheus.HistogramOpts{
 			Name:    HTTPClientRequestSize,
 			Help:    "size, in bytes, of the HTTP request body as sent from the client side",
 			Buckets: cfg.Buckets.RequestSizeHistogram,
 		}, labelNamesHTTPClient(cfg, ctxInfo)),
+		httpResponseSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    HTTPServerResponseSize,
+			Help:    "size, in bytes, of the HTTP response body as sent from the server side",
+			Buckets: cfg.Buckets.ResponseSizeHistogram,
+		}, labelNamesHTTP(cfg, ctxInfo)),
+		httpClientResponseSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    HTTPClientResponseSize,
+			Help:    "size, in bytes, of the HTTP response body as received at the client side",
+			Buckets: cfg.Buckets.ResponseSizeHistogram,
+		}, labelNamesHTTPClient(cfg, ctxInfo)),
+		httpCPUTime: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    HTTPServerCPUTime,
+			Help:    "time, in seconds, that the thread or goroutine serving the HTTP request spent on CPU",
+			Buckets: cfg.Buckets.CPUTimeHistogram,
+		}, labelNamesHTTP(cfg, ctxInfo)),
+		tcpSentBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: TCPSentBytes,
+			Help: "bytes sent from the source to the destination of the TCP connections",
+		}, labelNamesTCP(cfg, ctxInfo)),
+		tcpReceivedBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: TCPReceivedBytes,
+			Help: "bytes received by the source from the destination of the TCP connections",
+		}, labelNamesTCP(cfg, ctxInfo)),
+		tcpOpened: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: TCPConnectionsOpened,
+			Help: "number of established TCP connections",
+		}, labelNamesTCP(cfg, ctxInfo)),
+		tcpClosed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: TCPConnectionsClosed,
+			Help: "number of closed TCP connections",
+		}, labelNamesTCP(cfg, ctxInfo)),
+		tcpRefused: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: TCPConnectionsRefused,
+			Help: "number of TCP connections that couldn't be established",
+		}, labelNamesTCP(cfg, ctxInfo)),
+		tcpRetransmits: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: TCPRetransmits,
+			Help: "number of retransmitted TCP segments of the closed connections",
+		}, labelNamesTCP(cfg, ctxInfo)),
+		tcpSRTT: newHistogramVec(prometheus.HistogramOpts{
+			Name:    TCPSRTT,
+			Help:    "smoothed round trip time of the closed TCP connections, in seconds",
+			Buckets: cfg.Buckets.SRTTHistogram,
+		}, labelNamesTCP(cfg, ctxInfo)),
+		tcpConnectionDuration: newHistogramVec(prometheus.HistogramOpts{
+			Name:    TCPConnectionDuration,
+			Help:    "duration of the closed TCP connections, in seconds",
+			Buckets: cfg.Buckets.ConnectionDurationHistogram,
+		}, labelNamesTCP(cfg, ctxInfo)),
 	}
 	mr.promConnect.Register(cfg.Port, cfg.Path,
 		mr.httpClientRequestSize,
+		mr.httpClientResponseSize,
 		mr.httpClientDuration,
 		mr.grpcClientDuration,
 		mr.sqlClientDuration,
 		mr.httpRequestSize,
+		mr.httpResponseSize,
+		mr.httpCPUTime,
 		mr.httpDuration,
-		mr.grpcDuration)
+		mr.grpcDuration,
+		mr.tcpSentBytes,
+		mr.tcpReceivedBytes,
+		mr.tcpOpened,
+		mr.tcpClosed,
+		mr.tcpRefused,
+		mr.tcpRetransmits,
+		mr.tcpSRTT,
+		mr.tcpConnectionDuration)
 	return mr
 }
 
@@ -148,17 +252,39 @@ func (r *metricsReporter) reportMetrics(input <-chan []request.Span) {
 }
 
 func (r *metricsReporter) observe(span *request.Span) {
+	if span.Aggregated != nil {
+		r.observeAggregated(span)
+		return
+	}
+	if span.Network != nil {
+		r.observeNetwork(span)
+		return
+	}
 	t := span.Timings()
 	duration := t.End.Sub(t.RequestStart).Seconds()
 	switch span.Type {
 	case request.EventTypeHTTP:
 		lv := r.labelValuesHTTP(span)
 		r.httpDuration.WithLabelValues(lv...).Observe(duration)
-		r.httpRequestSize.WithLabelValues(lv...).Observe(float64(span.ContentLength))
+		// the sizes are -1 if they are unknown
+		if span.ContentLength >= 0 {
+			r.httpRequestSize.WithLabelValues(lv...).Observe(float64(span.ContentLength))
+		}
+		if span.ResponseLength >= 0 {
+			r.httpResponseSize.WithLabelValues(lv...).Observe(float64(span.ResponseLength))
+		}
+		if span.CPUTime != nil {
+			r.httpCPUTime.WithLabelValues(lv...).Observe(span.CPUTime.OnCPU.Seconds())
+		}
 	case request.EventTypeHTTPClient:
 		lv := r.labelValuesHTTPClient(span)
 		r.httpClientDuration.WithLabelValues(lv...).Observe(duration)
-		r.httpClientRequestSize.WithLabelValues(lv...).Observe(float64(span.ContentLength))
+		if span.ContentLength >= 0 {
+			r.httpClientRequestSize.WithLabelValues(lv...).Observe(float64(span.ContentLength))
+		}
+		if span.ResponseLength >= 0 {
+			r.httpClientResponseSize.WithLabelValues(lv...).Observe(float64(span.ResponseLength))
+		}
 	case request.EventTypeGRPC:
 		r.grpcDuration.WithLabelValues(r.labelValuesGRPC(span)...).Observe(duration)
 	case request.EventTypeGRPCClient:
@@ -168,12 +294,65 @@ func (r *metricsReporter) observe(span *request.Span) {
 	}
 }
 
+// observeAggregated records the metrics that were aggregated in the kernel
+func (r *metricsReporter) observeAggregated(span *request.Span) {
+	switch span.Type {
+	case request.EventTypeHTTP:
+		lv := r.labelValuesHTTP(span)
+		r.httpDuration.WithLabelValues(lv...).Merge(&span.Aggregated.Duration)
+		r.httpRequestSize.WithLabelValues(lv...).Merge(&span.Aggregated.ContentLength)
+	case request.EventTypeHTTPClient:
+		lv := r.labelValuesHTTPClient(span)
+		r.httpClientDuration.WithLabelValues(lv...).Merge(&span.Aggregated.Duration)
+		r.httpClientRequestSize.WithLabelValues(lv...).Merge(&span.Aggregated.ContentLength)
+	}
+}
+
+// observeNetwork records the metrics of the TCP connections that were aggregated in the kernel
+func (r *metricsReporter) observeNetwork(span *request.Span) {
+	lv := r.labelValuesTCP(span)
+	nm := span.Network
+	add := func(c *prometheus.CounterVec, v uint64) {
+		if v > 0 {
+			c.WithLabelValues(lv...).Add(float64(v))
+		}
+	}
+	add(r.tcpSentBytes, nm.BytesSent)
+	add(r.tcpReceivedBytes, nm.BytesReceived)
+	add(r.tcpOpened, nm.Opened)
+	add(r.tcpClosed, nm.Closed)
+	add(r.tcpRefused, nm.Refused)
+	add(r.tcpRetransmits, nm.Retransmits)
+	r.tcpSRTT.WithLabelValues(lv...).Merge(&nm.SRTT)
+	r.tcpConnectionDuration.WithLabelValues(lv...).Merge(&nm.Duration)
+}
+
+// labelNamesTCP must return the label names in the same order as would be returned
+// by labelValuesTCP
+func labelNamesTCP(cfg *PrometheusConfig, ctxInfo *global.ContextInfo) []string {
+	names := []string{serviceNameKey, serviceNamespaceKey, sourceAddressKey, destAddressKey, destPortKey}
+	if ctxInfo.K8sDecoration {
+		names = appendK8sLabelNames(names, cfg, ctxInfo)
+	}
+	return names
+}
+
+// labelValuesTCP must return the label values in the same order as would be returned
+// by labelNamesTCP
+func (r *metricsReporter) labelValuesTCP(span *request.Span) []string {
+	values := []string{span.ServiceID.Name, span.ServiceID.Namespace, span.Peer, span.Host, strconv.Itoa(span.HostPort)}
+	if r.ctxInfo.K8sDecoration {
+		values = r.appendK8sLabelValues(values, span)
+	}
+	return values
+}
+
 // labelNamesSQL must return the label names in the same order as would be returned
 // by labelValuesSQL
-func labelNamesSQL(ctxInfo *global.ContextInfo) []string {
+func labelNamesSQL(cfg *PrometheusConfig, ctxInfo *global.ContextInfo) []string {
 	names := []string{serviceNameKey, serviceNamespaceKey, DBOperationKey}
 	if ctxInfo.K8sDecoration {
-		names = appendK8sLabelNames(names)
+		names = appendK8sLabelNames(names, cfg, ctxInfo)
 	}
 	return names
 }
@@ -183,7 +362,7 @@ func labelNamesSQL(ctxInfo *global.ContextInfo) []string {
 func (r *metricsReporter) labelValuesSQL(span *request.Span) []string {
 	values := []string{span.ServiceID.Name, span.ServiceID.Namespace, span.Method}
 	if r.ctxInfo.K8sDecoration {
-		values = appendK8sLabelValues(values, span)
+		values = r.appendK8sLabelValues(values, span)
 	}
 	return values
 }
@@ -197,7 +376,7 @@ func labelNamesGRPC(cfg *PrometheusConfig, ctxInfo *global.ContextInfo) []string
 		names = append(names, netSockPeerAddrKey)
 	}
 	if ctxInfo.K8sDecoration {
-		names = appendK8sLabelNames(names)
+		names = appendK8sLabelNames(names, cfg, ctxInfo)
 	}
 	return names
 }
@@ -211,7 +390,7 @@ func (r *metricsReporter) labelValuesGRPC(span *request.Span) []string {
 		values = append(values, span.Peer) // netSockPeerAddrKey
 	}
 	if r.ctxInfo.K8sDecoration {
-		values = appendK8sLabelValues(values, span)
+		values = r.appendK8sLabelValues(values, span)
 	}
 	return values
 }
@@ -223,8 +402,9 @@ func labelNamesHTTPClient(cfg *PrometheusConfig, ctxInfo *global.ContextInfo) []
 	if cfg.ReportPeerInfo {
 		names = append(names, netSockPeerNameKey, netSockPeerPortKey)
 	}
+	names = appendHeaderLabelNames(names, ctxInfo)
 	if ctxInfo.K8sDecoration {
-		names = appendK8sLabelNames(names)
+		names = appendK8sLabelNames(names, cfg, ctxInfo)
 	}
 	return names
 }
@@ -238,8 +418,9 @@ func (r *metricsReporter) labelValuesHTTPClient(span *request.Span) []string {
 		// netSockPeerAddrKey, netSockPeerPortKey
 		values = append(values, span.Host, strconv.Itoa(span.HostPort))
 	}
+	values = appendHeaderLabelValues(values, span, r.ctxInfo)
 	if r.ctxInfo.K8sDecoration {
-		values = appendK8sLabelValues(values, span)
+		values = r.appendK8sLabelValues(values, span)
 	}
 	return values
 }
@@ -257,8 +438,9 @@ func labelNamesHTTP(cfg *PrometheusConfig, ctxInfo *global.ContextInfo) []string
 	if ctxInfo.ReportRoutes {
 		names = append(names, httpRouteKey)
 	}
+	names = appendHeaderLabelNames(names, ctxInfo)
 	if ctxInfo.K8sDecoration {
-		names = appendK8sLabelNames(names)
+		names = appendK8sLabelNames(names, cfg, ctxInfo)
 	}
 	return names
 }
@@ -277,18 +459,49 @@ func (r *metricsReporter) labelValuesHTTP(span *request.Span) []string {
 	if r.ctxInfo.ReportRoutes {
 		values = append(values, span.Route) // httpRouteKey
 	}
+	values = appendHeaderLabelValues(values, span, r.ctxInfo)
 	if r.ctxInfo.K8sDecoration {
-		values = appendK8sLabelValues(values, span)
+		values = r.appendK8sLabelValues(values, span)
+	}
+	return values
+}
+
+func appendHeaderLabelNames(names []string, ctxInfo *global.ContextInfo) []string {
+	for _, header := range ctxInfo.MetricRequestHeaders {
+		names = append(names, httpRequestHeaderPrefix+sanitizeLabelName(header))
+	}
+	for _, header := range ctxInfo.MetricResponseHeaders {
+		names = append(names, httpResponseHeaderPrefix+sanitizeLabelName(header))
+	}
+	return names
+}
+
+func appendHeaderLabelValues(values []string, span *request.Span, ctxInfo *global.ContextInfo) []string {
+	for _, header := range ctxInfo.MetricRequestHeaders {
+		values = append(values, span.RequestHeaders[header])
+	}
+	for _, header := range ctxInfo.MetricResponseHeaders {
+		values = append(values, span.ResponseHeaders[header])
 	}
 	return values
 }
 
-func appendK8sLabelNames(names []string) []string {
+func appendK8sLabelNames(names []string, cfg *PrometheusConfig, ctxInfo *global.ContextInfo) []string {
 	names = append(names, k8sSrcNameKey, k8sSrcNamespaceKey, k8sDstNameKey, k8sDstNamespaceKey, k8sDstTypeKey)
+	if cfg.ReportPodMetadata {
+		names = append(names, k8sNamespaceNameKey, k8sPodNameKey, k8sPodUIDKey, k8sNodeNameKey, k8sContainerNameKey,
+			k8sDeploymentNameKey, k8sReplicaSetNameKey, k8sStatefulSetNameKey, k8sDaemonSetNameKey)
+	}
+	for _, label := range ctxInfo.K8sPodLabels {
+		names = append(names, k8sPodLabelPrefix+sanitizeLabelName(label))
+	}
+	for _, annotation := range ctxInfo.K8sPodAnnotations {
+		names = append(names, k8sPodAnnotationPrefix+sanitizeLabelName(annotation))
+	}
 	return names
 }
 
-func appendK8sLabelValues(values []string, span *request.Span) []string {
+func (r *metricsReporter) appendK8sLabelValues(values []string, span *request.Span) []string {
 	// k8sSrcNameKey, k8sSrcNamespaceKey, k8sDstNameKey, k8sDstNamespaceKey, k8sDstTypeKey
 	values = append(values,
 		span.Metadata[transform.SrcNameKey],
@@ -297,5 +510,37 @@ func appendK8sLabelValues(values []string, span *request.Span) []string {
 		span.Metadata[transform.DstNamespaceKey],
 		span.Metadata[transform.DstTypeKey],
 	)
+	// metadata of the Pod where the service runs
+	svcMeta := span.ServiceID.Metadata
+	if r.cfg.ReportPodMetadata {
+		values = append(values,
+			svcMeta[transform.NamespaceNameKey],
+			svcMeta[transform.PodNameKey],
+			svcMeta[transform.PodUIDKey],
+			svcMeta[transform.NodeNameKey],
+			svcMeta[transform.ContainerNameKey],
+			svcMeta[transform.DeploymentNameKey],
+			svcMeta[transform.ReplicaSetNameKey],
+			svcMeta[transform.StatefulSetNameKey],
+			svcMeta[transform.DaemonSetNameKey],
+		)
+	}
+	for _, label := range r.ctxInfo.K8sPodLabels {
+		values = append(values, svcMeta[transform.PodLabelPrefix+label])
+	}
+	for _, annotation := range r.ctxInfo.K8sPodAnnotations {
+		values = append(values, svcMeta[transform.PodAnnotationPrefix+annotation])
+	}
 	return values
 }
+
+// sanitizeLabelName replaces by underscores any character that is not valid
+// for a Prometheus label name (e.g. app.kubernetes.io/name -> app_kubernetes_io_name)
+func sanitizeLabelName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+}
@@ -25,6 +25,13 @@ func findLibraryVersions(elfFile *elf.File) (map[string]string, error) {
 	return modsMap, nil
 }
 
+// GoVersion returns the version of the Go runtime (e.g. go1.21.3) that was used to build
+// the provided executable. It returns an error if it is not a Go executable.
+func GoVersion(elfFile *elf.File) (string, error) {
+	goVersion, _, err := getGoDetails(elfFile)
+	return goVersion, err
+}
+
 // The build info blob left by the linker is identified by
 // a 16-byte header, consisting of buildInfoMagic (14 bytes),
 // the binary's pointer size (1 byte),
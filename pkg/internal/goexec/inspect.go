@@ -0,0 +1,61 @@
+package goexec
+
+import (
+	"debug/elf"
+	"sort"
+)
+
+// FieldOffsetSource describes where the offset of a struct field has been taken from
+type FieldOffsetSource string
+
+const (
+	FieldOffsetDWARF      FieldOffsetSource = "DWARF"
+	FieldOffsetPrefetched FieldOffsetSource = "offsets.json"
+	FieldOffsetNotFound   FieldOffsetSource = "not found"
+)
+
+// FieldOffsetInfo reports the offset of a struct field that is required by the eBPF tracers
+type FieldOffsetInfo struct {
+	// Constant is the name of the eBPF constant that is overridden with the offset
+	Constant string
+	Struct   string
+	Field    string
+	Offset   any
+	Source   FieldOffsetSource
+}
+
+// LibraryVersions returns the version of the Go runtime and the modules that
+// have been used to build the executable. The Go version is stored in the "go" key.
+func LibraryVersions(elfFile *elf.File) (map[string]string, error) {
+	return findLibraryVersions(elfFile)
+}
+
+// InspectFieldOffsets returns the offsets of all the struct fields that are required by
+// the eBPF tracers, as well as their source: the DWARF info of the executable or the
+// prefetched offsets database.
+func InspectFieldOffsets(elfFile *elf.File) ([]FieldOffsetInfo, error) {
+	fromDwarf := FieldOffsets{}
+	if data, err := elfFile.DWARF(); err == nil {
+		fromDwarf, _ = structMemberOffsetsFromDwarf(data)
+	}
+	all, err := structMemberOffsets(elfFile)
+	if err != nil {
+		return nil, err
+	}
+	var infos []FieldOffsetInfo
+	for strName, strInfo := range structMembers {
+		for fieldName, constant := range strInfo.fields {
+			info := FieldOffsetInfo{Constant: constant, Struct: strName, Field: fieldName, Source: FieldOffsetNotFound}
+			if offset, ok := fromDwarf[constant]; ok {
+				info.Offset, info.Source = offset, FieldOffsetDWARF
+			} else if offset, ok := all[constant]; ok {
+				info.Offset, info.Source = offset, FieldOffsetPrefetched
+			}
+			infos = append(infos, info)
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Constant < infos[j].Constant
+	})
+	return infos, nil
+}
@@ -1,6 +1,7 @@
 package goexec
 
 import (
+	"bytes"
 	"debug/elf"
 	"debug/gosym"
 	"errors"
@@ -40,6 +41,9 @@ func instrumentationPoints(elfF *elf.File, funcNames []string) (map[string]FuncO
 		if err != nil {
 			return nil, err
 		}
+		if len(allSyms) == 0 {
+			ilog.Debug("executable without symbol tables (stripped?). Recovering functions from the Go pclntab")
+		}
 	}
 
 	// check which functions in the symbol table correspond to any of the functions
@@ -55,9 +59,9 @@ func instrumentationPoints(elfF *elf.File, funcNames []string) (map[string]FuncO
 		if _, ok := functions[fName]; ok {
 			// when we don't have a Go symbol table, the executable is statically linked, we don't look for offsets
 			// using the gosym tab, we lookup offsets just like a regular elf file.
-			// we still need to find the return statements, since go linkage is non-standard we can't use uretprobe
-			if gosyms == nil {
-				handleStaticSymbol(fName, allOffsets, allSyms, ilog)
+			// we still need to find the return statements, since go linkage is non-standard we can't use uretprobe.
+			// Stripped executables don't have ELF symbols either, so we fall back to the pclntab info
+			if gosyms == nil && handleStaticSymbol(fName, allOffsets, allSyms, ilog) {
 				continue
 			}
 
@@ -75,7 +79,9 @@ func instrumentationPoints(elfF *elf.File, funcNames []string) (map[string]FuncO
 	return allOffsets, nil
 }
 
-func handleStaticSymbol(fName string, allOffsets map[string]FuncOffsets, allSyms map[string]sym, ilog *slog.Logger) {
+// handleStaticSymbol looks for the offsets of the function in the ELF symbols table. It returns
+// false if the function symbol is not found there.
+func handleStaticSymbol(fName string, allOffsets map[string]FuncOffsets, allSyms map[string]sym, ilog *slog.Logger) bool {
 	s, ok := allSyms[fName]
 
 	if ok && s.prog != nil {
@@ -83,18 +89,19 @@ func handleStaticSymbol(fName string, allOffsets map[string]FuncOffsets, allSyms
 		_, err := s.prog.ReadAt(data, int64(s.off-s.prog.Off))
 		if err != nil {
 			ilog.Error("error reading instructions for symbol", "symbol", fName, "error", err)
-			return
+			return true
 		}
 
 		returns, err := findReturnOffssets(s.off, data)
 		if err != nil {
 			ilog.Error("error finding returns for symbol", "symbol", fName, "offset", s.off-s.prog.Off, "size", s.len, "error", err)
-			return
+			return true
 		}
 		allOffsets[fName] = FuncOffsets{Start: s.off, Returns: returns}
-	} else {
-		ilog.Debug("can't find in elf symbol table", "symbol", fName, "ok", ok, "prog", s.prog)
+		return true
 	}
+	ilog.Debug("can't find in elf symbol table. Using the Go pclntab info", "symbol", fName, "ok", ok, "prog", s.prog)
+	return false
 }
 
 // findFuncOffset gets the start address and end addresses of the function whose symbol is passed
@@ -127,15 +134,11 @@ func findFuncOffset(f *gosym.Func, elfF *elf.File) (FuncOffsets, bool, error) {
 }
 
 func findGoSymbolTable(elfF *elf.File) (*gosym.Table, error) {
-	var err error
-	var pclndat []byte
-	// program counter line table
-	if sec := elfF.Section(".gopclntab"); sec != nil {
-		if pclndat, err = sec.Data(); err != nil {
-			return nil, fmt.Errorf("acquiring .gopclntab data: %w", err)
-		}
+	pclndat, err := findPclntab(elfF)
+	if err != nil {
+		return nil, err
 	}
-	pcln := gosym.NewLineTable(pclndat, elfF.Section(".text").Addr)
+	pcln := gosym.NewLineTable(pclndat, textStart(elfF))
 	// First argument accepts the .gosymtab ELF section.
 	// Since Go 1.3, .gosymtab is empty so we just pass an nil slice
 	symTab, err := gosym.NewTable(nil, pcln)
@@ -145,6 +148,76 @@ func findGoSymbolTable(elfF *elf.File) (*gosym.Table, error) {
 	return symTab, nil
 }
 
+// pclntabMagics are the headers of the program counter line tables for the
+// different Go versions (1.20+, 1.18, 1.16 and 1.2), in little endian.
+var pclntabMagics = [][]byte{
+	{0xf1, 0xff, 0xff, 0xff, 0x00, 0x00},
+	{0xf0, 0xff, 0xff, 0xff, 0x00, 0x00},
+	{0xfa, 0xff, 0xff, 0xff, 0x00, 0x00},
+	{0xfb, 0xff, 0xff, 0xff, 0x00, 0x00},
+}
+
+// findPclntab returns the contents of the Go program counter line table. It is usually
+// stored in the .gopclntab section but, in executables built with external linking
+// (e.g. PIE or CGO) it is placed in the .data.rel.ro section, so we need to look for it.
+func findPclntab(elfF *elf.File) ([]byte, error) {
+	if sec := elfF.Section(".gopclntab"); sec != nil {
+		data, err := sec.Data()
+		if err != nil {
+			return nil, fmt.Errorf("acquiring .gopclntab data: %w", err)
+		}
+		return data, nil
+	}
+	for _, name := range []string{".data.rel.ro", ".rodata"} {
+		sec := elfF.Section(name)
+		if sec == nil || sec.Type == elf.SHT_NOBITS {
+			continue
+		}
+		data, err := sec.Data()
+		if err != nil {
+			return nil, fmt.Errorf("acquiring %s data: %w", name, err)
+		}
+		if tab := searchPclntab(data); tab != nil {
+			slog.Debug("found Go pclntab", "component", "goexec.instructions", "section", name)
+			return tab, nil
+		}
+	}
+	return nil, errors.New("can't find the Go pclntab. Not a Go executable?")
+}
+
+// searchPclntab looks for a pclntab header whose quantum and pointer size values are valid
+func searchPclntab(data []byte) []byte {
+	for _, magic := range pclntabMagics {
+		for off := 0; off+8 <= len(data); {
+			i := bytes.Index(data[off:], magic)
+			if i < 0 {
+				break
+			}
+			tab := data[off+i:]
+			quantum, ptrSize := tab[6], tab[7]
+			if (quantum == 1 || quantum == 2 || quantum == 4) && (ptrSize == 4 || ptrSize == 8) {
+				return tab
+			}
+			off += i + 1
+		}
+	}
+	return nil
+}
+
+// textStart returns the start address of the executable code. If the section headers are
+// stripped, it is the address of the first executable segment
+func textStart(elfF *elf.File) uint64 {
+	if sec := elfF.Section(".text"); sec != nil {
+		return sec.Addr
+	}
+	for _, prog := range elfF.Progs {
+		if prog.Type == elf.PT_LOAD && (prog.Flags&elf.PF_X) != 0 {
+			return prog.Vaddr
+		}
+	}
+	return 0
+}
+
 func findExeSymbols(f *elf.File) (map[string]sym, error) {
 	addresses := map[string]sym{}
 	syms, err := f.Symbols()
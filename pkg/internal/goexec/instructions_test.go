@@ -0,0 +1,27 @@
+package goexec
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstrumentationPoints_Stripped(t *testing.T) {
+	// smallELF has been built with the -s -w flags, so it has neither symbol table nor DWARF info
+	offs, err := instrumentationPoints(smallELF, []string{"net/http.HandlerFunc.ServeHTTP", "nonexisting.Function"})
+	require.NoError(t, err)
+	require.Contains(t, offs, "net/http.HandlerFunc.ServeHTTP")
+	assert.NotContains(t, offs, "nonexisting.Function")
+	fn := offs["net/http.HandlerFunc.ServeHTTP"]
+	assert.NotZero(t, fn.Start)
+	assert.NotEmpty(t, fn.Returns)
+}
+
+func TestSearchPclntab(t *testing.T) {
+	// invalid quantum and pointer size values are ignored
+	data := []byte{0x01, 0xf1, 0xff, 0xff, 0xff, 0x00, 0x00, 0x07, 0x08,
+		0xf1, 0xff, 0xff, 0xff, 0x00, 0x00, 0x01, 0x08, 0xaa}
+	assert.Equal(t, data[9:], searchPclntab(data))
+	assert.Nil(t, searchPclntab([]byte{0xf1, 0xff, 0xff, 0xff, 0x00, 0x00, 0x01, 0x03}))
+}
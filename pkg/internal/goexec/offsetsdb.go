@@ -0,0 +1,148 @@
+package goexec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/grafana/go-offsets-tracker/pkg/offsets"
+	"github.com/hashicorp/go-version"
+)
+
+// offsetsDB is the database of prefetched struct field offsets: the embedded offsets.json
+// file, optionally overridden by the contents of an user-provided file.
+var offsetsDB struct {
+	mt    sync.Mutex
+	track *offsets.Track
+}
+
+func prefetchedOffsetsDB() (*offsets.Track, error) {
+	offsetsDB.mt.Lock()
+	defer offsetsDB.mt.Unlock()
+	if offsetsDB.track == nil {
+		track, err := offsets.Read(bytes.NewBufferString(prefetchedOffsets))
+		if err != nil {
+			return nil, fmt.Errorf("reading offsets file contents: %w", err)
+		}
+		offsetsDB.track = track
+	}
+	return offsetsDB.track, nil
+}
+
+// LoadOffsetsFile merges the contents of the provided offsets file into the embedded offsets
+// database. The offsets from the file override the embedded offsets for the same struct
+// fields and versions.
+func LoadOffsetsFile(path string) error {
+	extra, err := offsets.Open(path)
+	if err != nil {
+		return fmt.Errorf("loading offsets file %s: %w", path, err)
+	}
+	db, err := prefetchedOffsetsDB()
+	if err != nil {
+		return err
+	}
+	offsetsDB.mt.Lock()
+	defer offsetsDB.mt.Unlock()
+	if err := MergeOffsets(db, extra); err != nil {
+		return fmt.Errorf("merging offsets file %s: %w", path, err)
+	}
+	log().Info("loaded extra offsets file", "path", path, "structs", len(extra.Data))
+	return nil
+}
+
+// MergeOffsets merges the src offsets into dst. For the same struct field, the offsets
+// of both sources are combined, and the src offsets override the dst offsets with the same
+// version. Redundant entries (those with the same offset as their previous version) are removed.
+func MergeOffsets(dst, src *offsets.Track) error {
+	if dst.Data == nil {
+		dst.Data = map[string]offsets.Struct{}
+	}
+	for strName, srcStruct := range src.Data {
+		dstStruct, ok := dst.Data[strName]
+		if !ok {
+			dstStruct = offsets.Struct{}
+			dst.Data[strName] = dstStruct
+		}
+		for fieldName, srcField := range srcStruct {
+			merged, err := mergeField(dstStruct[fieldName], srcField)
+			if err != nil {
+				return fmt.Errorf("%s.%s: %w", strName, fieldName, err)
+			}
+			dstStruct[fieldName] = merged
+		}
+	}
+	return nil
+}
+
+type versionedOffset struct {
+	version *version.Version
+	offsets.Versioned
+}
+
+func mergeField(dst, src offsets.Field) (offsets.Field, error) {
+	bySince := map[string]versionedOffset{}
+	for _, vs := range [][]offsets.Versioned{dst.Offsets, src.Offsets} {
+		for _, o := range vs {
+			v, err := version.NewVersion(o.Since)
+			if err != nil {
+				return offsets.Field{}, fmt.Errorf("invalid version %q: %w", o.Since, err)
+			}
+			// normalizing the key so 1.20 and 1.20.0 are considered the same version
+			bySince[v.String()] = versionedOffset{version: v, Versioned: o}
+		}
+	}
+	sorted := make([]versionedOffset, 0, len(bySince))
+	for _, o := range bySince {
+		sorted = append(sorted, o)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].version.LessThan(sorted[j].version)
+	})
+	if len(sorted) == 0 {
+		return dst, nil
+	}
+	merged := offsets.Field{}
+	for _, o := range sorted {
+		if n := len(merged.Offsets); n > 0 && merged.Offsets[n-1].Offset == o.Offset {
+			continue
+		}
+		merged.Offsets = append(merged.Offsets, o.Versioned)
+	}
+	merged.Versions.Oldest = minVersion(dst.Versions.Oldest, src.Versions.Oldest, sorted[0].Since)
+	merged.Versions.Newest = maxVersion(dst.Versions.Newest, src.Versions.Newest, sorted[len(sorted)-1].Since)
+	return merged, nil
+}
+
+func minVersion(vs ...string) string {
+	return pickVersion(vs, func(a, b *version.Version) bool { return a.LessThan(b) })
+}
+
+func maxVersion(vs ...string) string {
+	return pickVersion(vs, func(a, b *version.Version) bool { return a.GreaterThan(b) })
+}
+
+// pickVersion returns the version that is preferred over all the others, ignoring empty or invalid versions
+func pickVersion(vs []string, preferred func(a, b *version.Version) bool) string {
+	var picked string
+	var pickedVersion *version.Version
+	for _, v := range vs {
+		parsed, err := version.NewVersion(v)
+		if err != nil {
+			continue
+		}
+		if pickedVersion == nil || preferred(parsed, pickedVersion) {
+			picked, pickedVersion = v, parsed
+		}
+	}
+	return picked
+}
+
+// WriteOffsets writes the offsets database in the same format as the embedded offsets.json file
+func WriteOffsets(out io.Writer, track *offsets.Track) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(track)
+}
@@ -0,0 +1,129 @@
+package goexec
+
+import (
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/grafana/go-offsets-tracker/pkg/offsets"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeOffsets(t *testing.T) {
+	dst := &offsets.Track{Data: map[string]offsets.Struct{
+		"net/http.response": {"status": offsets.Field{
+			Versions: offsets.VersionInfo{Oldest: "1.17.0", Newest: "1.21.3"},
+			Offsets:  []offsets.Versioned{{Offset: 112, Since: "1.17.0"}, {Offset: 120, Since: "1.20.0"}},
+		}},
+	}}
+	src := &offsets.Track{Data: map[string]offsets.Struct{
+		"net/http.response": {
+			"status": offsets.Field{
+				Versions: offsets.VersionInfo{Oldest: "1.18.2", Newest: "1.23.1"},
+				Offsets: []offsets.Versioned{
+					// redundant entry, as 1.17.0 already defines the same offset
+					{Offset: 112, Since: "1.18.2"},
+					// overrides the existing entry
+					{Offset: 124, Since: "1.20"},
+					{Offset: 128, Since: "1.23.1"},
+				},
+			},
+			"req": offsets.Field{
+				Versions: offsets.VersionInfo{Oldest: "1.23.1", Newest: "1.23.1"},
+				Offsets:  []offsets.Versioned{{Offset: 8, Since: "1.23.1"}},
+			},
+		},
+	}}
+	require.NoError(t, MergeOffsets(dst, src))
+	assert.Equal(t, offsets.Struct{
+		"status": offsets.Field{
+			Versions: offsets.VersionInfo{Oldest: "1.17.0", Newest: "1.23.1"},
+			Offsets: []offsets.Versioned{
+				{Offset: 112, Since: "1.17.0"}, {Offset: 124, Since: "1.20"}, {Offset: 128, Since: "1.23.1"},
+			},
+		},
+		"req": offsets.Field{
+			Versions: offsets.VersionInfo{Oldest: "1.23.1", Newest: "1.23.1"},
+			Offsets:  []offsets.Versioned{{Offset: 8, Since: "1.23.1"}},
+		},
+	}, dst.Data["net/http.response"])
+
+	assert.Error(t, MergeOffsets(dst, &offsets.Track{Data: map[string]offsets.Struct{
+		"foo": {"bar": offsets.Field{Offsets: []offsets.Versioned{{Since: "not-a-version"}}}},
+	}}))
+}
+
+func TestLoadOffsetsFile(t *testing.T) {
+	defer func() {
+		// restore the embedded database for the rest of tests
+		offsetsDB.mt.Lock()
+		offsetsDB.track = nil
+		offsetsDB.mt.Unlock()
+	}()
+	file := path.Join(t.TempDir(), "offsets.json")
+	require.NoError(t, os.WriteFile(file, []byte(`{"data":{"net/http.response":{"status":{
+		"versions":{"oldest":"99.0.0","newest":"99.0.0"},
+		"offsets":[{"offset":999,"since":"99.0.0"}]}}}}`), 0o644))
+	require.NoError(t, LoadOffsetsFile(file))
+
+	db, err := prefetchedOffsetsDB()
+	require.NoError(t, err)
+	offset, ok := db.Find("net/http.response", "status", "99.1.0")
+	require.True(t, ok)
+	assert.Equal(t, uint64(999), offset)
+	// embedded offsets for older versions are kept
+	offset, ok = db.Find("net/http.response", "status", "1.21.0")
+	require.True(t, ok)
+	assert.Equal(t, uint64(120), offset)
+
+	assert.Error(t, LoadOffsetsFile(path.Join(t.TempDir(), "not-found.json")))
+}
+
+func TestOffsetsFromSource_GoRoot(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test that builds a Go program against the local GOROOT in short mode")
+	}
+	out, err := exec.Command("go", "env", "GOROOT").Output()
+	require.NoError(t, err)
+	goRoot := strings.TrimSpace(string(out))
+	goVersion, err := goRootVersion(goRoot)
+	require.NoError(t, err)
+	goVersion = offsetsDBVersion(goVersion)
+
+	track, err := OffsetsFromSource(goRoot, "")
+	require.NoError(t, err)
+	field, ok := track.Data["net/http.Request"]["URL"]
+	require.True(t, ok)
+	assert.Equal(t, offsets.VersionInfo{Oldest: goVersion, Newest: goVersion}, field.Versions)
+	assert.Equal(t, []offsets.Versioned{{Offset: 16, Since: goVersion}}, field.Offsets)
+	// libraries not used by the program are not reported
+	assert.NotContains(t, track.Data, "google.golang.org/grpc.ClientConn")
+}
+
+func TestOffsetsDBVersion(t *testing.T) {
+	for version, expected := range map[string]string{
+		"1.21.3":                             "1.21.3",
+		"v1.60.1":                            "1.60.1",
+		"v1.60.0-rc.1":                       "1.60.0",
+		"v0.0.0-20231010123456-abcdef123456": "0.0.0",
+		"v1.2.4-0.20231010123456-abcdef1234": "1.2.4",
+		"1.22rc1":                            "1.22",
+		"go1.21beta2":                        "1.21",
+		"devel go1.23-abcdef1 Mon Jan 1":     "1.23",
+		"unknown":                            "unknown",
+	} {
+		assert.Equal(t, expected, offsetsDBVersion(version), version)
+	}
+}
+
+func TestOffsetsFromSource_UnsupportedModule(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(dir, "go.mod"), []byte("module example.com/foo\n"), 0o644))
+	_, err := OffsetsFromSource(dir, "v1.0.0")
+	assert.ErrorContains(t, err, "unsupported library example.com/foo")
+	_, err = OffsetsFromSource(dir, "")
+	assert.ErrorContains(t, err, "version")
+}
@@ -0,0 +1,212 @@
+package goexec
+
+import (
+	"bufio"
+	"bytes"
+	"debug/elf"
+	"errors"
+	"fmt"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/grafana/go-offsets-tracker/pkg/offsets"
+)
+
+// inspectionPrograms are the Go programs that are compiled against a local library source tree
+// to get the offsets of its struct fields. They must make use of all the structs that are
+// listed in structMembers for that library, so their types are included in the DWARF info.
+var inspectionPrograms = map[string]string{
+	"go": `package main
+
+import (
+	"context"
+	"net/http"
+)
+
+func main() {
+	ctx := context.WithValue(context.Background(), "key", "value")
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://localhost/", nil)
+	_, _ = http.DefaultClient.Do(req)
+	_ = http.ListenAndServe(":0", http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+}
+`,
+	"google.golang.org/grpc": `package main
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+func main() {
+	conn, _ := grpc.Dial("localhost:0", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	_ = conn.Invoke(context.Background(), "/foo", nil, nil)
+	_ = status.Error(codes.OK, "")
+	_ = grpc.NewServer().Serve(nil)
+}
+`,
+}
+
+// OffsetsFromExecutable returns the offsets of the struct fields that are required by Beyla,
+// as found in the DWARF info of the provided Go executable. The offsets are tagged with the versions
+// of the libraries that are stored in the executable build info. The libVersions argument
+// allows overriding them.
+func OffsetsFromExecutable(elfFile *elf.File, libVersions map[string]string) (*offsets.Track, error) {
+	data, err := elfFile.DWARF()
+	if err != nil {
+		return nil, fmt.Errorf("executable without DWARF info: %w", err)
+	}
+	fieldOffsets, _ := structMemberOffsetsFromDwarf(data)
+	versions, err := findLibraryVersions(elfFile)
+	if err != nil {
+		return nil, err
+	}
+	for lib, version := range libVersions {
+		versions[lib] = version
+	}
+	track := &offsets.Track{Data: map[string]offsets.Struct{}}
+	for strName, strInfo := range structMembers {
+		version, ok := versions[strInfo.lib]
+		if !ok {
+			// the library is not used by the executable
+			continue
+		}
+		version = offsetsDBVersion(version)
+		for fieldName, constant := range strInfo.fields {
+			offset, ok := fieldOffsets[constant]
+			if !ok {
+				continue
+			}
+			if track.Data[strName] == nil {
+				track.Data[strName] = offsets.Struct{}
+			}
+			track.Data[strName][fieldName] = offsets.Field{
+				Versions: offsets.VersionInfo{Oldest: version, Newest: version},
+				Offsets:  []offsets.Versioned{{Offset: offset.(uint64), Since: version}},
+			}
+		}
+	}
+	return track, nil
+}
+
+// baseVersion matches the numeric part of a version, which precedes any pre-release or build suffix
+var baseVersion = regexp.MustCompile(`\d+(\.\d+)*`)
+
+// offsetsDBVersion converts a module or Go version to the format of the offsets database: its base
+// version, without "v" or "go" prefixes nor pre-release and build suffixes (e.g. v1.60.0-rc.1 is 1.60.0,
+// go1.22rc1 is 1.22 and the v0.0.0-2023... pseudo-versions are 0.0.0)
+func offsetsDBVersion(version string) string {
+	if base := baseVersion.FindString(version); base != "" {
+		return base
+	}
+	return version
+}
+
+// OffsetsFromSource compiles a program against a local source tree and returns the offsets of its
+// struct fields. The source tree can be either a Go distribution (GOROOT), whose version is taken
+// from its VERSION file, or a library module (e.g. google.golang.org/grpc), whose version must be
+// provided. The dependencies of the library must be available in the Go modules cache or
+// through the configured GOPROXY.
+func OffsetsFromSource(sourceDir, version string) (*offsets.Track, error) {
+	sourceDir, err := filepath.Abs(sourceDir)
+	if err != nil {
+		return nil, err
+	}
+	buildDir, err := os.MkdirTemp("", "beyla-offsets")
+	if err != nil {
+		return nil, fmt.Errorf("creating build directory: %w", err)
+	}
+	defer os.RemoveAll(buildDir)
+
+	goCmd := "go"
+	// the build directory has no vendor folder
+	env := append(os.Environ(), "GOFLAGS=-mod=mod")
+	var lib, goMod string
+	if goVersion, err := goRootVersion(sourceDir); err == nil {
+		lib, goCmd = "go", filepath.Join(sourceDir, "bin", "go")
+		if version == "" {
+			version = goVersion
+		}
+		env = append(env, "GOROOT="+sourceDir, "GOTOOLCHAIN=local")
+		goMod = "module beylaoffsets\n\ngo 1.17\n"
+	} else {
+		if lib, err = modulePath(sourceDir); err != nil {
+			return nil, fmt.Errorf("%s is neither a Go distribution nor a Go module: %w", sourceDir, err)
+		}
+		if version == "" {
+			return nil, fmt.Errorf("the version of the %s module must be provided", lib)
+		}
+		goMod = fmt.Sprintf("module beylaoffsets\n\ngo 1.17\n\nrequire %s v0.0.0\n\nreplace %s => %s\n",
+			lib, lib, sourceDir)
+	}
+	program, ok := inspectionPrograms[lib]
+	if !ok {
+		return nil, fmt.Errorf("unsupported library %s. Supported libraries: %s",
+			lib, strings.Join(supportedLibraries(), ", "))
+	}
+	if err := os.WriteFile(filepath.Join(buildDir, "go.mod"), []byte(goMod), 0o644); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(buildDir, "main.go"), []byte(program), 0o644); err != nil {
+		return nil, err
+	}
+	exePath := filepath.Join(buildDir, "inspected")
+	for _, args := range [][]string{{"mod", "tidy"}, {"build", "-o", exePath, "."}} {
+		cmd := osexec.Command(goCmd, args...)
+		cmd.Dir, cmd.Env = buildDir, env
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return nil, fmt.Errorf("running go %s: %w\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+	elfFile, err := elf.Open(exePath)
+	if err != nil {
+		return nil, err
+	}
+	defer elfFile.Close()
+	return OffsetsFromExecutable(elfFile, map[string]string{lib: version})
+}
+
+// goRootVersion returns the version of a Go distribution (without "go" prefix), as
+// read from its VERSION file
+func goRootVersion(dir string) (string, error) {
+	if _, err := os.Stat(filepath.Join(dir, "src", "net", "http")); err != nil {
+		return "", err
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "VERSION"))
+	if err != nil {
+		return "", err
+	}
+	line, _, _ := strings.Cut(string(content), "\n")
+	return strings.TrimPrefix(strings.TrimSpace(line), "go"), nil
+}
+
+// modulePath returns the module path as defined in the go.mod file of the provided directory
+func modulePath(dir string) (string, error) {
+	content, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return "", err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		if mod, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "module "); ok {
+			return strings.Trim(strings.TrimSpace(mod), `"`), nil
+		}
+	}
+	return "", errors.New("module path not found in go.mod")
+}
+
+func supportedLibraries() []string {
+	libs := make([]string, 0, len(inspectionPrograms))
+	for lib := range inspectionPrograms {
+		libs = append(libs, lib)
+	}
+	sort.Strings(libs)
+	return libs
+}
@@ -1,7 +1,6 @@
 package goexec
 
 import (
-	"bytes"
 	"debug/dwarf"
 	"debug/elf"
 	_ "embed"
@@ -10,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/grafana/go-offsets-tracker/pkg/offsets"
+	"github.com/hashicorp/go-version"
 )
 
 func log() *slog.Logger {
@@ -51,14 +51,18 @@ var structMembers = map[string]structInfo{
 	"net/http.response": {
 		lib: "go",
 		fields: map[string]string{
-			"status": "status_ptr_pos",
-			"req":    "resp_req_pos",
+			"status":        "status_ptr_pos",
+			"req":           "resp_req_pos",
+			"handlerHeader": "handler_header_ptr_pos",
+			"written":       "resp_written_pos",
 		},
 	},
 	"net/http.Response": {
 		lib: "go",
 		fields: map[string]string{
-			"StatusCode": "status_code_ptr_pos",
+			"StatusCode":    "status_code_ptr_pos",
+			"Header":        "resp_header_ptr_pos",
+			"ContentLength": "resp_content_length_ptr_pos",
 		},
 	},
 	"google.golang.org/grpc/internal/transport.Stream": {
@@ -123,6 +127,7 @@ func structMemberOffsets(elfFile *elf.File) (FieldOffsets, error) {
 			return offs, nil
 		}
 	} else {
+		log().Debug("executable without DWARF info (built with -w flag?)", "error", err)
 		// initialize empty offsets
 		offs = FieldOffsets{}
 	}
@@ -135,9 +140,9 @@ func structMemberOffsets(elfFile *elf.File) (FieldOffsets, error) {
 
 func structMemberPreFetchedOffsets(elfFile *elf.File, fieldOffsets FieldOffsets) (FieldOffsets, error) {
 	log := log().With("function", "structMemberPreFetchedOffsets")
-	offs, err := offsets.Read(bytes.NewBufferString(prefetchedOffsets))
+	offs, err := prefetchedOffsetsDB()
 	if err != nil {
-		return nil, fmt.Errorf("reading offsets file contents: %w", err)
+		return nil, err
 	}
 	libVersions, err := findLibraryVersions(elfFile)
 	if err != nil {
@@ -161,12 +166,17 @@ func structMemberPreFetchedOffsets(elfFile *elf.File, fieldOffsets FieldOffsets)
 
 		for fieldName, constantName := range strInfo.fields {
 			// look the version of the required field in the offsets.json memory copy
-			offset, ok := offs.Find(strName, fieldName, version)
+			offset, fallback, ok := findFieldOffset(offs, strName, fieldName, version)
 			if !ok {
 				log.Debug("can't find offsets for field",
 					"lib", strInfo.lib, "name", strName, "field", fieldName, "version", version)
 				continue
 			}
+			if fallback != "" {
+				log.Info("library version not tracked in the offsets database. Using offset from the nearest version",
+					"lib", strInfo.lib, "name", strName, "field", fieldName, "version", version,
+					"reason", fallback, "offset", offset)
+			}
 			log.Debug("found offset", "constantName", constantName, "offset", offset)
 			fieldOffsets[constantName] = offset
 		}
@@ -174,6 +184,35 @@ func structMemberPreFetchedOffsets(elfFile *elf.File, fieldOffsets FieldOffsets)
 	return fieldOffsets, nil
 }
 
+// findFieldOffset looks for the offset of a struct field for the given library version.
+// If the version is not in the range of tracked versions, or it can't be parsed, it
+// returns the offset of the nearest tracked version, as well as the reason of this fallback.
+func findFieldOffset(offs *offsets.Track, strName, fieldName, libVersion string) (uint64, string, bool) {
+	field, ok := offs.Data[strName][fieldName]
+	if !ok || len(field.Offsets) == 0 {
+		return 0, "", false
+	}
+	// offsets are sorted from older to newer versions
+	oldest, newest := &field.Offsets[0], &field.Offsets[len(field.Offsets)-1]
+	target, err := version.NewVersion(libVersion)
+	if err != nil {
+		return newest.Offset, fmt.Sprintf("can't parse version %q. Assuming newest tracked version %s",
+			libVersion, field.Versions.Newest), true
+	}
+	if target.LessThan(version.Must(version.NewVersion(oldest.Since))) {
+		return oldest.Offset, fmt.Sprintf("older than oldest tracked version %s", oldest.Since), true
+	}
+	offset, ok := field.GetOffset(libVersion)
+	if !ok {
+		return 0, "", false
+	}
+	if newestTracked, err := version.NewVersion(field.Versions.Newest); err == nil &&
+		target.GreaterThan(newestTracked) {
+		return offset, fmt.Sprintf("newer than newest tracked version %s", field.Versions.Newest), true
+	}
+	return offset, "", true
+}
+
 // structMemberOffsetsFromDwarf reads the executable dwarf information to get
 // the offsets specified in the structMembers map
 func structMemberOffsetsFromDwarf(data *dwarf.Data) (FieldOffsets, map[string]struct{}) {
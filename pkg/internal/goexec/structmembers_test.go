@@ -10,6 +10,7 @@ import (
 	"path"
 	"testing"
 
+	"github.com/grafana/go-offsets-tracker/pkg/offsets"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -114,3 +115,32 @@ func TestGoOffsetsFromDwarf_ErrorIfConstantNotFound(t *testing.T) {
 	_, missing := structMemberOffsetsFromDwarf(debugData)
 	assert.Contains(t, missing, "tralara")
 }
+
+func TestFindFieldOffset(t *testing.T) {
+	track := &offsets.Track{Data: map[string]offsets.Struct{
+		"net/http.response": {"status": offsets.Field{
+			Versions: offsets.VersionInfo{Oldest: "1.17.0", Newest: "1.21.3"},
+			Offsets:  []offsets.Versioned{{Offset: 112, Since: "1.17.0"}, {Offset: 120, Since: "1.20.0"}},
+		}},
+	}}
+	for _, tc := range []struct {
+		version  string
+		offset   uint64
+		fallback bool
+	}{
+		{version: "1.18.2", offset: 112},
+		{version: "1.21.3", offset: 120},
+		{version: "1.16.0", offset: 112, fallback: true},
+		{version: "1.23.1", offset: 120, fallback: true},
+		{version: "devel +b3a4ef", offset: 120, fallback: true},
+	} {
+		offset, fallback, ok := findFieldOffset(track, "net/http.response", "status", tc.version)
+		require.True(t, ok, tc.version)
+		assert.Equal(t, tc.offset, offset, tc.version)
+		assert.Equal(t, tc.fallback, fallback != "", tc.version)
+	}
+	_, _, ok := findFieldOffset(track, "net/http.response", "nonexisting", "1.20.0")
+	assert.False(t, ok)
+	_, _, ok = findFieldOffset(track, "nonexisting", "status", "1.20.0")
+	assert.False(t, ok)
+}
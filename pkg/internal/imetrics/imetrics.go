@@ -16,6 +16,21 @@ type Reporter interface {
 	Start(ctx context.Context)
 	// TracerFlush is invoked every time the eBPF tracer flushes a group of len traces.
 	TracerFlush(len int)
+	// TracerRingBufDrops is invoked periodically with the number of events that a given eBPF tracer
+	// couldn't submit because its ring buffer was full
+	TracerRingBufDrops(tracer string, drops int)
+	// TracerSampledOut is invoked periodically with the number of events that were discarded by the
+	// adaptive sampling of a given eBPF tracer
+	TracerSampledOut(tracer string, events int)
+	// TracerAggregationDrops is invoked periodically with the number of requests whose metrics couldn't
+	// be aggregated in the kernel by a given eBPF tracer, because its aggregation maps were full
+	TracerAggregationDrops(tracer string, requests int)
+	// TracerRingBufFill is invoked periodically with the fill ratio (from 0 to 1) of the ring buffer
+	// of a given eBPF tracer
+	TracerRingBufFill(tracer string, ratio float64)
+	// TracerSamplingRate is invoked periodically with the current sampling rate of a given eBPF
+	// tracer, where 1 out of rate events is submitted
+	TracerSamplingRate(tracer string, rate int)
 	// OTELMetricExport is invoked every time the OpenTelemetry Metrics exporter successfully exports metrics to
 	// a remote collector. It accounts the length, in metrics, for each invocation.
 	OTELMetricExport(len int)
@@ -33,10 +48,15 @@ type Reporter interface {
 // NoopReporter is a metrics Reporter that just does nothing
 type NoopReporter struct{}
 
-func (n NoopReporter) Start(_ context.Context)       {}
-func (n NoopReporter) TracerFlush(_ int)             {}
-func (n NoopReporter) OTELMetricExport(_ int)        {}
-func (n NoopReporter) OTELMetricExportError(_ error) {}
-func (n NoopReporter) OTELTraceExport(_ int)         {}
-func (n NoopReporter) OTELTraceExportError(_ error)  {}
-func (n NoopReporter) PrometheusRequest(_, _ string) {}
+func (n NoopReporter) Start(_ context.Context)                {}
+func (n NoopReporter) TracerFlush(_ int)                      {}
+func (n NoopReporter) TracerRingBufDrops(_ string, _ int)     {}
+func (n NoopReporter) TracerSampledOut(_ string, _ int)       {}
+func (n NoopReporter) TracerAggregationDrops(_ string, _ int) {}
+func (n NoopReporter) TracerRingBufFill(_ string, _ float64)  {}
+func (n NoopReporter) TracerSamplingRate(_ string, _ int)     {}
+func (n NoopReporter) OTELMetricExport(_ int)                 {}
+func (n NoopReporter) OTELMetricExportError(_ error)          {}
+func (n NoopReporter) OTELTraceExport(_ int)                  {}
+func (n NoopReporter) OTELTraceExportError(_ error)           {}
+func (n NoopReporter) PrometheusRequest(_, _ string)          {}
@@ -22,6 +22,11 @@ type PrometheusConfig struct {
 type PrometheusReporter struct {
 	connector            *connector.PrometheusManager
 	tracerFlushes        prometheus.Histogram
+	tracerRingBufDrops   *prometheus.CounterVec
+	tracerSampledOut     *prometheus.CounterVec
+	tracerAggrDrops      *prometheus.CounterVec
+	tracerRingBufFill    *prometheus.GaugeVec
+	tracerSamplingRate   *prometheus.GaugeVec
 	otelMetricExports    prometheus.Counter
 	otelMetricExportErrs *prometheus.CounterVec
 	otelTraceExports     prometheus.Counter
@@ -37,6 +42,26 @@ func NewPrometheusReporter(cfg *PrometheusConfig, manager *connector.PrometheusM
 			Help:    "length of the groups of traces flushed from the eBPF tracer to the next pipeline stage",
 			Buckets: pipelineBufferLengths,
 		}),
+		tracerRingBufDrops: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ebpf_tracer_ringbuf_drops",
+			Help: "events that couldn't be submitted by the eBPF tracer because its ring buffer was full",
+		}, []string{"tracer"}),
+		tracerSampledOut: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ebpf_tracer_sampled_out",
+			Help: "events that were discarded by the adaptive sampling of the eBPF tracer",
+		}, []string{"tracer"}),
+		tracerAggrDrops: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ebpf_tracer_aggregation_drops",
+			Help: "requests whose metrics couldn't be aggregated in the kernel by the eBPF tracer because its maps were full",
+		}, []string{"tracer"}),
+		tracerRingBufFill: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "ebpf_tracer_ringbuf_fill_ratio",
+			Help: "fill ratio, from 0 to 1, of the ring buffer of the eBPF tracer",
+		}, []string{"tracer"}),
+		tracerSamplingRate: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "ebpf_tracer_sampling_rate",
+			Help: "current adaptive sampling rate of the eBPF tracer, which submits 1 out of N events",
+		}, []string{"tracer"}),
 		otelMetricExports: prometheus.NewCounter(prometheus.CounterOpts{
 			Name: "otel_metric_exports",
 			Help: "length of the metric batches submitted to the remote OTEL collector",
@@ -60,6 +85,11 @@ func NewPrometheusReporter(cfg *PrometheusConfig, manager *connector.PrometheusM
 	}
 	manager.Register(cfg.Port, cfg.Path,
 		pr.tracerFlushes,
+		pr.tracerRingBufDrops,
+		pr.tracerSampledOut,
+		pr.tracerAggrDrops,
+		pr.tracerRingBufFill,
+		pr.tracerSamplingRate,
 		pr.otelMetricExports,
 		pr.otelMetricExportErrs,
 		pr.otelTraceExports,
@@ -77,6 +107,26 @@ func (p *PrometheusReporter) TracerFlush(len int) {
 	p.tracerFlushes.Observe(float64(len))
 }
 
+func (p *PrometheusReporter) TracerRingBufDrops(tracer string, drops int) {
+	p.tracerRingBufDrops.WithLabelValues(tracer).Add(float64(drops))
+}
+
+func (p *PrometheusReporter) TracerSampledOut(tracer string, events int) {
+	p.tracerSampledOut.WithLabelValues(tracer).Add(float64(events))
+}
+
+func (p *PrometheusReporter) TracerAggregationDrops(tracer string, requests int) {
+	p.tracerAggrDrops.WithLabelValues(tracer).Add(float64(requests))
+}
+
+func (p *PrometheusReporter) TracerRingBufFill(tracer string, ratio float64) {
+	p.tracerRingBufFill.WithLabelValues(tracer).Set(ratio)
+}
+
+func (p *PrometheusReporter) TracerSamplingRate(tracer string, rate int) {
+	p.tracerSamplingRate.WithLabelValues(tracer).Set(float64(rate))
+}
+
 func (p *PrometheusReporter) OTELMetricExport(len int) {
 	p.otelMetricExports.Add(float64(len))
 }
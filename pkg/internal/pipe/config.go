@@ -3,6 +3,7 @@ package pipe
 import (
 	"fmt"
 	"io"
+	"os"
 	"time"
 
 	"github.com/caarlos0/env/v9"
@@ -14,6 +15,7 @@ import (
 	"github.com/grafana/beyla/pkg/internal/export/otel"
 	"github.com/grafana/beyla/pkg/internal/export/prom"
 	"github.com/grafana/beyla/pkg/internal/imetrics"
+	"github.com/grafana/beyla/pkg/internal/split"
 	"github.com/grafana/beyla/pkg/internal/transform"
 )
 
@@ -21,9 +23,12 @@ var defaultConfig = Config{
 	ChannelBufferLen: 10,
 	LogLevel:         "INFO",
 	EBPF: ebpfcommon.TracerConfig{
-		BatchLength:  100,
-		BatchTimeout: time.Second,
-		BpfBaseDir:   "/var/run/beyla",
+		BatchLength:         100,
+		BatchTimeout:        time.Second,
+		WakeupTimeout:       200 * time.Millisecond,
+		BpfBaseDir:          "/var/run/beyla",
+		AggregationInterval: time.Second,
+		RequestCaptureSize:  1024,
 	},
 	Metrics: otel.MetricsConfig{
 		Protocol:          otel.ProtocolUnset,
@@ -57,14 +62,20 @@ var defaultConfig = Config{
 		InformersSyncTimeout: 30 * time.Second,
 	},
 	Routes: &transform.RoutesConfig{},
+	Split: split.Config{
+		SocketPath: "/var/run/beyla/loader.sock",
+	},
 }
 
 type Config struct {
 	EBPF ebpfcommon.TracerConfig `yaml:"ebpf"`
 
+	// Ignore is an optional node. If not set, data will be directly forwarded to the Routes node.
+	Ignore transform.IgnoreConfig `yaml:"ignore"`
 	// Routes is an optional node. If not set, data will be directly forwarded to exporters.
 	Routes     *transform.RoutesConfig       `yaml:"routes"`
 	Kubernetes transform.KubernetesDecorator `yaml:"kubernetes"`
+	Containers transform.ContainerDecorator  `yaml:"containers"`
 	Metrics    otel.MetricsConfig            `yaml:"otel_metrics_export"`
 	Traces     otel.TracesConfig             `yaml:"otel_traces_export"`
 	Prometheus prom.PrometheusConfig         `yaml:"prometheus_export"`
@@ -88,6 +99,9 @@ type Config struct {
 
 	LogLevel string `yaml:"log_level" env:"LOG_LEVEL"`
 
+	// Split allows running Beyla as a privileged loader and an unprivileged processor
+	Split split.Config `yaml:"split"`
+
 	// From this comment, the properties below will remain undocumented, as they
 	// are useful for development purposes. They might be helpful for customer support.
 
@@ -104,6 +118,106 @@ func (e ConfigError) Error() string {
 }
 
 func (c *Config) validateInstrumentation() error {
+	// in processor mode, the processes are discovered by the loader
+	if c.Split.Mode != split.ModeProcessor {
+		if err := c.validateDiscovery(); err != nil {
+			return err
+		}
+	}
+	if c.EBPF.BatchLength == 0 {
+		return ConfigError("BATCH_LENGTH must be at least 1")
+	}
+	if err := c.validateRingBuffers(); err != nil {
+		return err
+	}
+	if err := c.EBPF.HTTPHeaders.Validate(); err != nil {
+		return ConfigError(fmt.Sprintf("error in http_headers YAML property: %s", err.Error()))
+	}
+	if err := c.Ignore.Validate(); err != nil {
+		return ConfigError(fmt.Sprintf("error in ignore YAML property: %s", err.Error()))
+	}
+	if c.EBPF.NetworkMetrics {
+		if err := c.validateNetworkMetrics(); err != nil {
+			return err
+		}
+	}
+	if c.EBPF.AggregateMetrics {
+		return c.validateAggregation()
+	}
+	return nil
+}
+
+func (c *Config) validateRingBuffers() error {
+	if c.EBPF.SamplingFillThreshold < 0 || c.EBPF.SamplingFillThreshold > 100 {
+		return ConfigError("BPF_SAMPLING_FILL_THRESHOLD must be a percentage between 0 and 100")
+	}
+	if err := validRingBufferSize(c.EBPF.RingBufferSize); err != nil {
+		return ConfigError("BPF_RING_BUFFER_SIZE " + err.Error())
+	}
+	if c.EBPF.RequestCaptureSize < 0 || c.EBPF.RequestCaptureSize > ebpfcommon.MaxRequestCaptureSize {
+		return ConfigError(fmt.Sprintf("BPF_REQUEST_CAPTURE_SIZE must be between 0 and %d bytes",
+			ebpfcommon.MaxRequestCaptureSize))
+	}
+	for tracer, size := range c.EBPF.RingBufferSizes {
+		if err := validRingBufferSize(size); err != nil {
+			return ConfigError(fmt.Sprintf("ring buffer size of %s tracer %s", tracer, err.Error()))
+		}
+	}
+	return nil
+}
+
+// validRingBufferSize checks that the kernel would accept the size of a ring buffer. Zero means
+// that the default size is used.
+func validRingBufferSize(size int) error {
+	if size == 0 {
+		return nil
+	}
+	if size < os.Getpagesize() || size&(size-1) != 0 {
+		return fmt.Errorf("must be a power of 2 and at least the page size (%d bytes)", os.Getpagesize())
+	}
+	return nil
+}
+
+func (c *Config) validateAggregation() error {
+	if c.Traces.Enabled() {
+		return ConfigError("BPF_AGGREGATE_METRICS can't be set if traces export is enabled")
+	}
+	if c.EBPF.AggregationInterval <= 0 {
+		return ConfigError("BPF_AGGREGATION_INTERVAL must be greater than zero")
+	}
+	buckets := c.AggregatedBuckets()
+	if len(buckets.DurationHistogram) > ebpfcommon.MaxAggregatedHistogramBounds ||
+		len(buckets.RequestSizeHistogram) > ebpfcommon.MaxAggregatedHistogramBounds {
+		return ConfigError(fmt.Sprintf("histograms can't have more than %d buckets if BPF_AGGREGATE_METRICS is set",
+			ebpfcommon.MaxAggregatedHistogramBounds))
+	}
+	return nil
+}
+
+func (c *Config) validateNetworkMetrics() error {
+	if c.EBPF.AggregationInterval <= 0 {
+		return ConfigError("BPF_AGGREGATION_INTERVAL must be greater than zero if BPF_NETWORK_METRICS is set")
+	}
+	buckets := c.AggregatedBuckets()
+	if len(buckets.ConnectionDurationHistogram) > ebpfcommon.MaxAggregatedHistogramBounds ||
+		len(buckets.SRTTHistogram) > ebpfcommon.MaxAggregatedHistogramBounds {
+		return ConfigError(fmt.Sprintf("connection_duration_histogram and srtt_histogram can't have more than %d buckets",
+			ebpfcommon.MaxAggregatedHistogramBounds))
+	}
+	return nil
+}
+
+// AggregatedBuckets returns the histogram buckets that are used when the metrics are aggregated
+// in the kernel. If both the Prometheus and OTEL exporters are enabled, the Prometheus
+// buckets are used.
+func (c *Config) AggregatedBuckets() otel.Buckets {
+	if c.Prometheus.Enabled() {
+		return c.Prometheus.Buckets
+	}
+	return c.Metrics.Buckets
+}
+
+func (c *Config) validateDiscovery() error {
 	if err := c.Discovery.Services.Validate(); err != nil {
 		return ConfigError(fmt.Sprintf("error in services YAML property: %s", err.Error()))
 	}
@@ -113,9 +227,6 @@ func (c *Config) validateInstrumentation() error {
 	if (c.Port.Len() > 0 || c.Exec.IsSet() || len(c.Discovery.Services) > 0) && c.Discovery.SystemWide {
 		return ConfigError("you can't use SYSTEM_WIDE if any of EXECUTABLE_NAME, OPEN_PORT or services (YAML) is set")
 	}
-	if c.EBPF.BatchLength == 0 {
-		return ConfigError("BATCH_LENGTH must be at least 1")
-	}
 	return nil
 }
 
@@ -14,6 +14,7 @@ import (
 	"github.com/grafana/beyla/pkg/internal/export/otel"
 	"github.com/grafana/beyla/pkg/internal/export/prom"
 	"github.com/grafana/beyla/pkg/internal/imetrics"
+	"github.com/grafana/beyla/pkg/internal/split"
 	"github.com/grafana/beyla/pkg/internal/transform"
 )
 
@@ -67,9 +68,12 @@ kubernetes:
 		Printer:          false,
 		Noop:             true,
 		EBPF: ebpfcommon.TracerConfig{
-			BatchLength:  100,
-			BatchTimeout: time.Second,
-			BpfBaseDir:   "/var/run/beyla",
+			BatchLength:         100,
+			BatchTimeout:        time.Second,
+			WakeupTimeout:       200 * time.Millisecond,
+			BpfBaseDir:          "/var/run/beyla",
+			AggregationInterval: time.Second,
+			RequestCaptureSize:  1024,
 		},
 		Metrics: otel.MetricsConfig{
 			Interval:          5 * time.Second,
@@ -78,8 +82,12 @@ kubernetes:
 			Protocol:          otel.ProtocolUnset,
 			ReportersCacheLen: 16,
 			Buckets: otel.Buckets{
-				DurationHistogram:    []float64{0, 1, 2},
-				RequestSizeHistogram: otel.DefaultBuckets.RequestSizeHistogram,
+				DurationHistogram:           []float64{0, 1, 2},
+				RequestSizeHistogram:        otel.DefaultBuckets.RequestSizeHistogram,
+				ResponseSizeHistogram:       otel.DefaultBuckets.ResponseSizeHistogram,
+				ConnectionDurationHistogram: otel.DefaultBuckets.ConnectionDurationHistogram,
+				SRTTHistogram:               otel.DefaultBuckets.SRTTHistogram,
+				CPUTimeHistogram:            otel.DefaultBuckets.CPUTimeHistogram,
 			},
 		},
 		Traces: otel.TracesConfig{
@@ -94,8 +102,12 @@ kubernetes:
 		Prometheus: prom.PrometheusConfig{
 			Path: "/metrics",
 			Buckets: otel.Buckets{
-				DurationHistogram:    otel.DefaultBuckets.DurationHistogram,
-				RequestSizeHistogram: []float64{0, 10, 20, 22},
+				DurationHistogram:           otel.DefaultBuckets.DurationHistogram,
+				RequestSizeHistogram:        []float64{0, 10, 20, 22},
+				ResponseSizeHistogram:       otel.DefaultBuckets.ResponseSizeHistogram,
+				ConnectionDurationHistogram: otel.DefaultBuckets.ConnectionDurationHistogram,
+				SRTTHistogram:               otel.DefaultBuckets.SRTTHistogram,
+				CPUTimeHistogram:            otel.DefaultBuckets.CPUTimeHistogram,
 			}},
 		InternalMetrics: imetrics.Config{
 			Prometheus: imetrics.PrometheusConfig{
@@ -108,6 +120,9 @@ kubernetes:
 			InformersSyncTimeout: 30 * time.Second,
 		},
 		Routes: &transform.RoutesConfig{},
+		Split: split.Config{
+			SocketPath: "/var/run/beyla/loader.sock",
+		},
 	}, cfg)
 }
 
@@ -127,6 +142,11 @@ func TestConfigValidate(t *testing.T) {
 		{"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT": "localhost:1234", "EXECUTABLE_NAME": "foo", "INSTRUMENT_FUNC_NAME": "bar"},
 		{"PRINT_TRACES": "true", "EXECUTABLE_NAME": "foo", "INSTRUMENT_FUNC_NAME": "bar"},
 		{"BEYLA_PROMETHEUS_PORT": "8080", "EXECUTABLE_NAME": "foo", "INSTRUMENT_FUNC_NAME": "bar"},
+		// the processor does not need discovery criteria, as the processes are discovered by the loader
+		{"PRINT_TRACES": "true", "SPLIT_MODE": "processor"},
+		{"BEYLA_PROMETHEUS_PORT": "8080", "EXECUTABLE_NAME": "foo", "BPF_AGGREGATE_METRICS": "true"},
+		{"PRINT_TRACES": "true", "EXECUTABLE_NAME": "foo", "BPF_RING_BUFFER_SIZE": "1048576", "BPF_SAMPLING_FILL_THRESHOLD": "50"},
+		{"PRINT_TRACES": "true", "EXECUTABLE_NAME": "foo", "IGNORE_PATHS": "/health,/metrics", "IGNORE_PEER_CIDRS": "10.0.0.0/8"},
 	}
 	for n, tc := range testCases {
 		t.Run(fmt.Sprint("case", n), func(t *testing.T) {
@@ -140,6 +160,14 @@ func TestConfigValidate_error(t *testing.T) {
 	testCases := []map[string]string{
 		{"OTEL_EXPORTER_OTLP_ENDPOINT": "localhost:1234", "INSTRUMENT_FUNC_NAME": "bar"},
 		{"EXECUTABLE_NAME": "foo", "INSTRUMENT_FUNC_NAME": "bar", "PRINT_TRACES": "false"},
+		{"SPLIT_MODE": "processor", "PRINT_TRACES": "false"},
+		// traces can't be exported if the metrics are aggregated in the kernel
+		{"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT": "localhost:1234", "EXECUTABLE_NAME": "foo", "BPF_AGGREGATE_METRICS": "true"},
+		// the kernel requires power-of-2 ring buffer sizes
+		{"PRINT_TRACES": "true", "EXECUTABLE_NAME": "foo", "BPF_RING_BUFFER_SIZE": "1000000"},
+		{"PRINT_TRACES": "true", "EXECUTABLE_NAME": "foo", "BPF_RING_BUFFER_SIZE": "64"},
+		{"PRINT_TRACES": "true", "EXECUTABLE_NAME": "foo", "BPF_SAMPLING_FILL_THRESHOLD": "101"},
+		{"PRINT_TRACES": "true", "EXECUTABLE_NAME": "foo", "IGNORE_PEER_CIDRS": "10.0.0.1"},
 	}
 	for n, tc := range testCases {
 		t.Run(fmt.Sprint("case", n), func(t *testing.T) {
@@ -149,6 +177,87 @@ func TestConfigValidate_error(t *testing.T) {
 	}
 }
 
+func TestConfig_RingBufferSizes(t *testing.T) {
+	cfg, err := LoadConfig(bytes.NewBufferString(`
+ebpf:
+  ring_buffer_size: 1048576
+  ring_buffer_sizes:
+    httpfltr: 33554432
+`))
+	require.NoError(t, err)
+	assert.Equal(t, 33554432, cfg.EBPF.RingBufferSizeFor("httpfltr.Tracer"))
+	assert.Equal(t, 1048576, cfg.EBPF.RingBufferSizeFor("nethttp.GinTracer"))
+
+	cfg.EBPF.RingBufferSizes["grpc"] = 12345
+	assert.Error(t, cfg.validateRingBuffers())
+}
+
+func TestConfig_RequestCaptureSize(t *testing.T) {
+	cfg, err := LoadConfig(bytes.NewBufferString("ebpf:\n  request_capture_size: 8192\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 8192, cfg.EBPF.RequestCaptureSize)
+	assert.NoError(t, cfg.validateRingBuffers())
+
+	cfg.EBPF.RequestCaptureSize = ebpfcommon.MaxRequestCaptureSize + 1
+	assert.Error(t, cfg.validateRingBuffers())
+}
+
+func TestConfig_NetworkMetrics(t *testing.T) {
+	cfg, err := LoadConfig(bytes.NewBufferString(`ebpf:
+  network_metrics: true
+prometheus_export:
+  port: 8999
+  buckets:
+    srtt_histogram: [0, 0.001, 0.01, 0.1]
+`))
+	require.NoError(t, err)
+	assert.True(t, cfg.EBPF.NetworkMetrics)
+	assert.NoError(t, cfg.validateNetworkMetrics())
+	assert.Equal(t, []float64{0, 0.001, 0.01, 0.1}, cfg.AggregatedBuckets().SRTTHistogram)
+
+	cfg.Prometheus.Buckets.SRTTHistogram = make([]float64, ebpfcommon.MaxAggregatedHistogramBounds+1)
+	assert.Error(t, cfg.validateNetworkMetrics())
+
+	cfg.Prometheus.Buckets.SRTTHistogram = otel.DefaultBuckets.SRTTHistogram
+	cfg.EBPF.AggregationInterval = 0
+	assert.Error(t, cfg.validateNetworkMetrics())
+}
+
+func TestConfig_HTTPHeaders(t *testing.T) {
+	cfg, err := LoadConfig(bytes.NewBufferString(`ebpf:
+  http_headers:
+    request: ["X-Tenant-ID", "User-Agent"]
+    response: ["Content-Type"]
+    metric_labels: ["x-tenant-id"]
+`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"X-Tenant-ID", "User-Agent"}, cfg.EBPF.HTTPHeaders.Request)
+	assert.NoError(t, cfg.EBPF.HTTPHeaders.Validate())
+	assert.Equal(t, []string{"x-tenant-id"}, cfg.EBPF.HTTPHeaders.MetricRequestHeaders())
+	assert.Empty(t, cfg.EBPF.HTTPHeaders.MetricResponseHeaders())
+
+	// metric labels must be captured headers
+	cfg.EBPF.HTTPHeaders.MetricLabels = []string{"Accept"}
+	assert.Error(t, cfg.EBPF.HTTPHeaders.Validate())
+
+	cfg.EBPF.HTTPHeaders.MetricLabels = nil
+	cfg.EBPF.HTTPHeaders.Request = []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+	assert.Error(t, cfg.EBPF.HTTPHeaders.Validate())
+
+	cfg.EBPF.HTTPHeaders.Request = []string{"Invalid: Name"}
+	cfg.EBPF.HTTPHeaders.Response = nil
+	assert.Error(t, cfg.EBPF.HTTPHeaders.Validate())
+}
+
+func TestConfig_SplitMode(t *testing.T) {
+	cfg, err := LoadConfig(bytes.NewBufferString("split:\n  mode: loader\n"))
+	require.NoError(t, err)
+	assert.Equal(t, split.ModeLoader, cfg.Split.Mode)
+
+	_, err = LoadConfig(bytes.NewBufferString("split:\n  mode: foo\n"))
+	assert.Error(t, err)
+}
+
 func loadConfig(t *testing.T, env map[string]string) *Config {
 	for k, v := range env {
 		require.NoError(t, os.Setenv(k, v))
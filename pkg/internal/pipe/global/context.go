@@ -12,6 +12,14 @@ type ContextInfo struct {
 	ReportRoutes bool
 	// K8sDecoration specifies whether kubernetes decoration is enabled
 	K8sDecoration bool
+	// K8sPodLabels and K8sPodAnnotations are the names of the Pod labels and annotations
+	// that the kubernetes decoration adds to the services metadata
+	K8sPodLabels      []string
+	K8sPodAnnotations []string
+	// MetricRequestHeaders and MetricResponseHeaders are the lowercase names of the captured
+	// HTTP headers that are reported as metric attributes
+	MetricRequestHeaders  []string
+	MetricResponseHeaders []string
 	// Metrics  that are internal to the pipe components
 	Metrics imetrics.Reporter
 	// Prometheus connection manager to coordinate metrics exposition from diverse nodes
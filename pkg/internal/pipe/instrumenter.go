@@ -20,13 +20,27 @@ import (
 // nodesMap provides the architecture of the whole processing pipeline:
 // each node and which nodes are they connected to
 type nodesMap struct {
-	TracesReader traces.Reader `sendTo:"Routes"`
+	TracesReader traces.Reader `sendTo:"Ignore"`
+
+	// Ignore is an optional node. If not set, data will be bypassed to the next stage in the pipeline.
+	Ignore transform.IgnoreConfig `forwardTo:"Routes"`
 
 	// Routes is an optional node. If not set, data will be bypassed to the next stage in the pipeline.
 	Routes *transform.RoutesConfig `forwardTo:"Kubernetes"`
 
-	// Kubernetes is an optional node. If not set, data will be bypassed to the exporters.
-	Kubernetes transform.KubernetesDecorator `forwardTo:"Metrics,Traces,Prometheus,Printer,Noop"`
+	// Kubernetes is an optional node. If not set, data will be bypassed to the next stage in the pipeline.
+	Kubernetes transform.KubernetesDecorator `forwardTo:"Containers"`
+
+	// Containers is an optional node. If not set, data will be bypassed to the next stage in the pipeline.
+	Containers transform.ContainerDecorator `forwardTo:"Processes"`
+
+	// Processes is an optional node, only enabled in system-wide mode.
+	// If not set, data will be bypassed to the next stage in the pipeline.
+	Processes transform.ProcessDecorator `forwardTo:"HostNames"`
+
+	// HostNames is an optional node. If not set, data will be bypassed to the exporters.
+	// It must go after the Kubernetes decorator, which looks up the peers by their IP address.
+	HostNames transform.HostNameDecorator `forwardTo:"Metrics,Traces,Prometheus,Printer,Noop"`
 
 	Metrics    otel.MetricsConfig
 	Traces     otel.TracesConfig
@@ -37,8 +51,12 @@ type nodesMap struct {
 
 func configToNodesMap(cfg *Config) *nodesMap {
 	return &nodesMap{
+		Ignore:     cfg.Ignore,
 		Routes:     cfg.Routes,
 		Kubernetes: cfg.Kubernetes,
+		Containers: cfg.Containers,
+		Processes:  transform.ProcessDecorator(cfg.Discovery.SystemWide),
+		HostNames:  transform.HostNameDecorator(cfg.EBPF.DNSTracing),
 		Metrics:    cfg.Metrics,
 		Traces:     cfg.Traces,
 		Prometheus: cfg.Prometheus,
@@ -90,8 +108,12 @@ func newGraphBuilder(ctx context.Context, config *Config, ctxInfo *global.Contex
 	// type of each of the "nodesMap" struct fields, and returns the function that represents
 	// each node. Each function will have input and/or output channels.
 	graph.RegisterStart(gnb, gb.tracesListenerProvider)
+	graph.RegisterMiddle(gnb, transform.IgnoreProvider)
 	graph.RegisterMiddle(gnb, transform.RoutesProvider)
 	graph.RegisterMiddle(gnb, transform.KubeDecoratorProvider)
+	graph.RegisterMiddle(gnb, transform.ContainerDecoratorProvider)
+	graph.RegisterMiddle(gnb, transform.ProcessDecoratorProvider)
+	graph.RegisterMiddle(gnb, transform.HostNameDecoratorProvider)
 	graph.RegisterTerminal(gnb, gb.metricsReporterProvider)
 	graph.RegisterTerminal(gnb, gb.tracesReporterProvicer)
 	graph.RegisterTerminal(gnb, gb.prometheusProvider)
@@ -409,14 +409,15 @@ func matchTraceEvent(t require.TestingT, svcName, name string, event collector.T
 	assert.Equal(t, collector.TraceRecord{
 		Name: name,
 		Attributes: map[string]string{
-			string(semconv.ServiceNameKey):              svcName,
-			string(semconv.HTTPMethodKey):               "GET",
-			string(semconv.HTTPStatusCodeKey):           "404",
-			string(semconv.HTTPTargetKey):               "/foo/bar",
-			string(semconv.NetSockPeerAddrKey):          "1.1.1.1",
-			string(semconv.NetHostNameKey):              getHostname(),
-			string(semconv.NetHostPortKey):              "8080",
-			string(semconv.HTTPRequestContentLengthKey): "0",
+			string(semconv.ServiceNameKey):               svcName,
+			string(semconv.HTTPMethodKey):                "GET",
+			string(semconv.HTTPStatusCodeKey):            "404",
+			string(semconv.HTTPTargetKey):                "/foo/bar",
+			string(semconv.NetSockPeerAddrKey):           "1.1.1.1",
+			string(semconv.NetHostNameKey):               getHostname(),
+			string(semconv.NetHostPortKey):               "8080",
+			string(semconv.HTTPRequestContentLengthKey):  "0",
+			string(semconv.HTTPResponseContentLengthKey): "0",
 			"span_id":        event.Attributes["span_id"],
 			"parent_span_id": event.Attributes["parent_span_id"],
 		},
@@ -496,13 +497,14 @@ func matchInfoEvent(t *testing.T, name string, event collector.TraceRecord) {
 	assert.Equal(t, collector.TraceRecord{
 		Name: name,
 		Attributes: map[string]string{
-			string(semconv.HTTPMethodKey):               "PATCH",
-			string(semconv.HTTPStatusCodeKey):           "204",
-			string(semconv.HTTPTargetKey):               "/aaa/bbb",
-			string(semconv.NetSockPeerAddrKey):          "1.1.1.1",
-			string(semconv.NetHostNameKey):              getHostname(),
-			string(semconv.NetHostPortKey):              "8080",
-			string(semconv.HTTPRequestContentLengthKey): "0",
+			string(semconv.HTTPMethodKey):                "PATCH",
+			string(semconv.HTTPStatusCodeKey):            "204",
+			string(semconv.HTTPTargetKey):                "/aaa/bbb",
+			string(semconv.NetSockPeerAddrKey):           "1.1.1.1",
+			string(semconv.NetHostNameKey):               getHostname(),
+			string(semconv.NetHostPortKey):               "8080",
+			string(semconv.HTTPRequestContentLengthKey):  "0",
+			string(semconv.HTTPResponseContentLengthKey): "0",
 			"span_id":                      event.Attributes["span_id"],
 			"parent_span_id":               "",
 			string(semconv.ServiceNameKey): "comm",
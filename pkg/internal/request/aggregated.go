@@ -0,0 +1,70 @@
+package request
+
+import "sort"
+
+// AggregatedMetrics contains the metrics of multiple requests with the same attributes,
+// as aggregated by the eBPF programs in the kernel
+type AggregatedMetrics struct {
+	Count         uint64
+	Duration      Histogram
+	ContentLength Histogram
+}
+
+// Histogram of aggregated observations
+type Histogram struct {
+	// Bounds are the upper bounds of the histogram buckets
+	Bounds []float64
+	// Counts of each bucket, including an extra bucket for the values beyond the last bound
+	Counts []uint64
+	// Sum of all the observed values
+	Sum float64
+}
+
+// NewHistogram returns an empty histogram with the passed bucket bounds
+func NewHistogram(bounds []float64) *Histogram {
+	return &Histogram{Bounds: bounds, Counts: make([]uint64, len(bounds)+1)}
+}
+
+// Count returns the number of observations of the histogram
+func (h *Histogram) Count() uint64 {
+	var count uint64
+	for _, c := range h.Counts {
+		count += c
+	}
+	return count
+}
+
+// Record adds a single observation to the histogram
+func (h *Histogram) Record(v float64) {
+	h.Counts[h.bucket(v)]++
+	h.Sum += v
+}
+
+// Merge adds the observations of the passed histogram. If both histograms have different
+// bounds, the observations of each bucket are added to the bucket that contains its upper bound.
+func (h *Histogram) Merge(o *Histogram) {
+	for i, c := range o.Counts {
+		if i < len(o.Bounds) {
+			h.Counts[h.bucket(o.Bounds[i])] += c
+		} else {
+			h.Counts[len(h.Bounds)] += c
+		}
+	}
+	h.Sum += o.Sum
+}
+
+// CumulativeCounts returns the number of observations that are less or equal than each bound
+func (h *Histogram) CumulativeCounts() map[float64]uint64 {
+	counts := make(map[float64]uint64, len(h.Bounds))
+	var count uint64
+	for i, bound := range h.Bounds {
+		count += h.Counts[i]
+		counts[bound] = count
+	}
+	return counts
+}
+
+// bucket returns the index of the bucket whose (lower, upper] interval contains the passed value
+func (h *Histogram) bucket(v float64) int {
+	return sort.SearchFloat64s(h.Bounds, v)
+}
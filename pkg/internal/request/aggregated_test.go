@@ -0,0 +1,31 @@
+package request
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistogram_Record(t *testing.T) {
+	h := NewHistogram([]float64{1, 2, 4})
+	for _, v := range []float64{0.5, 1, 1.5, 4, 5} {
+		h.Record(v)
+	}
+	assert.Equal(t, []uint64{2, 1, 1, 1}, h.Counts)
+	assert.Equal(t, 12.0, h.Sum)
+	assert.Equal(t, uint64(5), h.Count())
+	assert.Equal(t, map[float64]uint64{1: 2, 2: 3, 4: 4}, h.CumulativeCounts())
+}
+
+func TestHistogram_Merge(t *testing.T) {
+	h := NewHistogram([]float64{1, 2, 4})
+	h.Merge(&Histogram{Bounds: []float64{1, 2, 4}, Counts: []uint64{1, 2, 3, 4}, Sum: 30})
+	h.Merge(&Histogram{Bounds: []float64{1, 2, 4}, Counts: []uint64{1, 0, 0, 1}, Sum: 6})
+	assert.Equal(t, []uint64{2, 2, 3, 5}, h.Counts)
+	assert.Equal(t, 36.0, h.Sum)
+
+	// different bounds are merged into the bucket that contains each upper bound
+	h = NewHistogram([]float64{1, 2, 4})
+	h.Merge(&Histogram{Bounds: []float64{0.5, 3, 10}, Counts: []uint64{1, 2, 3, 4}, Sum: 50})
+	assert.Equal(t, []uint64{1, 0, 2, 7}, h.Counts)
+}
@@ -0,0 +1,19 @@
+package request
+
+// NetworkMetrics contains the metrics of the TCP connections between a source (client)
+// and a destination (server), as aggregated by the eBPF programs in the kernel
+type NetworkMetrics struct {
+	// BytesSent from the source to the destination
+	BytesSent uint64
+	// BytesReceived by the source from the destination
+	BytesReceived uint64
+	Opened        uint64
+	Closed        uint64
+	// Refused connections are those that couldn't be established (e.g. refused or timed out)
+	Refused     uint64
+	Retransmits uint64
+	// Duration of the closed connections, in seconds
+	Duration Histogram
+	// SRTT is the smoothed round trip time of the closed connections, in seconds
+	SRTT Histogram
+}
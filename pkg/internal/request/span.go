@@ -18,6 +18,15 @@ const (
 	EventTypeHTTPClient
 	EventTypeGRPCClient
 	EventTypeSQLClient
+	// EventTypeTCPServer and EventTypeTCPClient are not sent by the eBPF programs. They are
+	// set to the spans that carry the metrics of the TCP connections accepted or started by
+	// the instrumented service.
+	EventTypeTCPServer
+	EventTypeTCPClient
+	// EventTypeDNSClient is set by the DNS tracer to the DNS queries of the instrumented service.
+	// The query name is stored in the Path, the query type in the Method and the response code
+	// in the Status.
+	EventTypeDNSClient
 )
 
 type converter struct {
@@ -39,13 +48,47 @@ type Span struct {
 	Host          string
 	HostPort      int
 	Status        int
-	ContentLength int64
-	RequestStart  int64
-	Start         int64
-	End           int64
-	ServiceID     svc.ID
-	Metadata      map[string]string
-	Traceparent   string
+	ContentLength int64 // size of the request body, in bytes, or -1 if it's unknown
+	// ResponseLength is the size of the response body, in bytes, or -1 if it's unknown
+	ResponseLength int64
+	RequestStart   int64
+	Start          int64
+	End            int64
+	ServiceID      svc.ID
+	Metadata       map[string]string
+	Traceparent    string
+	// RequestHeaders and ResponseHeaders contain the values of the captured HTTP headers,
+	// by their lowercase names
+	RequestHeaders  map[string]string
+	ResponseHeaders map[string]string
+	// Aggregated is not nil if the span does not represent a single request, but the
+	// metrics of multiple requests with the same attributes, aggregated in the kernel
+	Aggregated *AggregatedMetrics
+	// Network is not nil if the span contains the metrics of the TCP connections from
+	// Peer (the source) to Host:HostPort (the destination), aggregated in the kernel
+	Network *NetworkMetrics
+	// ResolvedAddresses contains the IP addresses of the answers to a DNS query
+	ResolvedAddresses []string
+	// CPUTime is not nil if the time that the thread, or goroutine, serving the request
+	// spent on and off CPU has been accounted
+	CPUTime *CPUTime
+}
+
+// CPUTime of the thread, or goroutine, that served a request while the request was in flight
+type CPUTime struct {
+	// OnCPU is the time that the request was running on CPU
+	OnCPU time.Duration
+	// OffCPU is the time that the request was scheduled out (e.g. waiting for I/O or locks)
+	OffCPU time.Duration
+}
+
+// NewCPUTime returns the CPU time of a request from the nanoseconds that were accounted by the
+// eBPF programs, or nil if it wasn't accounted
+func NewCPUTime(onCPUNs, offCPUNs uint64) *CPUTime {
+	if onCPUNs == 0 && offCPUNs == 0 {
+		return nil
+	}
+	return &CPUTime{OnCPU: time.Duration(onCPUNs), OffCPU: time.Duration(offCPUNs)}
 }
 
 func (s *Span) Inside(parent *Span) bool {
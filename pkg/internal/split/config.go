@@ -0,0 +1,54 @@
+// Package split allows running Beyla as two separate processes: a privileged loader, which
+// discovers and instruments the processes and pins the eBPF maps of their tracers, and
+// an unprivileged processor, which reads the pinned maps, decorates and exports the traces.
+// Both processes communicate through a local Unix socket.
+package split
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Mode of execution of Beyla
+type Mode string
+
+const (
+	// ModeAll runs discovery, instrumentation and export in the same process (default)
+	ModeAll = Mode("")
+	// ModeLoader only discovers and instruments processes, pinning the eBPF maps of their tracers
+	ModeLoader = Mode("loader")
+	// ModeProcessor reads the traces from the maps that are pinned by a loader, then processes
+	// and exports them
+	ModeProcessor = Mode("processor")
+)
+
+func (m *Mode) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.ScalarNode {
+		return fmt.Errorf("Mode: unexpected YAML node kind %d", value.Kind)
+	}
+	return m.UnmarshalText([]byte(value.Value))
+}
+
+func (m *Mode) UnmarshalText(text []byte) error {
+	switch mode := Mode(text); mode {
+	case ModeAll, ModeLoader, ModeProcessor:
+		*m = mode
+		return nil
+	default:
+		return fmt.Errorf("invalid mode %q. Accepted values: %q, %q", string(text), ModeLoader, ModeProcessor)
+	}
+}
+
+type Config struct {
+	Mode Mode `yaml:"mode" env:"SPLIT_MODE"`
+
+	// SocketPath of the Unix socket where the loader notifies the instrumented processes
+	// to the processor
+	SocketPath string `yaml:"socket_path" env:"SPLIT_SOCKET_PATH"`
+
+	// ProcessorUID and ProcessorGID are the user and group IDs of the processor. The loader makes
+	// them owners of the socket and the pinned maps, so the processor can access them.
+	ProcessorUID int `yaml:"processor_uid" env:"SPLIT_PROCESSOR_UID"`
+	ProcessorGID int `yaml:"processor_gid" env:"SPLIT_PROCESSOR_GID"`
+}
@@ -0,0 +1,195 @@
+package split
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/grafana/beyla/pkg/internal/ebpf"
+)
+
+// processorWriteTimeout is the maximum time to send a message to a processor. Processors that
+// don't read their messages in time are disconnected.
+const processorWriteTimeout = 10 * time.Second
+
+func llog() *slog.Logger {
+	return slog.With("component", "split.Loader")
+}
+
+// Loader notifies, through a Unix socket, the processes whose tracers' maps have been
+// pinned to all the connected processors. Processors connecting later receive all the
+// previously notified processes.
+type Loader struct {
+	cfg      *Config
+	log      *slog.Logger
+	listener net.Listener
+	// id is unique for each execution of the loader, so the processors can forget the
+	// processes from a previous execution when the loader is restarted
+	id string
+
+	access    sync.Mutex
+	published []*ebpf.PinnedProcess
+	conns     map[net.Conn]*processorConn
+	closed    bool
+	// updated is signalled when a process is published, a connection is closed or the loader
+	// is stopped
+	updated *sync.Cond
+}
+
+// processorConn is a connected processor. Each processor is sent its messages from its own
+// goroutine, so a processor that doesn't read them doesn't block the others.
+type processorConn struct {
+	conn net.Conn
+	// sent is the number of published processes that have been sent to the processor
+	sent int
+	// closed is set when the connection is closed by any of its ends
+	closed bool
+}
+
+// message is sent by the loader to the processors for each pinned process
+type message struct {
+	LoaderID string              `json:"loader_id"`
+	Process  *ebpf.PinnedProcess `json:"process"`
+}
+
+// Listen creates the loader socket, owned by the configured processor user.
+func Listen(cfg *Config) (*Loader, error) {
+	if err := os.MkdirAll(filepath.Dir(cfg.SocketPath), 0755); err != nil {
+		return nil, fmt.Errorf("creating socket directory: %w", err)
+	}
+	// removing the socket from a previous execution, if any
+	if err := os.Remove(cfg.SocketPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("removing old socket: %w", err)
+	}
+	listener, err := net.Listen("unix", cfg.SocketPath)
+	if err != nil {
+		return nil, fmt.Errorf("listening on %s: %w", cfg.SocketPath, err)
+	}
+	if err := os.Chmod(cfg.SocketPath, 0600); err != nil {
+		_ = listener.Close()
+		return nil, fmt.Errorf("setting socket permissions: %w", err)
+	}
+	if err := os.Chown(cfg.SocketPath, cfg.ProcessorUID, cfg.ProcessorGID); err != nil {
+		_ = listener.Close()
+		return nil, fmt.Errorf("setting socket owner: %w", err)
+	}
+	l := &Loader{
+		cfg:      cfg,
+		log:      llog().With("socket", cfg.SocketPath),
+		listener: listener,
+		id:       fmt.Sprintf("%d-%d", os.Getpid(), time.Now().UnixNano()),
+		conns:    map[net.Conn]*processorConn{},
+	}
+	l.updated = sync.NewCond(&l.access)
+	return l, nil
+}
+
+// Serve accepts processor connections until the passed context is cancelled.
+func (l *Loader) Serve(ctx context.Context) {
+	go func() {
+		<-ctx.Done()
+		l.log.Debug("context cancelled. Closing socket")
+		_ = l.listener.Close()
+		l.access.Lock()
+		l.closed = true
+		for conn := range l.conns {
+			_ = conn.Close()
+		}
+		l.updated.Broadcast()
+		l.access.Unlock()
+	}()
+	for {
+		conn, err := l.listener.Accept()
+		if err != nil {
+			if ctx.Err() == nil {
+				l.log.Error("accepting processor connection. Stopping loader socket", "error", err)
+			}
+			return
+		}
+		l.log.Info("processor connected")
+		pc := &processorConn{conn: conn}
+		l.access.Lock()
+		l.conns[conn] = pc
+		l.access.Unlock()
+		go l.watchClose(pc)
+		go l.serveProcessor(pc)
+	}
+}
+
+// Publish makes the processor the owner of the pinned maps and notifies them to all
+// the connected processors.
+func (l *Loader) Publish(pp *ebpf.PinnedProcess) error {
+	if err := l.chownTree(pp.PinPath); err != nil {
+		return fmt.Errorf("setting pinned maps owner: %w", err)
+	}
+	l.access.Lock()
+	l.published = append(l.published, pp)
+	l.updated.Broadcast()
+	l.access.Unlock()
+	return nil
+}
+
+// watchClose detects when the connection is closed. The processors never write to the socket.
+func (l *Loader) watchClose(pc *processorConn) {
+	_, _ = io.Copy(io.Discard, pc.conn)
+	l.access.Lock()
+	pc.closed = true
+	l.updated.Broadcast()
+	l.access.Unlock()
+}
+
+// serveProcessor sends all the published processes to the processor, as they are published,
+// until the connection is closed or the loader is stopped.
+func (l *Loader) serveProcessor(pc *processorConn) {
+	defer func() {
+		_ = pc.conn.Close()
+		l.access.Lock()
+		delete(l.conns, pc.conn)
+		l.access.Unlock()
+	}()
+	enc := json.NewEncoder(pc.conn)
+	for {
+		l.access.Lock()
+		for pc.sent == len(l.published) && !pc.closed && !l.closed {
+			l.updated.Wait()
+		}
+		if pc.closed || l.closed {
+			l.access.Unlock()
+			l.log.Info("processor disconnected")
+			return
+		}
+		pending := slices.Clone(l.published[pc.sent:])
+		l.access.Unlock()
+
+		for _, pp := range pending {
+			if err := pc.conn.SetWriteDeadline(time.Now().Add(processorWriteTimeout)); err != nil {
+				l.log.Info("processor disconnected", "reason", err)
+				return
+			}
+			if err := enc.Encode(message{LoaderID: l.id, Process: pp}); err != nil {
+				l.log.Info("processor disconnected", "reason", err)
+				return
+			}
+		}
+		pc.sent += len(pending)
+	}
+}
+
+func (l *Loader) chownTree(root string) error {
+	return filepath.WalkDir(root, func(path string, _ fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		return os.Lchown(path, l.cfg.ProcessorUID, l.cfg.ProcessorGID)
+	})
+}
@@ -0,0 +1,122 @@
+package split
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"time"
+
+	"github.com/grafana/beyla/pkg/internal/ebpf"
+)
+
+// reconnectInterval to the loader socket if it is not available (e.g. the loader hasn't
+// started yet or has been restarted)
+const reconnectInterval = time.Second
+
+func plog() *slog.Logger {
+	return slog.With("component", "split.Processor")
+}
+
+// PinnedEvent notifies a process that has been pinned by the loader, or a previously notified
+// process whose pinned maps must not be read anymore.
+type PinnedEvent struct {
+	Process *ebpf.PinnedProcess
+	// Removed is true if the process has been pinned by a previous execution of the loader,
+	// so its tracers must stop reading its pinned maps.
+	Removed bool
+}
+
+// Receive connects to the loader socket and forwards all the pinned processes that are
+// notified by the loader. If the connection is lost, it reconnects until the passed
+// context is cancelled. Processes are forwarded only once, even if the loader notifies
+// them again after a reconnection. If the loader is restarted, the processes from its
+// previous execution are forwarded as removed before any process from the new execution.
+func Receive(ctx context.Context, cfg *Config) <-chan PinnedEvent {
+	out := make(chan PinnedEvent)
+	go func() {
+		defer close(out)
+		log := plog().With("socket", cfg.SocketPath)
+		r := receiver{socketPath: cfg.SocketPath, out: out, seen: map[string]*ebpf.PinnedProcess{}}
+		for {
+			if err := r.receive(ctx); err != nil {
+				log.Debug("can't receive from loader. Retrying", "error", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(reconnectInterval):
+			}
+		}
+	}()
+	return out
+}
+
+type receiver struct {
+	socketPath string
+	out        chan<- PinnedEvent
+	// loaderID of the loader execution that notified the seen processes
+	loaderID string
+	// seen processes from the current loader execution, by their PinPath
+	seen map[string]*ebpf.PinnedProcess
+}
+
+func (r *receiver) receive(ctx context.Context) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", r.socketPath)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	plog().Info("connected to loader", "socket", r.socketPath)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.Close()
+		case <-done:
+		}
+	}()
+	dec := json.NewDecoder(conn)
+	for {
+		msg := message{}
+		if err := dec.Decode(&msg); err != nil {
+			return err
+		}
+		if msg.LoaderID != r.loaderID {
+			if err := r.removeAll(ctx); err != nil {
+				return err
+			}
+			r.loaderID = msg.LoaderID
+		}
+		if _, ok := r.seen[msg.Process.PinPath]; ok {
+			continue
+		}
+		r.seen[msg.Process.PinPath] = msg.Process
+		if err := r.forward(ctx, PinnedEvent{Process: msg.Process}); err != nil {
+			return err
+		}
+	}
+}
+
+// removeAll forwards as removed all the processes from the previous loader execution
+func (r *receiver) removeAll(ctx context.Context) error {
+	for pinPath, pp := range r.seen {
+		plog().Debug("loader restarted. Removing process", "path", pinPath)
+		if err := r.forward(ctx, PinnedEvent{Process: pp, Removed: true}); err != nil {
+			return err
+		}
+		delete(r.seen, pinPath)
+	}
+	return nil
+}
+
+func (r *receiver) forward(ctx context.Context, ev PinnedEvent) error {
+	select {
+	case r.out <- ev:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
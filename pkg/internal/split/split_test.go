@@ -0,0 +1,165 @@
+package split
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mariomac/guara/pkg/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/beyla/pkg/internal/ebpf"
+	"github.com/grafana/beyla/pkg/internal/svc"
+)
+
+const timeout = 5 * time.Second
+
+func TestMode_UnmarshalText(t *testing.T) {
+	var m Mode
+	require.NoError(t, m.UnmarshalText([]byte("loader")))
+	assert.Equal(t, ModeLoader, m)
+	require.NoError(t, m.UnmarshalText([]byte("processor")))
+	assert.Equal(t, ModeProcessor, m)
+	require.NoError(t, m.UnmarshalText([]byte("")))
+	assert.Equal(t, ModeAll, m)
+	assert.Error(t, m.UnmarshalText([]byte("foo")))
+}
+
+func TestLoaderProcessor(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tmp := t.TempDir()
+	cfg := &Config{
+		SocketPath:   filepath.Join(tmp, "run", "loader.sock"),
+		ProcessorUID: os.Getuid(),
+		ProcessorGID: os.Getgid(),
+	}
+	loader, err := Listen(cfg)
+	require.NoError(t, err)
+	go loader.Serve(ctx)
+
+	// GIVEN a process that has been pinned before the processor connects
+	first := pinnedProcess(t, tmp, "first")
+	require.NoError(t, loader.Publish(first))
+
+	// WHEN the processor connects
+	received := Receive(ctx, cfg)
+
+	// THEN it receives the previously published processes
+	assert.Equal(t, first, readPinned(t, received))
+
+	// AND the processes that are published later
+	second := pinnedProcess(t, tmp, "second")
+	require.NoError(t, loader.Publish(second))
+	assert.Equal(t, second, readPinned(t, received))
+
+	// AND processes are not forwarded twice when they are notified again after a reconnection
+	loader.access.Lock()
+	for conn := range loader.conns {
+		_ = conn.Close()
+	}
+	loader.access.Unlock()
+	third := pinnedProcess(t, tmp, "third")
+	test.Eventually(t, timeout, func(t require.TestingT) {
+		loader.access.Lock()
+		defer loader.access.Unlock()
+		require.Len(t, loader.conns, 1)
+	})
+	require.NoError(t, loader.Publish(third))
+	assert.Equal(t, third, readPinned(t, received))
+
+	// AND when the loader is restarted, the processes from its previous execution are removed
+	// before forwarding the processes that are published again with the same pin path
+	cancelLoader(loader)
+	restarted, err := Listen(cfg)
+	require.NoError(t, err)
+	go restarted.Serve(ctx)
+	require.NoError(t, restarted.Publish(first))
+	removed := map[string]bool{}
+	for i := 0; i < 3; i++ {
+		ev := readEvent(t, received)
+		require.True(t, ev.Removed)
+		removed[ev.Process.PinPath] = true
+	}
+	assert.Equal(t, map[string]bool{first.PinPath: true, second.PinPath: true, third.PinPath: true}, removed)
+	assert.Equal(t, first, readPinned(t, received))
+}
+
+func TestLoader_StuckProcessor(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tmp := t.TempDir()
+	cfg := &Config{
+		SocketPath:   filepath.Join(tmp, "loader.sock"),
+		ProcessorUID: os.Getuid(),
+		ProcessorGID: os.Getgid(),
+	}
+	loader, err := Listen(cfg)
+	require.NoError(t, err)
+	go loader.Serve(ctx)
+
+	// GIVEN a processor that connects but never reads its messages
+	stuck, err := net.Dial("unix", cfg.SocketPath)
+	require.NoError(t, err)
+	defer stuck.Close()
+
+	// WHEN enough processes are published to fill its socket buffer
+	pp := pinnedProcess(t, tmp, "process")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 10000; i++ {
+			require.NoError(t, loader.Publish(pp))
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		require.Fail(t, "publishing is blocked by the stuck processor")
+	}
+
+	// THEN other processors still receive the published processes
+	received := Receive(ctx, cfg)
+	assert.Equal(t, pp, readPinned(t, received))
+}
+
+// cancelLoader stops accepting processor connections and closes the existing ones
+func cancelLoader(loader *Loader) {
+	_ = loader.listener.Close()
+	loader.access.Lock()
+	for conn := range loader.conns {
+		_ = conn.Close()
+	}
+	loader.access.Unlock()
+}
+
+func pinnedProcess(t *testing.T, dir, name string) *ebpf.PinnedProcess {
+	pinPath := filepath.Join(dir, name)
+	tracerPath := filepath.Join(pinPath, "nethttp.Tracer")
+	require.NoError(t, os.MkdirAll(tracerPath, 0700))
+	return &ebpf.PinnedProcess{
+		Service: svc.ID{Name: name, ProcPID: 1234},
+		PinPath: pinPath,
+		Tracers: map[string]string{"nethttp.Tracer": tracerPath},
+	}
+}
+
+func readPinned(t *testing.T, in <-chan PinnedEvent) *ebpf.PinnedProcess {
+	ev := readEvent(t, in)
+	require.False(t, ev.Removed)
+	return ev.Process
+}
+
+func readEvent(t *testing.T, in <-chan PinnedEvent) PinnedEvent {
+	select {
+	case ev := <-in:
+		return ev
+	case <-time.After(timeout):
+		require.Fail(t, "timeout while waiting for pinned process")
+		return PinnedEvent{}
+	}
+}
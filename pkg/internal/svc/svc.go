@@ -1,10 +1,58 @@
 package svc
 
+import "maps"
+
 // ID stores the coordinates that uniquely identifies a service:
 // its name and optionally a namespace
 type ID struct {
 	Name      string
 	Namespace string
+	// ProcPID is the PID of the instrumented process. It is zero if unknown.
+	ProcPID int32
+	// Instance identifies a concrete instance of the service, when it can be
+	// provided by the decorators (e.g. the Kubernetes Pod UID and container name).
+	Instance string
+	// Metadata stores attributes that are common to all the spans of the same service
+	// instance (e.g. Kubernetes Pod metadata). They are reported as resource attributes.
+	// It must be treated as read-only, as it can be shared by many spans.
+	Metadata map[string]string
+	// SDKLanguage is the language of the instrumented service (go, java, python...), if known.
+	// It is reported as the telemetry.sdk.language resource attribute.
+	SDKLanguage string
+	// CommandArgs of the instrumented process, if known.
+	CommandArgs []string
+	// DiscoveryEntry is the position, starting at 1, of the discovery services' criteria entry
+	// that selected the instrumented process. It is zero if unknown.
+	DiscoveryEntry int
+}
+
+// UID is a comparable key that uniquely identifies a service instance. It can be used
+// as a cache key, as ID is not comparable.
+type UID struct {
+	Name      string
+	Namespace string
+	ProcPID   int32
+	Instance  string
+}
+
+func (i *ID) UID() UID {
+	return UID{Name: i.Name, Namespace: i.Namespace, ProcPID: i.ProcPID, Instance: i.Instance}
+}
+
+// AddMetadata adds the provided attributes to the service metadata. As the metadata map
+// can be shared with other spans, it is never modified in place but copied when required.
+func (i *ID) AddMetadata(attrs map[string]string) {
+	if len(attrs) == 0 {
+		return
+	}
+	if len(i.Metadata) == 0 {
+		i.Metadata = attrs
+		return
+	}
+	merged := make(map[string]string, len(i.Metadata)+len(attrs))
+	maps.Copy(merged, i.Metadata)
+	maps.Copy(merged, attrs)
+	i.Metadata = merged
 }
 
 func (i *ID) String() string {
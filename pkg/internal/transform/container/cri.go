@@ -0,0 +1,197 @@
+package container
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Methods and protobuf field numbers from the Kubernetes Container Runtime Interface
+// (k8s.io/cri-api/pkg/apis/runtime/v1/api.proto). The messages are encoded and decoded
+// manually to avoid depending on the whole CRI API module for just two calls.
+const (
+	criVersionMethod         = "/runtime.v1.RuntimeService/Version"
+	criContainerStatusMethod = "/runtime.v1.RuntimeService/ContainerStatus"
+
+	// VersionResponse
+	criVersionRuntimeName = 2
+	// ContainerStatusRequest
+	criStatusRequestContainerID = 1
+	// ContainerStatusResponse
+	criStatusResponseStatus = 1
+	// ContainerStatus
+	criStatusID       = 1
+	criStatusMetadata = 2
+	criStatusImage    = 8
+	criStatusLabels   = 12
+	// ContainerMetadata
+	criMetadataName = 1
+	// ImageSpec
+	criImageSpecImage = 1
+	// map<string, string> entries
+	criMapKey   = 1
+	criMapValue = 2
+)
+
+// criRuntime queries the CRI gRPC API of containerd or CRI-O through its Unix socket
+type criRuntime struct {
+	conn *grpc.ClientConn
+	// name of the runtime, as reported by the CRI Version method
+	name string
+}
+
+func newCRIRuntime(socket string) (*criRuntime, error) {
+	conn, err := grpc.Dial("unix://"+socket, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("connecting to CRI socket %s: %w", socket, err)
+	}
+	return &criRuntime{conn: conn}, nil
+}
+
+func (c *criRuntime) Info(ctx context.Context, containerID string) (*Info, error) {
+	if c.name == "" {
+		if err := c.fetchRuntimeName(ctx); err != nil {
+			return nil, err
+		}
+	}
+	req := protowire.AppendTag(nil, criStatusRequestContainerID, protowire.BytesType)
+	req = protowire.AppendString(req, containerID)
+	var resp []byte
+	if err := c.conn.Invoke(ctx, criContainerStatusMethod, &req, &resp, grpc.ForceCodec(rawCodec{})); err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("querying CRI container status: %w", err)
+	}
+	info := Info{Runtime: c.name}
+	err := forEachField(resp, func(num protowire.Number, value []byte) error {
+		if num == criStatusResponseStatus {
+			return parseCRIContainerStatus(value, &info)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("decoding CRI container status: %w", err)
+	}
+	return &info, nil
+}
+
+func (c *criRuntime) fetchRuntimeName(ctx context.Context) error {
+	req := []byte{}
+	var resp []byte
+	if err := c.conn.Invoke(ctx, criVersionMethod, &req, &resp, grpc.ForceCodec(rawCodec{})); err != nil {
+		return fmt.Errorf("querying CRI version: %w", err)
+	}
+	return forEachField(resp, func(num protowire.Number, value []byte) error {
+		if num == criVersionRuntimeName {
+			c.name = string(value)
+		}
+		return nil
+	})
+}
+
+func (c *criRuntime) Close() error {
+	return c.conn.Close()
+}
+
+func parseCRIContainerStatus(msg []byte, info *Info) error {
+	return forEachField(msg, func(num protowire.Number, value []byte) error {
+		switch num {
+		case criStatusID:
+			info.ID = string(value)
+		case criStatusMetadata:
+			return forEachField(value, func(num protowire.Number, value []byte) error {
+				if num == criMetadataName {
+					info.Name = string(value)
+				}
+				return nil
+			})
+		case criStatusImage:
+			return forEachField(value, func(num protowire.Number, value []byte) error {
+				if num == criImageSpecImage {
+					info.ImageName, info.ImageTag = splitImage(string(value))
+				}
+				return nil
+			})
+		case criStatusLabels:
+			var key, val string
+			if err := forEachField(value, func(num protowire.Number, value []byte) error {
+				switch num {
+				case criMapKey:
+					key = string(value)
+				case criMapValue:
+					val = string(value)
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+			if info.Labels == nil {
+				info.Labels = map[string]string{}
+			}
+			info.Labels[key] = val
+		}
+		return nil
+	})
+}
+
+// forEachField invokes the provided function for each length-delimited field of a
+// protobuf-encoded message (strings, bytes, embedded messages...). Other field types
+// are ignored.
+func forEachField(msg []byte, fn func(num protowire.Number, value []byte) error) error {
+	for len(msg) > 0 {
+		num, typ, n := protowire.ConsumeTag(msg)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		msg = msg[n:]
+		if typ == protowire.BytesType {
+			value, n := protowire.ConsumeBytes(msg)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			if err := fn(num, value); err != nil {
+				return err
+			}
+			msg = msg[n:]
+			continue
+		}
+		n = protowire.ConsumeFieldValue(num, typ, msg)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		msg = msg[n:]
+	}
+	return nil
+}
+
+// rawCodec sends and receives already encoded protobuf messages
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v any) ([]byte, error) {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return nil, fmt.Errorf("expecting *[]byte. Got: %T", v)
+	}
+	return *b, nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v any) error {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return errors.New("expecting *[]byte")
+	}
+	*b = append((*b)[:0], data...)
+	return nil
+}
+
+// Name must be "proto", so the request has the content type that is expected by the server
+func (rawCodec) Name() string {
+	return "proto"
+}
@@ -0,0 +1,73 @@
+package container
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// dockerRuntime queries the Docker Engine API through its Unix socket
+type dockerRuntime struct {
+	client http.Client
+}
+
+type dockerContainer struct {
+	ID     string `json:"Id"`
+	Name   string `json:"Name"`
+	Config struct {
+		Image  string            `json:"Image"`
+		Labels map[string]string `json:"Labels"`
+	} `json:"Config"`
+}
+
+func newDockerRuntime(socket string) *dockerRuntime {
+	dialer := net.Dialer{}
+	return &dockerRuntime{client: http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, "unix", socket)
+			},
+		},
+	}}
+}
+
+func (d *dockerRuntime) Info(ctx context.Context, containerID string) (*Info, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		"http://docker/containers/"+containerID+"/json", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("querying Docker API: %w", err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, ErrNotFound
+	default:
+		return nil, fmt.Errorf("unexpected Docker API response: %s", resp.Status)
+	}
+	dc := dockerContainer{}
+	if err := json.NewDecoder(resp.Body).Decode(&dc); err != nil {
+		return nil, fmt.Errorf("decoding Docker API response: %w", err)
+	}
+	imageName, imageTag := splitImage(dc.Config.Image)
+	return &Info{
+		ID:        dc.ID,
+		Name:      strings.TrimPrefix(dc.Name, "/"),
+		ImageName: imageName,
+		ImageTag:  imageTag,
+		Runtime:   "docker",
+		Labels:    dc.Config.Labels,
+	}, nil
+}
+
+func (d *dockerRuntime) Close() error {
+	d.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,99 @@
+// Package container provides access to the metadata of the containers from the
+// local container runtimes (Docker, containerd, CRI-O).
+package container
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+const (
+	// Resource attributes of the container where the instrumented service runs
+	IDKey        = "container.id"
+	NameKey      = "container.name"
+	ImageNameKey = "container.image.name"
+	ImageTagKey  = "container.image.tag"
+	RuntimeKey   = "container.runtime"
+	LabelPrefix  = "container.label."
+)
+
+// default sockets of the supported container runtimes, in the order they are tried
+var (
+	defaultDockerSockets = []string{"/var/run/docker.sock"}
+	defaultCRISockets    = []string{"/run/containerd/containerd.sock", "/var/run/crio/crio.sock"}
+)
+
+// ErrNotFound is returned when a container is not found in the runtime
+var ErrNotFound = errors.New("container not found")
+
+func clog() *slog.Logger {
+	return slog.With("component", "container.Runtime")
+}
+
+// Info of a container, as provided by its runtime
+type Info struct {
+	ID        string
+	Name      string
+	ImageName string
+	ImageTag  string
+	Runtime   string
+	Labels    map[string]string
+}
+
+// Runtime provides the metadata of the containers from a container runtime
+type Runtime interface {
+	// Info returns the metadata of the container with the given ID, or ErrNotFound
+	Info(ctx context.Context, containerID string) (*Info, error)
+	Close() error
+}
+
+// Connect to the container runtime that is listening in the provided Docker or CRI sockets.
+// If no sockets are provided, it tries the default locations of Docker, containerd and CRI-O.
+func Connect(dockerSocket, criSocket string) (Runtime, error) {
+	dockerSockets, criSockets := defaultDockerSockets, defaultCRISockets
+	if dockerSocket != "" || criSocket != "" {
+		dockerSockets, criSockets = nil, nil
+		if dockerSocket != "" {
+			dockerSockets = []string{dockerSocket}
+		}
+		if criSocket != "" {
+			criSockets = []string{criSocket}
+		}
+	}
+	for _, socket := range dockerSockets {
+		if isSocket(socket) {
+			clog().Debug("using Docker runtime", "socket", socket)
+			return newDockerRuntime(socket), nil
+		}
+	}
+	for _, socket := range criSockets {
+		if isSocket(socket) {
+			clog().Debug("using CRI runtime", "socket", socket)
+			return newCRIRuntime(socket)
+		}
+	}
+	return nil, fmt.Errorf("no container runtime socket found. Tried: %s",
+		strings.Join(append(dockerSockets, criSockets...), ", "))
+}
+
+func isSocket(path string) bool {
+	fi, err := os.Stat(path)
+	return err == nil && fi.Mode().Type() == os.ModeSocket
+}
+
+// splitImage returns the name and the tag of an image reference
+// (e.g. registry:5000/org/app:1.2@sha256:abcd -> registry:5000/org/app, 1.2)
+func splitImage(image string) (name, tag string) {
+	if i := strings.IndexByte(image, '@'); i >= 0 {
+		image = image[:i]
+	}
+	colon := strings.LastIndexByte(image, ':')
+	if colon < 0 || colon < strings.LastIndexByte(image, '/') {
+		return image, ""
+	}
+	return image[:colon], image[colon+1:]
+}
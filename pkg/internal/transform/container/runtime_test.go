@@ -0,0 +1,107 @@
+package container
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestSplitImage(t *testing.T) {
+	for _, tc := range []struct{ image, name, tag string }{
+		{image: "nginx", name: "nginx"},
+		{image: "nginx:1.25", name: "nginx", tag: "1.25"},
+		{image: "registry:5000/org/app", name: "registry:5000/org/app"},
+		{image: "registry:5000/org/app:v2", name: "registry:5000/org/app", tag: "v2"},
+		{image: "org/app:v2@sha256:abcd", name: "org/app", tag: "v2"},
+	} {
+		name, tag := splitImage(tc.image)
+		assert.Equal(t, tc.name, name, tc.image)
+		assert.Equal(t, tc.tag, tag, tc.image)
+	}
+}
+
+func TestDockerRuntime(t *testing.T) {
+	socket := path.Join(t.TempDir(), "docker.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/containers/abcd/json", func(rw http.ResponseWriter, _ *http.Request) {
+		_, _ = rw.Write([]byte(`{"Id":"abcd","Name":"/shop-frontend-1",` +
+			`"Config":{"Image":"shop/frontend:1.2","Labels":{"com.docker.compose.service":"frontend"}}}`))
+	})
+	srv := http.Server{Handler: mux}
+	go func() { _ = srv.Serve(listener) }()
+	defer srv.Close()
+
+	rt, err := Connect(socket, "")
+	require.NoError(t, err)
+	defer rt.Close()
+
+	info, err := rt.Info(context.Background(), "abcd")
+	require.NoError(t, err)
+	assert.Equal(t, &Info{
+		ID:        "abcd",
+		Name:      "shop-frontend-1",
+		ImageName: "shop/frontend",
+		ImageTag:  "1.2",
+		Runtime:   "docker",
+		Labels:    map[string]string{"com.docker.compose.service": "frontend"},
+	}, info)
+
+	_, err = rt.Info(context.Background(), "notfound")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestConnect_NoRuntime(t *testing.T) {
+	_, err := Connect(path.Join(t.TempDir(), "docker.sock"), "")
+	assert.Error(t, err)
+}
+
+func TestParseCRIContainerStatus(t *testing.T) {
+	appendMsg := func(b []byte, num protowire.Number, msg []byte) []byte {
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		return protowire.AppendBytes(b, msg)
+	}
+	appendString := func(b []byte, num protowire.Number, s string) []byte {
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		return protowire.AppendString(b, s)
+	}
+	var status []byte
+	status = appendString(status, criStatusID, "abcd")
+	status = appendMsg(status, criStatusMetadata, appendString(nil, criMetadataName, "frontend"))
+	// state field (varint), which must be ignored
+	status = protowire.AppendTag(status, 3, protowire.VarintType)
+	status = protowire.AppendVarint(status, 1)
+	status = appendMsg(status, criStatusImage, appendString(nil, criImageSpecImage, "docker.io/shop/frontend:1.2"))
+	status = appendMsg(status, criStatusLabels,
+		appendString(appendString(nil, criMapKey, "app"), criMapValue, "shop"))
+	status = appendMsg(status, criStatusLabels,
+		appendString(appendString(nil, criMapKey, "tier"), criMapValue, "web"))
+
+	info := Info{Runtime: "containerd"}
+	require.NoError(t, forEachField(appendMsg(nil, criStatusResponseStatus, status),
+		func(num protowire.Number, value []byte) error {
+			if num == criStatusResponseStatus {
+				return parseCRIContainerStatus(value, &info)
+			}
+			return nil
+		}))
+	assert.Equal(t, Info{
+		ID:        "abcd",
+		Name:      "frontend",
+		ImageName: "docker.io/shop/frontend",
+		ImageTag:  "1.2",
+		Runtime:   "containerd",
+		Labels:    map[string]string{"app": "shop", "tier": "web"},
+	}, info)
+
+	assert.Error(t, forEachField([]byte{0x0a, 0x10, 0x01}, func(_ protowire.Number, _ []byte) error {
+		return nil
+	}))
+}
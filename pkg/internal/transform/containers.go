@@ -0,0 +1,133 @@
+package transform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/simplelru"
+	"github.com/mariomac/pipes/pkg/node"
+
+	"github.com/grafana/beyla/pkg/internal/exec"
+	"github.com/grafana/beyla/pkg/internal/request"
+	"github.com/grafana/beyla/pkg/internal/svc"
+	"github.com/grafana/beyla/pkg/internal/transform/container"
+)
+
+const containerRuntimeTimeout = 2 * time.Second
+
+func clog() *slog.Logger {
+	return slog.With("component", "transform.ContainerDecorator")
+}
+
+// ContainerDecorator configures the decoration of the instrumented services with the
+// metadata of the container where they run, as provided by the local container runtime
+// (Docker, containerd or CRI-O).
+type ContainerDecorator struct {
+	Enable bool `yaml:"enable" env:"CONTAINER_METADATA_ENABLE"`
+	// DockerSocket is the path of the Docker Engine API socket
+	DockerSocket string `yaml:"docker_socket" env:"CONTAINER_METADATA_DOCKER_SOCKET"`
+	// CRISocket is the path of the CRI API socket of containerd or CRI-O
+	CRISocket string `yaml:"cri_socket" env:"CONTAINER_METADATA_CRI_SOCKET"`
+	// Labels is the list of container labels that will be reported as container.label.<name>
+	// attributes of the instrumented services
+	Labels []string `yaml:"labels" env:"CONTAINER_METADATA_LABELS"`
+}
+
+func (d ContainerDecorator) Enabled() bool {
+	return d.Enable
+}
+
+func ContainerDecoratorProvider(cfg ContainerDecorator) (node.MiddleFunc[[]request.Span, []request.Span], error) {
+	runtime, err := container.Connect(cfg.DockerSocket, cfg.CRISocket)
+	if err != nil {
+		return nil, fmt.Errorf("instantiating container metadata decorator: %w", err)
+	}
+	decorator := newContainerDecorator(&cfg, runtime)
+	return func(in <-chan []request.Span, out chan<- []request.Span) {
+		defer runtime.Close()
+		clog().Debug("starting container decoration loop")
+		for spans := range in {
+			for i := range spans {
+				decorator.decorateService(&spans[i].ServiceID)
+			}
+			out <- spans
+		}
+		clog().Debug("stopping container decoration loop")
+	}, nil
+}
+
+type containerDecorator struct {
+	cfg     *ContainerDecorator
+	runtime container.Runtime
+	// services caches the container metadata of the service instances, by PID
+	services *simplelru.LRU[int32, *serviceMetadata]
+	// containerID returns the ID of the container of a given process.
+	// It can be overridden for testing purposes
+	containerID func(pid int32) (string, error)
+}
+
+func newContainerDecorator(cfg *ContainerDecorator, runtime container.Runtime) *containerDecorator {
+	cd := &containerDecorator{cfg: cfg, runtime: runtime, containerID: exec.ContainerID}
+	cd.services, _ = simplelru.NewLRU[int32, *serviceMetadata](serviceMetadataCacheLen, nil)
+	return cd
+}
+
+// decorateService adds the metadata of the container where the service process runs.
+// If the service already has metadata from a previous decorator, both are merged.
+func (cd *containerDecorator) decorateService(id *svc.ID) {
+	if id.ProcPID == 0 {
+		return
+	}
+	meta, ok := cd.services.Get(id.ProcPID)
+	if !ok || time.Since(meta.lastLookup) > serviceMetadataTTL ||
+		(meta.attrs == nil && time.Since(meta.lastLookup) > serviceMetadataRetry) {
+		meta = cd.lookupService(id.ProcPID)
+		cd.services.Add(id.ProcPID, meta)
+	}
+	id.AddMetadata(meta.attrs)
+}
+
+func (cd *containerDecorator) lookupService(pid int32) *serviceMetadata {
+	meta := &serviceMetadata{lastLookup: time.Now()}
+	log := clog().With("pid", pid)
+	cid, err := cd.containerID(pid)
+	if err != nil {
+		log.Debug("can't get container ID of the service process", "error", err)
+		return meta
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), containerRuntimeTimeout)
+	defer cancel()
+	info, err := cd.runtime.Info(ctx, cid)
+	if errors.Is(err, container.ErrNotFound) {
+		// the container might belong to another runtime, but we still know its ID
+		log.Debug("container not found in the runtime", "containerID", cid)
+		meta.attrs = map[string]string{container.IDKey: cid}
+		return meta
+	}
+	if err != nil {
+		log.Warn("can't get container metadata", "containerID", cid, "error", err)
+		return meta
+	}
+	meta.attrs = cd.containerMetadata(info)
+	return meta
+}
+
+func (cd *containerDecorator) containerMetadata(info *container.Info) map[string]string {
+	attrs := make(map[string]string, 5+len(cd.cfg.Labels))
+	attrs[container.IDKey] = info.ID
+	attrs[container.NameKey] = info.Name
+	attrs[container.RuntimeKey] = info.Runtime
+	attrs[container.ImageNameKey] = info.ImageName
+	if info.ImageTag != "" {
+		attrs[container.ImageTagKey] = info.ImageTag
+	}
+	for _, label := range cd.cfg.Labels {
+		if v, ok := info.Labels[label]; ok {
+			attrs[container.LabelPrefix+label] = v
+		}
+	}
+	return attrs
+}
@@ -0,0 +1,78 @@
+package transform
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/grafana/beyla/pkg/internal/svc"
+	"github.com/grafana/beyla/pkg/internal/transform/container"
+)
+
+type fakeRuntime struct {
+	containers map[string]*container.Info
+	calls      int
+}
+
+func (f *fakeRuntime) Info(_ context.Context, containerID string) (*container.Info, error) {
+	f.calls++
+	if info, ok := f.containers[containerID]; ok {
+		return info, nil
+	}
+	return nil, container.ErrNotFound
+}
+
+func (f *fakeRuntime) Close() error { return nil }
+
+func TestContainerDecorator(t *testing.T) {
+	rt := &fakeRuntime{containers: map[string]*container.Info{
+		"abcd": {
+			ID: "abcd", Name: "frontend", ImageName: "shop/frontend", ImageTag: "1.2", Runtime: "docker",
+			Labels: map[string]string{"com.docker.compose.service": "frontend", "ignored": "label"},
+		},
+	}}
+	cd := newContainerDecorator(&ContainerDecorator{Labels: []string{"com.docker.compose.service"}}, rt)
+	cd.containerID = func(pid int32) (string, error) {
+		switch pid {
+		case 1:
+			return "abcd", nil
+		case 2:
+			return "ef01", nil
+		default:
+			return "", errors.New("not in a container")
+		}
+	}
+
+	id := svc.ID{Name: "frontend", ProcPID: 1}
+	cd.decorateService(&id)
+	assert.Equal(t, map[string]string{
+		"container.id":                               "abcd",
+		"container.name":                             "frontend",
+		"container.runtime":                          "docker",
+		"container.image.name":                       "shop/frontend",
+		"container.image.tag":                        "1.2",
+		"container.label.com.docker.compose.service": "frontend",
+	}, id.Metadata)
+
+	// metadata from previous decorators is kept
+	k8sMeta := map[string]string{"k8s.pod.name": "frontend-1234"}
+	id = svc.ID{Name: "frontend", ProcPID: 1, Metadata: k8sMeta}
+	cd.decorateService(&id)
+	assert.Equal(t, "frontend-1234", id.Metadata["k8s.pod.name"])
+	assert.Equal(t, "frontend", id.Metadata["container.name"])
+	assert.Len(t, k8sMeta, 1, "source metadata must not be modified")
+	// the runtime has been queried only once
+	assert.Equal(t, 1, rt.calls)
+
+	// containers unknown to the runtime are still reported by ID
+	id = svc.ID{Name: "other", ProcPID: 2}
+	cd.decorateService(&id)
+	assert.Equal(t, map[string]string{"container.id": "ef01"}, id.Metadata)
+
+	// processes out of containers are not decorated
+	id = svc.ID{Name: "host", ProcPID: 3}
+	cd.decorateService(&id)
+	assert.Empty(t, id.Metadata)
+}
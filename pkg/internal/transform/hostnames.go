@@ -0,0 +1,75 @@
+package transform
+
+import (
+	"log/slog"
+	"net"
+
+	"github.com/hashicorp/golang-lru/v2/simplelru"
+	"github.com/mariomac/pipes/pkg/node"
+
+	"github.com/grafana/beyla/pkg/internal/request"
+)
+
+// size of the cache of resolved host names, by process and IP address
+const hostNamesCacheLen = 4096
+
+func hnlog() *slog.Logger {
+	return slog.With("component", "transform.HostNameDecorator")
+}
+
+// HostNameDecorator enables the decoration of the HTTP and gRPC client spans with the
+// host names that the instrumented services resolved, instead of the IP addresses of
+// the servers. The host names are learned from the spans of the DNS tracer.
+type HostNameDecorator bool
+
+func (d HostNameDecorator) Enabled() bool {
+	return bool(d)
+}
+
+func HostNameDecoratorProvider(_ HostNameDecorator) (node.MiddleFunc[[]request.Span, []request.Span], error) {
+	decorator := newHostNameDecorator()
+	return func(in <-chan []request.Span, out chan<- []request.Span) {
+		hnlog().Debug("starting host name decoration loop")
+		for spans := range in {
+			for i := range spans {
+				decorator.decorate(&spans[i])
+			}
+			out <- spans
+		}
+		hnlog().Debug("stopping host name decoration loop")
+	}, nil
+}
+
+// the same IP address might be resolved from different names by different
+// processes (e.g. virtual hosts), so the names are cached by process
+type resolvedHost struct {
+	pid int32
+	ip  string
+}
+
+type hostNameDecorator struct {
+	names *simplelru.LRU[resolvedHost, string]
+}
+
+func newHostNameDecorator() *hostNameDecorator {
+	names, _ := simplelru.NewLRU[resolvedHost, string](hostNamesCacheLen, nil)
+	return &hostNameDecorator{names: names}
+}
+
+func (hd *hostNameDecorator) decorate(span *request.Span) {
+	switch span.Type {
+	case request.EventTypeDNSClient:
+		// the queried name is cached instead of the canonical name of the answers,
+		// as it is the name that the application actually resolved
+		for _, ip := range span.ResolvedAddresses {
+			hd.names.Add(resolvedHost{pid: span.ServiceID.ProcPID, ip: ip}, span.Path)
+		}
+	case request.EventTypeHTTPClient, request.EventTypeGRPCClient:
+		if net.ParseIP(span.Host) == nil {
+			return
+		}
+		if name, ok := hd.names.Get(resolvedHost{pid: span.ServiceID.ProcPID, ip: span.Host}); ok {
+			span.Host = name
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/grafana/beyla/pkg/internal/request"
+	"github.com/grafana/beyla/pkg/internal/svc"
+)
+
+func TestHostNameDecorator(t *testing.T) {
+	hd := newHostNameDecorator()
+	frontend := svc.ID{Name: "frontend", ProcPID: 1}
+	backend := svc.ID{Name: "backend", ProcPID: 2}
+
+	hd.decorate(&request.Span{
+		Type: request.EventTypeDNSClient, ServiceID: frontend, Path: "api.grafana.com",
+		ResolvedAddresses: []string{"34.120.177.193", "2600:1901:0:b3ea::"},
+	})
+	// a different process resolved the same address from another name
+	hd.decorate(&request.Span{
+		Type: request.EventTypeDNSClient, ServiceID: backend, Path: "grafana.com",
+		ResolvedAddresses: []string{"34.120.177.193"},
+	})
+
+	httpClient := request.Span{Type: request.EventTypeHTTPClient, ServiceID: frontend, Host: "34.120.177.193"}
+	hd.decorate(&httpClient)
+	assert.Equal(t, "api.grafana.com", httpClient.Host)
+
+	grpcClient := request.Span{Type: request.EventTypeGRPCClient, ServiceID: frontend, Host: "2600:1901:0:b3ea::"}
+	hd.decorate(&grpcClient)
+	assert.Equal(t, "api.grafana.com", grpcClient.Host)
+
+	httpClient = request.Span{Type: request.EventTypeHTTPClient, ServiceID: backend, Host: "34.120.177.193"}
+	hd.decorate(&httpClient)
+	assert.Equal(t, "grafana.com", httpClient.Host)
+
+	// unknown addresses, host names and server spans are not modified
+	httpClient = request.Span{Type: request.EventTypeHTTPClient, ServiceID: frontend, Host: "10.0.0.1"}
+	hd.decorate(&httpClient)
+	assert.Equal(t, "10.0.0.1", httpClient.Host)
+
+	httpClient = request.Span{Type: request.EventTypeHTTPClient, ServiceID: frontend, Host: "localhost"}
+	hd.decorate(&httpClient)
+	assert.Equal(t, "localhost", httpClient.Host)
+
+	httpServer := request.Span{Type: request.EventTypeHTTP, ServiceID: frontend, Host: "34.120.177.193"}
+	hd.decorate(&httpServer)
+	assert.Equal(t, "34.120.177.193", httpServer.Host)
+}
@@ -0,0 +1,145 @@
+package transform
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+
+	"github.com/mariomac/pipes/pkg/node"
+
+	"github.com/grafana/beyla/pkg/internal/request"
+)
+
+// MaxIgnorePrefixLen is the maximum length of the path and user agent prefixes that can be
+// matched by the eBPF programs
+const MaxIgnorePrefixLen = 64
+
+func ilog() *slog.Logger {
+	return slog.With("component", "transform.IgnoreFilter")
+}
+
+// IgnoreConfig lists the criteria of the requests that must not be instrumented, such as
+// health checks or metrics scrapes. When possible, the requests are discarded by the eBPF
+// programs before being sent to user space.
+type IgnoreConfig struct {
+	// Paths is a list of URL path prefixes of the requests to ignore
+	Paths []string `yaml:"paths" env:"IGNORE_PATHS"`
+	// Methods is a list of HTTP methods of the requests to ignore
+	Methods []string `yaml:"methods" env:"IGNORE_METHODS"`
+	// UserAgents is a list of User-Agent header prefixes of the requests to ignore. They are only
+	// matched by the generic HTTP tracer, and only if the header is in the first bytes of the request.
+	UserAgents []string `yaml:"user_agents" env:"IGNORE_USER_AGENTS"`
+	// PeerCIDRs is a list of CIDRs of the peers whose requests are ignored
+	PeerCIDRs []string `yaml:"peer_cidrs" env:"IGNORE_PEER_CIDRS"`
+}
+
+func (c IgnoreConfig) Enabled() bool {
+	return len(c.Paths) > 0 || len(c.Methods) > 0 || len(c.UserAgents) > 0 || len(c.PeerCIDRs) > 0
+}
+
+func (c *IgnoreConfig) Validate() error {
+	for _, p := range c.Paths {
+		if len(p) == 0 || len(p) > MaxIgnorePrefixLen {
+			return fmt.Errorf("ignored path prefix %q must have between 1 and %d characters", p, MaxIgnorePrefixLen)
+		}
+	}
+	for _, ua := range c.UserAgents {
+		if len(ua) == 0 || len(ua) > MaxIgnorePrefixLen {
+			return fmt.Errorf("ignored user agent prefix %q must have between 1 and %d characters", ua, MaxIgnorePrefixLen)
+		}
+	}
+	_, err := c.ParsePeerCIDRs()
+	return err
+}
+
+// ParsePeerCIDRs returns the networks of the PeerCIDRs
+func (c *IgnoreConfig) ParsePeerCIDRs() ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(c.PeerCIDRs))
+	for _, cidr := range c.PeerCIDRs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ignored peer CIDR: %w", err)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+// IgnoreProvider discards the spans matching the ignore criteria. It's a fallback for the
+// tracers that can't discard the requests in the kernel, such as the Go tracers.
+func IgnoreProvider(cfg IgnoreConfig) (node.MiddleFunc[[]request.Span, []request.Span], error) {
+	filter, err := newIgnoreFilter(&cfg)
+	if err != nil {
+		return nil, err
+	}
+	return func(in <-chan []request.Span, out chan<- []request.Span) {
+		ilog().Debug("starting ignore filter loop")
+		for spans := range in {
+			if filtered := filter.filter(spans); len(filtered) > 0 {
+				out <- filtered
+			}
+		}
+		ilog().Debug("stopping ignore filter loop")
+	}, nil
+}
+
+type ignoreFilter struct {
+	paths   []string
+	methods map[string]struct{}
+	peers   []*net.IPNet
+}
+
+func newIgnoreFilter(cfg *IgnoreConfig) (*ignoreFilter, error) {
+	peers, err := cfg.ParsePeerCIDRs()
+	if err != nil {
+		return nil, err
+	}
+	f := &ignoreFilter{paths: cfg.Paths, peers: peers, methods: map[string]struct{}{}}
+	for _, m := range cfg.Methods {
+		f.methods[strings.ToUpper(m)] = struct{}{}
+	}
+	return f, nil
+}
+
+// filter removes the ignored spans from the passed slice, reusing its backing array
+func (f *ignoreFilter) filter(spans []request.Span) []request.Span {
+	filtered := spans[:0]
+	for i := range spans {
+		if !f.ignore(&spans[i]) {
+			filtered = append(filtered, spans[i])
+		}
+	}
+	return filtered
+}
+
+func (f *ignoreFilter) ignore(span *request.Span) bool {
+	// the SQL and DNS spans don't have HTTP or gRPC attributes
+	if span.Type == request.EventTypeSQLClient || span.Type == request.EventTypeDNSClient {
+		return false
+	}
+	if _, ok := f.methods[span.Method]; ok {
+		return true
+	}
+	for _, p := range f.paths {
+		if strings.HasPrefix(span.Path, p) {
+			return true
+		}
+	}
+	if len(f.peers) > 0 {
+		// the peer of the server requests is the client. For client requests, it's the server
+		peer := span.Peer
+		switch span.Type {
+		case request.EventTypeHTTPClient, request.EventTypeGRPCClient, request.EventTypeTCPClient:
+			peer = span.Host
+		}
+		if ip := net.ParseIP(peer); ip != nil {
+			for _, n := range f.peers {
+				if n.Contains(ip) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
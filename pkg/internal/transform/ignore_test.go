@@ -0,0 +1,61 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/beyla/pkg/internal/request"
+	"github.com/grafana/beyla/pkg/internal/testutil"
+)
+
+func TestIgnoreFilter(t *testing.T) {
+	filter, err := IgnoreProvider(IgnoreConfig{
+		Paths:     []string{"/health", "/metrics"},
+		Methods:   []string{"options"},
+		PeerCIDRs: []string{"10.0.0.0/8", "fd00::/8"},
+	})
+	require.NoError(t, err)
+	in := make(chan []request.Span, 10)
+	defer close(in)
+	out := make(chan []request.Span, 10)
+	go filter(in, out)
+
+	in <- []request.Span{
+		{Type: request.EventTypeHTTP, Method: "GET", Path: "/healthz", Peer: "192.168.1.1"},
+		{Type: request.EventTypeHTTP, Method: "GET", Path: "/api/users", Peer: "192.168.1.1"},
+		{Type: request.EventTypeHTTP, Method: "OPTIONS", Path: "/api/users", Peer: "192.168.1.1"},
+		{Type: request.EventTypeHTTP, Method: "GET", Path: "/api/users", Peer: "10.1.2.3"},
+		{Type: request.EventTypeHTTPClient, Method: "GET", Path: "/metrics", Peer: "192.168.1.1"},
+		{Type: request.EventTypeGRPC, Path: "/foo.Bar/Baz", Peer: "fd00::1"},
+		// the peer of the client requests is the server
+		{Type: request.EventTypeHTTPClient, Method: "GET", Path: "/api/users", Peer: "192.168.1.1", Host: "10.1.2.3"},
+		{Type: request.EventTypeGRPCClient, Path: "/foo.Bar/Baz", Peer: "10.1.2.3", Host: "192.168.1.2"},
+		{Type: request.EventTypeSQLClient, Path: "/metrics"},
+		// the TCP client spans are also from the client (peer) to the server (host)
+		{Type: request.EventTypeTCPServer, Peer: "10.1.2.3", Host: "192.168.1.2"},
+		{Type: request.EventTypeTCPClient, Peer: "10.1.2.3", Host: "192.168.1.2"},
+		{Type: request.EventTypeTCPClient, Peer: "192.168.1.2", Host: "10.1.2.3"},
+	}
+	// spans from a batch where all of them are ignored are not forwarded
+	in <- []request.Span{{Type: request.EventTypeHTTP, Method: "GET", Path: "/health"}}
+	in <- []request.Span{{Type: request.EventTypeGRPC, Path: "/foo.Bar/Baz", Peer: "fe80::1"}}
+
+	spans := testutil.ReadChannel(t, out, testTimeout)
+	assert.Equal(t, []request.Span{
+		{Type: request.EventTypeHTTP, Method: "GET", Path: "/api/users", Peer: "192.168.1.1"},
+		{Type: request.EventTypeGRPCClient, Path: "/foo.Bar/Baz", Peer: "10.1.2.3", Host: "192.168.1.2"},
+		{Type: request.EventTypeSQLClient, Path: "/metrics"},
+		{Type: request.EventTypeTCPClient, Peer: "10.1.2.3", Host: "192.168.1.2"},
+	}, spans)
+	spans = testutil.ReadChannel(t, out, testTimeout)
+	assert.Equal(t, []request.Span{{Type: request.EventTypeGRPC, Path: "/foo.Bar/Baz", Peer: "fe80::1"}}, spans)
+}
+
+func TestIgnoreConfig_Validate(t *testing.T) {
+	assert.NoError(t, (&IgnoreConfig{Paths: []string{"/health"}, PeerCIDRs: []string{"10.0.0.0/8"}}).Validate())
+	assert.Error(t, (&IgnoreConfig{PeerCIDRs: []string{"10.0.0.0"}}).Validate())
+	assert.Error(t, (&IgnoreConfig{Paths: []string{""}}).Validate())
+	assert.Error(t, (&IgnoreConfig{UserAgents: []string{string(make([]byte, MaxIgnorePrefixLen+1))}}).Validate())
+}
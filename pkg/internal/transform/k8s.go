@@ -1,16 +1,20 @@
 package transform
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
 	"net"
 	"strings"
 	"time"
 
+	"github.com/hashicorp/golang-lru/v2/simplelru"
 	"github.com/mariomac/pipes/pkg/node"
 	"golang.org/x/exp/maps"
 
+	"github.com/grafana/beyla/pkg/internal/exec"
 	"github.com/grafana/beyla/pkg/internal/request"
+	"github.com/grafana/beyla/pkg/internal/svc"
 	"github.com/grafana/beyla/pkg/internal/transform/kube"
 )
 
@@ -27,6 +31,28 @@ const (
 	DstNameKey      = "k8s.dst.name"
 	DstNamespaceKey = "k8s.dst.namespace"
 	DstTypeKey      = "k8s.dst.type"
+
+	// Metadata of the Pod where the instrumented service runs. They are stored in the
+	// service ID metadata, and reported as resource attributes.
+	NamespaceNameKey    = "k8s.namespace.name"
+	PodNameKey          = "k8s.pod.name"
+	PodUIDKey           = "k8s.pod.uid"
+	NodeNameKey         = "k8s.node.name"
+	ContainerNameKey    = "k8s.container.name"
+	DeploymentNameKey   = "k8s.deployment.name"
+	ReplicaSetNameKey   = "k8s.replicaset.name"
+	StatefulSetNameKey  = "k8s.statefulset.name"
+	DaemonSetNameKey    = "k8s.daemonset.name"
+	PodLabelPrefix      = "k8s.pod.label."
+	PodAnnotationPrefix = "k8s.pod.annotation."
+
+	// size of the cache of service instances metadata, by PID
+	serviceMetadataCacheLen = 1024
+	// time after which the metadata of a service that couldn't be found is searched again
+	serviceMetadataRetry = 30 * time.Second
+	// time after which the metadata of a service is searched again, as its PID might have
+	// been reused by another process
+	serviceMetadataTTL = 5 * time.Minute
 )
 
 func klog() *slog.Logger {
@@ -39,6 +65,25 @@ type KubernetesDecorator struct {
 	KubeconfigPath string `yaml:"kubeconfig_path" env:"KUBE_METADATA_KUBECONFIG_PATH"`
 
 	InformersSyncTimeout time.Duration `yaml:"informers_sync_timeout" env:"KUBE_INFORMERS_SYNC_TIMEOUT"`
+
+	// PodLabels is the list of Pod labels that will be reported as k8s.pod.label.<name>
+	// attributes of the instrumented services
+	PodLabels []string `yaml:"pod_labels" env:"KUBE_METADATA_POD_LABELS"`
+	// PodAnnotations is the list of Pod annotations that will be reported as
+	// k8s.pod.annotation.<name> attributes of the instrumented services
+	PodAnnotations []string `yaml:"pod_annotations" env:"KUBE_METADATA_POD_ANNOTATIONS"`
+
+	// NodeLocalInformers restricts the Pods informer to the Pods running in the same node
+	// as Beyla, which is specified by the NodeName property. It reduces the load on the API
+	// server and the memory usage when Beyla runs as a DaemonSet in big clusters.
+	NodeLocalInformers bool `yaml:"node_local_informers" env:"KUBE_NODE_LOCAL_INFORMERS"`
+	// NodeName is the name of the node where Beyla is running. It is usually provided
+	// by the Kubernetes downward API, through the spec.nodeName field.
+	NodeName string `yaml:"node_name" env:"KUBE_NODE_NAME"`
+	// CacheService is the URL of a Beyla Kubernetes cache service. If set, the Services
+	// informer is disabled, and the remote peers that are not found in the local informers
+	// are looked up in the cache service.
+	CacheService string `yaml:"cache_service" env:"KUBE_CACHE_SERVICE"`
 }
 
 func (d KubernetesDecorator) Enabled() bool {
@@ -84,13 +129,45 @@ func KubeDecoratorProvider(cfg KubernetesDecorator) (node.MiddleFunc[[]request.S
 type metadataDecorator struct {
 	kube kube.Metadata
 	cfg  *KubernetesDecorator
+	// cache is only set if the remote peers are looked up in a cache service
+	cache *kube.CacheClient
 
+	ownPod           *kube.Info
 	ownMetadataAsSrc map[string]string
 	ownMetadataAsDst map[string]string
+
+	// services caches the metadata of the service instances, by PID
+	services *simplelru.LRU[int32, *serviceMetadata]
+	// containerID returns the ID of the container of a given process.
+	// It can be overridden for testing purposes
+	containerID func(pid int32) (string, error)
+}
+
+// serviceMetadata is the Kubernetes metadata of an instrumented service instance
+type serviceMetadata struct {
+	// instance is the Pod UID and container name, if the Pod was found
+	instance string
+	attrs    map[string]string
+	// lastLookup is the time of the last search for the service Pod, so it is retried if not found
+	lastLookup time.Time
 }
 
 func newMetadataDecorator(cfg *KubernetesDecorator) (*metadataDecorator, error) {
-	dec := &metadataDecorator{cfg: cfg}
+	dec := &metadataDecorator{cfg: cfg, containerID: exec.ContainerID}
+	dec.services, _ = simplelru.NewLRU[int32, *serviceMetadata](serviceMetadataCacheLen, nil)
+	dec.kube.PodLabels = cfg.PodLabels
+	dec.kube.PodAnnotations = cfg.PodAnnotations
+	if cfg.NodeLocalInformers {
+		if cfg.NodeName == "" {
+			return nil, errors.New("node_local_informers requires setting the node_name property " +
+				"or the KUBE_NODE_NAME environment variable")
+		}
+		dec.kube.NodeName = cfg.NodeName
+	}
+	if cfg.CacheService != "" {
+		dec.cache = kube.NewCacheClient(cfg.CacheService)
+		dec.kube.DisableServices = true
+	}
 	if err := dec.kube.InitFromConfig(cfg.KubeconfigPath, cfg.InformersSyncTimeout); err != nil {
 		return nil, err
 	}
@@ -98,6 +175,7 @@ func newMetadataDecorator(cfg *KubernetesDecorator) (*metadataDecorator, error)
 }
 
 func (md *metadataDecorator) do(span *request.Span) {
+	md.decorateService(&span.ServiceID)
 	if span.Metadata == nil {
 		span.Metadata = make(map[string]string, 5)
 	}
@@ -108,19 +186,31 @@ func (md *metadataDecorator) do(span *request.Span) {
 	// changes the way it works.
 	// Extensive integration test cases are provided as a safeguard.
 	switch span.Type {
-	case request.EventTypeGRPC, request.EventTypeHTTP:
-		if peerInfo, ok := md.kube.GetInfo(span.Peer); ok {
+	case request.EventTypeGRPC, request.EventTypeHTTP, request.EventTypeTCPServer:
+		if peerInfo, ok := md.getInfo(span.Peer); ok {
 			appendSRCMetadata(span.Metadata, peerInfo)
 		}
 		maps.Copy(span.Metadata, md.ownMetadataAsDst)
-	case request.EventTypeGRPCClient, request.EventTypeHTTPClient:
-		if peerInfo, ok := md.kube.GetInfo(span.Host); ok {
+	case request.EventTypeGRPCClient, request.EventTypeHTTPClient, request.EventTypeTCPClient:
+		if peerInfo, ok := md.getInfo(span.Host); ok {
 			appendDSTMetadata(span.Metadata, peerInfo)
 		}
 		maps.Copy(span.Metadata, md.ownMetadataAsSrc)
 	}
 }
 
+// getInfo looks up the peer in the local informers and, if not found there,
+// in the cache service
+func (md *metadataDecorator) getInfo(ipOrName string) (*kube.Info, bool) {
+	if info, ok := md.kube.GetInfo(ipOrName); ok {
+		return info, true
+	}
+	if md.cache != nil {
+		return md.cache.GetInfo(ipOrName)
+	}
+	return nil, false
+}
+
 // TODO: allow users to filter which attributes they want, instead of adding all of them
 // TODO: cache
 func appendDSTMetadata(to map[string]string, info *kube.Info) {
@@ -134,10 +224,74 @@ func appendSRCMetadata(to map[string]string, info *kube.Info) {
 	to[SrcNamespaceKey] = info.Namespace
 }
 
+// decorateService sets the metadata of the Pod and container where the service instance runs.
+// The Pod is looked up from the container of the service process. If it can't be found (e.g.
+// the PID is unknown) the service is assumed to run in the same Pod as Beyla.
+func (md *metadataDecorator) decorateService(id *svc.ID) {
+	meta, ok := md.services.Get(id.ProcPID)
+	if !ok || time.Since(meta.lastLookup) > serviceMetadataTTL ||
+		(meta.instance == "" && time.Since(meta.lastLookup) > serviceMetadataRetry) {
+		meta = md.lookupService(id.ProcPID)
+		md.services.Add(id.ProcPID, meta)
+	}
+	id.Instance = meta.instance
+	id.AddMetadata(meta.attrs)
+}
+
+func (md *metadataDecorator) lookupService(pid int32) *serviceMetadata {
+	meta := &serviceMetadata{lastLookup: time.Now()}
+	if pid != 0 {
+		cid, err := md.containerID(pid)
+		if err != nil {
+			klog().Debug("can't get container ID of the service process", "pid", pid, "error", err)
+		} else if pod, ok := md.kube.GetContainerPod(cid); ok {
+			containerName := pod.ContainerName(cid)
+			meta.instance = string(pod.UID) + "/" + containerName
+			meta.attrs = md.podMetadata(pod, containerName)
+			return meta
+		}
+	}
+	if md.ownPod != nil {
+		meta.attrs = md.podMetadata(md.ownPod, "")
+	}
+	return meta
+}
+
+func (md *metadataDecorator) podMetadata(pod *kube.Info, containerName string) map[string]string {
+	attrs := make(map[string]string, 6+len(pod.Labels)+len(pod.Annotations))
+	attrs[NamespaceNameKey] = pod.Namespace
+	attrs[PodNameKey] = pod.Name
+	attrs[PodUIDKey] = string(pod.UID)
+	attrs[NodeNameKey] = pod.NodeName
+	if containerName != "" {
+		attrs[ContainerNameKey] = containerName
+	}
+	if pod.Owner != nil {
+		switch pod.Owner.Kind {
+		case "Deployment":
+			attrs[DeploymentNameKey] = pod.Owner.Name
+		case "ReplicaSet":
+			attrs[ReplicaSetNameKey] = pod.Owner.Name
+		case "StatefulSet":
+			attrs[StatefulSetNameKey] = pod.Owner.Name
+		case "DaemonSet":
+			attrs[DaemonSetNameKey] = pod.Owner.Name
+		}
+	}
+	for k, v := range pod.Labels {
+		attrs[PodLabelPrefix+k] = v
+	}
+	for k, v := range pod.Annotations {
+		attrs[PodAnnotationPrefix+k] = v
+	}
+	return attrs
+}
+
 func (md *metadataDecorator) refreshOwnPodMetadata() {
 	for {
 		if info, ok := md.kube.GetInfo(getLocalIP()); ok {
 			klog().Debug("found local pod metadata", "metadata", info)
+			md.ownPod = info
 			md.ownMetadataAsSrc = make(map[string]string, 2)
 			md.ownMetadataAsDst = make(map[string]string, 2)
 			appendSRCMetadata(md.ownMetadataAsSrc, info)
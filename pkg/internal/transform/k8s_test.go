@@ -0,0 +1,88 @@
+package transform
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/simplelru"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/grafana/beyla/pkg/internal/svc"
+	"github.com/grafana/beyla/pkg/internal/transform/kube"
+)
+
+func TestPodMetadata(t *testing.T) {
+	md := metadataDecorator{}
+	attrs := md.podMetadata(&kube.Info{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "frontend-7c9f8d5b4-x2x8v",
+			Namespace:   "shop",
+			UID:         "0a1b2c3d",
+			Labels:      map[string]string{"app": "frontend"},
+			Annotations: map[string]string{"team": "web"},
+		},
+		NodeName: "node-1",
+		Owner:    &kube.Owner{Kind: "Deployment", Name: "frontend"},
+	}, "server")
+	assert.Equal(t, map[string]string{
+		"k8s.namespace.name":      "shop",
+		"k8s.pod.name":            "frontend-7c9f8d5b4-x2x8v",
+		"k8s.pod.uid":             "0a1b2c3d",
+		"k8s.node.name":           "node-1",
+		"k8s.container.name":      "server",
+		"k8s.deployment.name":     "frontend",
+		"k8s.pod.label.app":       "frontend",
+		"k8s.pod.annotation.team": "web",
+	}, attrs)
+}
+
+func TestDecorateService_OwnPodFallback(t *testing.T) {
+	md := metadataDecorator{
+		ownPod: &kube.Info{
+			ObjectMeta: metav1.ObjectMeta{Name: "beyla-sidecar", Namespace: "shop", UID: "1234"},
+			NodeName:   "node-1",
+			Owner:      &kube.Owner{Kind: "StatefulSet", Name: "beyla"},
+		},
+		containerID: func(_ int32) (string, error) {
+			return "", errors.New("not in a container")
+		},
+	}
+	md.services, _ = simplelru.NewLRU[int32, *serviceMetadata](10, nil)
+
+	id := svc.ID{Name: "frontend", ProcPID: 1234}
+	md.decorateService(&id)
+	assert.Empty(t, id.Instance)
+	assert.Equal(t, map[string]string{
+		"k8s.namespace.name":   "shop",
+		"k8s.pod.name":         "beyla-sidecar",
+		"k8s.pod.uid":          "1234",
+		"k8s.node.name":        "node-1",
+		"k8s.statefulset.name": "beyla",
+	}, id.Metadata)
+
+	// the metadata is cached for the same PID
+	md.containerID = func(_ int32) (string, error) {
+		assert.Fail(t, "container ID should not be looked up again")
+		return "", nil
+	}
+	id2 := svc.ID{Name: "frontend", ProcPID: 1234}
+	md.decorateService(&id2)
+	assert.Equal(t, id.Metadata, id2.Metadata)
+
+	// after the TTL, the metadata is looked up again, as the PID might have been reused
+	meta, ok := md.services.Get(1234)
+	require.True(t, ok)
+	meta.lastLookup = time.Now().Add(-serviceMetadataTTL - time.Second)
+	lookups := 0
+	md.containerID = func(_ int32) (string, error) {
+		lookups++
+		return "", errors.New("not in a container")
+	}
+	id3 := svc.ID{Name: "frontend", ProcPID: 1234}
+	md.decorateService(&id3)
+	assert.Equal(t, 1, lookups)
+	assert.Equal(t, id.Metadata, id3.Metadata)
+}
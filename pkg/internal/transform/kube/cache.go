@@ -0,0 +1,155 @@
+package kube
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/simplelru"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// CachePath is the HTTP path where the cache service serves the Pods and Services metadata
+	CachePath = "/info"
+	// cacheKeyParam is the query parameter that specifies the IP or name of the looked up entity
+	cacheKeyParam = "key"
+
+	cacheClientLen      = 4096
+	cacheClientTTL      = 30 * time.Second
+	cacheClientErrorTTL = 5 * time.Second
+	cacheClientTimeout  = time.Second
+	// maximum number of concurrent requests to the cache service
+	cacheClientMaxFetches = 16
+)
+
+// cachedInfo is the subset of Info that is sent by the cache service: the fields that are
+// required to decorate the remote peers of the instrumented services
+type cachedInfo struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Type      string `json:"type"`
+}
+
+// CacheHandler serves the metadata of the Pods and Services, given their IP or Service name.
+// It allows running a single, cluster-wide instance of Metadata that is queried by all the
+// Beyla instances that use node-local informers.
+func (k *Metadata) CacheHandler() http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		key := req.URL.Query().Get(cacheKeyParam)
+		if key == "" {
+			http.Error(rw, "missing '"+cacheKeyParam+"' query parameter", http.StatusBadRequest)
+			return
+		}
+		info, ok := k.GetInfo(key)
+		if !ok {
+			http.NotFound(rw, req)
+			return
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(rw).Encode(cachedInfo{
+			Name: info.Name, Namespace: info.Namespace, Type: info.Type,
+		}); err != nil {
+			klog().Debug("can't write cache response", "error", err)
+		}
+	})
+}
+
+// CacheClient looks up the metadata of Pods and Services in a remote cache service, keeping
+// a local copy of the recent responses. The cache service is queried in background, so the
+// callers are never blocked by it.
+type CacheClient struct {
+	url    string
+	client http.Client
+	// fetches limits the number of concurrent requests to the cache service
+	fetches chan struct{}
+
+	mt      sync.Mutex
+	entries *simplelru.LRU[string, cacheEntry]
+}
+
+type cacheEntry struct {
+	info    *Info
+	expires time.Time
+	// pending is true while the entry is being fetched from the cache service
+	pending bool
+}
+
+// NewCacheClient creates a CacheClient for the cache service at the provided base URL
+// (for example, http://beyla-k8s-cache:8999).
+func NewCacheClient(serviceURL string) *CacheClient {
+	entries, _ := simplelru.NewLRU[string, cacheEntry](cacheClientLen, nil)
+	return &CacheClient{
+		url:     strings.TrimSuffix(serviceURL, "/") + CachePath,
+		client:  http.Client{Timeout: cacheClientTimeout},
+		fetches: make(chan struct{}, cacheClientMaxFetches),
+		entries: entries,
+	}
+}
+
+// GetInfo returns the metadata of a Pod or Service given its IP or Service Name, from the local
+// copy of the cache service responses. If the entity is not in the local copy, or it has expired,
+// it is fetched in background, and the previously stored value (if any) is returned meanwhile.
+// Found, not found and error responses are cached for a short time.
+func (c *CacheClient) GetInfo(ipOrName string) (*Info, bool) {
+	c.mt.Lock()
+	defer c.mt.Unlock()
+	entry, ok := c.entries.Get(ipOrName)
+	if ok && (entry.pending || time.Now().Before(entry.expires)) {
+		return entry.info, entry.info != nil
+	}
+	select {
+	case c.fetches <- struct{}{}:
+		c.entries.Add(ipOrName, cacheEntry{info: entry.info, pending: true})
+		go c.update(ipOrName)
+	default:
+		// too many concurrent requests. The entity will be fetched in a later invocation
+	}
+	return entry.info, entry.info != nil
+}
+
+// update fetches the metadata of the entity from the cache service and stores it
+func (c *CacheClient) update(ipOrName string) {
+	defer func() { <-c.fetches }()
+	info, err := c.fetch(ipOrName)
+	ttl := cacheClientTTL
+	c.mt.Lock()
+	defer c.mt.Unlock()
+	if err != nil {
+		// keeping the previous value, if any, and retrying after a shorter time
+		klog().Debug("can't get metadata from cache service", "key", ipOrName, "error", err)
+		ttl = cacheClientErrorTTL
+		if entry, ok := c.entries.Peek(ipOrName); ok {
+			info = entry.info
+		}
+	}
+	c.entries.Add(ipOrName, cacheEntry{info: info, expires: time.Now().Add(ttl)})
+}
+
+// fetch returns nil, without error, if the entity is not found in the cache service
+func (c *CacheClient) fetch(ipOrName string) (*Info, error) {
+	resp, err := c.client.Get(c.url + "?" + cacheKeyParam + "=" + url.QueryEscape(ipOrName))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		ci := cachedInfo{}
+		if err := json.NewDecoder(resp.Body).Decode(&ci); err != nil {
+			return nil, fmt.Errorf("decoding response: %w", err)
+		}
+		return &Info{
+			ObjectMeta: metav1.ObjectMeta{Name: ci.Name, Namespace: ci.Namespace},
+			Type:       ci.Type,
+		}, nil
+	case http.StatusNotFound:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unexpected HTTP status: %s", resp.Status)
+	}
+}
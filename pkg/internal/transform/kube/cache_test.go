@@ -0,0 +1,97 @@
+package kube
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mariomac/guara/pkg/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const timeout = 5 * time.Second
+
+func TestCacheClient(t *testing.T) {
+	// cluster-wide metadata, as served by the cache service
+	pidx := IndexerMock{}
+	pidx.On("ByIndex", IndexIPOrName, "10.0.0.1").Return([]interface{}{&Info{
+		Type:       "Pod",
+		ObjectMeta: metav1.ObjectMeta{Name: "podName", Namespace: "podNamespace"},
+	}}, nil)
+	pidx.On("ByIndex", IndexIPOrName, "10.0.0.2").Return([]interface{}{}, os.ErrNotExist)
+	pim := InformerMock{}
+	pim.On("GetIndexer").Return(&pidx)
+	kubeData := Metadata{pods: &pim}
+
+	var requests atomic.Int32
+	handler := kubeData.CacheHandler()
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		requests.Add(1)
+		handler.ServeHTTP(rw, req)
+	}))
+	defer srv.Close()
+
+	client := NewCacheClient(srv.URL + "/")
+	// the first lookups are not blocked by the cache service requests
+	_, ok := client.GetInfo("10.0.0.1")
+	assert.False(t, ok)
+	_, ok = client.GetInfo("10.0.0.2")
+	assert.False(t, ok)
+
+	test.Eventually(t, timeout, func(t require.TestingT) {
+		info, ok := client.GetInfo("10.0.0.1")
+		require.True(t, ok)
+		assert.Equal(t, &Info{
+			Type:       "Pod",
+			ObjectMeta: metav1.ObjectMeta{Name: "podName", Namespace: "podNamespace"},
+		}, info)
+	}, test.Interval(10*time.Millisecond))
+	test.Eventually(t, timeout, func(t require.TestingT) {
+		entry, ok := peekEntry(client, "10.0.0.2")
+		require.True(t, ok)
+		require.False(t, entry.pending)
+	}, test.Interval(10*time.Millisecond))
+	assert.EqualValues(t, 2, requests.Load())
+
+	// found and not found responses are cached
+	_, ok = client.GetInfo("10.0.0.1")
+	assert.True(t, ok)
+	_, ok = client.GetInfo("10.0.0.2")
+	assert.False(t, ok)
+	assert.EqualValues(t, 2, requests.Load())
+}
+
+func TestCacheClient_ServiceDown(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+		rw.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	client := NewCacheClient(srv.URL)
+	_, ok := client.GetInfo("10.0.0.1")
+	assert.False(t, ok)
+	test.Eventually(t, timeout, func(t require.TestingT) {
+		entry, ok := peekEntry(client, "10.0.0.1")
+		require.True(t, ok)
+		require.False(t, entry.pending)
+		// errors are cached for a shorter time
+		assert.WithinDuration(t, time.Now().Add(cacheClientErrorTTL), entry.expires, time.Second)
+	}, test.Interval(10*time.Millisecond))
+	_, ok = client.GetInfo("10.0.0.1")
+	assert.False(t, ok)
+	assert.EqualValues(t, 1, requests.Load())
+}
+
+// peekEntry returns a copy of the cached entry, without holding the lock while the test asserts it
+func peekEntry(client *CacheClient, key string) (cacheEntry, bool) {
+	client.mt.Lock()
+	defer client.mt.Unlock()
+	return client.entries.Peek(key)
+}
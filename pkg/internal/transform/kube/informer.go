@@ -6,10 +6,12 @@ import (
 	"log/slog"
 	"os"
 	"path"
+	"strings"
 	"time"
 
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -21,8 +23,13 @@ const (
 	kubeConfigEnvVariable = "KUBECONFIG"
 	syncTime              = 10 * time.Minute
 	IndexIPOrName         = "idx"
+	IndexContainerID      = "cid"
 	typePod               = "Pod"
 	typeService           = "Service"
+
+	// ReplicaSets created by Deployments are named after the Deployment, and their Pods
+	// are labeled with the hash that is appended to the ReplicaSet name
+	podTemplateHashLabel = "pod-template-hash"
 )
 
 func klog() *slog.Logger {
@@ -31,6 +38,18 @@ func klog() *slog.Logger {
 
 // Metadata stores an in-memory copy of the different Kubernetes objects whose metadata is relevant to us.
 type Metadata struct {
+	// PodLabels and PodAnnotations specify the names of the Pod labels and annotations
+	// that are stored in the cache. Any other label or annotation is discarded to save memory.
+	// They must be set before invoking InitFromConfig.
+	PodLabels      []string
+	PodAnnotations []string
+	// NodeName, if set, restricts the Pods informer to the Pods running in the given node.
+	// It must be set before invoking InitFromConfig.
+	NodeName string
+	// DisableServices avoids starting the Services informer (for example, when the Services
+	// are looked up from a shared CacheClient). It must be set before invoking InitFromConfig.
+	DisableServices bool
+
 	// pods and services cache the different object types as *Info pointers
 	pods     cache.SharedIndexInformer
 	services cache.SharedIndexInformer
@@ -47,7 +66,36 @@ type Info struct {
 	// Informers need that internal object is an ObjectMeta instance
 	metav1.ObjectMeta
 	Type string
-	ips  []string
+	// NodeName is only set for Pods
+	NodeName string
+	// Owner is only set for Pods that are managed by a controller
+	Owner *Owner
+
+	ips        []string
+	containers []container
+}
+
+// Owner of a Pod. Pods owned by a ReplicaSet are reported as owned by the ReplicaSet's
+// Deployment, if any.
+type Owner struct {
+	Kind string
+	Name string
+}
+
+type container struct {
+	id   string
+	name string
+}
+
+// ContainerName returns the name of the Pod container with the given ID, or
+// an empty string if it is not found.
+func (i *Info) ContainerName(containerID string) string {
+	for _, c := range i.containers {
+		if c.id == containerID {
+			return c.name
+		}
+	}
+	return ""
 }
 
 var indexers = cache.Indexers{
@@ -71,17 +119,45 @@ var indexers = cache.Indexers{
 	},
 }
 
+var podIndexers = cache.Indexers{
+	IndexIPOrName: indexers[IndexIPOrName],
+	IndexContainerID: func(obj interface{}) ([]string, error) {
+		oi := obj.(*Info)
+		cids := make([]string, 0, len(oi.containers))
+		for _, c := range oi.containers {
+			cids = append(cids, c.id)
+		}
+		return cids, nil
+	},
+}
+
 // GetInfo fetches metadata from Pod, Service given its IP or Service Name
 func (k *Metadata) GetInfo(ipOrName string) (*Info, bool) {
 	if info, ok := infoForIP(k.pods.GetIndexer(), ipOrName); ok {
 		return info, true
 	}
+	if k.services == nil {
+		return nil, false
+	}
 	if info, ok := infoForIP(k.services.GetIndexer(), ipOrName); ok {
 		return info, true
 	}
 	return nil, false
 }
 
+// GetContainerPod fetches metadata from the Pod that contains the given container ID
+func (k *Metadata) GetContainerPod(containerID string) (*Info, bool) {
+	objs, err := k.pods.GetIndexer().ByIndex(IndexContainerID, containerID)
+	if err != nil {
+		klog().Debug("error accessing index by container ID. Ignoring", "error", err, "containerID", containerID)
+		return nil, false
+	}
+	if len(objs) == 0 {
+		return nil, false
+	}
+	return objs[0].(*Info), true
+}
+
 func infoForIP(idx cache.Indexer, ip string) (*Info, bool) {
 	objs, err := idx.ByIndex(IndexIPOrName, ip)
 	if err != nil {
@@ -98,30 +174,10 @@ func (k *Metadata) initPodInformer(informerFactory informers.SharedInformerFacto
 	pods := informerFactory.Core().V1().Pods().Informer()
 	// Transform any *v1.Pod instance into a *Info instance to save space
 	// in the informer's cache
-	if err := pods.SetTransform(func(i interface{}) (interface{}, error) {
-		pod, ok := i.(*v1.Pod)
-		if !ok {
-			return nil, fmt.Errorf("was expecting a Pod. Got: %T", i)
-		}
-		ips := make([]string, 0, len(pod.Status.PodIPs))
-		for _, ip := range pod.Status.PodIPs {
-			// ignoring host-networked Pod IPs
-			if ip.IP != pod.Status.HostIP {
-				ips = append(ips, ip.IP)
-			}
-		}
-		return &Info{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      pod.Name,
-				Namespace: pod.Namespace,
-			},
-			Type: typePod,
-			ips:  ips,
-		}, nil
-	}); err != nil {
+	if err := pods.SetTransform(k.podTransform); err != nil {
 		return fmt.Errorf("can't set pods transform: %w", err)
 	}
-	if err := pods.AddIndexers(indexers); err != nil {
+	if err := pods.AddIndexers(podIndexers); err != nil {
 		return fmt.Errorf("can't add %s indexer to Pods informer: %w", IndexIPOrName, err)
 	}
 
@@ -129,6 +185,85 @@ func (k *Metadata) initPodInformer(informerFactory informers.SharedInformerFacto
 	return nil
 }
 
+// podTransform converts any *v1.Pod instance into a *Info instance
+func (k *Metadata) podTransform(i interface{}) (interface{}, error) {
+	pod, ok := i.(*v1.Pod)
+	if !ok {
+		return nil, fmt.Errorf("was expecting a Pod. Got: %T", i)
+	}
+	ips := make([]string, 0, len(pod.Status.PodIPs))
+	for _, ip := range pod.Status.PodIPs {
+		// ignoring host-networked Pod IPs
+		if ip.IP != pod.Status.HostIP {
+			ips = append(ips, ip.IP)
+		}
+	}
+	containers := make([]container, 0, len(pod.Status.ContainerStatuses))
+	for i := range pod.Status.ContainerStatuses {
+		cs := &pod.Status.ContainerStatuses[i]
+		if cs.ContainerID == "" {
+			continue
+		}
+		containers = append(containers, container{
+			id:   trimContainerIDScheme(cs.ContainerID),
+			name: cs.Name,
+		})
+	}
+	return &Info{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        pod.Name,
+			Namespace:   pod.Namespace,
+			UID:         pod.UID,
+			Labels:      filterKeys(pod.Labels, k.PodLabels),
+			Annotations: filterKeys(pod.Annotations, k.PodAnnotations),
+		},
+		Type:       typePod,
+		NodeName:   pod.Spec.NodeName,
+		Owner:      podOwner(pod),
+		ips:        ips,
+		containers: containers,
+	}, nil
+}
+
+// podOwner returns the controller of the Pod. For Pods owned by a ReplicaSet that has been
+// created by a Deployment, it returns the Deployment instead.
+func podOwner(pod *v1.Pod) *Owner {
+	ref := metav1.GetControllerOf(pod)
+	if ref == nil {
+		return nil
+	}
+	if ref.Kind == "ReplicaSet" {
+		if hash := pod.Labels[podTemplateHashLabel]; hash != "" && strings.HasSuffix(ref.Name, "-"+hash) {
+			return &Owner{Kind: "Deployment", Name: strings.TrimSuffix(ref.Name, "-"+hash)}
+		}
+	}
+	return &Owner{Kind: ref.Kind, Name: ref.Name}
+}
+
+// trimContainerIDScheme removes the runtime prefix from the container ID, as reported
+// by the Pod status (e.g. containerd://<id> or docker://<id>).
+func trimContainerIDScheme(containerID string) string {
+	if i := strings.Index(containerID, "://"); i >= 0 {
+		return containerID[i+len("://"):]
+	}
+	return containerID
+}
+
+// filterKeys returns a map containing only the entries from the source whose keys
+// are in the provided list, or nil if there is none.
+func filterKeys(src map[string]string, keys []string) map[string]string {
+	var dst map[string]string
+	for _, key := range keys {
+		if v, ok := src[key]; ok {
+			if dst == nil {
+				dst = make(map[string]string, len(keys))
+			}
+			dst[key] = v
+		}
+	}
+	return dst
+}
+
 func (k *Metadata) initServiceInformer(informerFactory informers.SharedInformerFactory) error {
 	services := informerFactory.Core().V1().Services().Informer()
 	// Transform any *v1.Service instance into a *Info instance to save space
@@ -210,22 +345,38 @@ func LoadConfig(kubeConfigPath string) (*rest.Config, error) {
 }
 
 func (k *Metadata) initInformers(client kubernetes.Interface, timeout time.Duration) error {
-	informerFactory := informers.NewSharedInformerFactory(client, syncTime)
-	err := k.initPodInformer(informerFactory)
-	if err != nil {
-		return err
+	log := klog()
+	// Pods and Services need different factories, as the field selector of the
+	// node-local Pods informer is not valid for Services
+	podsFactory := informers.NewSharedInformerFactory(client, syncTime)
+	if k.NodeName != "" {
+		log.Debug("restricting Pods informer to the local node", "node", k.NodeName)
+		podsFactory = informers.NewSharedInformerFactoryWithOptions(client, syncTime,
+			informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+				options.FieldSelector = fields.OneTermEqualSelector("spec.nodeName", k.NodeName).String()
+			}))
 	}
-	err = k.initServiceInformer(informerFactory)
-	if err != nil {
+	if err := k.initPodInformer(podsFactory); err != nil {
 		return err
 	}
+	factories := []informers.SharedInformerFactory{podsFactory}
+	if !k.DisableServices {
+		servicesFactory := informers.NewSharedInformerFactory(client, syncTime)
+		if err := k.initServiceInformer(servicesFactory); err != nil {
+			return err
+		}
+		factories = append(factories, servicesFactory)
+	}
 
-	log := klog()
 	log.Debug("starting kubernetes informers, waiting for syncronization")
-	informerFactory.Start(k.stopChan)
 	finishedCacheSync := make(chan struct{})
+	for _, factory := range factories {
+		factory.Start(k.stopChan)
+	}
 	go func() {
-		informerFactory.WaitForCacheSync(k.stopChan)
+		for _, factory := range factories {
+			factory.WaitForCacheSync(k.stopChan)
+		}
 		close(finishedCacheSync)
 	}()
 	select {
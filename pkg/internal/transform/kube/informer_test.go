@@ -4,8 +4,10 @@ import (
 	"os"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/tools/cache"
 )
@@ -85,3 +87,77 @@ func TestGetInfoByIP(t *testing.T) {
 		},
 	})
 }
+
+func TestPodTransform(t *testing.T) {
+	kubeData := Metadata{
+		PodLabels:      []string{"app", "version"},
+		PodAnnotations: []string{"team"},
+	}
+	isController := true
+	info, err := kubeData.podTransform(&v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "frontend-7c9f8d5b4-x2x8v",
+			Namespace: "shop",
+			UID:       "0a1b2c3d",
+			Labels: map[string]string{
+				"app":               "frontend",
+				"pod-template-hash": "7c9f8d5b4",
+			},
+			Annotations: map[string]string{
+				"team":     "web",
+				"ignored":  "value",
+				"whatever": "value",
+			},
+			OwnerReferences: []metav1.OwnerReference{{
+				Kind: "ReplicaSet", Name: "frontend-7c9f8d5b4", Controller: &isController,
+			}},
+		},
+		Spec: v1.PodSpec{NodeName: "node-1"},
+		Status: v1.PodStatus{
+			HostIP: "192.168.0.3",
+			PodIPs: []v1.PodIP{{IP: "10.0.0.7"}},
+			ContainerStatuses: []v1.ContainerStatus{
+				{Name: "frontend", ContainerID: "containerd://1234abcd"},
+				{Name: "sidecar", ContainerID: "docker://5678ef00"},
+				{Name: "waiting"},
+			},
+		},
+	})
+	require.NoError(t, err)
+	pod := info.(*Info)
+	assert.Equal(t, "frontend-7c9f8d5b4-x2x8v", pod.Name)
+	assert.Equal(t, "shop", pod.Namespace)
+	assert.EqualValues(t, "0a1b2c3d", pod.UID)
+	assert.Equal(t, "node-1", pod.NodeName)
+	assert.Equal(t, &Owner{Kind: "Deployment", Name: "frontend"}, pod.Owner)
+	assert.Equal(t, map[string]string{"app": "frontend"}, pod.Labels)
+	assert.Equal(t, map[string]string{"team": "web"}, pod.Annotations)
+	assert.Equal(t, []string{"10.0.0.7"}, pod.ips)
+	assert.Equal(t, "frontend", pod.ContainerName("1234abcd"))
+	assert.Equal(t, "sidecar", pod.ContainerName("5678ef00"))
+	assert.Empty(t, pod.ContainerName("containerd://1234abcd"))
+
+	cids, err := podIndexers[IndexContainerID](pod)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1234abcd", "5678ef00"}, cids)
+}
+
+func TestPodOwner(t *testing.T) {
+	isController := true
+	ownedBy := func(kind, name string, labels map[string]string) *v1.Pod {
+		return &v1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Labels: labels,
+			OwnerReferences: []metav1.OwnerReference{{
+				Kind: kind, Name: name, Controller: &isController,
+			}},
+		}}
+	}
+	assert.Nil(t, podOwner(&v1.Pod{}))
+	assert.Equal(t, &Owner{Kind: "StatefulSet", Name: "db"}, podOwner(ownedBy("StatefulSet", "db", nil)))
+	assert.Equal(t, &Owner{Kind: "DaemonSet", Name: "agent"}, podOwner(ownedBy("DaemonSet", "agent", nil)))
+	// ReplicaSets that are not managed by a Deployment
+	assert.Equal(t, &Owner{Kind: "ReplicaSet", Name: "standalone"},
+		podOwner(ownedBy("ReplicaSet", "standalone", nil)))
+	assert.Equal(t, &Owner{Kind: "ReplicaSet", Name: "standalone"},
+		podOwner(ownedBy("ReplicaSet", "standalone", map[string]string{"pod-template-hash": "abcde"})))
+}
@@ -0,0 +1,86 @@
+// Package process provides the attributes of the instrumented processes and their runtimes
+package process
+
+import (
+	"regexp"
+
+	"github.com/prometheus/procfs"
+
+	"github.com/grafana/beyla/pkg/internal/exec"
+	"github.com/grafana/beyla/pkg/internal/goexec"
+)
+
+// Resource attributes describing the instrumented process
+const (
+	ExecNameKey       = "process.executable.name"
+	ExecPathKey       = "process.executable.path"
+	RuntimeNameKey    = "process.runtime.name"
+	RuntimeVersionKey = "process.runtime.version"
+)
+
+// Runtime describes the language runtime of an instrumented process
+type Runtime struct {
+	Name     string
+	Version  string
+	Language string
+}
+
+// interpreters of the languages that Beyla can instrument generically. The version
+// is taken from the executable name, when available (e.g. python3.11)
+var interpreters = []struct {
+	exe      *regexp.Regexp
+	name     string
+	language string
+}{
+	{exe: regexp.MustCompile(`^java$`), name: "java", language: "java"},
+	{exe: regexp.MustCompile(`^python(\d+(\.\d+)*)?$`), name: "python", language: "python"},
+	{exe: regexp.MustCompile(`^node(js)?$`), name: "nodejs", language: "nodejs"},
+	{exe: regexp.MustCompile(`^ruby(\d+(\.\d+)*)?$`), name: "ruby", language: "ruby"},
+	{exe: regexp.MustCompile(`^php(-fpm)?(\d+(\.\d+)*)?$`), name: "php", language: "php"},
+	{exe: regexp.MustCompile(`^dotnet$`), name: "dotnet", language: "dotnet"},
+}
+
+var versionRegexp = regexp.MustCompile(`\d+(\.\d+)*$`)
+
+// DetectRuntime returns the runtime of the executable: the Go runtime, as stored in the
+// build information of Go executables, or the interpreter/virtual machine of other languages,
+// as deduced from the executable name.
+func DetectRuntime(fi *exec.FileInfo) (Runtime, bool) {
+	if fi.ELF != nil {
+		if version, err := goexec.GoVersion(fi.ELF); err == nil {
+			return Runtime{Name: "go", Version: version, Language: "go"}, true
+		}
+	}
+	exeName := fi.ExecutableName()
+	for _, in := range interpreters {
+		if in.exe.MatchString(exeName) {
+			return Runtime{
+				Name:     in.name,
+				Version:  versionRegexp.FindString(exeName),
+				Language: in.language,
+			}, true
+		}
+	}
+	return Runtime{}, false
+}
+
+// Decorate adds the attributes of the instrumented process and its runtime to
+// its service
+func Decorate(fi *exec.FileInfo) {
+	meta := map[string]string{
+		ExecNameKey: fi.ExecutableName(),
+		ExecPathKey: fi.CmdExePath,
+	}
+	if rt, ok := DetectRuntime(fi); ok {
+		meta[RuntimeNameKey] = rt.Name
+		if rt.Version != "" {
+			meta[RuntimeVersionKey] = rt.Version
+		}
+		fi.Service.SDKLanguage = rt.Language
+	}
+	fi.Service.AddMetadata(meta)
+	if proc, err := procfs.NewProc(int(fi.Pid)); err == nil {
+		// errors are ignored, as the command args are not essential
+		fi.Service.CommandArgs, _ = proc.CmdLine()
+	}
+}
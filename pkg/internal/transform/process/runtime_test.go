@@ -0,0 +1,28 @@
+package process
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/grafana/beyla/pkg/internal/exec"
+)
+
+func TestDetectRuntime(t *testing.T) {
+	for _, tc := range []struct {
+		exe string
+		rt  Runtime
+		ok  bool
+	}{
+		{exe: "/usr/lib/jvm/bin/java", rt: Runtime{Name: "java", Language: "java"}, ok: true},
+		{exe: "/usr/bin/python3.11", rt: Runtime{Name: "python", Version: "3.11", Language: "python"}, ok: true},
+		{exe: "/usr/local/bin/node", rt: Runtime{Name: "nodejs", Language: "nodejs"}, ok: true},
+		{exe: "/usr/sbin/php-fpm8.2", rt: Runtime{Name: "php", Version: "8.2", Language: "php"}, ok: true},
+		{exe: "/usr/sbin/nginx"},
+		{exe: "/opt/javaagent"},
+	} {
+		rt, ok := DetectRuntime(&exec.FileInfo{CmdExePath: tc.exe})
+		assert.Equal(t, tc.ok, ok, tc.exe)
+		assert.Equal(t, tc.rt, rt, tc.exe)
+	}
+}
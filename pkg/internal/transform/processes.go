@@ -0,0 +1,113 @@
+package transform
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/simplelru"
+	"github.com/mariomac/pipes/pkg/node"
+	"github.com/prometheus/procfs"
+
+	"github.com/grafana/beyla/pkg/internal/discover/services"
+	"github.com/grafana/beyla/pkg/internal/exec"
+	"github.com/grafana/beyla/pkg/internal/request"
+	"github.com/grafana/beyla/pkg/internal/svc"
+	"github.com/grafana/beyla/pkg/internal/transform/process"
+)
+
+func plog() *slog.Logger {
+	return slog.With("component", "transform.ProcessDecorator")
+}
+
+// ProcessDecorator enables the decoration of the spans with the attributes of the process
+// that generated them and its runtime. The processes that are selected by the discovery
+// criteria are decorated when they are instrumented, but in system-wide mode the processes
+// are not known until their spans are traced.
+type ProcessDecorator bool
+
+func (d ProcessDecorator) Enabled() bool {
+	return bool(d)
+}
+
+func ProcessDecoratorProvider(_ ProcessDecorator) (node.MiddleFunc[[]request.Span, []request.Span], error) {
+	decorator := newProcessDecorator()
+	return func(in <-chan []request.Span, out chan<- []request.Span) {
+		plog().Debug("starting process decoration loop")
+		for spans := range in {
+			for i := range spans {
+				decorator.decorateService(&spans[i].ServiceID)
+			}
+			out <- spans
+		}
+		plog().Debug("stopping process decoration loop")
+	}, nil
+}
+
+type processDecorator struct {
+	// services caches the process metadata of the service instances, by PID
+	services *simplelru.LRU[int32, *processMetadata]
+}
+
+// processMetadata is the metadata of an instrumented process
+type processMetadata struct {
+	attrs       map[string]string
+	language    string
+	commandArgs []string
+	// lastLookup is the time of the last search for the process, so it is retried if not found
+	lastLookup time.Time
+}
+
+func newProcessDecorator() *processDecorator {
+	pd := &processDecorator{}
+	pd.services, _ = simplelru.NewLRU[int32, *processMetadata](serviceMetadataCacheLen, nil)
+	return pd
+}
+
+// decorateService adds the metadata of the service process. If the service already has
+// metadata from a previous decorator, both are merged.
+func (pd *processDecorator) decorateService(id *svc.ID) {
+	if id.ProcPID == 0 {
+		return
+	}
+	meta, ok := pd.services.Get(id.ProcPID)
+	if !ok || time.Since(meta.lastLookup) > serviceMetadataTTL ||
+		(meta.attrs == nil && time.Since(meta.lastLookup) > serviceMetadataRetry) {
+		meta = lookupProcess(id.ProcPID)
+		pd.services.Add(id.ProcPID, meta)
+	}
+	id.AddMetadata(meta.attrs)
+	if id.SDKLanguage == "" {
+		id.SDKLanguage = meta.language
+	}
+	if id.CommandArgs == nil {
+		id.CommandArgs = meta.commandArgs
+	}
+}
+
+func lookupProcess(pid int32) *processMetadata {
+	meta := &processMetadata{lastLookup: time.Now()}
+	log := plog().With("pid", pid)
+	proc, err := procfs.NewProc(int(pid))
+	if err != nil {
+		log.Debug("can't find the service process", "error", err)
+		return meta
+	}
+	exePath, err := proc.Executable()
+	if err != nil {
+		log.Debug("can't get the executable of the service process", "error", err)
+		return meta
+	}
+	fi, err := exec.FindExecELF(&services.ProcessInfo{Pid: pid, ExePath: exePath}, svc.ID{})
+	if err != nil {
+		// the runtime of non-Go executables is still known from the executable name
+		log.Debug("can't open the executable of the service process", "error", err)
+		fi = &exec.FileInfo{Pid: pid, CmdExePath: exePath}
+	} else {
+		defer fi.ELF.Close()
+	}
+	process.Decorate(fi)
+	meta.attrs = fi.Service.Metadata
+	meta.language = fi.Service.SDKLanguage
+	meta.commandArgs = fi.Service.CommandArgs
+	return meta
+}
@@ -0,0 +1,40 @@
+package transform
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/beyla/pkg/internal/svc"
+	"github.com/grafana/beyla/pkg/internal/transform/process"
+)
+
+func TestProcessDecorator(t *testing.T) {
+	exePath, err := os.Executable()
+	require.NoError(t, err)
+
+	pd := newProcessDecorator()
+	// the test executable is a Go program
+	id := svc.ID{Name: "transform.test", ProcPID: int32(os.Getpid()), Metadata: map[string]string{"k8s.pod.name": "foo"}}
+	pd.decorateService(&id)
+	assert.Equal(t, exePath, id.Metadata[process.ExecPathKey])
+	assert.Equal(t, "go", id.Metadata[process.RuntimeNameKey])
+	assert.NotEmpty(t, id.Metadata[process.RuntimeVersionKey])
+	assert.Equal(t, "foo", id.Metadata["k8s.pod.name"])
+	assert.Equal(t, "go", id.SDKLanguage)
+	assert.Equal(t, os.Args, id.CommandArgs)
+
+	// the metadata is cached
+	assert.Equal(t, 1, pd.services.Len())
+	id = svc.ID{Name: "transform.test", ProcPID: int32(os.Getpid())}
+	pd.decorateService(&id)
+	assert.Equal(t, exePath, id.Metadata[process.ExecPathKey])
+
+	// spans without a process are not decorated
+	id = svc.ID{Name: "unknown"}
+	pd.decorateService(&id)
+	assert.Empty(t, id.Metadata)
+	assert.Equal(t, 1, pd.services.Len())
+}
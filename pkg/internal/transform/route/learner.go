@@ -0,0 +1,181 @@
+package route
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// LearnedParam is the path segment that replaces a high-cardinality path folder in the
+// learned route templates
+const LearnedParam = "{param}"
+
+// Learner builds, for each service, a tree of the observed URL paths. When a given path
+// folder position has observed more distinct values than the configured threshold, all
+// the values are collapsed into a single LearnedParam wildcard, so routes like
+// /users/john/orders and /users/mary/orders end up in the same /users/{param}/orders template.
+type Learner struct {
+	threshold int
+
+	mt       sync.RWMutex
+	services map[string]*learnNode
+}
+
+// learnNode is a path folder in the tree of observed paths
+type learnNode struct {
+	// End is true if any observed path ended at this node
+	End bool
+	// Child nodes for each distinct path folder. It will be emptied once the node
+	// is collapsed into the Param wildcard node
+	Child map[string]*learnNode
+	// Param, if not nil, matches any path folder
+	Param *learnNode
+}
+
+func newLearnNode() *learnNode {
+	return &learnNode{Child: map[string]*learnNode{}}
+}
+
+// NewLearner creates a Learner that will collapse a path folder position into a
+// LearnedParam wildcard when it has observed more than the given threshold of distinct values.
+func NewLearner(threshold int) *Learner {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &Learner{threshold: threshold, services: map[string]*learnNode{}}
+}
+
+// Learn accounts the URL path for the given service and returns the route template
+// that it currently matches.
+func (l *Learner) Learn(service, path string) string {
+	if path == "" {
+		return ""
+	}
+	tokens := tokenize(StripQuery(path))
+	if len(tokens) > maxSegments {
+		tokens = tokens[:maxSegments]
+	}
+
+	l.mt.Lock()
+	defer l.mt.Unlock()
+	root, ok := l.services[service]
+	if !ok {
+		root = newLearnNode()
+		l.services[service] = root
+	}
+	route := make([]string, 0, len(tokens))
+	n := root
+	for _, token := range tokens {
+		var segment string
+		n, segment = l.walk(n, token)
+		route = append(route, segment)
+	}
+	n.End = true
+	return "/" + strings.Join(route, "/")
+}
+
+// walk returns the child node for the given path folder, creating it if necessary, as well
+// as the route segment that it represents
+func (l *Learner) walk(n *learnNode, token string) (*learnNode, string) {
+	if child, ok := n.Child[token]; ok {
+		return child, token
+	}
+	if n.Param != nil {
+		return n.Param, LearnedParam
+	}
+	if token != LearnedParam && len(n.Child) < l.threshold {
+		child := newLearnNode()
+		n.Child[token] = child
+		return child, token
+	}
+	// the threshold of distinct values has been reached at this folder position:
+	// collapsing all the known children into a parameter node
+	n.Param = newLearnNode()
+	for _, child := range n.Child {
+		l.merge(n.Param, child)
+	}
+	n.Child = map[string]*learnNode{}
+	return n.Param, LearnedParam
+}
+
+// merge the src subtree into the dst subtree, collapsing any of the resulting nodes
+// that exceed the distinct values threshold.
+func (l *Learner) merge(dst, src *learnNode) {
+	dst.End = dst.End || src.End
+	for token, srcChild := range src.Child {
+		var dstChild *learnNode
+		if dst.Param != nil {
+			dstChild = dst.Param
+		} else if c, ok := dst.Child[token]; ok {
+			dstChild = c
+		} else {
+			dstChild, _ = l.walk(dst, token)
+		}
+		l.merge(dstChild, srcChild)
+	}
+	if src.Param != nil {
+		if dst.Param == nil {
+			dst.Param = newLearnNode()
+			for _, child := range dst.Child {
+				l.merge(dst.Param, child)
+			}
+			dst.Child = map[string]*learnNode{}
+		}
+		l.merge(dst.Param, src.Param)
+	}
+}
+
+// Seed adds the provided route templates (e.g. previously persisted Patterns) to the
+// learned tree of the given service.
+func (l *Learner) Seed(service string, routes []string) {
+	l.mt.Lock()
+	defer l.mt.Unlock()
+	root, ok := l.services[service]
+	if !ok {
+		root = newLearnNode()
+		l.services[service] = root
+	}
+	for _, route := range routes {
+		n := root
+		for _, token := range tokenize(route) {
+			if token == LearnedParam {
+				if n.Param == nil {
+					// forcing the collapse of the node
+					n, _ = l.walk(n, LearnedParam)
+				} else {
+					n = n.Param
+				}
+				continue
+			}
+			n, _ = l.walk(n, token)
+		}
+		n.End = true
+	}
+}
+
+// Patterns returns, for each service, the sorted list of the route templates that
+// have been learned so far.
+func (l *Learner) Patterns() map[string][]string {
+	l.mt.RLock()
+	defer l.mt.RUnlock()
+	patterns := make(map[string][]string, len(l.services))
+	for service, root := range l.services {
+		var routes []string
+		collectRoutes(root, nil, &routes)
+		sort.Strings(routes)
+		patterns[service] = routes
+	}
+	return patterns
+}
+
+func collectRoutes(n *learnNode, prefix []string, routes *[]string) {
+	if n.End {
+		*routes = append(*routes, "/"+strings.Join(prefix, "/"))
+	}
+	for token, child := range n.Child {
+		collectRoutes(child, append(prefix, token), routes)
+	}
+	if n.Param != nil {
+		collectRoutes(n.Param, append(prefix, LearnedParam), routes)
+	}
+}
@@ -0,0 +1,62 @@
+package route
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLearner(t *testing.T) {
+	l := NewLearner(3)
+	assert.Equal(t, "/users/john/orders", l.Learn("svc", "/users/john/orders"))
+	assert.Equal(t, "/users/mary/orders", l.Learn("svc", "/users/mary/orders"))
+	assert.Equal(t, "/users/anna", l.Learn("svc", "/users/anna"))
+	assert.Equal(t, "/health", l.Learn("svc", "/health"))
+	// threshold is exceeded: the users' IDs are collapsed
+	assert.Equal(t, "/users/{param}/orders", l.Learn("svc", "/users/peter/orders"))
+	assert.Equal(t, "/users/{param}/orders", l.Learn("svc", "/users/john/orders"))
+	assert.Equal(t, "/users/{param}", l.Learn("svc", "/users/mike"))
+	assert.Equal(t, "/health", l.Learn("svc", "/health"))
+
+	// the trees of other services are not affected
+	assert.Equal(t, "/users/john/orders", l.Learn("other", "/users/john/orders"))
+
+	assert.Equal(t, map[string][]string{
+		"svc":   {"/health", "/users/{param}", "/users/{param}/orders"},
+		"other": {"/users/john/orders"},
+	}, l.Patterns())
+}
+
+func TestLearner_MergesCollapsedSubtrees(t *testing.T) {
+	l := NewLearner(2)
+	l.Learn("svc", "/items/1/parts/a")
+	l.Learn("svc", "/items/2/parts/b")
+	l.Learn("svc", "/items/3/parts/c")
+	// after merging all the items, the parts of the subtree also exceed the threshold
+	assert.Equal(t, map[string][]string{
+		"svc": {"/items/{param}/parts/{param}"},
+	}, l.Patterns())
+	assert.Equal(t, "/items/{param}/parts/{param}", l.Learn("svc", "/items/4/parts/d"))
+}
+
+func TestLearner_Seed(t *testing.T) {
+	l := NewLearner(5)
+	l.Seed("svc", []string{"/users/{param}/orders", "/health"})
+	assert.Equal(t, "/users/{param}/orders", l.Learn("svc", "/users/john/orders"))
+	assert.Equal(t, "/health", l.Learn("svc", "/health"))
+	assert.Equal(t, "/metrics", l.Learn("svc", "/metrics"))
+	assert.Equal(t, map[string][]string{
+		"svc": {"/health", "/metrics", "/users/{param}/orders"},
+	}, l.Patterns())
+}
+
+func TestLearner_BoundedCardinality(t *testing.T) {
+	l := NewLearner(10)
+	for i := 0; i < 1000; i++ {
+		l.Learn("svc", fmt.Sprintf("/products/%d/reviews/%d", i, i*7))
+	}
+	assert.Equal(t, map[string][]string{
+		"svc": {"/products/{param}/reviews/{param}"},
+	}, l.Patterns())
+}
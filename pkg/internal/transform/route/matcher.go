@@ -1,6 +1,7 @@
 package route
 
 import (
+	"fmt"
 	"regexp"
 	"strings"
 )
@@ -8,8 +9,17 @@ import (
 // wildcard format. By now, we will suppport wildcards in the form:
 // - /user/:userId/details (Gin)
 // - /user/{userId}/details (Gorilla)
+// - /user/*/details (glob)
 // More formats will be appended at some point
-var wildcard = regexp.MustCompile(`^((:\w*)|(\{\w*}))$`)
+var wildcard = regexp.MustCompile(`^((:[^/}]+)|(\{[^/}:]+})|\*)$`)
+
+// regexpWildcard matches path folders that are restricted by a regular expression,
+// in the form /user/{userId:[0-9]+}/details
+var regexpWildcard = regexp.MustCompile(`^\{([^/}:]+):(.+)}$`)
+
+// catchAll, when used as the last folder of a pattern, matches any number of the
+// remaining path folders (including none). E.g. /static/**
+const catchAll = "**"
 
 // Matcher allows matching a given URL path towards a set of framework-like provided
 // patterns.
@@ -26,59 +36,120 @@ type node struct {
 	// Child nodes for a given path folder
 	Child map[string]*node
 
+	// Regexps contains the child subtrees that match path folders that fulfill a regular expression.
+	// They are evaluated in the same order as they were defined.
+	Regexps []regexpChild
+
 	// Wildcard is a child subtree that, if not nil, matches any path folder
 	Wildcard *node
+
+	// CatchAll is the full route that matches any number of remaining path folders, if not empty.
+	CatchAll string
+}
+
+type regexpChild struct {
+	re   *regexp.Regexp
+	node *node
 }
 
 // NewMatcher creates a new Matcher that would allow validating given URL paths towards
 // the provided set of routes
-func NewMatcher(routes []string) Matcher {
+func NewMatcher(routes []string) (Matcher, error) {
 	m := Matcher{root: &node{Child: map[string]*node{}}}
 	for _, route := range routes {
-		appendRoute(route, tokenize(route), m.root)
+		if err := appendRoute(route, tokenize(route), m.root); err != nil {
+			return Matcher{}, fmt.Errorf("invalid route %q: %w", route, err)
+		}
 	}
-	return m
+	return m, nil
 }
 
 // Find the router pattern that would match a given URL path, or empty if no pattern
-// matches it.
+// matches it. The query string of the URL, if any, is ignored.
 func (rm *Matcher) Find(path string) string {
-	return find(tokenize(path), rm.root)
+	return find(tokenize(StripQuery(path)), rm.root)
+}
+
+// StripQuery removes the query string and the fragment from the provided URL path
+func StripQuery(path string) string {
+	if i := strings.IndexAny(path, "?#"); i >= 0 {
+		return path[:i]
+	}
+	return path
 }
 
 func find(path []string, pathNode *node) string {
 	// if we walked all the path tokens and this node resolves to a full route, it matched a path
 	// (if FullRoute is empty, it means it didn't match)
 	if len(path) == 0 {
-		return pathNode.FullRoute
+		if pathNode.FullRoute != "" {
+			return pathNode.FullRoute
+		}
+		return pathNode.CatchAll
 	}
 	// if the current path resolved to an explicit path folder, keep searching through the
 	// child node
 	if child, ok := pathNode.Child[path[0]]; ok {
-		return find(path[1:], child)
+		if route := find(path[1:], child); route != "" {
+			return route
+		}
+	}
+	// otherwise (or if the rest of the path didn't match from the explicit folder), keep
+	// searching through the regular expression children
+	for _, rc := range pathNode.Regexps {
+		if rc.re.MatchString(path[0]) {
+			if route := find(path[1:], rc.node); route != "" {
+				return route
+			}
+		}
 	}
 	// otherwise, keep searching through the wildcard child, if any
 	if pathNode.Wildcard != nil {
-		return find(path[1:], pathNode.Wildcard)
+		if route := find(path[1:], pathNode.Wildcard); route != "" {
+			return route
+		}
 	}
-	return ""
+	return pathNode.CatchAll
 }
 
-func appendRoute(fullRoute string, path []string, pathNode *node) {
+func appendRoute(fullRoute string, path []string, pathNode *node) error {
 	// if we walked all the path tokens, the current node resolves to the full route
 	if len(path) == 0 {
 		pathNode.FullRoute = fullRoute
-		return
+		return nil
 	}
 	currentName := path[0]
+	// a catch-all token matches the rest of the path. It is only accepted at the end of the route
+	if currentName == catchAll {
+		if len(path) > 1 {
+			return fmt.Errorf("%s is only accepted as the last element of the route", catchAll)
+		}
+		pathNode.CatchAll = fullRoute
+		return nil
+	}
 	// if the current token is a wildcard, add it as a child and keep processing the
 	// wildcard node
 	if wildcard.MatchString(currentName) {
 		if pathNode.Wildcard == nil {
 			pathNode.Wildcard = &node{Child: map[string]*node{}}
 		}
-		appendRoute(fullRoute, path[1:], pathNode.Wildcard)
-		return
+		return appendRoute(fullRoute, path[1:], pathNode.Wildcard)
+	}
+	// if the current token is a regular expression, reuse the node of any other route that
+	// has been defined with the same expression, or add it otherwise
+	if sm := regexpWildcard.FindStringSubmatch(currentName); sm != nil {
+		re, err := regexp.Compile("^(?:" + sm[2] + ")$")
+		if err != nil {
+			return fmt.Errorf("invalid regular expression in %q: %w", currentName, err)
+		}
+		for _, rc := range pathNode.Regexps {
+			if rc.re.String() == re.String() {
+				return appendRoute(fullRoute, path[1:], rc.node)
+			}
+		}
+		child := &node{Child: map[string]*node{}}
+		pathNode.Regexps = append(pathNode.Regexps, regexpChild{re: re, node: child})
+		return appendRoute(fullRoute, path[1:], child)
 	}
 	// keep processing the child node belonging to the current path token, adding it
 	// if it does not yet exist
@@ -87,7 +158,7 @@ func appendRoute(fullRoute string, path []string, pathNode *node) {
 		child = &node{Child: map[string]*node{}}
 		pathNode.Child[currentName] = child
 	}
-	appendRoute(fullRoute, path[1:], child)
+	return appendRoute(fullRoute, path[1:], child)
 }
 
 // tokenizes and normalizes the resulting slice, so we make sure
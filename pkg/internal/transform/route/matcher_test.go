@@ -4,25 +4,107 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestFind(t *testing.T) {
-	m := NewMatcher([]string{
+	m, err := NewMatcher([]string{
 		"/foo/bar/bae/",
 		"/foo/:id",
 		"/foo/{id}/push",
 		"/"})
+	require.NoError(t, err)
 
 	assert.Equal(t, "/", m.Find("/"))
 	assert.Equal(t, "/foo/bar/bae/", m.Find("/foo/bar/bae"))
 	assert.Equal(t, "/foo/:id", m.Find("/foo/1234"))
 	assert.Equal(t, "/foo/:id", m.Find("/foo/someId"))
 	assert.Equal(t, "/foo/{id}/push", m.Find("/foo/5678/push"))
+	// the explicit bar folder does not resolve to a route, so it falls back to the wildcard
+	assert.Equal(t, "/foo/:id", m.Find("/foo/bar"))
 
 	assert.Empty(t, m.Find("/foo"))
-	assert.Empty(t, m.Find("/foo/bar"))
 	assert.Empty(t, m.Find("/foo/bar/bae/baz"))
 	assert.Empty(t, m.Find("/traca"))
 	assert.Empty(t, m.Find("/foo/1234/down"))
 	assert.Empty(t, m.Find("/foo/5678/push/up"))
 }
+
+func TestFind_Backtracking(t *testing.T) {
+	m, err := NewMatcher([]string{
+		"/users/me/profile",
+		"/users/{id}/orders",
+		"/items/{id:[0-9]+}/details",
+		"/items/{name}/reviews"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "/users/me/profile", m.Find("/users/me/profile"))
+	assert.Equal(t, "/users/{id}/orders", m.Find("/users/me/orders"))
+	assert.Equal(t, "/users/{id}/orders", m.Find("/users/1234/orders"))
+	assert.Equal(t, "/items/{id:[0-9]+}/details", m.Find("/items/123/details"))
+	assert.Equal(t, "/items/{name}/reviews", m.Find("/items/123/reviews"))
+
+	assert.Empty(t, m.Find("/users/1234/profile"))
+	assert.Empty(t, m.Find("/users/me"))
+}
+
+func TestFind_Glob(t *testing.T) {
+	m, err := NewMatcher([]string{
+		"/static/**",
+		"/users/*/orders",
+		"/users/me",
+		"/**"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "/static/**", m.Find("/static"))
+	assert.Equal(t, "/static/**", m.Find("/static/css/main.css"))
+	assert.Equal(t, "/users/*/orders", m.Find("/users/1234/orders"))
+	assert.Equal(t, "/users/me", m.Find("/users/me"))
+	assert.Equal(t, "/**", m.Find("/users/1234"))
+	assert.Equal(t, "/**", m.Find("/"))
+	assert.Equal(t, "/**", m.Find("/traca/trucu"))
+
+	_, err = NewMatcher([]string{"/static/**/foo"})
+	assert.Error(t, err)
+}
+
+func TestFind_Regexp(t *testing.T) {
+	m, err := NewMatcher([]string{
+		"/users/{id:[0-9]+}",
+		"/users/{id:[0-9]+}/orders/{order:[a-f0-9]{4}}",
+		"/users/{name}/orders",
+		"/users/{name}"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "/users/{id:[0-9]+}", m.Find("/users/1234"))
+	assert.Equal(t, "/users/{name}", m.Find("/users/john"))
+	assert.Equal(t, "/users/{id:[0-9]+}/orders/{order:[a-f0-9]{4}}", m.Find("/users/12/orders/be3f"))
+	// regular expressions are fully anchored to the path folder
+	assert.Equal(t, "/users/{name}", m.Find("/users/12a"))
+	// if the regular expression subtree does not match, the wildcard subtree is evaluated
+	assert.Equal(t, "/users/{name}/orders", m.Find("/users/12/orders"))
+	assert.Empty(t, m.Find("/users/12/orders/xxxx"))
+
+	_, err = NewMatcher([]string{"/users/{id:[0-9+}"})
+	assert.Error(t, err)
+}
+
+func TestFind_ParamNames(t *testing.T) {
+	m, err := NewMatcher([]string{
+		"/orgs/{org-id}/members/:member.name",
+		"/repos/{repo.name:[a-z]+}"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "/orgs/{org-id}/members/:member.name", m.Find("/orgs/grafana/members/john"))
+	assert.Equal(t, "/repos/{repo.name:[a-z]+}", m.Find("/repos/beyla"))
+	assert.Empty(t, m.Find("/repos/beyla2"))
+}
+
+func TestFind_QueryString(t *testing.T) {
+	m, err := NewMatcher([]string{"/users/{id}", "/search"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "/users/{id}", m.Find("/users/1234?details=true"))
+	assert.Equal(t, "/search", m.Find("/search?q=foo/bar"))
+	assert.Equal(t, "/search", m.Find("/search#results"))
+}
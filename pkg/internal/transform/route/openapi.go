@@ -0,0 +1,156 @@
+package route
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const openAPIFetchTimeout = 10 * time.Second
+
+// OpenAPIRoute is an HTTP operation as defined in an OpenAPI 3 or Swagger 2 specification
+type OpenAPIRoute struct {
+	// Pattern of the route, in a format that is accepted by the Matcher
+	Pattern string
+	// Method of the operation, in uppercase
+	Method string
+	// OperationID of the operation. It might be empty if not defined in the specification.
+	OperationID string
+}
+
+// openAPISpec contains the parts of the OpenAPI 3 and Swagger 2 specifications that are
+// relevant to us
+type openAPISpec struct {
+	Swagger  string `yaml:"swagger"`
+	OpenAPI  string `yaml:"openapi"`
+	BasePath string `yaml:"basePath"`
+	Servers  []struct {
+		URL string `yaml:"url"`
+	} `yaml:"servers"`
+	Paths map[string]map[string]any `yaml:"paths"`
+}
+
+// the keys of a path item object that define operations
+var openAPIMethods = map[string]struct{}{
+	"get": {}, "put": {}, "post": {}, "delete": {}, "options": {}, "head": {}, "patch": {}, "trace": {},
+}
+
+// LoadOpenAPI reads the routes from an OpenAPI 3 or Swagger 2 specification, in JSON or
+// YAML format, from a local file or an HTTP(S) URL.
+func LoadOpenAPI(location string) ([]OpenAPIRoute, error) {
+	content, err := readOpenAPI(location)
+	if err != nil {
+		return nil, fmt.Errorf("reading OpenAPI specification %s: %w", location, err)
+	}
+	routes, err := ParseOpenAPI(content)
+	if err != nil {
+		return nil, fmt.Errorf("parsing OpenAPI specification %s: %w", location, err)
+	}
+	return routes, nil
+}
+
+func readOpenAPI(location string) ([]byte, error) {
+	if !strings.HasPrefix(location, "http://") && !strings.HasPrefix(location, "https://") {
+		return os.ReadFile(location)
+	}
+	client := http.Client{Timeout: openAPIFetchTimeout}
+	resp, err := client.Get(location)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected HTTP status: %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// ParseOpenAPI extracts the routes from the content of an OpenAPI 3 or Swagger 2 specification,
+// in JSON or YAML format. The returned routes are prefixed by the base path of the API
+// (Swagger 2 basePath or the path of the OpenAPI 3 servers' URLs).
+func ParseOpenAPI(content []byte) ([]OpenAPIRoute, error) {
+	// JSON is a subset of YAML, so we can parse both formats with the YAML parser
+	spec := openAPISpec{}
+	if err := yaml.Unmarshal(content, &spec); err != nil {
+		return nil, err
+	}
+	if spec.Swagger == "" && spec.OpenAPI == "" {
+		return nil, errors.New("missing 'openapi' or 'swagger' version field")
+	}
+	basePaths := spec.basePaths()
+	var routes []OpenAPIRoute
+	for path, item := range spec.Paths {
+		for method, op := range item {
+			if _, ok := openAPIMethods[strings.ToLower(method)]; !ok {
+				continue
+			}
+			operationID := ""
+			if opFields, ok := op.(map[string]any); ok {
+				operationID, _ = opFields["operationId"].(string)
+			}
+			for _, basePath := range basePaths {
+				routes = append(routes, OpenAPIRoute{
+					Pattern:     joinPaths(basePath, path),
+					Method:      strings.ToUpper(method),
+					OperationID: operationID,
+				})
+			}
+		}
+	}
+	// sorting for the sake of predictability
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Pattern == routes[j].Pattern {
+			return routes[i].Method < routes[j].Method
+		}
+		return routes[i].Pattern < routes[j].Pattern
+	})
+	return routes, nil
+}
+
+func (s *openAPISpec) basePaths() []string {
+	if s.Swagger != "" {
+		return []string{s.BasePath}
+	}
+	if len(s.Servers) == 0 {
+		return []string{""}
+	}
+	seen := map[string]struct{}{}
+	var paths []string
+	for _, server := range s.Servers {
+		path := serverURLPath(server.URL)
+		if _, ok := seen[path]; !ok {
+			seen[path] = struct{}{}
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// serverURLPath returns the path of an OpenAPI 3 server URL. We don't use url.Parse because
+// server URLs can contain variables (e.g. https://{region}.example.com/{version}), and can be
+// relative (e.g. /v1).
+func serverURLPath(serverURL string) string {
+	if i := strings.Index(serverURL, "://"); i >= 0 {
+		serverURL = serverURL[i+len("://"):]
+		if i = strings.IndexByte(serverURL, '/'); i >= 0 {
+			return serverURL[i:]
+		}
+		return ""
+	}
+	return serverURL
+}
+
+func joinPaths(basePath, path string) string {
+	basePath = strings.TrimSuffix(basePath, "/")
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return basePath + path
+}
@@ -0,0 +1,62 @@
+package route
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOpenAPI_V3(t *testing.T) {
+	routes, err := ParseOpenAPI([]byte(`
+openapi: 3.0.1
+servers:
+  - url: https://{region}.example.com/api/v1
+  - url: /api/v1
+paths:
+  /users/{id}:
+    parameters:
+      - name: id
+        in: path
+    get:
+      operationId: getUser
+    delete:
+      summary: deletes an user
+  /users:
+    post:
+      operationId: createUser
+`))
+	require.NoError(t, err)
+	assert.Equal(t, []OpenAPIRoute{
+		{Pattern: "/api/v1/users", Method: "POST", OperationID: "createUser"},
+		{Pattern: "/api/v1/users/{id}", Method: "DELETE"},
+		{Pattern: "/api/v1/users/{id}", Method: "GET", OperationID: "getUser"},
+	}, routes)
+}
+
+func TestParseOpenAPI_V2(t *testing.T) {
+	routes, err := ParseOpenAPI([]byte(`{
+  "swagger": "2.0",
+  "basePath": "/v2/",
+  "paths": {
+    "/pet/{petId}": {
+      "get": {"operationId": "getPetById"},
+      "put": {"operationId": "updatePet"}
+    }
+  }
+}`))
+	require.NoError(t, err)
+	assert.Equal(t, []OpenAPIRoute{
+		{Pattern: "/v2/pet/{petId}", Method: "GET", OperationID: "getPetById"},
+		{Pattern: "/v2/pet/{petId}", Method: "PUT", OperationID: "updatePet"},
+	}, routes)
+}
+
+func TestParseOpenAPI_Errors(t *testing.T) {
+	_, err := ParseOpenAPI([]byte(`paths: {}`))
+	assert.Error(t, err)
+	_, err = ParseOpenAPI([]byte(`{"openapi": `))
+	assert.Error(t, err)
+	_, err = LoadOpenAPI("/this/file/does/not/exist.yaml")
+	assert.Error(t, err)
+}
@@ -2,11 +2,14 @@
 package transform
 
 import (
+	"fmt"
 	"log/slog"
+	"maps"
 
 	"github.com/mariomac/pipes/pkg/node"
 
 	"github.com/grafana/beyla/pkg/internal/request"
+	"github.com/grafana/beyla/pkg/internal/svc"
 	"github.com/grafana/beyla/pkg/internal/transform/route"
 )
 
@@ -22,63 +25,225 @@ const (
 	UnmatchWildcard = UnmatchType("wildcard")
 	// UnmatchHeuristic detects the route field using a heuristic
 	UnmatchHeuristic = UnmatchType("heuristic")
+	// UnmatchLearn sets the route field from the route templates that are learned
+	// from the observed traffic of each service
+	UnmatchLearn = UnmatchType("learn")
 
 	UnmatchDefault = UnmatchWildcard
 )
 
 const wildCard = "/**"
 
+// OperationIDKey is the span metadata key of the operation ID of the routes that have been
+// imported from OpenAPI specifications
+const OperationIDKey = "openapi.operation_id"
+
 // RoutesConfig allows grouping URLs sharing a given pattern.
 type RoutesConfig struct {
 	// Unmatch specifies what to do when a route pattern is not matched
 	Unmatch UnmatchType `yaml:"unmatch"`
 	// Patterns of the paths that will match to a route
 	Patterns []string `yaml:"patterns"`
+	// OpenAPISpecs is a list of OpenAPI 3 or Swagger 2 specification files or URLs, whose paths
+	// will be added to the Patterns.
+	OpenAPISpecs []string `yaml:"openapi_specs"`
+	// Services allows overriding the Patterns and the Unmatch policy for a given set of services.
+	// The services that do not match any entry will use the above global Patterns and Unmatch
+	// properties.
+	Services []ServiceRoutesConfig `yaml:"services"`
+	// Learn configures the route learning when Unmatch is set to "learn"
+	Learn LearnConfig `yaml:"learn"`
+}
+
+// ServiceRoutesConfig specifies the route patterns for the services matching the
+// provided name, namespace and discovery services' criteria entry.
+type ServiceRoutesConfig struct {
+	// Name of the service, as defined in the discovery services' criteria or, if not defined there,
+	// the name of the instrumented executable. If unset, services with any name will match.
+	Name string `yaml:"name"`
+	// Namespace of the service. If unset, services from any namespace will match.
+	Namespace string `yaml:"namespace"`
+	// DiscoveryEntry is the position, starting at 1, of the discovery services' criteria entry
+	// that selected the service. If unset, services selected by any entry will match.
+	DiscoveryEntry int `yaml:"discovery_entry"`
+	// Unmatch specifies what to do when a route pattern is not matched. If unset, it will
+	// take the value of the global RoutesConfig Unmatch property.
+	Unmatch UnmatchType `yaml:"unmatch"`
+	// Patterns of the paths that will match to a route
+	Patterns []string `yaml:"patterns"`
+	// OpenAPISpecs is a list of OpenAPI 3 or Swagger 2 specification files or URLs, whose paths
+	// will be added to the Patterns.
+	OpenAPISpecs []string `yaml:"openapi_specs"`
+}
+
+func (sr *ServiceRoutesConfig) matches(id *svc.ID) bool {
+	return (sr.Name == "" || sr.Name == id.Name) &&
+		(sr.Namespace == "" || sr.Namespace == id.Namespace) &&
+		(sr.DiscoveryEntry == 0 || sr.DiscoveryEntry == id.DiscoveryEntry)
+}
+
+// routerKey identifies the services that are routed by the same router
+type routerKey struct {
+	name           string
+	namespace      string
+	discoveryEntry int
+}
+
+// router matches the route of the spans of a given service
+type router struct {
+	matcher route.Matcher
+	unmatch func(span *request.Span)
+	// operations maps each "METHOD route" to its OpenAPI operation ID, if any
+	operations map[string]string
+}
+
+func (r *router) do(span *request.Span) {
+	span.Route = r.matcher.Find(span.Path)
+	if span.Route != "" && len(r.operations) > 0 {
+		if opID, ok := r.operations[span.Method+" "+span.Route]; ok {
+			// the metadata map might be shared with other spans, so it is copied before being modified
+			metadata := make(map[string]string, len(span.Metadata)+1)
+			maps.Copy(metadata, span.Metadata)
+			metadata[OperationIDKey] = opID
+			span.Metadata = metadata
+		}
+	}
+	r.unmatch(span)
+}
+
+// routers selects the router of each span according to its service
+type routers struct {
+	cfg *RoutesConfig
+	// global router, for the services that aren't explicitly configured
+	global router
+	// services' routers, in the same order as in RoutesConfig.Services
+	services []router
+	// bySvc caches the router that has been selected for each service name, namespace
+	// and discovery entry
+	bySvc map[routerKey]*router
+	// learner is only instantiated if any of the routers uses the UnmatchLearn policy
+	learner *routesLearner
 }
 
 func RoutesProvider(rc *RoutesConfig) (node.MiddleFunc[[]request.Span, []request.Span], error) {
-	// set default value for Unmatch action
-	var unmatchAction func(span *request.Span)
-	switch rc.Unmatch {
-	case UnmatchWildcard, "":
-		unmatchAction = setUnmatchToWildcard
-
-		if len(rc.Patterns) == 0 {
-			slog.With("component", "RoutesProvider").
-				Warn("No route match patterns configured. " +
-					"Without route definitions Beyla will not be able to generate a low cardinality " +
-					"route for trace span names. For optimal experience, please define your application " +
-					"HTTP route patterns or enable the route 'heuristic' mode. " +
-					"For more information please see the documentation at: " +
-					"https://grafana.com/docs/grafana-cloud/monitor-applications/beyla/configure/options/#routes-decorator. " +
-					"If your application is only using gRPC you can ignore this warning.")
+	rs, err := newRouters(rc)
+	if err != nil {
+		return nil, err
+	}
+	return func(in <-chan []request.Span, out chan<- []request.Span) {
+		if rs.learner != nil {
+			stop := rs.learner.start()
+			defer stop()
+		}
+		for spans := range in {
+			for i := range spans {
+				rs.forService(&spans[i].ServiceID).do(&spans[i])
+			}
+			out <- spans
 		}
+	}, nil
+}
+
+func newRouters(rc *RoutesConfig) (*routers, error) {
+	rs := &routers{cfg: rc, bySvc: map[routerKey]*router{}}
+	var err error
+	if rs.global, err = rs.newRouter(rc.Unmatch, rc.Patterns, rc.OpenAPISpecs); err != nil {
+		return nil, err
+	}
+	if (rc.Unmatch == UnmatchWildcard || rc.Unmatch == "") &&
+		len(rc.Patterns) == 0 && len(rc.OpenAPISpecs) == 0 && len(rc.Services) == 0 {
+		slog.With("component", "RoutesProvider").
+			Warn("No route match patterns configured. " +
+				"Without route definitions Beyla will not be able to generate a low cardinality " +
+				"route for trace span names. For optimal experience, please define your application " +
+				"HTTP route patterns or enable the route 'heuristic' mode. " +
+				"For more information please see the documentation at: " +
+				"https://grafana.com/docs/grafana-cloud/monitor-applications/beyla/configure/options/#routes-decorator. " +
+				"If your application is only using gRPC you can ignore this warning.")
+	}
+	for i := range rc.Services {
+		sr := &rc.Services[i]
+		unmatch := sr.Unmatch
+		if unmatch == "" {
+			unmatch = rc.Unmatch
+		}
+		r, err := rs.newRouter(unmatch, sr.Patterns, sr.OpenAPISpecs)
+		if err != nil {
+			return nil, fmt.Errorf("routes for service %s/%s: %w", sr.Namespace, sr.Name, err)
+		}
+		rs.services = append(rs.services, r)
+	}
+	return rs, nil
+}
+
+func (rs *routers) newRouter(unmatch UnmatchType, patterns, openAPISpecs []string) (router, error) {
+	r := router{}
+	if len(openAPISpecs) > 0 {
+		// copying the patterns slice to not modify the configuration
+		patterns = append([]string{}, patterns...)
+		r.operations = map[string]string{}
+		for _, spec := range openAPISpecs {
+			routes, err := route.LoadOpenAPI(spec)
+			if err != nil {
+				return router{}, err
+			}
+			for i := range routes {
+				patterns = append(patterns, routes[i].Pattern)
+				if routes[i].OperationID != "" {
+					r.operations[routes[i].Method+" "+routes[i].Pattern] = routes[i].OperationID
+				}
+			}
+		}
+	}
+	var err error
+	if r.matcher, err = route.NewMatcher(patterns); err != nil {
+		return router{}, err
+	}
+	switch unmatch {
+	case UnmatchWildcard, "":
+		r.unmatch = setUnmatchToWildcard
 	case UnmatchUnset:
-		unmatchAction = leaveUnmatchEmpty
+		r.unmatch = leaveUnmatchEmpty
 	case UnmatchPath:
-		unmatchAction = setUnmatchToPath
-	case UnmatchHeuristic: // default
-		err := route.InitAutoClassifier()
-		if err != nil {
-			return nil, err
+		r.unmatch = setUnmatchToPath
+	case UnmatchHeuristic:
+		if err := route.InitAutoClassifier(); err != nil {
+			return router{}, err
+		}
+		r.unmatch = classifyFromPath
+	case UnmatchLearn:
+		if rs.learner == nil {
+			rs.learner, err = newRoutesLearner(&rs.cfg.Learn)
+			if err != nil {
+				return router{}, err
+			}
 		}
-		unmatchAction = classifyFromPath
+		r.unmatch = rs.learner.classify
 	default:
 		slog.With("component", "RoutesProvider").
 			Warn("invalid 'unmatch' value in configuration, defaulting to '"+string(UnmatchDefault)+"'",
-				"value", rc.Unmatch)
-		unmatchAction = setUnmatchToWildcard
+				"value", unmatch)
+		r.unmatch = setUnmatchToWildcard
 	}
-	matcher := route.NewMatcher(rc.Patterns)
-	return func(in <-chan []request.Span, out chan<- []request.Span) {
-		for spans := range in {
-			for i := range spans {
-				spans[i].Route = matcher.Find(spans[i].Path)
-				unmatchAction(&spans[i])
-			}
-			out <- spans
+	return r, nil
+}
+
+// forService returns the router for the given service: the router from the first matching
+// RoutesConfig.Services entry, or the global router if none matches
+func (rs *routers) forService(id *svc.ID) *router {
+	key := routerKey{name: id.Name, namespace: id.Namespace, discoveryEntry: id.DiscoveryEntry}
+	if r, ok := rs.bySvc[key]; ok {
+		return r
+	}
+	r := &rs.global
+	for i := range rs.cfg.Services {
+		if rs.cfg.Services[i].matches(id) {
+			r = &rs.services[i]
+			break
 		}
-	}, nil
+	}
+	rs.bySvc[key] = r
+	return r
 }
 
 func leaveUnmatchEmpty(_ *request.Span) {}
@@ -91,12 +256,12 @@ func setUnmatchToWildcard(str *request.Span) {
 
 func setUnmatchToPath(str *request.Span) {
 	if str.Route == "" {
-		str.Route = str.Path
+		str.Route = route.StripQuery(str.Path)
 	}
 }
 
 func classifyFromPath(s *request.Span) {
 	if s.Route == "" && (s.Type == request.EventTypeHTTP || s.Type == request.EventTypeHTTPClient) {
-		s.Route = route.ClusterPath(s.Path)
+		s.Route = route.ClusterPath(route.StripQuery(s.Path))
 	}
 }
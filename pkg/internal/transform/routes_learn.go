@@ -0,0 +1,189 @@
+package transform
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/grafana/beyla/pkg/internal/request"
+	"github.com/grafana/beyla/pkg/internal/transform/route"
+)
+
+const (
+	defaultLearnThreshold       = 10
+	defaultLearnPath            = "/routes"
+	defaultLearnPersistInterval = time.Minute
+)
+
+func llog() *slog.Logger {
+	return slog.With("component", "transform.RoutesLearner")
+}
+
+// LearnConfig configures the inference of route templates from the observed traffic.
+type LearnConfig struct {
+	// Threshold of distinct values that a path folder position can have before being
+	// collapsed into a {param} wildcard. Defaults to 10.
+	Threshold int `yaml:"threshold"`
+	// Port of the HTTP endpoint that exports the learned route templates. If unset, the
+	// endpoint is disabled.
+	Port int `yaml:"port"`
+	// Path of the HTTP endpoint that exports the learned route templates. Defaults to /routes
+	Path string `yaml:"path"`
+	// PersistFile is the path of a file where the learned route templates will be stored,
+	// so they can be loaded again after a restart. If unset, the learned templates are
+	// kept only in memory.
+	PersistFile string `yaml:"persist_file"`
+	// PersistInterval specifies how often the learned route templates are stored in the
+	// PersistFile. Defaults to 1 minute.
+	PersistInterval time.Duration `yaml:"persist_interval"`
+}
+
+type routesLearner struct {
+	cfg     *LearnConfig
+	learner *route.Learner
+}
+
+func newRoutesLearner(lc *LearnConfig) (*routesLearner, error) {
+	cfg := *lc
+	if cfg.Threshold <= 0 {
+		cfg.Threshold = defaultLearnThreshold
+	}
+	if cfg.Path == "" {
+		cfg.Path = defaultLearnPath
+	}
+	if cfg.PersistInterval <= 0 {
+		cfg.PersistInterval = defaultLearnPersistInterval
+	}
+	rl := &routesLearner{
+		cfg:     &cfg,
+		learner: route.NewLearner(cfg.Threshold),
+	}
+	if err := rl.load(); err != nil {
+		return nil, fmt.Errorf("loading learned routes: %w", err)
+	}
+	return rl, nil
+}
+
+// start the HTTP endpoint and the periodic persistence of the learned routes, if configured.
+// The returned function stops them.
+func (rl *routesLearner) start() func() {
+	log := llog()
+	var server *http.Server
+	if rl.cfg.Port != 0 {
+		server = rl.startHTTP(log)
+	}
+	var done chan struct{}
+	if rl.cfg.PersistFile != "" {
+		done = make(chan struct{})
+		go rl.persistPeriodically(log, done)
+	}
+	return func() {
+		if server != nil {
+			_ = server.Close()
+		}
+		if done != nil {
+			close(done)
+			rl.persist(log)
+		}
+	}
+}
+
+// classify sets the route of the unmatched HTTP spans from the learned route templates
+func (rl *routesLearner) classify(span *request.Span) {
+	if span.Route == "" && (span.Type == request.EventTypeHTTP || span.Type == request.EventTypeHTTPClient) {
+		span.Route = rl.learner.Learn(span.ServiceID.String(), span.Path)
+	}
+}
+
+// load the route templates that might have been persisted in a previous execution
+func (rl *routesLearner) load() error {
+	if rl.cfg.PersistFile == "" {
+		return nil
+	}
+	content, err := os.ReadFile(rl.cfg.PersistFile)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			llog().Debug("learned routes file does not exist yet", "file", rl.cfg.PersistFile)
+			return nil
+		}
+		return err
+	}
+	var patterns map[string][]string
+	if err := json.Unmarshal(content, &patterns); err != nil {
+		return fmt.Errorf("parsing %s: %w", rl.cfg.PersistFile, err)
+	}
+	for service, routes := range patterns {
+		rl.learner.Seed(service, routes)
+	}
+	return nil
+}
+
+func (rl *routesLearner) persistPeriodically(log *slog.Logger, done <-chan struct{}) {
+	ticker := time.NewTicker(rl.cfg.PersistInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			rl.persist(log)
+		}
+	}
+}
+
+// persist stores the learned routes into a temporary file that replaces the
+// persist file, to avoid leaving it corrupted if Beyla is stopped in the middle.
+func (rl *routesLearner) persist(log *slog.Logger) {
+	content, err := json.Marshal(rl.learner.Patterns())
+	if err != nil {
+		log.Warn("can't serialize learned routes", "error", err)
+		return
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(rl.cfg.PersistFile), filepath.Base(rl.cfg.PersistFile)+".*")
+	if err != nil {
+		log.Warn("can't create learned routes file", "error", err)
+		return
+	}
+	if _, err := tmp.Write(content); err != nil {
+		log.Warn("can't write learned routes file", "file", tmp.Name(), "error", err)
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return
+	}
+	_ = tmp.Close()
+	if err := os.Rename(tmp.Name(), rl.cfg.PersistFile); err != nil {
+		log.Warn("can't replace learned routes file", "file", rl.cfg.PersistFile, "error", err)
+		_ = os.Remove(tmp.Name())
+		return
+	}
+	log.Debug("stored learned routes", "file", rl.cfg.PersistFile)
+}
+
+func (rl *routesLearner) startHTTP(log *slog.Logger) *http.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc(rl.cfg.Path, rl.serveHTTP)
+	server := &http.Server{Addr: fmt.Sprintf(":%d", rl.cfg.Port), Handler: mux}
+	log = log.With("port", rl.cfg.Port, "path", rl.cfg.Path)
+	log.Info("opening learned routes endpoint")
+	go func() {
+		err := server.ListenAndServe()
+		if errors.Is(err, http.ErrServerClosed) {
+			log.Debug("HTTP server was closed", "error", err)
+		} else {
+			log.Error("HTTP service ended unexpectedly", "error", err)
+		}
+	}()
+	return server
+}
+
+func (rl *routesLearner) serveHTTP(rw http.ResponseWriter, _ *http.Request) {
+	rw.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(rw).Encode(rl.learner.Patterns()); err != nil {
+		llog().Debug("can't write learned routes response", "error", err)
+	}
+}
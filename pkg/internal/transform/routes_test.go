@@ -1,6 +1,8 @@
 package transform
 
 import (
+	"os"
+	"path"
 	"testing"
 	"time"
 
@@ -8,6 +10,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/grafana/beyla/pkg/internal/request"
+	"github.com/grafana/beyla/pkg/internal/svc"
 	"github.com/grafana/beyla/pkg/internal/testutil"
 )
 
@@ -136,3 +139,183 @@ func benchProvider(b *testing.B, unmatch UnmatchType) {
 		<-outCh
 	}
 }
+
+func TestUnmatchedLearn(t *testing.T) {
+	persistFile := path.Join(t.TempDir(), "routes.json")
+	router, err := RoutesProvider(&RoutesConfig{
+		Unmatch:  UnmatchLearn,
+		Patterns: []string{"/user/:id"},
+		Learn:    LearnConfig{Threshold: 2, PersistFile: persistFile},
+	})
+	require.NoError(t, err)
+	in, out := make(chan []request.Span, 10), make(chan []request.Span, 10)
+	finished := make(chan struct{})
+	go func() {
+		router(in, out)
+		close(finished)
+	}()
+	in <- []request.Span{{Path: "/user/1234", Type: request.EventTypeHTTP}}
+	assert.Equal(t, "/user/:id", testutil.ReadChannel(t, out, testTimeout)[0].Route)
+	in <- []request.Span{
+		{Path: "/customer/john/jobs", Type: request.EventTypeHTTP},
+		{Path: "/customer/mary/jobs", Type: request.EventTypeHTTP},
+		{Path: "/customer/anna/jobs", Type: request.EventTypeHTTP},
+		{Path: "/customer/mike/jobs", Type: request.EventTypeHTTPClient},
+	}
+	spans := testutil.ReadChannel(t, out, testTimeout)
+	assert.Equal(t, "/customer/john/jobs", spans[0].Route)
+	assert.Equal(t, "/customer/mary/jobs", spans[1].Route)
+	assert.Equal(t, "/customer/{param}/jobs", spans[2].Route)
+	assert.Equal(t, "/customer/{param}/jobs", spans[3].Route)
+
+	// when the node is stopped, the learned routes are persisted
+	close(in)
+	testutil.ReadChannel(t, finished, testTimeout)
+	content, err := os.ReadFile(persistFile)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"":["/customer/{param}/jobs"]}`, string(content))
+
+	// a new node starts from the previously persisted routes
+	router, err = RoutesProvider(&RoutesConfig{
+		Unmatch: UnmatchLearn,
+		Learn:   LearnConfig{Threshold: 2, PersistFile: persistFile},
+	})
+	require.NoError(t, err)
+	in, out = make(chan []request.Span, 10), make(chan []request.Span, 10)
+	defer close(in)
+	go router(in, out)
+	in <- []request.Span{{Path: "/customer/peter/jobs", Type: request.EventTypeHTTP}}
+	assert.Equal(t, "/customer/{param}/jobs", testutil.ReadChannel(t, out, testTimeout)[0].Route)
+}
+
+func TestServiceRoutes(t *testing.T) {
+	router, err := RoutesProvider(&RoutesConfig{
+		Unmatch:  UnmatchWildcard,
+		Patterns: []string{"/user/:id"},
+		Services: []ServiceRoutesConfig{{
+			Name:     "products",
+			Unmatch:  UnmatchPath,
+			Patterns: []string{"/product/{id:[0-9]+}", "/static/**"},
+		}, {
+			Name:      "users",
+			Namespace: "prod",
+			Patterns:  []string{"/user/{name}/info"},
+		}},
+	})
+	require.NoError(t, err)
+	in, out := make(chan []request.Span, 10), make(chan []request.Span, 10)
+	defer close(in)
+	go router(in, out)
+	in <- []request.Span{
+		{Path: "/user/1234", ServiceID: svc.ID{Name: "other"}},
+		{Path: "/product/1234?color=red", ServiceID: svc.ID{Name: "other"}},
+		{Path: "/product/1234?color=red", ServiceID: svc.ID{Name: "products"}},
+		{Path: "/product/abc?color=red", ServiceID: svc.ID{Name: "products", Namespace: "foo"}},
+		{Path: "/static/img/logo.png", ServiceID: svc.ID{Name: "products"}},
+		{Path: "/user/1234", ServiceID: svc.ID{Name: "users", Namespace: "prod"}},
+		{Path: "/user/john/info", ServiceID: svc.ID{Name: "users", Namespace: "prod"}},
+		{Path: "/user/john/info", ServiceID: svc.ID{Name: "users", Namespace: "dev"}},
+	}
+	var routes []string
+	for _, s := range testutil.ReadChannel(t, out, testTimeout) {
+		routes = append(routes, s.Route)
+	}
+	assert.Equal(t, []string{
+		"/user/:id",
+		"/**",
+		"/product/{id:[0-9]+}",
+		"/product/abc",
+		"/static/**",
+		"/**",
+		"/user/{name}/info",
+		"/**",
+	}, routes)
+}
+
+func TestServiceRoutes_DiscoveryEntry(t *testing.T) {
+	router, err := RoutesProvider(&RoutesConfig{
+		Unmatch: UnmatchWildcard,
+		Services: []ServiceRoutesConfig{{
+			DiscoveryEntry: 2,
+			Patterns:       []string{"/item/{id}"},
+		}, {
+			Name:     "backend",
+			Patterns: []string{"/user/{id}"},
+		}},
+	})
+	require.NoError(t, err)
+	in, out := make(chan []request.Span, 10), make(chan []request.Span, 10)
+	defer close(in)
+	go router(in, out)
+	in <- []request.Span{
+		{Path: "/item/1234", ServiceID: svc.ID{Name: "backend", DiscoveryEntry: 1}},
+		{Path: "/user/1234", ServiceID: svc.ID{Name: "backend", DiscoveryEntry: 1}},
+		{Path: "/item/1234", ServiceID: svc.ID{Name: "backend", DiscoveryEntry: 2}},
+		{Path: "/user/1234", ServiceID: svc.ID{Name: "backend", DiscoveryEntry: 2}},
+		{Path: "/item/1234", ServiceID: svc.ID{Name: "frontend", DiscoveryEntry: 2}},
+	}
+	var routes []string
+	for _, s := range testutil.ReadChannel(t, out, testTimeout) {
+		routes = append(routes, s.Route)
+	}
+	assert.Equal(t, []string{
+		"/**",
+		"/user/{id}",
+		"/item/{id}",
+		"/**",
+		"/item/{id}",
+	}, routes)
+}
+
+func TestServiceRoutes_InvalidPattern(t *testing.T) {
+	_, err := RoutesProvider(&RoutesConfig{
+		Services: []ServiceRoutesConfig{{Name: "foo", Patterns: []string{"/product/{id:[0-9+}"}}},
+	})
+	assert.Error(t, err)
+}
+
+func TestOpenAPIRoutes(t *testing.T) {
+	spec := path.Join(t.TempDir(), "openapi.yml")
+	require.NoError(t, os.WriteFile(spec, []byte(`
+openapi: 3.0.1
+servers:
+  - url: http://localhost:8080/api
+paths:
+  /users/{id}:
+    get:
+      operationId: getUser
+    put: {}
+`), 0644))
+	router, err := RoutesProvider(&RoutesConfig{
+		Patterns:     []string{"/health"},
+		OpenAPISpecs: []string{spec},
+	})
+	require.NoError(t, err)
+	in, out := make(chan []request.Span, 10), make(chan []request.Span, 10)
+	defer close(in)
+	go router(in, out)
+	shared := map[string]string{"foo": "bar"}
+	in <- []request.Span{
+		{Method: "GET", Path: "/api/users/123"},
+		{Method: "PUT", Path: "/api/users/123"},
+		{Method: "GET", Path: "/health"},
+		{Method: "GET", Path: "/api/users/456", Metadata: shared},
+		{Method: "GET", Path: "/health", Metadata: shared},
+	}
+	assert.Equal(t, []request.Span{
+		{Method: "GET", Path: "/api/users/123", Route: "/api/users/{id}",
+			Metadata: map[string]string{OperationIDKey: "getUser"}},
+		{Method: "PUT", Path: "/api/users/123", Route: "/api/users/{id}"},
+		{Method: "GET", Path: "/health", Route: "/health"},
+		{Method: "GET", Path: "/api/users/456", Route: "/api/users/{id}",
+			Metadata: map[string]string{"foo": "bar", OperationIDKey: "getUser"}},
+		{Method: "GET", Path: "/health", Route: "/health", Metadata: map[string]string{"foo": "bar"}},
+	}, testutil.ReadChannel(t, out, testTimeout))
+	// the metadata that is shared between spans must not be modified
+	assert.Equal(t, map[string]string{"foo": "bar"}, shared)
+
+	_, err = RoutesProvider(&RoutesConfig{
+		Services: []ServiceRoutesConfig{{Name: "foo", OpenAPISpecs: []string{"/does/not/exist.json"}}},
+	})
+	assert.Error(t, err)
+}